@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -35,18 +36,19 @@ type options struct {
 }
 
 func (o *options) parse() error {
-	var registryDir string
+	var registryDir, versionsDir string
 
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 
 	fs.StringVar(&registryDir, "registry", "", "Path to the step registry directory")
+	fs.StringVar(&versionsDir, "registry-versions", "", "Optional directory with versions of the step registry tests can pin components to")
 	o.Options.Bind(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return fmt.Errorf("failed to parse flags: %w", err)
 	}
 
-	if err := o.loadResolver(registryDir); err != nil {
+	if err := o.loadResolver(registryDir, versionsDir); err != nil {
 		return fmt.Errorf("failed to load registry: %w", err)
 	}
 	ciOPConfigAgent, err := agents.NewConfigAgent(o.ConfigDir, agents.WithOrg(o.Org), agents.WithRepo(o.Repo))
@@ -102,7 +104,7 @@ func (o *options) validate() (ret []error) {
 	return append(ret, validateTags(seen)...)
 }
 
-func (o *options) loadResolver(path string) error {
+func (o *options) loadResolver(path, versionsPath string) error {
 	if path == "" {
 		return nil
 	}
@@ -110,7 +112,13 @@ func (o *options) loadResolver(path string) error {
 	if err != nil {
 		return err
 	}
-	o.resolver = registry.NewResolver(refs, chains, workflows, observers)
+	var versions registry.VersionByName
+	if versionsPath != "" {
+		if versions, err = load.RegistryVersions(versionsPath, load.RegistryFlag(0), nil); err != nil {
+			return err
+		}
+	}
+	o.resolver = registry.NewVersionedResolver(refs, chains, workflows, observers, versions)
 	return nil
 }
 
@@ -124,6 +132,8 @@ func (o *options) validateConfiguration(
 			return err
 		} else if err := validator.IsValidResolvedConfiguration(&c); err != nil {
 			return err
+		} else {
+			warnDeprecated(c, time.Now())
 		}
 	}
 	if _, err := o.ciOPConfigAgent.GetMatchingConfig(configuration.Metadata); err != nil {
@@ -142,6 +152,22 @@ func (o *options) validateConfiguration(
 	return nil
 }
 
+// warnDeprecated reports the deprecated registry components a resolved
+// configuration uses, which are not an error until they are removed
+func warnDeprecated(configuration api.ReleaseBuildConfiguration, now time.Time) {
+	for _, test := range configuration.Tests {
+		if test.MultiStageTestConfigurationLiteral == nil {
+			continue
+		}
+		for _, component := range test.MultiStageTestConfigurationLiteral.Deprecated {
+			logrus.WithFields(logrus.Fields{
+				"config": configuration.Metadata.RelativePath(),
+				"test":   test.As,
+			}).Warn(component.Warning(now))
+		}
+	}
+}
+
 func validateTags(seen tagSet) []error {
 	var dupes []error
 	for tag, infos := range seen {
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -25,6 +26,8 @@ import (
 	"github.com/openshift/ci-tools/pkg/config"
 	"github.com/openshift/ci-tools/pkg/html"
 	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/provenance"
+	"github.com/openshift/ci-tools/pkg/registry/bundle"
 	registryserver "github.com/openshift/ci-tools/pkg/registry/server"
 	"github.com/openshift/ci-tools/pkg/webreg"
 )
@@ -32,6 +35,7 @@ import (
 type options struct {
 	configPath             string
 	registryPath           string
+	registryVersionsPath   string
 	logLevel               string
 	address                string
 	releaseRepoGitSyncPath string
@@ -41,6 +45,10 @@ type options struct {
 	gracePeriod            time.Duration
 	validateOnly           bool
 	flatRegistry           bool
+	snapshot               bool
+	bundlePath             string
+	signingKeyPath         string
+	stepGraphDir           string
 	instrumentationOptions flagutil.InstrumentationOptions
 }
 
@@ -53,6 +61,7 @@ func gatherOptions() (options, error) {
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	fs.StringVar(&o.configPath, "config", "", "Path to config dirs")
 	fs.StringVar(&o.registryPath, "registry", "", "Path to registry dirs")
+	fs.StringVar(&o.registryVersionsPath, "registry-versions", "", "Optional directory with versions of the registry, one subdirectory for each revision or version tag tests can pin components to.")
 	fs.StringVar(&o.releaseRepoGitSyncPath, "release-repo-git-sync-path", "", "Path to release repository dir")
 	fs.StringVar(&o.logLevel, "log-level", "info", "Level at which to log output.")
 	fs.StringVar(&o.address, "address", ":8080", "DEPRECATED: Address to run server on")
@@ -63,8 +72,17 @@ func gatherOptions() (options, error) {
 	_ = fs.Duration("cycle", time.Minute*2, "Legacy flag kept for compatibility. Does nothing")
 	fs.BoolVar(&o.validateOnly, "validate-only", false, "Load the config and registry, validate them and exit.")
 	fs.BoolVar(&o.flatRegistry, "flat-registry", false, "Disable directory structure based registry validation")
+	fs.StringVar(&o.stepGraphDir, "step-graph-dir", "", "Optional directory with step graphs of past jobs, used to show the median durations of steps in the registry UI.")
+	fs.StringVar(&o.bundlePath, "bundle", "", "Path to write the bundle to (for the snapshot command).")
+	fs.StringVar(&o.signingKeyPath, "signing-key", "", "Path to the PEM encoded ECDSA private key to sign the bundle with (for the snapshot command).")
 	o.instrumentationOptions.AddFlags(fs)
-	if err := fs.Parse(os.Args[1:]); err != nil {
+	args := os.Args[1:]
+	// `snapshot` writes a bundle of the configurations and the registry and exits
+	if len(args) > 0 && args[0] == "snapshot" {
+		o.snapshot = true
+		args = args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
 		return o, fmt.Errorf("failed to parse flags: %w", err)
 	}
 	return o, nil
@@ -114,9 +132,28 @@ func validateOptions(o *options) error {
 		o.registryPath = filepath.Join(o.releaseRepoGitSyncPath, config.RegistryPath)
 	}
 
+	if o.registryVersionsPath != "" {
+		if _, err := os.Stat(o.registryVersionsPath); err != nil {
+			return fmt.Errorf("error getting stat info for --registry-versions directory: %w", err)
+		}
+	}
+
 	if o.validateOnly && o.flatRegistry {
 		return errors.New("--validate-only and --flat-registry flags cannot be set simultaneously")
 	}
+	if o.snapshot {
+		if o.bundlePath == "" {
+			return errors.New("--bundle is required for the snapshot command")
+		}
+		if o.signingKeyPath == "" {
+			return errors.New("--signing-key is required for the snapshot command")
+		}
+		if o.validateOnly {
+			return errors.New("--validate-only cannot be set for the snapshot command")
+		}
+	} else if o.bundlePath != "" || o.signingKeyPath != "" {
+		return errors.New("--bundle and --signing-key can only be set for the snapshot command")
+	}
 	return o.instrumentationOptions.Validate(false)
 }
 
@@ -134,6 +171,48 @@ func getRegistryGeneration(agent agents.RegistryAgent) http.HandlerFunc {
 	}
 }
 
+// snapshot writes a signed bundle of what the agents loaded, one generation after the
+// bundle it replaces, so ci-operator can resolve configurations without us
+func snapshot(o options, configAgent agents.ConfigAgent, registryAgent agents.RegistryAgent) error {
+	signer, err := provenance.LoadSigner(o.signingKeyPath)
+	if err != nil {
+		return err
+	}
+	generation, err := bundle.NextGeneration(o.bundlePath, signer.Verifier())
+	if err != nil {
+		return err
+	}
+	if err := bundle.Write(o.bundlePath, bundle.New(configAgent, registryAgent, generation, time.Now()), signer); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"bundle": o.bundlePath, "generation": generation}).Info("Wrote bundle.")
+	return nil
+}
+
+// stepDurations periodically loads the median durations of steps from the step graphs
+// in the directory, keeping the last durations that could be loaded
+func stepDurations(dir string) func() webreg.StepDurations {
+	lock := &sync.RWMutex{}
+	var durations webreg.StepDurations
+	reload := func() {
+		loaded, err := webreg.LoadStepDurations(dir)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to load step durations.")
+			return
+		}
+		lock.Lock()
+		durations = loaded
+		lock.Unlock()
+		logrus.WithField("steps", len(loaded)).Info("Loaded step durations.")
+	}
+	interrupts.TickLiteral(reload, time.Hour)
+	return func() webreg.StepDurations {
+		lock.RLock()
+		defer lock.RUnlock()
+		return durations
+	}
+}
+
 // l and v keep the tree legible
 func l(fragment string, children ...simplifypath.Node) simplifypath.Node {
 	return simplifypath.L(fragment, children...)
@@ -182,7 +261,7 @@ func main() {
 		logrus.Fatalf("Failed to get config agent: %v", err)
 	}
 
-	registryAgent, err := agents.NewRegistryAgent(o.registryPath, agents.WithRegistryMetrics(configresolverMetrics.ErrorRate), agents.WithRegistryFlat(o.flatRegistry), registryAgentOption)
+	registryAgent, err := agents.NewRegistryAgent(o.registryPath, agents.WithRegistryMetrics(configresolverMetrics.ErrorRate), agents.WithRegistryFlat(o.flatRegistry), agents.WithRegistryVersions(o.registryVersionsPath), registryAgentOption)
 	if err != nil {
 		logrus.Fatalf("Failed to get registry agent: %v", err)
 	}
@@ -190,6 +269,16 @@ func main() {
 	if o.validateOnly {
 		os.Exit(0)
 	}
+	if o.snapshot {
+		if err := snapshot(o, configAgent, registryAgent); err != nil {
+			logrus.WithError(err).Fatal("Failed to snapshot the configurations and the registry")
+		}
+		os.Exit(0)
+	}
+	catalog, err := registryserver.NewCatalog(interrupts.Context(), configAgent, registryAgent, configresolverMetrics)
+	if err != nil {
+		logrus.Fatalf("Failed to create catalog: %v", err)
+	}
 	static, err := fs.Sub(html.StaticFS, html.StaticSubdir)
 	if err != nil {
 		logrus.WithError(err).Fatal("failed to open static subdirectory")
@@ -201,6 +290,9 @@ func main() {
 		l("resolve"),
 		l("configGeneration"),
 		l("registryGeneration"),
+		l("configs"),
+		l("search"),
+		l("deprecations"),
 	))
 
 	uisimplifier := simplifypath.NewSimplifier(l("", // shadow element mimicing the root
@@ -210,6 +302,7 @@ func main() {
 		l("reference"),
 		l("chain"),
 		l("workflow"),
+		l("explore"),
 	))
 	handler := metrics.TraceHandler(simplifier, configresolverMetrics.HTTPRequestDuration, configresolverMetrics.HTTPResponseSize)
 	uihandler := metrics.TraceHandler(uisimplifier, configresolverMetrics.HTTPRequestDuration, configresolverMetrics.HTTPResponseSize)
@@ -220,11 +313,20 @@ func main() {
 	http.HandleFunc("/resolve", handler(registryserver.ResolveLiteralConfig(registryAgent, configresolverMetrics)).ServeHTTP)
 	http.HandleFunc("/configGeneration", handler(getConfigGeneration(configAgent)).ServeHTTP)
 	http.HandleFunc("/registryGeneration", handler(getRegistryGeneration(registryAgent)).ServeHTTP)
+	http.HandleFunc("/configs", handler(catalog.ListConfigs()).ServeHTTP)
+	http.HandleFunc("/search", handler(catalog.Search()).ServeHTTP)
+	http.HandleFunc("/deprecations", handler(registryserver.Deprecations(configAgent, registryAgent, configresolverMetrics)).ServeHTTP)
+	// events need to be flushed as they happen, which the tracing handler does not support
+	http.HandleFunc("/watch", catalog.Watch())
 	http.HandleFunc("/readyz", func(_ http.ResponseWriter, _ *http.Request) {})
 	interrupts.ListenAndServe(&http.Server{Addr: ":" + strconv.Itoa(o.port)}, o.gracePeriod)
 	uiMux := http.NewServeMux()
 	uiMux.HandleFunc(html.StaticURL, handler(http.StripPrefix(html.StaticURL, http.FileServer(http.FS(static)))).ServeHTTP)
-	uiMux.Handle("/", uihandler(webreg.WebRegHandler(registryAgent, configAgent)))
+	var durations func() webreg.StepDurations
+	if o.stepGraphDir != "" {
+		durations = stepDurations(o.stepGraphDir)
+	}
+	uiMux.Handle("/", uihandler(webreg.WebRegHandler(registryAgent, configAgent, durations)))
 	uiServer := &http.Server{
 		Addr:    ":" + strconv.Itoa(o.uiPort),
 		Handler: uiMux,
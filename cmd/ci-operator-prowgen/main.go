@@ -33,6 +33,7 @@ type options struct {
 	toReleaseRepo bool
 
 	registryPath string
+	versionsPath string
 	resolver     registry.Resolver
 
 	help bool
@@ -48,6 +49,7 @@ func bindOptions(flag *flag.FlagSet) *options {
 	flag.BoolVar(&opt.toReleaseRepo, "to-release-repo", false, "If set, it behaves like --to-dir=$GOPATH/src/github.com/openshift/release/ci-operator/jobs")
 
 	flag.StringVar(&opt.registryPath, "registry", "", "Path to the step registry directory")
+	flag.StringVar(&opt.versionsPath, "registry-versions", "", "Optional directory with versions of the step registry tests can pin components to")
 
 	flag.BoolVar(&opt.help, "h", false, "Show help for ci-operator-prowgen")
 
@@ -88,15 +90,29 @@ func (o *options) process() error {
 		return fmt.Errorf("failed to complete config options: %w", err)
 	}
 	if o.registryPath != "" {
-		refs, chains, workflows, _, _, observers, err := load.Registry(o.registryPath, load.RegistryFlag(0))
-		if err != nil {
-			return fmt.Errorf("failed to load registry: %w", err)
+		if o.resolver, err = loadResolver(o.registryPath, o.versionsPath); err != nil {
+			return err
 		}
-		o.resolver = registry.NewResolver(refs, chains, workflows, observers)
 	}
 	return nil
 }
 
+// loadResolver loads the registry and, when versionsPath is set, the versions
+// of the registry tests can pin components to
+func loadResolver(registryPath, versionsPath string) (registry.Resolver, error) {
+	refs, chains, workflows, _, _, observers, err := load.Registry(registryPath, load.RegistryFlag(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry: %w", err)
+	}
+	var versions registry.VersionByName
+	if versionsPath != "" {
+		if versions, err = load.RegistryVersions(versionsPath, load.RegistryFlag(0), nil); err != nil {
+			return nil, fmt.Errorf("failed to load registry versions: %w", err)
+		}
+	}
+	return registry.NewVersionedResolver(refs, chains, workflows, observers, versions), nil
+}
+
 func readProwgenConfig(path string) (*config.Prowgen, error) {
 	var pConfig *config.Prowgen
 	b, err := ioutil.ReadFile(path)
@@ -304,3 +304,90 @@ tests:
 		})
 	}
 }
+
+func TestGenerateJobsWithPinnedWorkflow(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile := func(path, content string) {
+		path = filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Unexpected error creating dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0664); err != nil {
+			t.Fatalf("Unexpected error writing %s: %v", path, err)
+		}
+	}
+	writeRegistry := func(root, clusterProfile string) {
+		writeFile(filepath.Join(root, "ipi/aws/ipi-aws-workflow.yaml"), fmt.Sprintf(`workflow:
+  as: ipi-aws
+  steps:
+    cluster_profile: %s
+    test:
+    - ref: ipi-aws-install
+  documentation: The IPI workflow on AWS
+`, clusterProfile))
+		writeFile(filepath.Join(root, "ipi/aws/install/ipi-aws-install-ref.yaml"), `ref:
+  as: ipi-aws-install
+  from: installer
+  commands: ipi-aws-install-commands.sh
+  resources:
+    requests:
+      cpu: 1000m
+  documentation: The IPI install step
+`)
+		writeFile(filepath.Join(root, "ipi/aws/install/ipi-aws-install-commands.sh"), "openshift-install create cluster")
+	}
+	// the workflow runs on another cluster profile at HEAD than in the pinned version
+	writeRegistry("registry", "aws-2")
+	writeRegistry("versions/v3", "aws")
+	writeFile("config/super/duper/super-duper-branch.yaml", `build_root:
+  image_stream_tag:
+    name: release
+    namespace: openshift
+    tag: golang-1.10
+resources:
+  '*':
+    requests:
+      cpu: 10Mi
+tests:
+- as: e2e-aws
+  steps:
+    workflow: ipi-aws@v3
+`)
+
+	testCases := []struct {
+		name          string
+		versionsPath  string
+		expectedError bool
+	}{
+		{
+			name:         "pinned workflow is resolved from the registry version",
+			versionsPath: filepath.Join(tempDir, "versions"),
+		},
+		{
+			name:          "pinned workflow cannot be resolved without the registry versions",
+			expectedError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver, err := loadResolver(filepath.Join(tempDir, "registry"), tc.versionsPath)
+			if err != nil {
+				t.Fatalf("Unexpected error loading the registry: %v", err)
+			}
+			toDir := filepath.Join(tempDir, "jobs", strings.ReplaceAll(tc.name, " ", "-"))
+			o := options{fromDir: filepath.Join(tempDir, "config"), toDir: toDir, resolver: resolver}
+			err = o.generateJobsToDir("", map[string]*config.Prowgen{})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedError, err)
+			}
+			if tc.expectedError {
+				return
+			}
+			presubmitData, err := ioutil.ReadFile(filepath.Join(toDir, "super", "duper", "super-duper-branch-presubmits.yaml"))
+			if err != nil {
+				t.Fatalf("Unexpected error reading generated presubmits: %v", err)
+			}
+			testhelper.CompareWithFixture(t, presubmitData)
+		})
+	}
+}
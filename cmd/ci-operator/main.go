@@ -50,6 +50,7 @@ import (
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config/secret"
 	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/pod-utils/decorate"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"k8s.io/test-infra/prow/version"
 	utilpointer "k8s.io/utils/pointer"
@@ -77,7 +78,9 @@ import (
 	"github.com/openshift/ci-tools/pkg/junit"
 	"github.com/openshift/ci-tools/pkg/lease"
 	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/provenance"
 	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/registry/bundle"
 	"github.com/openshift/ci-tools/pkg/registry/server"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/secrets"
@@ -391,7 +394,13 @@ type options struct {
 	resolverAddress string
 	resolverClient  server.ResolverClient
 
+	resolverBundlePath          string
+	resolverBundlePublicKeyPath string
+	resolverBundleMinGeneration int64
+	resolverBundleVerifier      *provenance.Verifier
+
 	registryPath string
+	versionsPath string
 	org          string
 	repo         string
 	branch       string
@@ -407,6 +416,12 @@ type options struct {
 	pushSecretPath string
 	pushSecret     *coreapi.Secret
 
+	provenanceSigningKeyPath string
+	provenanceSigner         *provenance.Signer
+
+	provenancePublicKeyPath string
+	provenanceVerifier      *provenance.Verifier
+
 	uploadSecretPath string
 	uploadSecret     *coreapi.Secret
 
@@ -443,6 +458,7 @@ func bindOptions(flag *flag.FlagSet) *options {
 	flag.StringVar(&opt.leaseServerCredentialsFile, "lease-server-credentials-file", "", "The path to credentials file used to access the lease server. The content is of the form <username>:<password>.")
 	flag.DurationVar(&opt.leaseAcquireTimeout, "lease-acquire-timeout", leaseAcquireTimeout, "Maximum amount of time to wait for lease acquisition")
 	flag.StringVar(&opt.registryPath, "registry", "", "Path to the step registry directory")
+	flag.StringVar(&opt.versionsPath, "registry-versions", "", "Optional directory with versions of the step registry tests can pin components to")
 	flag.StringVar(&opt.configSpecPath, "config", "", "The configuration file. If not specified the CONFIG_SPEC environment variable or the configresolver will be used.")
 	flag.StringVar(&opt.unresolvedConfigPath, "unresolved-config", "", "The configuration file, before resolution. If not specified the UNRESOLVED_CONFIG environment variable will be used, if set.")
 	flag.Var(&opt.targets, "target", "One or more targets in the configuration to build. Only steps that are required for this target will be run.")
@@ -475,6 +491,9 @@ func bindOptions(flag *flag.FlagSet) *options {
 
 	// flags needed for the configresolver
 	flag.StringVar(&opt.resolverAddress, "resolver-address", configResolverAddress, "Address of configresolver")
+	flag.StringVar(&opt.resolverBundlePath, "resolver-bundle", "", "Resolve the configuration locally from a bundle written by the configresolver snapshot command instead of contacting the configresolver")
+	flag.StringVar(&opt.resolverBundlePublicKeyPath, "resolver-bundle-public-key", "", "Path to the PEM encoded ECDSA public key the bundle must be signed with (required with --resolver-bundle)")
+	flag.Int64Var(&opt.resolverBundleMinGeneration, "resolver-bundle-min-generation", 0, "Refuse bundles older than this generation")
 	flag.StringVar(&opt.org, "org", "", "Org of the project (used by configresolver)")
 	flag.StringVar(&opt.repo, "repo", "", "Repo of the project (used by configresolver)")
 	flag.StringVar(&opt.branch, "branch", "", "Branch of the project (used by configresolver)")
@@ -484,6 +503,8 @@ func bindOptions(flag *flag.FlagSet) *options {
 
 	flag.StringVar(&opt.pullSecretPath, "image-import-pull-secret", "", "A set of dockercfg credentials used to import images for the tag_specification.")
 	flag.StringVar(&opt.pushSecretPath, "image-mirror-push-secret", "", "A set of dockercfg credentials used to mirror images for the promotion.")
+	flag.StringVar(&opt.provenanceSigningKeyPath, "provenance-signing-key", "", "A PEM encoded ECDSA private key used to sign the provenance of the promoted images. If not set, no provenance is recorded.")
+	flag.StringVar(&opt.provenancePublicKeyPath, "provenance-public-key", "", "A PEM encoded ECDSA public key the provenance of the images imported into assembled release payloads must be signed with. Requires --image-import-pull-secret. If not set, the provenance is not verified.")
 	flag.StringVar(&opt.uploadSecretPath, "gcs-upload-secret", "", "GCS credentials used to upload logs and artifacts.")
 
 	flag.StringVar(&opt.hiveKubeconfigPath, "hive-kubeconfig", "", "Path to the kubeconfig file to use for requests to Hive.")
@@ -540,6 +561,21 @@ func (o *options) Complete() error {
 		return err
 	}
 
+	if o.resolverBundlePath != "" {
+		if o.unresolvedConfigPath != "" || o.configSpecPath != "" {
+			return errors.New("cannot set --resolver-bundle together with --config or --unresolved-config")
+		}
+		if injectTest != nil {
+			return errors.New("cannot request config with injected test from --resolver-bundle")
+		}
+		if o.resolverBundlePublicKeyPath == "" {
+			return errors.New("--resolver-bundle-public-key is required with --resolver-bundle")
+		}
+		if o.resolverBundleVerifier, err = provenance.LoadVerifier(o.resolverBundlePublicKeyPath); err != nil {
+			return fmt.Errorf("--resolver-bundle-public-key error: %w", err)
+		}
+	}
+
 	var config *api.ReleaseBuildConfiguration
 	if injectTest != nil {
 		if o.resolverAddress == "" {
@@ -565,10 +601,18 @@ func (o *options) Complete() error {
 	if err := validation.IsValidResolvedConfiguration(o.configSpec); err != nil {
 		return results.ForReason("validating_config").ForError(err)
 	}
+	additionalRefSteps, err := o.additionalRefSteps(info)
+	if err != nil {
+		return results.ForReason("loading_config").WithError(err).Errorf("failed to load the configuration of additional repositories: %v", err)
+	}
+	o.configSpec.RawSteps = append(o.configSpec.RawSteps, additionalRefSteps...)
 	o.graphConfig = defaults.FromConfigStatic(o.configSpec)
 	if err := validation.IsValidGraphConfiguration(o.graphConfig.Steps); err != nil {
 		return results.ForReason("validating_config").ForError(err)
 	}
+	for _, warning := range deprecationWarnings(o.configSpec, o.targets.values, time.Now()) {
+		logrus.Warn(warning)
+	}
 	if o.verbose {
 		config, _ := yaml.Marshal(o.configSpec)
 		logrus.WithField("config", string(config)).Trace("Resolved configuration.")
@@ -681,6 +725,19 @@ func (o *options) Complete() error {
 			return fmt.Errorf("could not get push secret %s from path %s: %w", api.RegistryPushCredentialsCICentralSecret, o.pushSecretPath, err)
 		}
 	}
+	if o.provenanceSigningKeyPath != "" {
+		if o.provenanceSigner, err = provenance.LoadSigner(o.provenanceSigningKeyPath); err != nil {
+			return fmt.Errorf("could not load provenance signing key from path %s: %w", o.provenanceSigningKeyPath, err)
+		}
+	}
+	if o.provenancePublicKeyPath != "" {
+		if o.pullSecret == nil {
+			return errors.New("--image-import-pull-secret is required to verify the provenance with --provenance-public-key")
+		}
+		if o.provenanceVerifier, err = provenance.LoadVerifier(o.provenancePublicKeyPath); err != nil {
+			return fmt.Errorf("could not load provenance public key from path %s: %w", o.provenancePublicKeyPath, err)
+		}
+	}
 
 	if o.uploadSecretPath != "" {
 		gcsSecretName := resolveGCSCredentialsSecret(o.jobSpec)
@@ -877,7 +934,7 @@ func (o *options) Run() []error {
 	}
 
 	// load the graph from the configuration
-	buildSteps, postSteps, err := defaults.FromConfig(ctx, o.configSpec, &o.graphConfig, o.jobSpec, o.templates, o.writeParams, o.promote, o.clusterConfig, o.podPendingTimeout, leaseClient, o.targets.values, o.cloneAuthConfig, o.pullSecret, o.pushSecret, o.censor, o.hiveKubeconfig, o.consoleHost, o.nodeName, nodeArchitectures, o.targetAdditionalSuffix)
+	buildSteps, postSteps, err := defaults.FromConfig(ctx, o.configSpec, &o.graphConfig, o.jobSpec, o.templates, o.writeParams, o.promote, o.clusterConfig, o.podPendingTimeout, leaseClient, o.targets.values, o.cloneAuthConfig, o.pullSecret, o.pushSecret, o.provenanceSigner, o.provenanceVerifier, o.censor, o.hiveKubeconfig, o.consoleHost, o.nodeName, nodeArchitectures, o.targetAdditionalSuffix)
 	if err != nil {
 		return []error{results.ForReason("defaulting_config").WithError(err).Errorf("failed to generate steps from config: %v", err)}
 	}
@@ -1189,6 +1246,9 @@ func (o *options) initializeNamespace() error {
 	// This label makes sure that the namespace is active, and the value will be updated
 	// if the namespace will be reused.
 	annotationUpdates[nsttl.AnnotationNamespaceLastActive] = time.Now().Format(time.RFC3339)
+	if o.jobSpec.ProwJobID != "" {
+		annotationUpdates[nsttl.AnnotationProwJobID] = o.jobSpec.ProwJobID
+	}
 
 	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		ns := &coreapi.Namespace{}
@@ -2038,6 +2098,53 @@ func (o *options) getResolverInfo(jobSpec *api.JobSpec) *api.Metadata {
 	return info
 }
 
+// additionalRefSteps returns the steps building the images of every other repository
+// in extra_refs with pull requests under test, so that the changes to all of them are
+// tested together. Each repository is built from its own configuration.
+func (o *options) additionalRefSteps(info *api.Metadata) ([]api.StepConfiguration, error) {
+	var ret []api.StepConfiguration
+	for i, ref := range o.jobSpec.ExtraRefs {
+		if len(ref.Pulls) == 0 || (ref.Org == info.Org && ref.Repo == info.Repo) {
+			continue
+		}
+		metadata := &api.Metadata{Org: ref.Org, Repo: ref.Repo, Branch: ref.BaseRef}
+		config, err := o.loadAdditionalConfig(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the configuration for %s: %w", metadata.AsString(), err)
+		}
+		// the repository was cloned by the pod utilities next to the one under test
+		checkout := decorate.DetermineWorkDir(os.Getenv("GOPATH"), []prowapi.Refs{ref})
+		readFile := func(name string) ([]byte, error) {
+			return ioutil.ReadFile(filepath.Join(checkout, name))
+		}
+		steps, err := defaults.AdditionalRefSteps(api.RefString(ref.Org, ref.Repo), config, readFile)
+		if err != nil {
+			return nil, err
+		}
+		if config.CanonicalGoRepository != nil {
+			o.jobSpec.ExtraRefs[i].PathAlias = *config.CanonicalGoRepository
+		}
+		logrus.Infof("Building the images of %s from its configuration", metadata.AsString())
+		ret = append(ret, steps...)
+	}
+	return ret, nil
+}
+
+// loadAdditionalConfig loads the configuration of a repository other than the one
+// under test, which is only available from the resolver bundle or the configresolver
+func (o *options) loadAdditionalConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration, error) {
+	if o.resolverBundlePath != "" {
+		resolverBundle, err := bundle.Read(o.resolverBundlePath, o.resolverBundleVerifier, o.resolverBundleMinGeneration)
+		if err != nil {
+			return nil, results.ForReason("resolver_bundle").ForError(fmt.Errorf("--resolver-bundle error: %w", err))
+		}
+		configSpec, err := resolverBundle.Config(*info)
+		return configSpec, results.ForReason("resolver_bundle").ForError(err)
+	}
+	configSpec, err := o.resolverClient.Config(info)
+	return configSpec, results.ForReason("config_resolver").ForError(err)
+}
+
 func (o *options) getInjectTest() (*api.MetadataWithTest, error) {
 	if o.injectTest == "" {
 		return nil, nil
@@ -2050,7 +2157,24 @@ func (o *options) getInjectTest() (*api.MetadataWithTest, error) {
 	return api.MetadataTestFromString(o.injectTest)
 }
 
-// loadConfig loads the standard configuration path, env, or configresolver (in that order of priority)
+// deprecationWarnings describes the deprecated registry components used by the
+// tests that are targeted, or by all tests when no target is set
+func deprecationWarnings(config *api.ReleaseBuildConfiguration, targets []string, now time.Time) []string {
+	targeted := sets.NewString(targets...)
+	var warnings []string
+	for _, test := range config.Tests {
+		if test.MultiStageTestConfigurationLiteral == nil || (targeted.Len() > 0 && !targeted.Has(test.As)) {
+			continue
+		}
+		for _, component := range test.MultiStageTestConfigurationLiteral.Deprecated {
+			warnings = append(warnings, fmt.Sprintf("Test %s uses a deprecated component: %s", test.As, component.Warning(now)))
+		}
+	}
+	return warnings
+}
+
+// loadConfig loads the standard configuration path, env, or configresolver (in that order of priority).
+// When a resolver bundle is provided, it is used in place of the configresolver.
 func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration, error) {
 	var raw string
 
@@ -2090,6 +2214,15 @@ func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration
 		configSpec, err := o.resolverClient.Resolve([]byte(unresolvedConfigEnv))
 		err = results.ForReason("config_resolver_literal").ForError(err)
 		return configSpec, err
+	case len(o.resolverBundlePath) > 0:
+		resolverBundle, err := bundle.Read(o.resolverBundlePath, o.resolverBundleVerifier, o.resolverBundleMinGeneration)
+		if err != nil {
+			return nil, results.ForReason("resolver_bundle").ForError(fmt.Errorf("--resolver-bundle error: %w", err))
+		}
+		logrus.Infof("Loading configuration from generation %d of the resolver bundle, created at %s, for %s", resolverBundle.Generation, resolverBundle.Created.Format(time.RFC3339), info.AsString())
+		configSpec, err := resolverBundle.Config(*info)
+		err = results.ForReason("resolver_bundle").ForError(err)
+		return configSpec, err
 	default:
 		configSpec, err := o.resolverClient.Config(info)
 		err = results.ForReason("config_resolver").ForError(err)
@@ -2107,7 +2240,13 @@ func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration
 		if err != nil {
 			return nil, fmt.Errorf("failed to load registry: %w", err)
 		}
-		configSpec, err = registry.ResolveConfig(registry.NewResolver(refs, chains, workflows, observers), configSpec)
+		var versions registry.VersionByName
+		if o.versionsPath != "" {
+			if versions, err = load.RegistryVersions(o.versionsPath, load.RegistryFlag(0), nil); err != nil {
+				return nil, fmt.Errorf("failed to load registry versions: %w", err)
+			}
+		}
+		configSpec, err = registry.ResolveConfig(registry.NewVersionedResolver(refs, chains, workflows, observers, versions), configSpec)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve configuration: %w", err)
 		}
@@ -3,7 +3,12 @@ package main
 import (
 	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -11,6 +16,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -28,6 +34,10 @@ import (
 	imagev1 "github.com/openshift/api/image/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/provenance"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/registry/bundle"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps"
@@ -964,6 +974,91 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFromResolverBundle(t *testing.T) {
+	if value, set := os.LookupEnv("CONFIG_SPEC"); set {
+		if err := os.Unsetenv("CONFIG_SPEC"); err != nil {
+			t.Fatalf("failed to unset CONFIG_SPEC: %v", err)
+		}
+		defer os.Setenv("CONFIG_SPEC", value)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	private, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	signer, err := provenance.NewSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private}))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.json.gz")
+	if err := bundle.Write(path, &bundle.Bundle{
+		Version:    bundle.Version,
+		Generation: 2,
+		Configs: config.ByOrgRepo{"org": {"repo": {{
+			Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+			Tests: []api.TestStepConfiguration{{
+				As:                          "e2e",
+				MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: pointer.String("step")}}},
+			}},
+		}}}},
+		References: registry.ReferenceByName{"step": {As: "step", From: "src", Commands: "make test"}},
+	}, signer); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+
+	var testCases = []struct {
+		name          string
+		minGeneration int64
+		metadata      api.Metadata
+		expected      *api.ReleaseBuildConfiguration
+		expectedError bool
+	}{
+		{
+			name:     "configuration is resolved from the bundle",
+			metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+			expected: &api.ReleaseBuildConfiguration{
+				Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+				Tests: []api.TestStepConfiguration{{
+					As: "e2e",
+					MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						Test: []api.LiteralTestStep{{As: "step", From: "src", Commands: "make test"}},
+					},
+				}},
+			},
+		},
+		{
+			name:          "configuration missing from the bundle",
+			metadata:      api.Metadata{Org: "org", Repo: "repo", Branch: "release-4.12"},
+			expectedError: true,
+		},
+		{
+			name:          "bundle is older than the minimum generation",
+			minGeneration: 3,
+			metadata:      api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+			expectedError: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			o := &options{
+				resolverBundlePath:          path,
+				resolverBundleVerifier:      signer.Verifier(),
+				resolverBundleMinGeneration: testCase.minGeneration,
+			}
+			actual, err := o.loadConfig(&testCase.metadata)
+			if (err != nil) != testCase.expectedError {
+				t.Fatalf("%s: expected error: %v, got: %v", testCase.name, testCase.expectedError, err)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect configuration: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
 func TestErrWroteJUnit(t *testing.T) {
 	// this simulates the error chain bubbling up to the top of the call chain
 	rootCause := errors.New("failure")
@@ -1610,3 +1705,53 @@ func TestHandleTargetAdditionalSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestDeprecationWarnings(t *testing.T) {
+	now := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	config := &api.ReleaseBuildConfiguration{
+		Tests: []api.TestStepConfiguration{
+			{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+			{
+				As: "e2e",
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+					Deprecated: []api.DeprecatedComponent{{Type: "workflow", Name: "old", Deprecation: api.Deprecation{ReplacedBy: "new"}}},
+				},
+			},
+			{
+				As: "upgrade",
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+					Deprecated: []api.DeprecatedComponent{{Type: "reference", Name: "gather", Deprecation: api.Deprecation{RemovalDate: "2022-12-01"}}},
+				},
+			},
+		},
+	}
+	var testCases = []struct {
+		name     string
+		targets  []string
+		expected []string
+	}{
+		{
+			name: "no targets",
+			expected: []string{
+				"Test e2e uses a deprecated component: workflow old is deprecated, use workflow new instead",
+				"Test upgrade uses a deprecated component: reference gather is deprecated and will be removed after 2022-12-01",
+			},
+		},
+		{
+			name:     "only the targeted test",
+			targets:  []string{"src", "upgrade"},
+			expected: []string{"Test upgrade uses a deprecated component: reference gather is deprecated and will be removed after 2022-12-01"},
+		},
+		{
+			name:    "targeted test without deprecated components",
+			targets: []string{"unit"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if diff := cmp.Diff(testCase.expected, deprecationWarnings(config, testCase.targets, now)); diff != "" {
+				t.Errorf("%s: got incorrect warnings: %v", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/bombsimon/logrusr/v3"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/fsnotify.v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -30,9 +31,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	imagev1 "github.com/openshift/api/image/v1"
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/config"
+	imagestreamtaggc "github.com/openshift/ci-tools/pkg/controller/imagestreamtag_gc"
+	namespacereaper "github.com/openshift/ci-tools/pkg/controller/namespace_reaper"
 	"github.com/openshift/ci-tools/pkg/controller/promotionreconciler"
 	serviceaccountsecretrefresher "github.com/openshift/ci-tools/pkg/controller/serviceaccount_secret_refresher"
 	testimagesdistributor "github.com/openshift/ci-tools/pkg/controller/test-images-distributor"
@@ -50,6 +55,8 @@ var allControllers = sets.NewString(
 	testimagesdistributor.ControllerName,
 	serviceaccountsecretrefresher.ControllerName,
 	testimagestreamimportcleaner.ControllerName,
+	namespacereaper.ControllerName,
+	imagestreamtaggc.ControllerName,
 )
 
 type options struct {
@@ -68,6 +75,8 @@ type options struct {
 	serviceAccountSecretRefresherOptions serviceAccountSecretRefresherOptions
 	imagePusherOptions                   imagePusherOptions
 	promotionReconcilerOptions           promotionReconcilerOptions
+	namespaceReaperOptions               namespaceReaperOptions
+	imageStreamTagGCOptions              imageStreamTagGCOptions
 	*flagutil.GitHubOptions
 	releaseRepoGitSyncPath string
 }
@@ -87,11 +96,29 @@ type testImagesDistributorOptions struct {
 	forbiddenRegistries                sets.String
 	ignoreClusterNamesRaw              flagutil.Strings
 	ignoreClusterNames                 sets.String
+	prewarmBudgetsRaw                  flagutil.Strings
+	prewarmBudgets                     map[string]resource.Quantity
+	prewarmNamespace                   string
+	prewarmInterval                    time.Duration
+	prewarmWindow                      time.Duration
+	prewarmInstallerImage              string
 }
 
 type promotionReconcilerOptions struct {
 	ignoreImageStreamsRaw flagutil.Strings
 	ignoreImageStreams    []*regexp.Regexp
+	reportInterval        time.Duration
+}
+
+type namespaceReaperOptions struct {
+	abandonedAfter time.Duration
+}
+
+type imageStreamTagGCOptions struct {
+	ignoredImageStreamTagsRaw flagutil.Strings
+	ignoredImageStreamTags    []*regexp.Regexp
+	gracePeriod               time.Duration
+	interval                  time.Duration
 }
 
 type imagePusherOptions struct {
@@ -123,6 +150,11 @@ func newOpts() (*options, error) {
 	fs.Var(&opts.testImagesDistributorOptions.additionalImageStreamNamespacesRaw, "testImagesDistributorOptions.additional-image-stream-namespace", "A namespace in which imagestreams will be distributed even if no test explicitly references them (e.G `ci`). Can be passed multiple times.")
 	fs.Var(&opts.testImagesDistributorOptions.forbiddenRegistriesRaw, "testImagesDistributorOptions.forbidden-registry", "The hostname of an image registry from which there is no synchronization of its images. Can be passed multiple times.")
 	fs.Var(&opts.testImagesDistributorOptions.ignoreClusterNamesRaw, "testImagesDistributorOptions.ignore-cluster-name", "The cluster name to which there is no synchronization of test images. Can be passed multiple times.")
+	fs.Var(&opts.testImagesDistributorOptions.prewarmBudgetsRaw, "testImagesDistributorOptions.prewarm-budget", "The disk space on every node of a build farm cluster the most used test images are kept pulled in, in cluster=quantity format (e.G `build01=50Gi`). Clusters without a budget are not pre-warmed. Can be passed multiple times.")
+	fs.StringVar(&opts.testImagesDistributorOptions.prewarmNamespace, "testImagesDistributorOptions.prewarm-namespace", "ci", "The namespace on the build farm clusters the pre-puller DaemonSet runs in.")
+	fs.DurationVar(&opts.testImagesDistributorOptions.prewarmInterval, "testImagesDistributorOptions.prewarm-interval", time.Hour, "How often to determine the images to pre-warm.")
+	fs.DurationVar(&opts.testImagesDistributorOptions.prewarmWindow, "testImagesDistributorOptions.prewarm-window", 24*time.Hour, "How far back to look at ProwJobs to determine which images are used most.")
+	fs.StringVar(&opts.testImagesDistributorOptions.prewarmInstallerImage, "testImagesDistributorOptions.prewarm-installer-image", "", "An amd64 image with a statically linked busybox at /bin/busybox, like the musl variant of docker.io/library/busybox, pinned by digest. The pre-puller runs it in the pre-pulled images. Required with a pre-warming budget.")
 	fs.DurationVar(&opts.blockProfileRate, "block-profile-rate", time.Duration(0), "The block profile rate. Set to non-zero to enable.")
 	fs.StringVar(&opts.registryClusterName, "registry-cluster-name", "app.ci", "the cluster name on which the CI central registry is running")
 	fs.Var(&opts.serviceAccountSecretRefresherOptions.enabledNamespaces, "serviceAccountRefresherOptions.enabled-namespace", "A namespace for which the serviceaccount_secret_refresher should be enabled. Can be passed multiple times.")
@@ -130,6 +162,11 @@ func newOpts() (*options, error) {
 	fs.Var(&opts.serviceAccountSecretRefresherOptions.ignoreServiceAccounts, "serviceAccountRefresherOptions.ignore-service-account", "The service account to ignore. It must be in namespace/name format (e.G `ci/sync-rover-groups-updater`). Can be passed multiple times.")
 	fs.Var(&opts.imagePusherOptions.imageStreamsRaw, "imagePusherOptions.image-stream", "An imagestream that will be synced. It must be in namespace/name format (e.G `ci/clonerefs`). Can be passed multiple times.")
 	fs.Var(&opts.promotionReconcilerOptions.ignoreImageStreamsRaw, "promotionReconcilerOptions.ignore-image-stream", "The image stream to ignore. It is an regular expression (e.G ^openshift-priv/.+). Can be passed multiple times.")
+	fs.DurationVar(&opts.promotionReconcilerOptions.reportInterval, "promotionReconcilerOptions.report-interval", 0, fmt.Sprintf("How often to report the state of all tags the promotion configurations promote, as metrics and as JSON on %s of the metrics server. Zero disables the report.", promotionreconciler.ReportPath))
+	fs.DurationVar(&opts.namespaceReaperOptions.abandonedAfter, "namespaceReaperOptions.abandoned-after", time.Hour, "How long a namespace must not have been active before it is deleted as abandoned if its ProwJob completed. Zero disables the detection of abandoned namespaces.")
+	fs.Var(&opts.imageStreamTagGCOptions.ignoredImageStreamTagsRaw, "imageStreamTagGCOptions.ignored-image-stream-tag", "A regular expression matching tags in namespace/name:tag format that are never deleted. Can be passed multiple times.")
+	fs.DurationVar(&opts.imageStreamTagGCOptions.gracePeriod, "imageStreamTagGCOptions.grace-period", 7*24*time.Hour, "How long an imported tag must not be referenced before it is deleted.")
+	fs.DurationVar(&opts.imageStreamTagGCOptions.interval, "imageStreamTagGCOptions.interval", time.Hour, fmt.Sprintf("How often to collect unreferenced tags. The report is served as JSON on %s of the metrics server.", imagestreamtaggc.ReportPath))
 	fs.BoolVar(&opts.dryRun, "dry-run", true, "Whether to run the controller-manager with dry-run")
 	fs.StringVar(&opts.releaseRepoGitSyncPath, "release-repo-git-sync-path", "", "Path to release repository dir")
 	if err := fs.Parse(os.Args[1:]); err != nil {
@@ -185,6 +222,28 @@ func newOpts() (*options, error) {
 	opts.testImagesDistributorOptions.additionalImageStreamNamespaces = completeSet(opts.testImagesDistributorOptions.additionalImageStreamNamespacesRaw)
 	opts.testImagesDistributorOptions.forbiddenRegistries = completeSet(opts.testImagesDistributorOptions.forbiddenRegistriesRaw)
 	opts.testImagesDistributorOptions.ignoreClusterNames = completeSet(opts.testImagesDistributorOptions.ignoreClusterNamesRaw)
+	opts.testImagesDistributorOptions.prewarmBudgets = map[string]resource.Quantity{}
+	for _, raw := range opts.testImagesDistributorOptions.prewarmBudgetsRaw.Strings() {
+		cluster, rawQuantity, ok := strings.Cut(raw, "=")
+		if !ok || cluster == "" {
+			errs = append(errs, fmt.Errorf("--testImagesDistributorOptions.prewarm-budget %q is not in cluster=quantity format", raw))
+			continue
+		}
+		quantity, err := resource.ParseQuantity(rawQuantity)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("--testImagesDistributorOptions.prewarm-budget %q has an invalid quantity: %w", raw, err))
+			continue
+		}
+		opts.testImagesDistributorOptions.prewarmBudgets[cluster] = quantity
+	}
+	if len(opts.testImagesDistributorOptions.prewarmBudgets) > 0 && (opts.testImagesDistributorOptions.prewarmInterval <= 0 || opts.testImagesDistributorOptions.prewarmWindow <= 0) {
+		errs = append(errs, errors.New("--testImagesDistributorOptions.prewarm-interval and --testImagesDistributorOptions.prewarm-window must be positive"))
+	}
+	if len(opts.testImagesDistributorOptions.prewarmBudgets) > 0 {
+		if ref, err := reference.Parse(opts.testImagesDistributorOptions.prewarmInstallerImage); err != nil || ref.ID == "" {
+			errs = append(errs, fmt.Errorf("--testImagesDistributorOptions.prewarm-installer-image %q must be an image pinned by digest", opts.testImagesDistributorOptions.prewarmInstallerImage))
+		}
+	}
 
 	imagePusherImageStreams, isErrors := completeImageStream("uniRegistrySyncerOptions.image-stream", opts.imagePusherOptions.imageStreamsRaw)
 	errs = append(errs, isErrors...)
@@ -201,8 +260,22 @@ func newOpts() (*options, error) {
 		}
 	}
 
-	if opts.enabledControllersSet.Has(testimagesdistributor.ControllerName) && opts.stepConfigPath == "" {
-		errs = append(errs, fmt.Errorf("--step-config-path is required when the %s controller is enabled", testimagesdistributor.ControllerName))
+	for _, raw := range opts.imageStreamTagGCOptions.ignoredImageStreamTagsRaw.Strings() {
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to compile regex from %q: %w", raw, err))
+			continue
+		}
+		opts.imageStreamTagGCOptions.ignoredImageStreamTags = append(opts.imageStreamTagGCOptions.ignoredImageStreamTags, re)
+	}
+
+	for _, controller := range []string{testimagesdistributor.ControllerName, imagestreamtaggc.ControllerName} {
+		if opts.enabledControllersSet.Has(controller) && opts.stepConfigPath == "" {
+			errs = append(errs, fmt.Errorf("--step-config-path is required when the %s controller is enabled", controller))
+		}
+	}
+	if opts.enabledControllersSet.Has(imagestreamtaggc.ControllerName) && opts.imageStreamTagGCOptions.interval <= 0 {
+		errs = append(errs, errors.New("--imageStreamTagGCOptions.interval must be positive"))
 	}
 
 	if opts.enabledControllersSet.Has(serviceaccountsecretrefresher.ControllerName) {
@@ -435,6 +508,7 @@ func main() {
 			GitHubClient:          gitHubClient,
 			RegistryManager:       registryMgr,
 			IgnoredImageStreams:   opts.promotionReconcilerOptions.ignoreImageStreams,
+			ReportInterval:        opts.promotionReconcilerOptions.reportInterval,
 		}
 		if err := promotionreconciler.AddToManager(mgr, promotionreconcilerOptions); err != nil {
 			logrus.WithError(err).Fatal("Failed to add imagestreamtagreconciler")
@@ -447,12 +521,15 @@ func main() {
 		}
 	}
 
-	if opts.enabledControllersSet.Has(testimagesdistributor.ControllerName) {
-		registryConfigAgent, err := agents.NewRegistryAgent(opts.stepConfigPath, registryAgentOption)
+	var registryConfigAgent agents.RegistryAgent
+	if opts.enabledControllersSet.Has(testimagesdistributor.ControllerName) || opts.enabledControllersSet.Has(imagestreamtaggc.ControllerName) {
+		registryConfigAgent, err = agents.NewRegistryAgent(opts.stepConfigPath, registryAgentOption)
 		if err != nil {
 			logrus.WithError(err).Fatal("failed to construct registryAgent")
 		}
+	}
 
+	if opts.enabledControllersSet.Has(testimagesdistributor.ControllerName) {
 		registriesExceptAppCI := sets.NewString()
 		for cluster := range allClustersExceptRegistryCluster {
 			domain, err := api.RegistryDomainForClusterName(cluster)
@@ -479,6 +556,18 @@ func main() {
 		); err != nil {
 			logrus.WithError(err).Fatal("failed to add testimagesdistributor")
 		}
+		if len(opts.testImagesDistributorOptions.prewarmBudgets) > 0 {
+			if err := testimagesdistributor.AddPrewarmerToManager(mgr, allClustersExceptRegistryCluster, ciOPConfigAgent, registryConfigAgent, testimagesdistributor.PrewarmOptions{
+				Budgets:          opts.testImagesDistributorOptions.prewarmBudgets,
+				Namespace:        opts.testImagesDistributorOptions.prewarmNamespace,
+				Interval:         opts.testImagesDistributorOptions.prewarmInterval,
+				Window:           opts.testImagesDistributorOptions.prewarmWindow,
+				InstallerImage:   opts.testImagesDistributorOptions.prewarmInstallerImage,
+				ProwJobNamespace: func() string { return configAgent.Config().ProwJobNamespace },
+			}); err != nil {
+				logrus.WithError(err).Fatal("failed to add the test images prewarmer")
+			}
+		}
 	}
 
 	if opts.enabledControllersSet.Has(serviceaccountsecretrefresher.ControllerName) {
@@ -495,6 +584,39 @@ func main() {
 		}
 	}
 
+	if opts.enabledControllersSet.Has(imagestreamtaggc.ControllerName) {
+		if err := imagestreamtaggc.AddToManager(mgr, allManagers, imagestreamtaggc.Options{
+			DryRun:                          opts.dryRun,
+			ConfigAgent:                     ciOPConfigAgent,
+			RegistryAgent:                   registryConfigAgent,
+			AdditionalImageStreamTags:       opts.testImagesDistributorOptions.additionalImageStreamTags,
+			AdditionalImageStreams:          opts.testImagesDistributorOptions.additionalImageStreams,
+			AdditionalImageStreamNamespaces: opts.testImagesDistributorOptions.additionalImageStreamNamespaces,
+			IgnoredImageStreamTags:          opts.imageStreamTagGCOptions.ignoredImageStreamTags,
+			GracePeriod:                     opts.imageStreamTagGCOptions.gracePeriod,
+			Interval:                        opts.imageStreamTagGCOptions.interval,
+		}); err != nil {
+			logrus.WithError(err).Fatal("Failed to construct the imagestreamtag_gc controller")
+		}
+	}
+
+	if opts.enabledControllersSet.Has(namespacereaper.ControllerName) {
+		namespaceReaperOptions := namespacereaper.Options{
+			ProwJobClient:  mgr.GetClient(),
+			ConfigGetter:   configAgent.Config,
+			AbandonedAfter: opts.namespaceReaperOptions.abandonedAfter,
+		}
+		if hiveMgr, ok := allManagers[string(api.ClusterHive)]; ok {
+			if err := hivev1.AddToScheme(hiveMgr.GetScheme()); err != nil {
+				logrus.WithError(err).Fatal("Failed to add hivev1 to scheme")
+			}
+			namespaceReaperOptions.Hooks = append(namespaceReaperOptions.Hooks, namespacereaper.NewClusterClaimHook(hiveMgr.GetClient()))
+		}
+		if err := namespacereaper.AddToManager(mgr, allManagers, namespaceReaperOptions); err != nil {
+			logrus.WithError(err).Fatal("Failed to construct the namespace_reaper controller")
+		}
+	}
+
 	if err := mgr.Start(ctx); err != nil {
 		logrus.WithError(err).Fatal("Manager ended with error")
 	}
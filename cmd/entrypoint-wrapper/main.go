@@ -75,6 +75,7 @@ type options struct {
 	name             string
 	srcPath          string
 	dstPath          string
+	storePath        string
 	waitPath         string
 	waitTimeoutStr   string
 	waitTimeout      time.Duration
@@ -90,6 +91,7 @@ func bindOptions(flag *flag.FlagSet) *options {
 	opt := &options{}
 	flag.BoolVar(&opt.dry, "dry-run", false, "Print the secret instead of creating it")
 	flag.StringVar(&opt.waitPath, "wait-for-file", "", "Wait for a file to appear at this path before starting the program")
+	flag.StringVar(&opt.storePath, "shared-dir-store", "", "Directory of a volume to move the files that do not fit in the SHARED_DIR secret to")
 	flag.StringVar(&opt.waitTimeoutStr, "wait-timeout", "", "Used with --wait-for-file, maximum wait time before starting the program")
 	flag.StringVar(&opt.mode, "mode", manageKubeconfigMode, fmt.Sprintf("Set how kubeconfig should be managed. Allowed values are: %s, %s or %s", manageKubeconfigMode, skipKubeconfigMode, observerMode))
 	return opt
@@ -159,6 +161,9 @@ func (o *options) run() error {
 	if err := copyDir(o.dstPath, o.srcPath); err != nil {
 		return fmt.Errorf("failed to copy secret mount: %w", err)
 	}
+	if err := restoreSharedDir(o.dstPath, o.storePath); err != nil {
+		return fmt.Errorf("failed to restore SHARED_DIR from its storage: %w", err)
+	}
 	if o.waitPath != "" {
 		if err := waitForFile(o.waitPath, o.waitTimeout); err != nil {
 			return fmt.Errorf("failed to wait for file: %w", err)
@@ -167,17 +172,23 @@ func (o *options) run() error {
 	var errs []error
 	ctx, cancel := context.WithCancel(context.Background())
 	if o.uploadKubeconfig {
-		go uploadKubeconfig(ctx, o.client, o.name, o.dstPath, o.dry)
+		go uploadKubeconfig(ctx, o.client, o.name, o.dstPath, o.storePath, o.dry)
 	}
-	if err := o.execCmd(); err != nil {
+	prof := newProfiler("/proc")
+	started := time.Now()
+	pid, state, err := o.execCmd(prof)
+	if err != nil {
 		errs = append(errs, fmt.Errorf("failed to execute wrapped command: %w", err))
 	}
+	if pid != 0 {
+		o.recordProfile(prof.profile(pid, state, started, time.Now()), err == nil)
+	}
 	// we will upload the secret from the post-execution state, so we know
 	// that the best-effort upload of the kubeconfig can exit now and so as
 	// not to race with the post-execution one
 	cancel()
 	if o.updateSharedDir {
-		if err := createSecret(o.client, o.name, o.dstPath, o.dry); err != nil {
+		if err := createSecret(o.client, o.name, o.dstPath, o.storePath, o.dry); err != nil {
 			errs = append(errs, fmt.Errorf("failed to create/update secret: %w", err))
 		}
 	}
@@ -267,7 +278,39 @@ func waitForFile(path string, timeout time.Duration) error {
 	}
 }
 
-func (o *options) execCmd() error {
+// recordProfile writes the profile of the command to the artifacts of the step
+// and, if the command succeeded without writing a termination message itself, to
+// the termination message of the container. The termination message of a failed
+// command is left alone, as it falls back to the end of the log, which explains
+// the failure.
+func (o *options) recordProfile(profile api.StepProfile, succeeded bool) {
+	var err error
+	if profile.SharedDirModifiedBytes, err = modifiedBytes(o.dstPath, profile.StartedAt); err != nil {
+		logrus.WithError(err).Warn("Failed to determine the size of the files modified in SHARED_DIR")
+	}
+	artifactDir := os.Getenv("ARTIFACT_DIR")
+	if artifactDir != "" {
+		if profile.ArtifactModifiedBytes, err = modifiedBytes(artifactDir, profile.StartedAt); err != nil {
+			logrus.WithError(err).Warn("Failed to determine the size of the files modified in ARTIFACT_DIR")
+		}
+		if err := writeProfile(artifactDir, profile); err != nil {
+			logrus.WithError(err).Warn("Failed to write the step profile")
+		}
+	}
+	if !succeeded || o.dry {
+		return
+	}
+	if _, err := os.Stat(terminationMessagePath); err != nil {
+		return
+	}
+	if err := writeTerminationMessage(terminationMessagePath, profile); err != nil {
+		logrus.WithError(err).Warn("Failed to write the step profile to the termination message")
+	}
+}
+
+// execCmd runs the wrapped command, returning its PID and final state once it
+// exits, or a zero PID if it could not be started
+func (o *options) execCmd(prof *profiler) (int, *os.ProcessState, error) {
 	argv := o.cmd
 	proc := exec.Command(argv[0], argv[1:]...)
 	proc.Stdout = os.Stdout
@@ -280,21 +323,26 @@ func (o *options) execCmd() error {
 	}
 	home := manageHome(proc)
 	if err := manageGitConfig(home); err != nil {
-		return fmt.Errorf("failed to create Git configuration: %w", err)
+		return 0, nil, fmt.Errorf("failed to create Git configuration: %w", err)
 	}
 	manageCLI(proc)
 	if o.rwKubeconfig {
 		if err := manageKubeconfig(proc); err != nil {
-			return err
+			return 0, nil, err
 		}
 	}
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	if err := proc.Start(); err != nil {
-		return fmt.Errorf("failed to start main process: %w", err)
+		return 0, nil, fmt.Errorf("failed to start main process: %w", err)
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	profiled := make(chan struct{})
+	go func() {
+		prof.run(ctx, proc.Process.Pid)
+		close(profiled)
+	}()
 	go func() {
 		for {
 			select {
@@ -308,7 +356,10 @@ func (o *options) execCmd() error {
 			}
 		}
 	}()
-	return proc.Wait()
+	err := proc.Wait()
+	cancel()
+	<-profiled
+	return proc.Process.Pid, proc.ProcessState, err
 }
 
 // manageCLI configures the PATH to include a CLI_DIR if one was provided
@@ -413,7 +464,9 @@ func manageKubeconfig(proc *exec.Cmd) error {
 	return nil
 }
 
-func createSecret(client coreclientset.SecretInterface, name, dir string, dry bool) error {
+// createSecret uploads SHARED_DIR to its secret. When a volume backs SHARED_DIR,
+// the files that do not fit in the secret are moved to it.
+func createSecret(client coreclientset.SecretInterface, name, dir, store string, dry bool) error {
 	if _, err := os.Stat(dir); err != nil {
 		if os.IsNotExist(err) {
 			return nil
@@ -424,6 +477,16 @@ func createSecret(client coreclientset.SecretInterface, name, dir string, dry bo
 	if err != nil {
 		return fmt.Errorf("failed to generate secret: %w", err)
 	}
+	// the index is only ever written by us, never carried over from the command
+	delete(secret.Data, api.SharedDirStoreIndexKey)
+	if store != "" {
+		if err := spillToStore(secret.Data, store); err != nil {
+			return err
+		}
+	}
+	if err := util.ValidateSecretSize(secret.Data); err != nil {
+		return fmt.Errorf("SHARED_DIR is too large: %w", err)
+	}
 	secret.Name = name
 	if secret.Labels == nil {
 		secret.Labels = map[string]string{}
@@ -448,7 +511,7 @@ func createSecret(client coreclientset.SecretInterface, name, dir string, dry bo
 // make a minimally functional kubeconfig available for tasks that need to run
 // before the final complete kubeconfig is available for general usage. An example
 // use case is for observers to start observing while install is still in progress.
-func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface, name, dir string, dry bool) {
+func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface, name, dir, store string, dry bool) {
 	if _, err := os.Stat(path.Join(dir, "kubeconfig")); err == nil {
 		// kubeconfig already exists, no need to do anything
 		return
@@ -458,7 +521,7 @@ func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface,
 	if err := wait.PollUntil(time.Second, func() (done bool, err error) {
 		if !minimalUploaded {
 			if _, uploadErr = os.Stat(path.Join(dir, "kubeconfig-minimal")); uploadErr == nil {
-				uploadErr = createSecret(client, name, dir, dry)
+				uploadErr = createSecret(client, name, dir, store, dry)
 				if uploadErr == nil {
 					minimalUploaded = true
 				}
@@ -469,7 +532,7 @@ func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface,
 			return false, nil
 		}
 		// kubeconfig exists, we can upload it
-		uploadErr = createSecret(client, name, dir, dry)
+		uploadErr = createSecret(client, name, dir, store, dry)
 		return uploadErr == nil, nil // retry errors
 	}, ctx.Done()); err != nil && !errors.Is(err, wait.ErrWaitTimeout) {
 		log.Printf("Failed to upload $KUBECONFIG: %v: %v\n", err, uploadErr)
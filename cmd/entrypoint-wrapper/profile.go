@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// profileInterval is how often the processes of the command are sampled
+	profileInterval = time.Second
+	// maxCommandLength truncates the command lines of processes in the profile
+	maxCommandLength = 256
+	// maxTerminationMessageBytes is the size limit Kubernetes imposes on the
+	// termination message of a container
+	maxTerminationMessageBytes = 4096
+	terminationMessagePath     = "/dev/termination-log"
+)
+
+// profiler samples the processes the wrapped command starts
+type profiler struct {
+	procDir string
+
+	lock      sync.Mutex
+	processes map[int]*api.ProcessProfile
+	lastSeen  map[int]time.Time
+}
+
+func newProfiler(procDir string) *profiler {
+	return &profiler{
+		procDir:   procDir,
+		processes: map[int]*api.ProcessProfile{},
+		lastSeen:  map[int]time.Time{},
+	}
+}
+
+// run samples the descendants of the root process until the context is cancelled
+func (p *profiler) run(ctx context.Context, root int) {
+	ticker := time.NewTicker(profileInterval)
+	defer ticker.Stop()
+	for {
+		p.sample(root, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample records the root process and all its descendants running now
+func (p *profiler) sample(root int, now time.Time) {
+	entries, err := os.ReadDir(p.procDir)
+	if err != nil {
+		return
+	}
+	commands, parents := map[int]string{}, map[int]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// processes may exit at any time, those are simply not recorded
+		command, parent, err := readProcess(filepath.Join(p.procDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		commands[pid], parents[pid] = command, parent
+	}
+	descends := map[int]bool{root: true}
+	var isDescendant func(pid int, depth int) bool
+	isDescendant = func(pid int, depth int) bool {
+		if ret, known := descends[pid]; known {
+			return ret
+		}
+		parent, ok := parents[pid]
+		// the depth guards against a cycle created by PID reuse mid-sample
+		ret := ok && depth < len(parents) && isDescendant(parent, depth+1)
+		descends[pid] = ret
+		return ret
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for pid, command := range commands {
+		if !isDescendant(pid, 0) {
+			continue
+		}
+		if _, seen := p.processes[pid]; !seen {
+			process := &api.ProcessProfile{PID: pid, Command: command, StartedAt: now}
+			if pid != root {
+				process.Parent = parents[pid]
+			}
+			p.processes[pid] = process
+		}
+		p.lastSeen[pid] = now
+	}
+}
+
+// readProcess reads the command line and the parent of a process from its
+// directory in procfs
+func readProcess(dir string) (string, int, error) {
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return "", 0, err
+	}
+	// the format is `pid (comm) state ppid ...`, where comm may contain spaces
+	// and parentheses itself
+	open, closing := strings.IndexByte(string(stat), '('), strings.LastIndexByte(string(stat), ')')
+	if open == -1 || closing < open {
+		return "", 0, fmt.Errorf("malformed stat: %q", string(stat))
+	}
+	fields := strings.Fields(string(stat[closing+1:]))
+	if len(fields) < 2 {
+		return "", 0, fmt.Errorf("malformed stat: %q", string(stat))
+	}
+	parent, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed parent in stat: %w", err)
+	}
+	command := string(stat[open+1 : closing])
+	// the command line is empty for zombies, which only have their comm left
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		if args := strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " ")); args != "" {
+			command = args
+		}
+	}
+	if len(command) > maxCommandLength {
+		command = command[:maxCommandLength]
+	}
+	return command, parent, nil
+}
+
+// profile builds the profile of a command run by the root process, which the
+// wrapper started and waited for itself
+func (p *profiler) profile(root int, state *os.ProcessState, started, finished time.Time) api.StepProfile {
+	profile := api.StepProfile{
+		StartedAt:  started,
+		FinishedAt: finished,
+		WallTime:   finished.Sub(started),
+	}
+	if state != nil {
+		if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+			// the kernel reports the peak of the largest process the root waited for, in KiB
+			profile.MaxProcessRSSBytes = usage.Maxrss * 1024
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for pid, process := range p.processes {
+		if pid == root {
+			continue
+		}
+		process.Duration = p.lastSeen[pid].Sub(process.StartedAt)
+		profile.Processes = append(profile.Processes, *process)
+	}
+	sort.Slice(profile.Processes, func(i, j int) bool {
+		if !profile.Processes[i].StartedAt.Equal(profile.Processes[j].StartedAt) {
+			return profile.Processes[i].StartedAt.Before(profile.Processes[j].StartedAt)
+		}
+		return profile.Processes[i].PID < profile.Processes[j].PID
+	})
+	command := p.processes[root]
+	if command == nil {
+		command = &api.ProcessProfile{PID: root}
+	}
+	command.StartedAt, command.Duration = started, profile.WallTime
+	profile.Processes = append([]api.ProcessProfile{*command}, profile.Processes...)
+	return profile
+}
+
+// modifiedBytes sums the sizes of the files under a directory that were
+// modified since a time
+func modifiedBytes(dir string, since time.Time) (int64, error) {
+	var written int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !info.ModTime().Before(since) {
+			written += info.Size()
+		}
+		return nil
+	})
+	return written, err
+}
+
+// writeProfile writes the profile to the artifacts of the step
+func writeProfile(artifactDir string, profile api.StepProfile) error {
+	raw, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+	if err := os.MkdirAll(artifactDir, 0755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(artifactDir, api.StepProfileFilename), raw, 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+// writeTerminationMessage writes the profile to the termination message, unless
+// the command left a termination message of its own, which is not overwritten
+func writeTerminationMessage(path string, profile api.StepProfile) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat the termination message: %w", err)
+	}
+	if info.Size() > 0 {
+		return nil
+	}
+	raw, err := terminationMessage(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write the termination message: %w", err)
+	}
+	return nil
+}
+
+// terminationMessage serializes the profile so it fits in the termination
+// message of the container, for ci-operator to pick up. The processes that
+// ran the shortest are dropped until it does.
+func terminationMessage(profile api.StepProfile) ([]byte, error) {
+	processes := profile.Processes
+	for n := len(processes); ; n /= 2 {
+		profile.Processes = longestRunning(processes, n)
+		raw, err := json.Marshal(profile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal profile: %w", err)
+		}
+		if len(raw) <= maxTerminationMessageBytes {
+			return raw, nil
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("profile is larger than %d bytes without any processes", maxTerminationMessageBytes)
+		}
+	}
+}
+
+// longestRunning keeps the n processes that ran the longest, in their original order
+func longestRunning(processes []api.ProcessProfile, n int) []api.ProcessProfile {
+	if n >= len(processes) {
+		return processes
+	}
+	indices := make([]int, len(processes))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return processes[indices[i]].Duration > processes[indices[j]].Duration
+	})
+	indices = indices[:n]
+	sort.Ints(indices)
+	var ret []api.ProcessProfile
+	for _, i := range indices {
+		ret = append(ret, processes[i])
+	}
+	return ret
+}
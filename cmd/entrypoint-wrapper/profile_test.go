@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+type fakeProcess struct {
+	pid, parent   int
+	comm, cmdline string
+}
+
+func writeProcesses(t *testing.T, procDir string, processes []fakeProcess) {
+	if err := os.RemoveAll(procDir); err != nil {
+		t.Fatalf("failed to clean up processes: %v", err)
+	}
+	for _, p := range processes {
+		dir := filepath.Join(procDir, strconv.Itoa(p.pid))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create process directory: %v", err)
+		}
+		stat := fmt.Sprintf("%d (%s) S %d 1 1 0 -1", p.pid, p.comm, p.parent)
+		if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+			t.Fatalf("failed to write stat: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(p.cmdline), 0644); err != nil {
+			t.Fatalf("failed to write cmdline: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(procDir, "sys"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	procDir := filepath.Join(t.TempDir(), "proc")
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	p := newProfiler(procDir)
+	writeProcesses(t, procDir, []fakeProcess{
+		{pid: 1, comm: "entrypoint"},
+		{pid: 10, parent: 1, comm: "bash", cmdline: "/bin/bash\x00-c\x00make test\x00"},
+		{pid: 11, parent: 10, comm: "make", cmdline: "make\x00test\x00"},
+		{pid: 20, parent: 1, comm: "sidecar", cmdline: "sidecar\x00"},
+	})
+	p.sample(10, start)
+	writeProcesses(t, procDir, []fakeProcess{
+		{pid: 1, comm: "entrypoint"},
+		{pid: 10, parent: 1, comm: "bash", cmdline: "/bin/bash\x00-c\x00make test\x00"},
+		{pid: 11, parent: 10, comm: "make", cmdline: "make\x00test\x00"},
+		{pid: 12, parent: 11, comm: "go (test)"},
+	})
+	p.sample(10, start.Add(time.Second))
+	writeProcesses(t, procDir, []fakeProcess{
+		{pid: 1, comm: "entrypoint"},
+		{pid: 10, parent: 1, comm: "bash", cmdline: "/bin/bash\x00-c\x00make test\x00"},
+		{pid: 11, parent: 10, comm: "make", cmdline: "make\x00test\x00"},
+	})
+	p.sample(10, start.Add(3*time.Second))
+
+	expected := api.StepProfile{
+		StartedAt:  start,
+		FinishedAt: start.Add(4 * time.Second),
+		WallTime:   4 * time.Second,
+		Processes: []api.ProcessProfile{
+			{PID: 10, Command: "/bin/bash -c make test", StartedAt: start, Duration: 4 * time.Second},
+			{PID: 11, Parent: 10, Command: "make test", StartedAt: start, Duration: 3 * time.Second},
+			{PID: 12, Parent: 11, Command: "go (test)", StartedAt: start.Add(time.Second)},
+		},
+	}
+	if diff := cmp.Diff(expected, p.profile(10, nil, start, start.Add(4*time.Second))); diff != "" {
+		t.Errorf("unexpected profile: %s", diff)
+	}
+}
+
+func TestModifiedBytes(t *testing.T) {
+	dir := t.TempDir()
+	since := time.Now().Add(-time.Minute)
+	for name, content := range map[string]string{"old": "content", "new": "new content", "nested/new": "more"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	old := since.Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old"), old, old); err != nil {
+		t.Fatalf("failed to change times: %v", err)
+	}
+	modified, err := modifiedBytes(dir, since)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := int64(len("new content") + len("more")); modified != expected {
+		t.Errorf("expected %d bytes modified, got %d", expected, modified)
+	}
+	if modified, err := modifiedBytes(filepath.Join(dir, "missing"), since); err != nil || modified != 0 {
+		t.Errorf("expected no bytes modified in a missing directory, got %d: %v", modified, err)
+	}
+}
+
+func TestTerminationMessage(t *testing.T) {
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	profile := api.StepProfile{StartedAt: start, WallTime: time.Hour}
+	for i := 0; i < 100; i++ {
+		profile.Processes = append(profile.Processes, api.ProcessProfile{
+			PID:       i + 1,
+			Command:   strings.Repeat("x", 100),
+			StartedAt: start.Add(time.Duration(i) * time.Second),
+			Duration:  time.Duration(i%10) * time.Second,
+		})
+	}
+	raw, err := terminationMessage(profile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(raw) > maxTerminationMessageBytes {
+		t.Errorf("termination message has %d bytes, more than the limit", len(raw))
+	}
+	if !strings.Contains(string(raw), `"pid":10,`) || strings.Contains(string(raw), `"pid":1,`) {
+		t.Errorf("expected the processes that ran the shortest to be dropped: %s", raw)
+	}
+
+	small := api.StepProfile{StartedAt: start, WallTime: time.Hour, Processes: profile.Processes[:2]}
+	raw, err = terminationMessage(small)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(raw), `"pid":1,`) || !strings.Contains(string(raw), `"pid":2,`) {
+		t.Errorf("expected all processes to be kept: %s", raw)
+	}
+}
+
+func TestWriteTerminationMessage(t *testing.T) {
+	profile := api.StepProfile{StartedAt: time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC), WallTime: time.Hour}
+	expected, err := terminationMessage(profile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		existing string
+		expected string
+	}{
+		{
+			name:     "empty termination message is replaced by the profile",
+			expected: string(expected),
+		},
+		{
+			name:     "termination message of the command is kept",
+			existing: "the command explains itself",
+			expected: "the command explains itself",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "termination-log")
+			if err := os.WriteFile(path, []byte(tc.existing), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			if err := writeTerminationMessage(path, profile); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(actual)); diff != "" {
+				t.Errorf("unexpected termination message: %s", diff)
+			}
+		})
+	}
+}
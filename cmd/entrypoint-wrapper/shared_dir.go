@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+// restoreSharedDir copies the files that were moved to the volume backing
+// SHARED_DIR back into it, so the command sees every file wherever it is kept.
+// The index of the volume is never left for the command to see.
+func restoreSharedDir(dir, store string) error {
+	indexPath := filepath.Join(dir, api.SharedDirStoreIndexKey)
+	raw, err := os.ReadFile(indexPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read the index: %w", err)
+	}
+	if err := os.Remove(indexPath); err != nil {
+		return fmt.Errorf("failed to remove the index: %w", err)
+	}
+	var index api.SharedDirStoreIndex
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return fmt.Errorf("failed to parse the index: %w", err)
+	}
+	names := make([]string, 0, len(index))
+	for name := range index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if store == "" {
+		logrus.Warnf("The SHARED_DIR files %v are kept in a volume that is not available to this step.", names)
+		return nil
+	}
+	for _, name := range names {
+		entry := index[name]
+		if name == "." || name == ".." || filepath.Base(name) != name {
+			return fmt.Errorf("invalid file name in the index: %q", name)
+		}
+		if digest, err := hex.DecodeString(entry.SHA256); err != nil || len(digest) != sha256.Size {
+			return fmt.Errorf("invalid digest in the index for %s: %q", name, entry.SHA256)
+		}
+		content, err := os.ReadFile(filepath.Join(store, entry.SHA256))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// spillToStore moves the largest files of SHARED_DIR to the volume backing it
+// until the rest fits in a secret, recording the moved files in an index that
+// is kept in the secret
+func spillToStore(data map[string][]byte, store string) error {
+	index := api.SharedDirStoreIndex{}
+	var raw []byte
+	for len(data) > 0 {
+		total := len(raw)
+		largest := ""
+		for name, content := range data {
+			total += len(content)
+			if largest == "" || len(content) > len(data[largest]) || (len(content) == len(data[largest]) && name < largest) {
+				largest = name
+			}
+		}
+		if total <= coreapi.MaxSecretSize {
+			break
+		}
+		content := data[largest]
+		digest := sha256.Sum256(content)
+		entry := api.SharedDirStoreEntry{Size: int64(len(content)), SHA256: hex.EncodeToString(digest[:])}
+		if err := storeFile(store, entry.SHA256, content); err != nil {
+			return fmt.Errorf("failed to move %s to the SHARED_DIR storage: %w", largest, err)
+		}
+		logrus.Infof("Moved %s (%s) to the SHARED_DIR storage.", largest, util.FormatSize(len(content)))
+		index[largest] = entry
+		delete(data, largest)
+		var err error
+		if raw, err = json.Marshal(index); err != nil {
+			return fmt.Errorf("failed to marshal the index: %w", err)
+		}
+	}
+	if len(index) > 0 {
+		data[api.SharedDirStoreIndexKey] = raw
+	}
+	return nil
+}
+
+// storeFile writes a file to the volume under its digest. Files are written
+// once, atomically, as concurrent uploads of SHARED_DIR may store the same one.
+func storeFile(store, digest string, content []byte) error {
+	path := filepath.Join(store, digest)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp(store, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
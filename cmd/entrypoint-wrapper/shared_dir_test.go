@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+func TestSpillAndRestoreSharedDir(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), coreapi.MaxSecretSize/2)
+	larger := bytes.Repeat([]byte("b"), coreapi.MaxSecretSize/2+1)
+	original := map[string][]byte{"kubeconfig": []byte("kubeconfig"), "large": large, "larger": larger}
+	data := map[string][]byte{}
+	for name, content := range original {
+		data[name] = content
+	}
+	store := t.TempDir()
+	if err := spillToStore(data, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, moved := data["larger"]; moved {
+		t.Errorf("expected the largest file to be moved to the store")
+	}
+	if _, moved := data["large"]; !moved {
+		t.Errorf("expected only as many files as needed to be moved to the store")
+	}
+	if err := util.ValidateSecretSize(data); err != nil {
+		t.Errorf("expected the data to fit in a secret: %v", err)
+	}
+	// storing the same content again is a no-op
+	if err := spillToStore(map[string][]byte{"large": large, "larger": larger}, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries, err := os.ReadDir(store); err != nil || len(entries) != 1 {
+		t.Errorf("expected a single file in the store, got %v: %v", entries, err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range data {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := restoreSharedDir(dir, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restored, err := util.SecretFromDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if diff := cmp.Diff(original, restored.Data); diff != "" {
+		t.Errorf("unexpected restored files: %s", diff)
+	}
+}
+
+func TestRestoreSharedDir(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		index    string
+		store    bool
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "no index",
+			store:    true,
+			expected: []string{"kubeconfig"},
+		},
+		{
+			name:     "no store, index is removed",
+			index:    `{"large":{"size":1,"sha256":"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}}`,
+			expected: []string{"kubeconfig"},
+		},
+		{
+			name:  "file name outside of the directory",
+			index: `{"../large":{"size":1,"sha256":"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}}`,
+			store: true,
+			err:   true,
+		},
+		{
+			name:  "digest outside of the store",
+			index: `{"large":{"size":1,"sha256":"../etc/passwd"}}`,
+			store: true,
+			err:   true,
+		},
+		{
+			name:  "file missing from the store",
+			index: `{"large":{"size":1,"sha256":"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}}`,
+			store: true,
+			err:   true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "kubeconfig"), []byte("kubeconfig"), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			if tc.index != "" {
+				if err := os.WriteFile(filepath.Join(dir, api.SharedDirStoreIndexKey), []byte(tc.index), 0644); err != nil {
+					t.Fatalf("failed to write index: %v", err)
+				}
+			}
+			var store string
+			if tc.store {
+				store = t.TempDir()
+			}
+			err := restoreSharedDir(dir, store)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error: %t, got: %v", tc.err, err)
+			}
+			if tc.err {
+				return
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("failed to read directory: %v", err)
+			}
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			if diff := cmp.Diff(tc.expected, names); diff != "" {
+				t.Errorf("unexpected files: %s", diff)
+			}
+		})
+	}
+}
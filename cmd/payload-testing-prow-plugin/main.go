@@ -34,6 +34,7 @@ type options struct {
 	namespace                string
 	ciOpConfigDir            string
 	webhookSecretFile        string
+	comparisonReportInterval time.Duration
 }
 
 func gatherOptions() options {
@@ -48,6 +49,7 @@ func gatherOptions() options {
 	o.kubernetesOptions.AddFlags(fs)
 	fs.StringVar(&o.namespace, "namespace", "ci", "Namespace to create PullRequestPayloadQualificationRuns.")
 	fs.StringVar(&o.ciOpConfigDir, "ci-op-config-dir", "", "Path to CI Operator configuration directory.")
+	fs.DurationVar(&o.comparisonReportInterval, "comparison-report-interval", 5*time.Minute, "How often to report the comparison of finished runs with the base payload to their PRs.")
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
 	}
@@ -62,6 +64,9 @@ func (o *options) Validate() error {
 	if o.ciOpConfigDir == "" {
 		return fmt.Errorf("--ci-op-config-dir must be set")
 	}
+	if o.comparisonReportInterval <= 0 {
+		return fmt.Errorf("--comparison-report-interval must be positive")
+	}
 	if err := o.kubernetesOptions.Validate(false); err != nil {
 		return err
 	}
@@ -168,6 +173,10 @@ func main() {
 		eventServer.GracefulShutdown()
 	})
 
+	interrupts.TickLiteral(func() {
+		serv.reportComparisons(logger)
+	}, o.comparisonReportInterval)
+
 	health := pjutil.NewHealth()
 	health.ServeReady()
 
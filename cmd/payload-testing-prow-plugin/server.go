@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
@@ -28,11 +30,16 @@ import (
 
 const (
 	prPayloadTestsUIURL = "https://pr-payload-tests.ci.openshift.org/runs"
+
+	// comparisonReportedAnnotation holds the digest of the comparison last reported for a run
+	comparisonReportedAnnotation = "ci.openshift.io/payload-comparison-reported"
 )
 
 type githubClient interface {
 	CreateComment(owner, repo string, number int, comment string) error
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+	EditComment(org, repo string, id int, comment string) error
 }
 
 var (
@@ -40,6 +47,8 @@ var (
 	ocpPayloadJobTestsPattern           = regexp.MustCompile(`(?mi)^/payload-job\s+((?:[-\w.]+\s*?)+)\s*$`)
 	ocpPayloadAggregatedJobTestsPattern = regexp.MustCompile(`(?mi)^/payload-aggregate\s+(?P<job>[-\w.]+)\s+(?P<aggregate>\d+)\s*$`)
 	ocpPayloadAbortPattern              = regexp.MustCompile(`(?mi)^/payload-abort$`)
+	ocpPayloadWithPRsPattern            = regexp.MustCompile(`(?mi)^/payload-with-prs\s+(?P<ocp>4\.\d+)\s+(?P<release>\w+)\s+(?P<jobs>\w+)\s+(?P<prs>(?:[-\w.]+/[-\w.]+#\d+\s*?)+)\s*$`)
+	pullRequestReferencePattern         = regexp.MustCompile(`^(?P<org>[-\w.]+)/(?P<repo>[-\w.]+)#(?P<number>\d+)$`)
 )
 
 func helpProvider(_ []prowconfig.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -61,6 +70,12 @@ func helpProvider(_ []prowconfig.OrgRepo) (*pluginhelp.PluginHelp, error) {
 		WhoCanUse:   "Members of the trusted organization for the repo.",
 		Examples:    []string{"/payload-abort"},
 	})
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       "/payload-with-prs <ocp> <release> <jobs> <org/repo#number>...",
+		Description: "The payload-testing plugin triggers a run of specified release qualification jobs against a payload built from the code of the PR together with the listed PRs, which may target other repositories and branches",
+		WhoCanUse:   "Members of the trusted organization for the repo.",
+		Examples:    []string{"/payload-with-prs 4.14 nightly informing openshift/installer#123", "/payload-with-prs 4.14 ci blocking openshift/installer#123 openshift/api#456"},
+	})
 	return pluginHelp, nil
 }
 
@@ -95,6 +110,18 @@ type jobSetSpecification struct {
 	ocp         string
 	releaseType api.ReleaseStream
 	jobs        config.JobType
+	// prs are the PRs to be tested together with the PR the command was issued on
+	prs []pullRequestReference
+}
+
+type pullRequestReference struct {
+	org    string
+	repo   string
+	number int
+}
+
+func (r pullRequestReference) String() string {
+	return fmt.Sprintf("%s/%s#%d", r.org, r.repo, r.number)
 }
 
 type jobResolver interface {
@@ -107,9 +134,6 @@ type testResolver interface {
 
 func specsFromComment(comment string) []jobSetSpecification {
 	matches := ocpPayloadTestsPattern.FindAllStringSubmatch(comment, -1)
-	if len(matches) == 0 {
-		return nil
-	}
 	var specs []jobSetSpecification
 	ocpIdx := ocpPayloadTestsPattern.SubexpIndex("ocp")
 	releaseIdx := ocpPayloadTestsPattern.SubexpIndex("release")
@@ -122,6 +146,42 @@ func specsFromComment(comment string) []jobSetSpecification {
 			jobs:        config.JobType(matches[i][jobsIdx]),
 		})
 	}
+	return append(specs, specsWithPRsFromComment(comment)...)
+}
+
+func specsWithPRsFromComment(comment string) []jobSetSpecification {
+	var specs []jobSetSpecification
+	ocpIdx := ocpPayloadWithPRsPattern.SubexpIndex("ocp")
+	releaseIdx := ocpPayloadWithPRsPattern.SubexpIndex("release")
+	jobsIdx := ocpPayloadWithPRsPattern.SubexpIndex("jobs")
+	prsIdx := ocpPayloadWithPRsPattern.SubexpIndex("prs")
+	for _, match := range ocpPayloadWithPRsPattern.FindAllStringSubmatch(comment, -1) {
+		spec := jobSetSpecification{
+			ocp:         match[ocpIdx],
+			releaseType: api.ReleaseStream(match[releaseIdx]),
+			jobs:        config.JobType(match[jobsIdx]),
+		}
+		for _, ref := range strings.Fields(match[prsIdx]) {
+			refMatch := pullRequestReferencePattern.FindStringSubmatch(ref)
+			if refMatch == nil {
+				// This should never happen
+				logrus.WithField("ref", ref).WithField("comment", comment).Error("failed to parse the pull request reference")
+				continue
+			}
+			number, err := strconv.Atoi(refMatch[pullRequestReferencePattern.SubexpIndex("number")])
+			if err != nil {
+				// This should never happen
+				logrus.WithField("ref", ref).WithField("comment", comment).WithError(err).Error("failed to parse the pull request number")
+				continue
+			}
+			spec.prs = append(spec.prs, pullRequestReference{
+				org:    refMatch[pullRequestReferencePattern.SubexpIndex("org")],
+				repo:   refMatch[pullRequestReferencePattern.SubexpIndex("repo")],
+				number: number,
+			})
+		}
+		specs = append(specs, spec)
+	}
 	return specs
 }
 
@@ -260,6 +320,11 @@ func (s *server) handle(l *logrus.Entry, ic github.IssueCommentEvent) string {
 			"jobs":        spec.jobs,
 		})
 		builder.spec = spec
+		additionalPRs, err := s.additionalPullRequests(org, repo, prNumber, spec.prs, specLogger)
+		if err != nil {
+			return formatError(err)
+		}
+		builder.additionalPRs = additionalPRs
 		var jobNames []string
 		var releaseJobSpecs []prpqv1.ReleaseJobSpec
 
@@ -340,6 +405,24 @@ func (s *server) handle(l *logrus.Entry, ic github.IssueCommentEvent) string {
 	return strings.Join(messages, "\n")
 }
 
+// additionalPullRequests returns the PRs to be tested together with the PR the command was issued on.
+// The PRs may target any repository and branch: the images of every repository are built into the payload.
+func (s *server) additionalPullRequests(org, repo string, prNumber int, refs []pullRequestReference, logger *logrus.Entry) ([]prpqv1.PullRequestUnderTest, error) {
+	var prs []prpqv1.PullRequestUnderTest
+	for _, ref := range refs {
+		if ref.org == org && ref.repo == repo && ref.number == prNumber {
+			continue
+		}
+		pr, err := s.ghc.GetPullRequest(ref.org, ref.repo, ref.number)
+		if err != nil {
+			logger.WithError(err).WithField("pr", ref.String()).Error("could not get pull request")
+			return nil, fmt.Errorf("could not get pull request https://github.com/%s/%s/pull/%d: %w", ref.org, ref.repo, ref.number, err)
+		}
+		prs = append(prs, pullRequestUnderTest(ref.org, ref.repo, ref.number, pr))
+	}
+	return prs, nil
+}
+
 func (s *server) abortAll(logger *logrus.Entry, ic github.IssueCommentEvent) string {
 	org := ic.Repo.Owner.Login
 	repo := ic.Repo.Name
@@ -438,6 +521,8 @@ type prpqrBuilder struct {
 	counter   int
 	pr        *github.PullRequest
 	spec      jobSetSpecification
+	// additionalPRs are tested together with pr
+	additionalPRs []prpqv1.PullRequestUnderTest
 }
 
 func (b *prpqrBuilder) build(releaseJobSpecs []prpqv1.ReleaseJobSpec) *prpqv1.PullRequestPayloadQualificationRun {
@@ -463,24 +548,140 @@ func (b *prpqrBuilder) build(releaseJobSpecs []prpqv1.ReleaseJobSpec) *prpqv1.Pu
 					Specifier: string(b.spec.jobs),
 				},
 			},
-			PullRequest: prpqv1.PullRequestUnderTest{
-				Org:     b.org,
-				Repo:    b.repo,
-				BaseRef: b.pr.Base.Ref,
-				BaseSHA: b.pr.Base.SHA,
-				PullRequest: prpqv1.PullRequest{
-					Number: b.prNumber,
-					Author: b.pr.User.Login,
-					SHA:    b.pr.Head.SHA,
-					Title:  b.pr.Title,
-				},
-			},
+			PullRequest: pullRequestUnderTest(b.org, b.repo, b.prNumber, b.pr),
 		},
 	}
+	if len(b.additionalPRs) > 0 {
+		run.Spec.PullRequests = append([]prpqv1.PullRequestUnderTest{run.Spec.PullRequest}, b.additionalPRs...)
+	}
 	b.counter++
 	return run
 }
 
+func pullRequestUnderTest(org, repo string, number int, pr *github.PullRequest) prpqv1.PullRequestUnderTest {
+	return prpqv1.PullRequestUnderTest{
+		Org:     org,
+		Repo:    repo,
+		BaseRef: pr.Base.Ref,
+		BaseSHA: pr.Base.SHA,
+		PullRequest: prpqv1.PullRequest{
+			Number: number,
+			Author: pr.User.Login,
+			SHA:    pr.Head.SHA,
+			Title:  pr.Title,
+		},
+	}
+}
+
+// reportComparisons comments on the PRs the comparison of the results of their finished runs
+// with the results on the base payload. The results on the base payload can become known after
+// the runs finished, so the comment of a run is updated in place whenever its comparison changes.
+func (s *server) reportComparisons(logger *logrus.Entry) {
+	var runs prpqv1.PullRequestPayloadQualificationRunList
+	if err := s.kubeClient.List(s.ctx, &runs, ctrlruntimeclient.InNamespace(s.namespace), ctrlruntimeclient.MatchingLabels{api.DPTPRequesterLabel: pluginName}); err != nil {
+		logger.WithError(err).Error("failed to list runs")
+		return
+	}
+	for i := range runs.Items {
+		run := &runs.Items[i]
+		if meta.FindStatusCondition(run.Status.Conditions, prpqv1.ConditionComparedToBase) == nil {
+			continue
+		}
+		message := comparisonMessage(run)
+		digest := fmt.Sprintf("%x", sha256.Sum256([]byte(message)))
+		if run.Annotations[comparisonReportedAnnotation] == digest {
+			continue
+		}
+		runLogger := logger.WithField("run", run.Name)
+		org, repo := run.Labels[kube.OrgLabel], run.Labels[kube.RepoLabel]
+		prNumber, err := strconv.Atoi(run.Labels[kube.PullLabel])
+		if err != nil {
+			runLogger.WithError(err).Error("failed to determine the pull request of the run")
+			continue
+		}
+		if err := s.upsertComparisonComment(org, repo, prNumber, run, message); err != nil {
+			runLogger.WithError(err).Error("failed to report the comparison")
+			continue
+		}
+
+		original := run.DeepCopy()
+		if run.Annotations == nil {
+			run.Annotations = map[string]string{}
+		}
+		run.Annotations[comparisonReportedAnnotation] = digest
+		if err := s.kubeClient.Patch(s.ctx, run, ctrlruntimeclient.MergeFrom(original)); err != nil {
+			runLogger.WithError(err).Error("failed to mark the run as reported")
+		}
+	}
+}
+
+// comparisonMarker starts the comment reporting the comparison of a run, so it can be found again
+func comparisonMarker(run *prpqv1.PullRequestPayloadQualificationRun) string {
+	return fmt.Sprintf("<!-- payload-comparison %s/%s -->", run.Namespace, run.Name)
+}
+
+// upsertComparisonComment posts the comparison of a run, or updates the comment it was posted in before
+func (s *server) upsertComparisonComment(org, repo string, number int, run *prpqv1.PullRequestPayloadQualificationRun, message string) error {
+	comments, err := s.ghc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to get comments for pull request: %w", err)
+	}
+	for _, comment := range comments {
+		if strings.HasPrefix(comment.Body, comparisonMarker(run)) {
+			if err := s.ghc.EditComment(org, repo, comment.ID, message); err != nil {
+				return fmt.Errorf("failed to update comment: %w", err)
+			}
+			return nil
+		}
+	}
+	if err := s.ghc.CreateComment(org, repo, number, message); err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	return nil
+}
+
+func comparisonMessage(run *prpqv1.PullRequestPayloadQualificationRun) string {
+	lines := []string{
+		comparisonMarker(run),
+		fmt.Sprintf("Payload jobs of %s/%s/%s finished, compared with the same jobs on the base payload:", prPayloadTestsUIURL, run.Namespace, run.Name),
+		"",
+		"Job | Result | Base | Comparison",
+		"--- | --- | --- | ---",
+	}
+	for _, job := range run.Status.Jobs {
+		base := "N/A"
+		if job.BaseStatus != nil {
+			base = formatJobResult(*job.BaseStatus)
+			if job.BasePayload != "" {
+				base = fmt.Sprintf("%s on %s", base, job.BasePayload)
+			}
+		}
+		var comparison string
+		switch job.Comparison {
+		case prpqv1.PayloadJobRegressed:
+			comparison = ":x: regressed"
+		case prpqv1.PayloadJobFixed:
+			comparison = ":heavy_check_mark: fixed"
+		case prpqv1.PayloadJobSameAsBase:
+			comparison = "same as base"
+		default:
+			comparison = "N/A"
+		}
+		lines = append(lines, fmt.Sprintf("%s | %s | %s | %s", job.ReleaseJobName, formatJobResult(job.Status), base, comparison))
+	}
+	if condition := meta.FindStatusCondition(run.Status.Conditions, prpqv1.ConditionComparedToBase); condition != nil {
+		lines = append(lines, "", condition.Message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatJobResult(status prowapi.ProwJobStatus) string {
+	if status.URL == "" {
+		return string(status.State)
+	}
+	return fmt.Sprintf("[%s](%s)", status.State, status.URL)
+}
+
 func message(spec jobSetSpecification, tests []string) string {
 	var b strings.Builder
 	if spec.ocp == "" {
@@ -488,6 +689,13 @@ func message(spec jobSetSpecification, tests []string) string {
 	} else {
 		b.WriteString(fmt.Sprintf("trigger %d job(s) of type %s for the %s release of OCP %s\n", len(tests), spec.jobs, spec.releaseType, spec.ocp))
 	}
+	if len(spec.prs) > 0 {
+		var prs []string
+		for _, pr := range spec.prs {
+			prs = append(prs, pr.String())
+		}
+		b.WriteString(fmt.Sprintf("together with %s\n", strings.Join(prs, ", ")))
+	}
 	for _, test := range tests {
 		b.WriteString(fmt.Sprintf("- %s\n", test))
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"strings"
 	"testing"
@@ -15,6 +16,7 @@ import (
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/kube"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/openshift/ci-tools/pkg/api"
@@ -24,7 +26,7 @@ import (
 )
 
 func (j1 jobSetSpecification) Equals(j2 jobSetSpecification) bool {
-	return cmp.Equal(j1, j2, cmp.AllowUnexported(jobSetSpecification{}))
+	return cmp.Equal(j1, j2, cmp.AllowUnexported(jobSetSpecification{}, pullRequestReference{}))
 }
 
 func TestSpecsFromComment(t *testing.T) {
@@ -52,12 +54,29 @@ func TestSpecsFromComment(t *testing.T) {
 			comment:  "/payload 4.10 nightly informing\n/payload 4.8 ci all",
 			expected: []jobSetSpecification{{ocp: "4.10", releaseType: "nightly", jobs: "informing"}, {ocp: "4.8", releaseType: "ci", jobs: "all"}},
 		},
+		{
+			name:    "/payload-with-prs 4.14 nightly informing openshift/api#123 openshift/installer#456",
+			comment: "/payload-with-prs 4.14 nightly informing openshift/api#123 openshift/installer#456",
+			expected: []jobSetSpecification{{ocp: "4.14", releaseType: "nightly", jobs: "informing", prs: []pullRequestReference{
+				{org: "openshift", repo: "api", number: 123},
+				{org: "openshift", repo: "installer", number: 456},
+			}}},
+		},
+		{
+			name:    "/payload-with-prs without PRs",
+			comment: "/payload-with-prs 4.14 nightly informing",
+		},
+		{
+			name:     "/payload and /payload-with-prs",
+			comment:  "/payload 4.10 nightly informing\n/payload-with-prs 4.14 ci blocking openshift/api#123",
+			expected: []jobSetSpecification{{ocp: "4.10", releaseType: "nightly", jobs: "informing"}, {ocp: "4.14", releaseType: "ci", jobs: "blocking", prs: []pullRequestReference{{org: "openshift", repo: "api", number: 123}}}},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			actual := specsFromComment(tc.comment)
 			if diff := cmp.Diff(tc.expected, actual, cmp.Comparer(func(x, y jobSetSpecification) bool {
-				return cmp.Diff(x.ocp, y.ocp) == "" && cmp.Diff(x.releaseType, y.releaseType) == "" && cmp.Diff(x.jobs, y.jobs) == ""
+				return x.Equals(y)
 			})); diff != "" {
 				t.Errorf("%s differs from expected:\n%s", tc.name, diff)
 			}
@@ -118,6 +137,15 @@ func TestMessage(t *testing.T) {
 			expected: `trigger 2 job(s) of type informing for the nightly release of OCP 4.10
 - dummy-ocp-4.10-nightly-informing-job1
 - dummy-ocp-4.10-nightly-informing-job2
+`,
+		},
+		{
+			name: "with additional PRs",
+			spec: jobSetSpecification{ocp: "4.10", releaseType: "nightly", jobs: "informing", prs: []pullRequestReference{{org: "openshift", repo: "api", number: 123}}},
+			expected: `trigger 2 job(s) of type informing for the nightly release of OCP 4.10
+together with openshift/api#123
+- dummy-ocp-4.10-nightly-informing-job1
+- dummy-ocp-4.10-nightly-informing-job2
 `,
 		},
 	}
@@ -310,10 +338,11 @@ func init() {
 
 func TestBuild(t *testing.T) {
 	testCases := []struct {
-		name      string
-		spec      jobSetSpecification
-		jobTuples []prpqv1.ReleaseJobSpec
-		expected  *prpqv1.PullRequestPayloadQualificationRun
+		name          string
+		spec          jobSetSpecification
+		additionalPRs []prpqv1.PullRequestUnderTest
+		jobTuples     []prpqv1.ReleaseJobSpec
+		expected      *prpqv1.PullRequestPayloadQualificationRun
 	}{
 		{
 			name: "basic case",
@@ -438,6 +467,51 @@ func TestBuild(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:          "additional PRs",
+			spec:          jobSetSpecification{ocp: "4.14", releaseType: "ci", jobs: "blocking", prs: []pullRequestReference{{org: "openshift", repo: "api", number: 456}}},
+			additionalPRs: []prpqv1.PullRequestUnderTest{{Org: "org", Repo: "repo", BaseRef: "ref", BaseSHA: "sha", PullRequest: prpqv1.PullRequest{Number: 456, Author: "other", SHA: "other-head-sha", Title: "other change"}}},
+			jobTuples: []prpqv1.ReleaseJobSpec{
+				{
+					CIOperatorConfig: prpqv1.CIOperatorMetadata{Org: "openshift", Repo: "release", Branch: "master", Variant: "ci-4.14"},
+					Test:             "e2e-aws",
+				},
+			},
+			expected: &prpqv1.PullRequestPayloadQualificationRun{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-guid-0",
+					Namespace: "ci",
+					Labels: map[string]string{
+						"dptp.openshift.io/requester": "payload-testing",
+						"event-GUID":                  "some-guid",
+						"prow.k8s.io/refs.org":        "org",
+						"prow.k8s.io/refs.pull":       "123",
+						"prow.k8s.io/refs.repo":       "repo",
+						"prow.k8s.io/refs.base_ref":   "ref",
+					},
+				},
+				Spec: prpqv1.PullRequestPayloadTestSpec{
+					PullRequest: prpqv1.PullRequestUnderTest{Org: "org",
+						Repo:        "repo",
+						BaseRef:     "ref",
+						BaseSHA:     "sha",
+						PullRequest: prpqv1.PullRequest{Number: 123, Author: "login", SHA: "head-sha", Title: "title"}},
+					PullRequests: []prpqv1.PullRequestUnderTest{
+						{Org: "org", Repo: "repo", BaseRef: "ref", BaseSHA: "sha", PullRequest: prpqv1.PullRequest{Number: 123, Author: "login", SHA: "head-sha", Title: "title"}},
+						{Org: "org", Repo: "repo", BaseRef: "ref", BaseSHA: "sha", PullRequest: prpqv1.PullRequest{Number: 456, Author: "other", SHA: "other-head-sha", Title: "other change"}},
+					},
+					Jobs: prpqv1.PullRequestPayloadJobSpec{
+						ReleaseControllerConfig: prpqv1.ReleaseControllerConfig{OCP: "4.14", Release: "ci", Specifier: "blocking"},
+						Jobs: []prpqv1.ReleaseJobSpec{
+							{
+								CIOperatorConfig: prpqv1.CIOperatorMetadata{Org: "openshift", Repo: "release", Branch: "master", Variant: "ci-4.14"},
+								Test:             "e2e-aws",
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -460,7 +534,8 @@ func TestBuild(t *testing.T) {
 						Login: "login",
 					},
 				},
-				spec: tc.spec,
+				spec:          tc.spec,
+				additionalPRs: tc.additionalPRs,
 			}
 			actual := builder.build(tc.jobTuples)
 			if diff := cmp.Diff(tc.expected, actual, testhelper.RuntimeObjectIgnoreRvTypeMeta); diff != "" {
@@ -483,7 +558,9 @@ func (c *fakeTrustedChecker) trustedUser(author, _, _ string, _ int) (bool, erro
 func TestHandle(t *testing.T) {
 	ghc := fakegithub.NewFakeClient()
 	pr123 := github.PullRequest{}
-	ghc.PullRequests = map[int]*github.PullRequest{123: &pr123}
+	pr124 := github.PullRequest{}
+	pr125 := github.PullRequest{Base: github.PullRequestBranch{Ref: "release-4.10"}}
+	ghc.PullRequests = map[int]*github.PullRequest{123: &pr123, 124: &pr124, 125: &pr125}
 
 	testCases := []struct {
 		name     string
@@ -618,6 +695,139 @@ See details on https://pr-payload-tests.ci.openshift.org/runs/ci/guid-0
 See details on https://pr-payload-tests.ci.openshift.org/runs/ci/guid-0
 
 trigger 0 job(s) of type all for the ci release of OCP 4.8
+`,
+		},
+		{
+			name: "payload-with-prs",
+			s: &server{
+				ghc:        ghc,
+				ctx:        context.TODO(),
+				kubeClient: fakeclient.NewClientBuilder().Build(),
+				namespace:  "ci",
+				jobResolver: newFakeJobResolver(map[string][]config.Job{"4.10": {
+					{Name: "periodic-ci-openshift-release-master-nightly-4.10-e2e-aws-serial"},
+				}}),
+				testResolver:       newFakeTestResolver(),
+				trustedChecker:     &fakeTrustedChecker{},
+				ciOpConfigResolver: &fakeCIOpConfigResolver{},
+			},
+			ic: github.IssueCommentEvent{
+				GUID: "guid",
+				Repo: github.Repo{Owner: github.User{Login: "openshift"}, Name: "origin"},
+				Issue: github.Issue{
+					Number:      123,
+					PullRequest: &struct{}{},
+				},
+				Comment: github.IssueComment{
+					Body: "/payload-with-prs 4.10 nightly informing openshift/origin#124",
+				},
+			},
+			expected: `trigger 1 job(s) of type informing for the nightly release of OCP 4.10
+together with openshift/origin#124
+- periodic-ci-openshift-release-master-nightly-4.10-e2e-aws-serial
+
+See details on https://pr-payload-tests.ci.openshift.org/runs/ci/guid-0
+`,
+		},
+		{
+			name: "payload-with-prs for a missing PR",
+			s: &server{
+				ghc:        ghc,
+				ctx:        context.TODO(),
+				kubeClient: fakeclient.NewClientBuilder().Build(),
+				namespace:  "ci",
+				jobResolver: newFakeJobResolver(map[string][]config.Job{"4.10": {
+					{Name: "periodic-ci-openshift-release-master-nightly-4.10-e2e-aws-serial"},
+				}}),
+				testResolver:       newFakeTestResolver(),
+				trustedChecker:     &fakeTrustedChecker{},
+				ciOpConfigResolver: &fakeCIOpConfigResolver{},
+			},
+			ic: github.IssueCommentEvent{
+				GUID: "guid",
+				Repo: github.Repo{Owner: github.User{Login: "openshift"}, Name: "origin"},
+				Issue: github.Issue{
+					Number:      123,
+					PullRequest: &struct{}{},
+				},
+				Comment: github.IssueComment{
+					Body: "/payload-with-prs 4.10 nightly informing openshift/origin#999",
+				},
+			},
+			expected: `An error was encountered. No known errors were detected, please see the full error message for details.
+
+<details><summary>Full error message.</summary>
+
+<code>
+could not get pull request https://github.com/openshift/origin/pull/999: pull request number 999 does not exist
+</code>
+
+</details>
+
+Please contact an administrator to resolve this issue.`,
+		},
+		{
+			name: "payload-with-prs for a PR to another repository",
+			s: &server{
+				ghc:        ghc,
+				ctx:        context.TODO(),
+				kubeClient: fakeclient.NewClientBuilder().Build(),
+				namespace:  "ci",
+				jobResolver: newFakeJobResolver(map[string][]config.Job{"4.10": {
+					{Name: "periodic-ci-openshift-release-master-nightly-4.10-e2e-aws-serial"},
+				}}),
+				testResolver:       newFakeTestResolver(),
+				trustedChecker:     &fakeTrustedChecker{},
+				ciOpConfigResolver: &fakeCIOpConfigResolver{},
+			},
+			ic: github.IssueCommentEvent{
+				GUID: "guid",
+				Repo: github.Repo{Owner: github.User{Login: "openshift"}, Name: "origin"},
+				Issue: github.Issue{
+					Number:      123,
+					PullRequest: &struct{}{},
+				},
+				Comment: github.IssueComment{
+					Body: "/payload-with-prs 4.10 nightly informing openshift/api#124",
+				},
+			},
+			expected: `trigger 1 job(s) of type informing for the nightly release of OCP 4.10
+together with openshift/api#124
+- periodic-ci-openshift-release-master-nightly-4.10-e2e-aws-serial
+
+See details on https://pr-payload-tests.ci.openshift.org/runs/ci/guid-0
+`,
+		},
+		{
+			name: "payload-with-prs for a PR to another branch",
+			s: &server{
+				ghc:        ghc,
+				ctx:        context.TODO(),
+				kubeClient: fakeclient.NewClientBuilder().Build(),
+				namespace:  "ci",
+				jobResolver: newFakeJobResolver(map[string][]config.Job{"4.10": {
+					{Name: "periodic-ci-openshift-release-master-nightly-4.10-e2e-aws-serial"},
+				}}),
+				testResolver:       newFakeTestResolver(),
+				trustedChecker:     &fakeTrustedChecker{},
+				ciOpConfigResolver: &fakeCIOpConfigResolver{},
+			},
+			ic: github.IssueCommentEvent{
+				GUID: "guid",
+				Repo: github.Repo{Owner: github.User{Login: "openshift"}, Name: "origin"},
+				Issue: github.Issue{
+					Number:      123,
+					PullRequest: &struct{}{},
+				},
+				Comment: github.IssueComment{
+					Body: "/payload-with-prs 4.10 nightly informing openshift/origin#125",
+				},
+			},
+			expected: `trigger 1 job(s) of type informing for the nightly release of OCP 4.10
+together with openshift/origin#125
+- periodic-ci-openshift-release-master-nightly-4.10-e2e-aws-serial
+
+See details on https://pr-payload-tests.ci.openshift.org/runs/ci/guid-0
 `,
 		},
 		{
@@ -783,6 +993,95 @@ func (r *fakeTestResolver) resolve(job string) (api.MetadataWithTest, error) {
 	return api.MetadataWithTest{}, fmt.Errorf("failed to resolve job %s", job)
 }
 
+func TestReportComparisons(t *testing.T) {
+	run := func(name string, annotations map[string]string, conditions []metav1.Condition) *prpqv1.PullRequestPayloadQualificationRun {
+		return &prpqv1.PullRequestPayloadQualificationRun{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "ci",
+				Annotations: annotations,
+				Labels: map[string]string{
+					api.DPTPRequesterLabel: pluginName,
+					kube.OrgLabel:          "org",
+					kube.RepoLabel:         "repo",
+					kube.PullLabel:         "123",
+				},
+			},
+			Status: prpqv1.PullRequestPayloadTestStatus{
+				Conditions: conditions,
+				Jobs: []prpqv1.PullRequestPayloadJobStatus{
+					{
+						ReleaseJobName: "periodic-ci-openshift-release-master-nightly-4.10-e2e-aws",
+						Status:         prowapi.ProwJobStatus{State: prowapi.FailureState, URL: "https://prow/pr"},
+						BasePayload:    "4.10.0-0.nightly-2023-01-01-000000",
+						BaseStatus:     &prowapi.ProwJobStatus{State: prowapi.SuccessState, URL: "https://prow/base"},
+						Comparison:     prpqv1.PayloadJobRegressed,
+					},
+					{
+						ReleaseJobName: "periodic-ci-openshift-release-master-nightly-4.10-e2e-gcp",
+						Status:         prowapi.ProwJobStatus{State: prowapi.SuccessState},
+					},
+				},
+			},
+		}
+	}
+	compared := []metav1.Condition{{Type: prpqv1.ConditionComparedToBase, Status: metav1.ConditionFalse, Reason: "Regressed", Message: "1 of 2 jobs were compared with the base payload, jobs [periodic-ci-openshift-release-master-nightly-4.10-e2e-aws] regressed"}}
+	reported := run("reported", nil, compared)
+	reported.Annotations = map[string]string{comparisonReportedAnnotation: fmt.Sprintf("%x", sha256.Sum256([]byte(comparisonMessage(reported))))}
+	kubeClient := fakeclient.NewClientBuilder().WithObjects(
+		run("finished", nil, compared),
+		run("running", nil, nil),
+		reported,
+	).Build()
+	ghc := fakegithub.NewFakeClient()
+	s := &server{ghc: ghc, ctx: context.TODO(), kubeClient: kubeClient, namespace: "ci"}
+
+	s.reportComparisons(logrus.NewEntry(logrus.StandardLogger()))
+
+	var comments []string
+	for _, comment := range ghc.IssueComments[123] {
+		comments = append(comments, comment.Body)
+	}
+	expected := []string{`<!-- payload-comparison ci/finished -->
+Payload jobs of https://pr-payload-tests.ci.openshift.org/runs/ci/finished finished, compared with the same jobs on the base payload:
+
+Job | Result | Base | Comparison
+--- | --- | --- | ---
+periodic-ci-openshift-release-master-nightly-4.10-e2e-aws | [failure](https://prow/pr) | [success](https://prow/base) on 4.10.0-0.nightly-2023-01-01-000000 | :x: regressed
+periodic-ci-openshift-release-master-nightly-4.10-e2e-gcp | success | N/A | N/A
+
+1 of 2 jobs were compared with the base payload, jobs [periodic-ci-openshift-release-master-nightly-4.10-e2e-aws] regressed`}
+	if diff := cmp.Diff(expected, comments); diff != "" {
+		t.Errorf("comments differ from expected:\n%s", diff)
+	}
+
+	finished := &prpqv1.PullRequestPayloadQualificationRun{}
+	if err := kubeClient.Get(context.TODO(), ctrlruntimeclient.ObjectKey{Namespace: "ci", Name: "finished"}, finished); err != nil {
+		t.Fatalf("failed to get run: %v", err)
+	}
+	if _, reported := finished.Annotations[comparisonReportedAnnotation]; !reported {
+		t.Error("expected the finished run to be marked as reported")
+	}
+
+	// Runs are reported again only when their comparison changes, in the same comment
+	s.reportComparisons(logrus.NewEntry(logrus.StandardLogger()))
+	if len(ghc.IssueComments[123]) != 1 || len(ghc.IssueCommentsEdited) != 0 {
+		t.Errorf("expected a single unchanged comment, got %d comments and %d edits", len(ghc.IssueComments[123]), len(ghc.IssueCommentsEdited))
+	}
+	finished.Status.Jobs[1].BaseStatus = &prowapi.ProwJobStatus{State: prowapi.SuccessState}
+	finished.Status.Jobs[1].Comparison = prpqv1.PayloadJobSameAsBase
+	if err := kubeClient.Update(context.TODO(), finished); err != nil {
+		t.Fatalf("failed to update run: %v", err)
+	}
+	s.reportComparisons(logrus.NewEntry(logrus.StandardLogger()))
+	if len(ghc.IssueComments[123]) != 1 || len(ghc.IssueCommentsEdited) != 1 {
+		t.Fatalf("expected a single comment edited once, got %d comments and %d edits", len(ghc.IssueComments[123]), len(ghc.IssueCommentsEdited))
+	}
+	if edit := ghc.IssueCommentsEdited[0]; !strings.Contains(edit, "periodic-ci-openshift-release-master-nightly-4.10-e2e-gcp | success | success | same as base") {
+		t.Errorf("expected the comment to be updated with the new comparison, got:\n%s", edit)
+	}
+}
+
 func TestFormatError(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -89,7 +89,7 @@ Created: {{ .ObjectMeta.CreationTimestamp }}
 
 <h2>Pull request</h2>
 
-{{ with .PullRequest }}
+{{ range .PullRequestsUnderTest }}
 {{ prLink . }} by {{ authorLink .PullRequest.Author }}
 <ul>
 	<li>Repository: {{ repoLink .Org .Repo }}</li>
@@ -98,7 +98,7 @@ Created: {{ .ObjectMeta.CreationTimestamp }}
 		Base: <tt>{{ refLink . .BaseRef }}</tt> (<tt>{{ shaLink . .BaseSHA }}</tt>)
 	</li>
 </ul>
-{{ end }}{{/* with .PullRequest */}}
+{{ end }}{{/* range .PullRequestsUnderTest */}}
 
 {{ with .Jobs }}
 
@@ -125,6 +125,7 @@ Created: {{ .ObjectMeta.CreationTimestamp }}
         {{ else }}
           {{ $status.ProwJob }}
         {{ end }}
+        {{ with $status.Comparison }}({{ . }} compared with the base payload){{ end }}
       {{ else }}
         {{ jobText $job }}
       {{ end }}
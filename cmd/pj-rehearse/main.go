@@ -39,11 +39,15 @@ type options struct {
 	noTemplates       bool
 	noRegistry        bool
 	noClusterProfiles bool
+	versionsPath      string
 
 	normalLimit int
 	moreLimit   int
 	maxLimit    int
 
+	costModelPath string
+	budget        float64
+
 	gcsBucket          string
 	gcsCredentialsFile string
 	gcsBrowserPrefix   string
@@ -75,11 +79,15 @@ func gatherOptions() (options, error) {
 	fs.BoolVar(&o.noTemplates, "no-templates", false, "If true, do not attempt to compare templates")
 	fs.BoolVar(&o.noRegistry, "no-registry", false, "If true, do not attempt to compare step registry content")
 	fs.BoolVar(&o.noClusterProfiles, "no-cluster-profiles", false, "If true, do not attempt to compare cluster profiles")
+	fs.StringVar(&o.versionsPath, "registry-versions", "", "Optional directory with versions of the step registry tests can pin components to")
 
 	fs.IntVar(&o.normalLimit, "normal-limit", 10, "Upper limit of jobs attempted to rehearse with normal command (if more jobs are being touched, only this many will be rehearsed)")
 	fs.IntVar(&o.moreLimit, "more-limit", 20, "Upper limit of jobs attempted to rehearse with more command (if more jobs are being touched, only this many will be rehearsed)")
 	fs.IntVar(&o.maxLimit, "max-limit", 35, "Upper limit of jobs attempted to rehearse with max command (if more jobs are being touched, only this many will be rehearsed)")
 
+	fs.StringVar(&o.costModelPath, "rehearsal-cost-model", "", "Path to a file with the historical cost model of jobs. If set, the jobs to rehearse are selected to cover the most changes within --rehearsal-budget.")
+	fs.Float64Var(&o.budget, "rehearsal-budget", 10, "Cost of rehearsals allowed with the normal command, scaled proportionally to the limits of the other commands. Only used with --rehearsal-cost-model.")
+
 	fs.Var(&o.stickyLabelAuthors, "sticky-label-author", "PR Author for which the 'rehearsals-ack' label will not be removed upon a new push. Can be passed multiple times.")
 	fs.StringVar(&o.webhookSecretFile, "hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret.")
 
@@ -105,6 +113,10 @@ func (o *options) validate() error {
 	}
 	logrus.SetLevel(level)
 
+	if o.costModelPath != "" && o.budget <= 0 {
+		errs = append(errs, errors.New("--rehearsal-budget must be positive"))
+	}
+
 	if o.dryRun {
 		errs = append(errs, o.dryRunOptions.validate())
 	} else {
@@ -138,27 +150,40 @@ func (o *dryRunOptions) validate() error {
 	return nil
 }
 
-func rehearsalConfigFromOptions(o options) rehearse.RehearsalConfig {
-	return rehearse.RehearsalConfig{
-		ProwjobKubeconfig:  o.prowjobKubeconfig,
-		KubernetesOptions:  o.kubernetesOptions,
-		NoTemplates:        o.noTemplates,
-		NoRegistry:         o.noRegistry,
-		NoClusterProfiles:  o.noClusterProfiles,
-		DryRun:             o.dryRun,
-		NormalLimit:        o.normalLimit,
-		MoreLimit:          o.moreLimit,
-		MaxLimit:           o.maxLimit,
-		StickyLabelAuthors: o.stickyLabelAuthors.StringSet(),
-		GCSBucket:          o.gcsBucket,
-		GCSCredentialsFile: o.gcsCredentialsFile,
-		GCSBrowserPrefix:   o.gcsBrowserPrefix,
+func rehearsalConfigFromOptions(o options) (rehearse.RehearsalConfig, error) {
+	rc := rehearse.RehearsalConfig{
+		ProwjobKubeconfig:    o.prowjobKubeconfig,
+		KubernetesOptions:    o.kubernetesOptions,
+		NoTemplates:          o.noTemplates,
+		NoRegistry:           o.noRegistry,
+		NoClusterProfiles:    o.noClusterProfiles,
+		RegistryVersionsPath: o.versionsPath,
+		DryRun:               o.dryRun,
+		NormalLimit:          o.normalLimit,
+		MoreLimit:            o.moreLimit,
+		MaxLimit:             o.maxLimit,
+		StickyLabelAuthors:   o.stickyLabelAuthors.StringSet(),
+		GCSBucket:            o.gcsBucket,
+		GCSCredentialsFile:   o.gcsCredentialsFile,
+		GCSBrowserPrefix:     o.gcsBrowserPrefix,
+		Budget:               o.budget,
+	}
+	if o.costModelPath != "" {
+		costModel, err := rehearse.LoadCostModel(o.costModelPath)
+		if err != nil {
+			return rc, err
+		}
+		rc.CostModel = costModel
 	}
+	return rc, nil
 }
 
 func dryRun(o options, logger *logrus.Entry) error {
 	dro := o.dryRunOptions
-	rc := rehearsalConfigFromOptions(o)
+	rc, err := rehearsalConfigFromOptions(o)
+	if err != nil {
+		return err
+	}
 	rc.ProwjobNamespace = dro.testNamespace
 	rc.PodNamespace = dro.testNamespace
 
@@ -174,11 +199,16 @@ func dryRun(o options, logger *logrus.Entry) error {
 	candidatePath := dro.dryRunPath
 	candidate := rehearse.RehearsalCandidateFromPullRequest(pr, pr.Base.SHA)
 
-	presubmits, periodics, changedTemplates, changedClusterProfiles, err := rc.DetermineAffectedJobs(candidate, candidatePath, logger)
+	presubmits, periodics, changedTemplates, changedClusterProfiles, coverage, err := rc.DetermineAffectedJobs(candidate, candidatePath, logger)
 	if err != nil {
 		return fmt.Errorf("error determining affected jobs: %w: %s", err, "ERROR: pj-rehearse: misconfiguration")
 	}
 
+	presubmits, periodics, selection := rc.SelectJobsByCost(presubmits, periodics, coverage, dro.limit, logger)
+	if selection != nil {
+		logger.Info(selection.Describe())
+	}
+
 	prConfig, prRefs, imageStreamTags, presubmitsToRehearse, err := rc.SetupJobs(candidate, candidatePath, presubmits, periodics, changedTemplates, changedClusterProfiles, dro.limit, logger)
 	if err != nil {
 		return fmt.Errorf("error setting up jobs: %w: %s", err, "ERROR: pj-rehearse: setup failure")
@@ -189,7 +219,7 @@ func dryRun(o options, logger *logrus.Entry) error {
 			return fmt.Errorf("%s: %w", "ERROR: pj-rehearse: failed to validate rehearsal jobs", err)
 		}
 
-		_, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
+		_, _, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
 		return err
 	}
 
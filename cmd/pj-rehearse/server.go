@@ -7,6 +7,7 @@ import (
 	"io"
 	"math"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -24,6 +25,7 @@ import (
 
 const (
 	rehearsalNotifier  = "[REHEARSALNOTIFIER]"
+	rehearsalSummary   = "[REHEARSALSUMMARY]"
 	pjRehearse         = "pj-rehearse"
 	needsOkToTestLabel = "needs-ok-to-test"
 	rehearseNormal     = "/pj-rehearse"
@@ -34,6 +36,7 @@ const (
 	rehearseReject     = "/pj-rehearse reject"
 	rehearseAutoAck    = "/pj-rehearse auto-ack"
 	rehearseAbort      = "/pj-rehearse abort"
+	rehearseList       = "/pj-rehearse list"
 )
 
 var commentRegex = regexp.MustCompile(`(?m)^/pj-rehearse\f*.*$`)
@@ -46,6 +49,7 @@ type githubClient interface {
 	GetRef(org, repo, ref string) (string, error)
 	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
 	DeleteComment(org, repo string, id int) error
+	EditComment(org, repo string, id int, comment string) error
 }
 
 type server struct {
@@ -71,6 +75,18 @@ func (s *server) helpProvider(_ []prowconfig.OrgRepo) (*pluginhelp.PluginHelp, e
 		WhoCanUse:   "Anyone can use on trusted PRs",
 		Examples:    []string{fmt.Sprintf("%s {some-test} {another-test}", rehearseNormal)},
 	})
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       fmt.Sprintf("%s profile={cluster-profile} ref={registry-step}", rehearseNormal),
+		Description: fmt.Sprintf("Run up to %d rehearsals of the affected jobs using a cluster profile, or a step, chain, workflow or observer of the step registry. Can be combined with test names.", s.rehearsalConfig.NormalLimit),
+		WhoCanUse:   "Anyone can use on trusted PRs",
+		Examples:    []string{fmt.Sprintf("%s profile=aws", rehearseNormal), fmt.Sprintf("%s ref=ipi-install-install", rehearseNormal)},
+	})
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       fmt.Sprintf("%s [profile={cluster-profile}] [ref={registry-step}]", rehearseList),
+		Description: "List the affected jobs that can be rehearsed, and why each of them is affected",
+		WhoCanUse:   "Anyone can use on trusted PRs",
+		Examples:    []string{rehearseList, fmt.Sprintf("%s profile=aws", rehearseList)},
+	})
 	pluginHelp.AddCommand(pluginhelp.Command{
 		Usage:       rehearseAck,
 		Description: fmt.Sprintf("Acknowledge the rehearsal result (either passing, failing, or skipped), and add the '%s' label allowing merge once other requirements are met.", rehearse.RehearsalsAckLabel),
@@ -134,7 +150,10 @@ func serverFromOptions(o options) (*server, error) {
 	}
 	c := configAgent.Config()
 
-	rehearsalConfig := rehearsalConfigFromOptions(o)
+	rehearsalConfig, err := rehearsalConfigFromOptions(o)
+	if err != nil {
+		return nil, fmt.Errorf("error creating rehearsal config: %w", err)
+	}
 	rehearsalConfig.ProwjobNamespace = c.ProwJobNamespace
 	rehearsalConfig.PodNamespace = c.PodNamespace
 
@@ -301,15 +320,17 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 		for _, command := range pjRehearseComments {
 			command = strings.TrimSpace(command)
 			logger.Debugf("handling command: %s", command)
-			switch command {
-			case rehearseAck, rehearseSkip:
+			switch {
+			case command == rehearseAck || command == rehearseSkip:
 				s.acknowledgeRehearsals(org, repo, number, logger)
-			case rehearseReject:
+			case command == rehearseReject:
 				if err := s.ghc.RemoveLabel(org, repo, number, rehearse.RehearsalsAckLabel); err != nil {
 					logger.WithError(err).Errorf("failed to remove '%s' label", rehearse.RehearsalsAckLabel)
 				}
-			case rehearseAbort:
+			case command == rehearseAbort:
 				s.rehearsalConfig.AbortAllRehearsalJobs(org, repo, number, logger)
+			case command == rehearseList || strings.HasPrefix(command, rehearseList+" "):
+				s.listAffectedJobs(pullRequest, command, user, logger)
 			default:
 				if rehearsalsTriggered {
 					// We don't want to trigger rehearsals more than once per comment
@@ -337,19 +358,26 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 				//TODO(DPTP-2888): this is the point at which we can use repoClient.RevParse() to see if we even need to load the configs at all, and also prune the set of loaded configs to only the changed files
 
 				candidatePath := repoClient.Directory()
-				presubmits, periodics, changedTemplates, changedClusterProfiles, err := rc.DetermineAffectedJobs(candidate, candidatePath, logger)
+				presubmits, periodics, changedTemplates, changedClusterProfiles, coverage, err := rc.DetermineAffectedJobs(candidate, candidatePath, logger)
 				if err != nil {
 					logger.WithError(err).Error("couldn't determine affected jobs")
 					s.reportFailure("unable to determine affected jobs", err, org, repo, user, number, true, false, logger)
 					continue
 				}
 				requestedOnly := command != rehearseNormal && command != rehearseMore && command != rehearseMax && command != rehearseAutoAck
+				// Only rehearsals of jobs requested by name are not limited, as a selector can match any number of jobs
+				limited := !requestedOnly
 
 				if requestedOnly {
-					rawJobs := strings.TrimPrefix(command, rehearseNormal+" ")
-					requestedJobs := strings.Split(rawJobs, " ")
+					selector := rehearse.ParseRehearsalSelector(strings.Split(strings.TrimPrefix(command, rehearseNormal+" "), " "))
+					limited = !selector.OnlyJobs()
 					var unaffected []string
-					presubmits, periodics, unaffected = rehearse.FilterJobsByRequested(requestedJobs, presubmits, periodics, logger)
+					presubmits, periodics, unaffected, err = rc.FilterJobsBySelector(selector, candidatePath, presubmits, periodics, logger)
+					if err != nil {
+						logger.WithError(err).Error("couldn't select requested jobs")
+						s.reportFailure("unable to select the requested jobs", err, org, repo, user, number, false, true, logger)
+						continue
+					}
 					if len(unaffected) > 0 {
 						message := fmt.Sprintf("@%s: job(s): %s either don't exist or were not found to be affected, and cannot be rehearsed", user, strings.Join(unaffected, ", "))
 						if err = s.ghc.CreateComment(org, repo, number, message); err != nil {
@@ -359,7 +387,7 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 				}
 				if len(presubmits) > 0 || len(periodics) > 0 {
 					limit := math.MaxInt
-					if command == rehearseNormal || command == rehearseAutoAck {
+					if command == rehearseNormal || command == rehearseAutoAck || (requestedOnly && limited) {
 						limit = rc.NormalLimit
 					} else if command == rehearseMore {
 						limit = rc.MoreLimit
@@ -367,6 +395,17 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 						limit = rc.MaxLimit
 					}
 
+					var selection *rehearse.BudgetSelection
+					if limited {
+						presubmits, periodics, selection = rc.SelectJobsByCost(presubmits, periodics, coverage, limit, logger)
+						if len(presubmits) == 0 && len(periodics) == 0 {
+							if err := s.ghc.CreateComment(org, repo, number, fmt.Sprintf("@%s: no affected job fits the rehearsal budget. %s", user, selection.Describe())); err != nil {
+								logger.WithError(err).Error("failed to create comment")
+							}
+							continue
+						}
+					}
+
 					prConfig, prRefs, imageStreamTags, presubmitsToRehearse, err := rc.SetupJobs(candidate, candidatePath, presubmits, periodics, changedTemplates, changedClusterProfiles, limit, logger)
 					if err != nil {
 						logger.WithError(err).Error("couldn't set up jobs")
@@ -380,7 +419,10 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 						continue
 					}
 
-					success, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
+					success, summary, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
+					if len(summary) > 0 {
+						s.upsertSummaryComment(pullRequest, summary, selection, logger)
+					}
 					if err != nil {
 						logger.WithError(err).Error("couldn't rehearse jobs")
 						s.reportFailure("failed to create rehearsal jobs", err, org, repo, user, number, true, false, logger)
@@ -402,6 +444,80 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 	}
 }
 
+// listAffectedJobs replies with the affected jobs matching the selector of the list command, and why each of them is affected
+func (s *server) listAffectedJobs(pullRequest *github.PullRequest, command, user string, logger *logrus.Entry) {
+	rc := s.rehearsalConfig
+	org := pullRequest.Base.Repo.Owner.Login
+	repo := pullRequest.Base.Repo.Name
+	number := pullRequest.Number
+	repoClient, err := s.getRepoClient(org, repo)
+	if err != nil {
+		logger.WithError(err).Error("couldn't create repo client")
+		return
+	}
+	defer func() {
+		if err := repoClient.Clean(); err != nil {
+			logrus.WithError(err).Error("couldn't clean temporary repo folder")
+		}
+	}()
+
+	candidate, err := s.prepareCandidate(repoClient, pullRequest)
+	if err != nil {
+		s.reportFailure("unable prepare a candidate for rehearsal. This could be due to a branch that needs to be rebased.", err, org, repo, user, number, false, false, logger)
+		return
+	}
+	candidatePath := repoClient.Directory()
+	presubmits, periodics, _, _, coverage, err := rc.DetermineAffectedJobs(candidate, candidatePath, logger)
+	if err != nil {
+		logger.WithError(err).Error("couldn't determine affected jobs")
+		s.reportFailure("unable to determine affected jobs", err, org, repo, user, number, true, false, logger)
+		return
+	}
+	selector := rehearse.ParseRehearsalSelector(strings.Split(strings.TrimPrefix(command, rehearseList), " "))
+	var unmatched []string
+	if !selector.IsEmpty() {
+		presubmits, periodics, unmatched, err = rc.FilterJobsBySelector(selector, candidatePath, presubmits, periodics, logger)
+		if err != nil {
+			logger.WithError(err).Error("couldn't select requested jobs")
+			s.reportFailure("unable to select the requested jobs", err, org, repo, user, number, false, true, logger)
+			return
+		}
+	}
+	if err := s.ghc.CreateComment(org, repo, number, strings.Join(getJobsListLines(presubmits, periodics, coverage, unmatched, user), "\n")); err != nil {
+		logger.WithError(err).Error("failed to create comment")
+	}
+}
+
+// getJobsListLines returns a Markdown formatted table of the listed jobs, with the changes each of them covers
+func getJobsListLines(presubmits config.Presubmits, periodics config.Periodics, coverage rehearse.JobCoverage, unmatched []string, user string) []string {
+	var lines []string
+	if len(presubmits) == 0 && len(periodics) == 0 {
+		lines = append(lines, fmt.Sprintf("@%s: no rehearsable tests are affected by this change", user))
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("@%s: the following rehearsable tests have been affected by this change:", user),
+			"",
+			"Test name | Repo | Type | Reason | Covers",
+			"--- | --- | --- | --- | ---",
+		)
+		var jobs []string
+		for repoName, tests := range presubmits {
+			for _, presubmit := range tests {
+				jobs = append(jobs, fmt.Sprintf("%s | %s | %s | %s | %s", presubmit.Name, repoName, "presubmit", config.GetSourceType(presubmit.Labels).GetDisplayText(), strings.Join(coverage.Describe(presubmit.Name), ", ")))
+			}
+		}
+		for jobName, periodic := range periodics {
+			jobs = append(jobs, fmt.Sprintf("%s | N/A | %s | %s | %s", jobName, "periodic", config.GetSourceType(periodic.Labels).GetDisplayText(), strings.Join(coverage.Describe(jobName), ", ")))
+		}
+		sort.Strings(jobs)
+		lines = append(lines, jobs...)
+	}
+	if len(unmatched) > 0 {
+		lines = append(lines, "", fmt.Sprintf("No affected jobs match: %s", strings.Join(unmatched, ", ")))
+	}
+	return lines
+}
+
 func (s *server) getAffectedJobs(pullRequest *github.PullRequest, logger *logrus.Entry) (config.Presubmits, config.Periodics, error) {
 	rc := s.rehearsalConfig
 	org := pullRequest.Base.Repo.Owner.Login
@@ -426,10 +542,38 @@ func (s *server) getAffectedJobs(pullRequest *github.PullRequest, logger *logrus
 	//TODO(DPTP-2888): this is the point at which we can use repoClient.RevParse() to see if we even need to load the configs at all, and also prune the set of loaded configs to only the changed files
 
 	candidatePath := repoClient.Directory()
-	presubmits, periodics, _, _, err := rc.DetermineAffectedJobs(candidate, candidatePath, logger)
+	presubmits, periodics, _, _, _, err := rc.DetermineAffectedJobs(candidate, candidatePath, logger)
 	return presubmits, periodics, err
 }
 
+// upsertSummaryComment posts the summary of the rehearsal results, or updates the summary posted for previous rehearsals.
+// When the jobs were selected within the rehearsal budget, the summary explains why they were selected.
+func (s *server) upsertSummaryComment(pullRequest *github.PullRequest, summary rehearse.RehearsalSummary, selection *rehearse.BudgetSelection, logger *logrus.Entry) {
+	org := pullRequest.Base.Repo.Owner.Login
+	repo := pullRequest.Base.Repo.Name
+	number := pullRequest.Number
+	body := fmt.Sprintf("%s \n@%s: rehearsals of %s have finished. The results are compared to the latest results of the production jobs:\n\n%s", rehearsalSummary, pullRequest.User.Login, pullRequest.Head.SHA, summary.Describe(s.rehearsalConfig.GCSBrowserPrefix))
+	if selection != nil {
+		body += "\n\n" + selection.Describe()
+	}
+
+	comments, err := s.ghc.ListIssueComments(org, repo, number)
+	if err != nil {
+		logger.WithError(err).Error("failed to get comments for pull request")
+	}
+	for _, comment := range comments {
+		if strings.HasPrefix(comment.Body, rehearsalSummary) {
+			if err := s.ghc.EditComment(org, repo, comment.ID, body); err != nil {
+				logger.WithError(err).Error("failed to update summary comment")
+			}
+			return
+		}
+	}
+	if err := s.ghc.CreateComment(org, repo, number, body); err != nil {
+		logger.WithError(err).Error("failed to create comment")
+	}
+}
+
 func (s *server) reportFailure(message string, err error, org, repo, user string, number int, addContact, addUsageDetails bool, l *logrus.Entry) {
 	comment := fmt.Sprintf("@%s, `pj-rehearse`: %s ERROR: \n ```\n%v\n```\n", user, message, err)
 	if addContact {
@@ -533,6 +677,8 @@ func (s *server) getUsageDetailsLines() []string {
 		fmt.Sprintf("Comment: `%s` to run up to %d rehearsals", rehearseNormal, rc.NormalLimit),
 		fmt.Sprintf("Comment: `%s` to opt-out of rehearsals", rehearseSkip),
 		fmt.Sprintf("Comment: `%s {test-name}`, with each test separated by a space, to run one or more specific rehearsals", rehearseNormal),
+		fmt.Sprintf("Comment: `%s profile={cluster-profile}` or `%s ref={registry-step}` to run up to %d rehearsals using a cluster profile or step registry content", rehearseNormal, rehearseNormal, rc.NormalLimit),
+		fmt.Sprintf("Comment: `%s` to list the affected jobs and why each of them is affected", rehearseList),
 		fmt.Sprintf("Comment: `%s` to run up to %d rehearsals", rehearseMore, rc.MoreLimit),
 		fmt.Sprintf("Comment: `%s` to run up to %d rehearsals", rehearseMax, rc.MaxLimit),
 		fmt.Sprintf("Comment: `%s` to run up to %d rehearsals, and add the `%s` label on success", rehearseAutoAck, rc.NormalLimit, rehearse.RehearsalsAckLabel),
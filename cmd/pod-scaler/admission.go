@@ -33,7 +33,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/steps"
 )
 
-func admit(port, healthPort int, certDir string, client buildclientv1.BuildV1Interface, loaders map[string][]*cacheReloader, mutateResourceLimits bool, cpuCap int64, memoryCap string, cpuPriorityScheduling int64, reporter results.PodScalerReporter) {
+func admit(port, healthPort int, certDir string, client buildclientv1.BuildV1Interface, loaders map[string][]*cacheReloader, mutateResourceLimits bool, cpuCap int64, memoryCap, ephemeralStorageCap string, cpuPriorityScheduling int64, reporter results.PodScalerReporter) {
 	logger := logrus.WithField("component", "pod-scaler admission")
 	logger.Infof("Initializing admission webhook server with %d loaders.", len(loaders))
 	health := pjutil.NewHealthOnPort(healthPort)
@@ -44,7 +44,8 @@ func admit(port, healthPort int, certDir string, client buildclientv1.BuildV1Int
 		Port:    port,
 		CertDir: certDir,
 	})
-	server.Register("/pods", &webhook.Admission{Handler: &podMutator{logger: logger, client: client, decoder: decoder, resources: resources, mutateResourceLimits: mutateResourceLimits, cpuCap: cpuCap, memoryCap: memoryCap, cpuPriorityScheduling: cpuPriorityScheduling, reporter: reporter}})
+	server.Register("/readyz", readyz(loaders))
+	server.Register("/pods", &webhook.Admission{Handler: &podMutator{logger: logger, client: client, decoder: decoder, resources: resources, mutateResourceLimits: mutateResourceLimits, cpuCap: cpuCap, memoryCap: memoryCap, ephemeralStorageCap: ephemeralStorageCap, cpuPriorityScheduling: cpuPriorityScheduling, reporter: reporter}})
 	logger.Info("Serving admission webhooks.")
 	if err := server.Start(interrupts.Context()); err != nil {
 		logrus.WithError(err).Fatal("Failed to serve webhooks.")
@@ -59,6 +60,7 @@ type podMutator struct {
 	decoder               *admission.Decoder
 	cpuCap                int64
 	memoryCap             string
+	ephemeralStorageCap   string
 	cpuPriorityScheduling int64
 	reporter              results.PodScalerReporter
 }
@@ -97,7 +99,7 @@ func (m *podMutator) Handle(ctx context.Context, req admission.Request) admissio
 		logger.WithError(err).Error("Failed to handle rehearsal Pod.")
 		return admission.Allowed("Failed to handle rehearsal Pod, ignoring.")
 	}
-	mutatePodResources(pod, m.resources, m.mutateResourceLimits, m.cpuCap, m.memoryCap, m.reporter, logger)
+	mutatePodResources(pod, m.resources, m.mutateResourceLimits, m.cpuCap, m.memoryCap, m.ephemeralStorageCap, m.reporter, logger)
 	m.addPriorityClass(pod)
 
 	marshaledPod, err := json.Marshal(pod)
@@ -213,7 +215,7 @@ func useOursIfLarger(allOfOurs, allOfTheirs *corev1.ResourceRequirements, worklo
 		{ours: &allOfOurs.Requests, theirs: &allOfTheirs.Requests, resource: "request"},
 		{ours: &allOfOurs.Limits, theirs: &allOfTheirs.Limits, resource: "limit"},
 	} {
-		for _, field := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		for _, field := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
 			our := (*pair.ours)[field]
 			their := (*pair.theirs)[field]
 			fieldLogger := logger.WithFields(logrus.Fields{
@@ -242,7 +244,8 @@ func useOursIfLarger(allOfOurs, allOfTheirs *corev1.ResourceRequirements, worklo
 
 // reconcileLimits ensures that container resource limits do not set anything for CPU (as we
 // are fairly certain this is never a useful thing to do) and that the limits are >=200% of
-// requests (which they may not be any longer if we've changed requests)
+// requests (which they may not be any longer if we've changed requests). Exceeding an ephemeral
+// storage limit evicts the Pod, so we only ever raise one that is already set.
 func reconcileLimits(resources *corev1.ResourceRequirements) {
 	if resources.Limits == nil {
 		return
@@ -259,9 +262,16 @@ func reconcileLimits(resources *corev1.ResourceRequirements) {
 	if currentLimit.Cmp(minimumLimit) == -1 {
 		resources.Limits[corev1.ResourceMemory] = minimumLimit
 	}
+	if currentLimit, limited := resources.Limits[corev1.ResourceEphemeralStorage]; limited {
+		minimumLimit := resources.Requests[corev1.ResourceEphemeralStorage]
+		minimumLimit.Add(minimumLimit)
+		if currentLimit.Cmp(minimumLimit) == -1 {
+			resources.Limits[corev1.ResourceEphemeralStorage] = minimumLimit
+		}
+	}
 }
 
-func preventUnschedulable(resources *corev1.ResourceRequirements, cpuCap int64, memoryCap string, logger *logrus.Entry) {
+func preventUnschedulable(resources *corev1.ResourceRequirements, cpuCap int64, memoryCap, ephemeralStorageCap string, logger *logrus.Entry) {
 	if resources.Requests == nil {
 		logger.Debug("no requests, skipping")
 		return
@@ -283,9 +293,17 @@ func preventUnschedulable(resources *corev1.ResourceRequirements, cpuCap int64,
 			resources.Requests[corev1.ResourceMemory] = memoryRequestCap
 		}
 	}
+
+	if _, ok := resources.Requests[corev1.ResourceEphemeralStorage]; ok {
+		ephemeralStorageRequestCap := resource.MustParse(ephemeralStorageCap)
+		if resources.Requests.StorageEphemeral().Cmp(ephemeralStorageRequestCap) == 1 {
+			logger.Debugf("setting original ephemeral storage request of: %s to cap", resources.Requests.StorageEphemeral())
+			resources.Requests[corev1.ResourceEphemeralStorage] = ephemeralStorageRequestCap
+		}
+	}
 }
 
-func mutatePodResources(pod *corev1.Pod, server *resourceServer, mutateResourceLimits bool, cpuCap int64, memoryCap string, reporter results.PodScalerReporter, logger *logrus.Entry) {
+func mutatePodResources(pod *corev1.Pod, server *resourceServer, mutateResourceLimits bool, cpuCap int64, memoryCap, ephemeralStorageCap string, reporter results.PodScalerReporter, logger *logrus.Entry) {
 	mutateResources := func(containers []corev1.Container) {
 		for i := range containers {
 			meta := pod_scaler.MetadataFor(pod.ObjectMeta.Labels, pod.ObjectMeta.Name, containers[i].Name)
@@ -299,7 +317,7 @@ func mutatePodResources(pod *corev1.Pod, server *resourceServer, mutateResourceL
 					reconcileLimits(&containers[i].Resources)
 				}
 			}
-			preventUnschedulable(&containers[i].Resources, cpuCap, memoryCap, logger)
+			preventUnschedulable(&containers[i].Resources, cpuCap, memoryCap, ephemeralStorageCap, logger)
 		}
 	}
 	mutateResources(pod.Spec.InitContainers)
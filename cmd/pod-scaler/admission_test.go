@@ -554,7 +554,7 @@ func TestMutatePodResources(t *testing.T) {
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
 			original := testCase.pod.DeepCopy()
-			mutatePodResources(testCase.pod, testCase.server, testCase.mutateResourceLimits, 10, "20Gi", &defaultReporter, logrus.WithField("test", testCase.name))
+			mutatePodResources(testCase.pod, testCase.server, testCase.mutateResourceLimits, 10, "20Gi", "100Gi", &defaultReporter, logrus.WithField("test", testCase.name))
 			diff := cmp.Diff(original, testCase.pod)
 			// In some cases, cmp.Diff decides to use non-breaking spaces, and it's not
 			// particularly deterministic about this. We don't care.
@@ -581,6 +581,25 @@ func TestUseOursIfLarger(t *testing.T) {
 				Limits:   corev1.ResourceList{},
 			},
 		},
+		{
+			name: "ours has larger ephemeral storage",
+			ours: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+			theirs: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(1e10, resource.BinarySI),
+				},
+			},
+			expected: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+				Limits: corev1.ResourceList{},
+			},
+		},
 		{
 			name: "nothing in theirs",
 			ours: corev1.ResourceRequirements{
@@ -880,6 +899,40 @@ func TestReconcileLimits(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "increase low ephemeral storage limits",
+			input: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+			expected: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(4e10, resource.BinarySI),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+		},
+		{
+			name: "do not add ephemeral storage limits",
+			input: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{},
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+			expected: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{},
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: *resource.NewQuantity(2e10, resource.BinarySI),
+				},
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -927,6 +980,7 @@ func TestRehearsalMetadata(t *testing.T) {
 func TestPreventUnschedulable(t *testing.T) {
 	cpuCap := int64(10)
 	memoryCap := "20Gi"
+	ephemeralStorageCap := "100Gi"
 	testCases := []struct {
 		name      string
 		resources *corev1.ResourceRequirements
@@ -988,6 +1042,19 @@ func TestPreventUnschedulable(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "too much ephemeral storage",
+			resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse("150Gi"),
+				},
+			},
+			expected: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse(ephemeralStorageCap),
+				},
+			},
+		},
 		{
 			name:      "no requests",
 			resources: &corev1.ResourceRequirements{},
@@ -996,7 +1063,7 @@ func TestPreventUnschedulable(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			preventUnschedulable(tc.resources, cpuCap, memoryCap, logrus.WithField("test", tc.name))
+			preventUnschedulable(tc.resources, cpuCap, memoryCap, ephemeralStorageCap, logrus.WithField("test", tc.name))
 			if diff := cmp.Diff(tc.expected, tc.resources); diff != "" {
 				t.Fatalf("result doesn't match expected, diff: %s", diff)
 			}
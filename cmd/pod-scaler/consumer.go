@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/interrupts"
@@ -34,9 +38,26 @@ type cacheReloader struct {
 
 	lock        *sync.RWMutex
 	lastUpdated time.Time
+	// current is the generation of data subscribers were last sent, if any
+	current     *snapshot
 	subscribers []chan<- *pod_scaler.CachedQuery
 }
 
+var (
+	datasetGeneration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pod_scaler_dataset_generation",
+		Help: "Generation of the cached data being served, by cache.",
+	}, []string{"cache"})
+	datasetCreated = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pod_scaler_dataset_created_timestamp_seconds",
+		Help: "Time at which the generation of the cached data being served was produced, by cache.",
+	}, []string{"cache"})
+)
+
+func init() {
+	prometheus.MustRegister(datasetGeneration, datasetCreated)
+}
+
 func (c *cacheReloader) subscribe(out chan<- *pod_scaler.CachedQuery) {
 	c.lock.Lock()
 	c.subscribers = append(c.subscribers, out)
@@ -66,17 +87,23 @@ func (c *cacheReloader) reload() {
 	}
 	logger.Debug("Newer update available in cloud storage, reloading data.")
 
-	data, err := loadCache(c.cache, c.name, c.logger)
+	data, current, err := loadCacheSnapshot(c.cache, c.name, c.logger)
 	if err != nil {
 		logger.WithError(err).Warn("Failed to read cached data, won't reload this tick.")
 		return
 	}
+	logger = logger.WithField("generation", current.Generation)
 	c.lock.Lock()
 	if len(c.subscribers) > 0 {
 		c.lastUpdated = lastUpdated
+		c.current = &current
 		for _, subscriber := range c.subscribers {
 			subscriber <- data
 		}
+		datasetGeneration.WithLabelValues(c.name).Set(float64(current.Generation))
+		if !current.Created.IsZero() {
+			datasetCreated.WithLabelValues(c.name).Set(float64(current.Created.Unix()))
+		}
 	} else {
 		logger.Warn("no subscribers yet, won't mark as loaded")
 	}
@@ -84,6 +111,55 @@ func (c *cacheReloader) reload() {
 	logger.Debug("Newer update loaded.")
 }
 
+// served returns the generation of data subscribers were last sent
+func (c *cacheReloader) served() (snapshot, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.current == nil {
+		return snapshot{}, false
+	}
+	return *c.current, true
+}
+
+// datasetStatus describes the generations of data a consumer serves
+type datasetStatus struct {
+	Ready bool `json:"ready"`
+	// Datasets holds the generation served for every cache, or null if nothing was loaded yet
+	Datasets map[string]*snapshot `json:"datasets"`
+}
+
+// readyz reports which generation of data is served from every cache, and is only ready
+// once data from all of them has been loaded
+func readyz(loaders map[string][]*cacheReloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := datasetStatus{Ready: true, Datasets: map[string]*snapshot{}}
+		for _, reloaders := range loaders {
+			for _, reloader := range reloaders {
+				current, ok := reloader.served()
+				if !ok {
+					status.Ready = false
+					status.Datasets[reloader.name] = nil
+					continue
+				}
+				status.Datasets[reloader.name] = &current
+			}
+		}
+		raw, err := json.Marshal(status)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to marshal status: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if _, err := w.Write(raw); err != nil {
+			logrus.WithError(err).Warn("Could not write dataset status.")
+		}
+	}
+}
+
 func digestAll(data map[string][]*cacheReloader, digesters map[string]digester, health *pjutil.Health, logger *logrus.Entry) {
 	var infos []digestInfo
 	for id, d := range digesters {
@@ -111,6 +187,15 @@ func digestAll(data map[string][]*cacheReloader, digesters map[string]digester,
 
 type digester func(query *pod_scaler.CachedQuery)
 
+// digestWithAll feeds the same data to more than one digester
+func digestWithAll(digesters ...digester) digester {
+	return func(query *pod_scaler.CachedQuery) {
+		for _, digest := range digesters {
+			digest(query)
+		}
+	}
+}
+
 type digestInfo struct {
 	name   string
 	data   *cacheReloader
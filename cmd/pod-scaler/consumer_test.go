@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadyz(t *testing.T) {
+	created := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	reloader := func(name string, current *snapshot) *cacheReloader {
+		return &cacheReloader{name: name, lock: &sync.RWMutex{}, current: current}
+	}
+	var testCases = []struct {
+		name         string
+		loaders      map[string][]*cacheReloader
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "all data loaded",
+			loaders: map[string][]*cacheReloader{
+				"metric": {
+					reloader("pods/metric", &snapshot{Generation: 2, Digest: "abc", Created: created}),
+					reloader("steps/metric", &snapshot{}),
+				},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"ready":true,"datasets":{"pods/metric":{"generation":2,"digest":"abc","created":"2022-10-01T00:00:00Z"},"steps/metric":{"generation":0,"created":"0001-01-01T00:00:00Z"}}}`,
+		},
+		{
+			name: "data still loading",
+			loaders: map[string][]*cacheReloader{
+				"metric": {
+					reloader("pods/metric", &snapshot{Generation: 2, Digest: "abc", Created: created}),
+					reloader("steps/metric", nil),
+				},
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"ready":false,"datasets":{"pods/metric":{"generation":2,"digest":"abc","created":"2022-10-01T00:00:00Z"},"steps/metric":null}}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			readyz(testCase.loaders).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if recorder.Code != testCase.expectedCode {
+				t.Errorf("%s: expected status %d, got %d", testCase.name, testCase.expectedCode, recorder.Code)
+			}
+			if diff := cmp.Diff(testCase.expectedBody, recorder.Body.String()); diff != "" {
+				t.Errorf("%s: got incorrect body: %v", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -201,7 +201,7 @@ var (
 	static embed.FS
 )
 
-func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheReloader) {
+func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheReloader, simulation simulator) {
 	logger := logrus.WithField("component", "pod-scaler frontend")
 	server := &frontendServer{
 		logger:   logger,
@@ -211,10 +211,12 @@ func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheRe
 		dataDir:  dataDir,
 	}
 	health := pjutil.NewHealthOnPort(healthPort)
-	digestAll(loaders, map[string]digester{
-		MetricNameCPUUsage:         server.digestCPU,
-		MetricNameMemoryWorkingSet: server.digestMemory,
-	}, health, logger)
+	// simulations need the recommendations of the admission webhook, which are digested from the same data
+	simulation.resources = emptyResourceServer()
+	digesters := simulation.resources.digesters()
+	digesters[MetricNameCPUUsage] = digestWithAll(server.digestCPU, digesters[MetricNameCPUUsage])
+	digesters[MetricNameMemoryWorkingSet] = digestWithAll(server.digestMemory, digesters[MetricNameMemoryWorkingSet])
+	digestAll(loaders, digesters, health, logger)
 
 	var nodes []simplifypath.Node
 	for name := range server.mappings {
@@ -230,6 +232,7 @@ func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheRe
 			l("indicies",
 				nodes...,
 			),
+			l("simulate"),
 		),
 	))
 	handler := metrics.TraceHandler(simplifier, uiMetrics.HTTPRequestDuration, uiMetrics.HTTPResponseSize)
@@ -255,6 +258,7 @@ func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheRe
 		}
 	})).ServeHTTP)
 	mux.HandleFunc("/static/", handler(http.StripPrefix("/static/", http.FileServer(http.FS(stripped)))).ServeHTTP)
+	mux.HandleFunc("/api/simulate", handler(&simulation).ServeHTTP)
 	for name := range server.mappings {
 		mux.HandleFunc(fmt.Sprintf("/api/data/%s", name), handler(server.getData(name)).ServeHTTP)
 		mux.HandleFunc(fmt.Sprintf("/api/indices/%s", name), handler(server.getIndex(name)).ServeHTTP)
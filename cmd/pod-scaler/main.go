@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -37,6 +38,8 @@ type options struct {
 	mode string
 	producerOptions
 	consumerOptions
+	simulateOptions
+	rollbackOptions
 
 	instrumentationOptions prowflagutil.InstrumentationOptions
 
@@ -47,6 +50,11 @@ type options struct {
 	cacheBucket        string
 	gcsCredentialsFile string
 
+	cacheS3Bucket     string
+	cacheS3Endpoint   string
+	cacheS3Region     string
+	s3CredentialsFile string
+
 	resultsOptions results.Options
 }
 
@@ -65,9 +73,21 @@ type consumerOptions struct {
 	mutateResourceLimits  bool
 	cpuCap                int64
 	memoryCap             string
+	ephemeralStorageCap   string
 	cpuPriorityScheduling int64
 }
 
+type simulateOptions struct {
+	simulateConfigPath   string
+	simulateRegistryPath string
+	simulateVersionsPath string
+}
+
+type rollbackOptions struct {
+	rollbackMetrics    prowflagutil.Strings
+	rollbackGeneration int64
+}
+
 func bindOptions(fs *flag.FlagSet) *options {
 	o := options{producerOptions: producerOptions{kubernetesOptions: prowflagutil.KubernetesOptions{NOInClusterConfigDefault: true}}}
 	o.instrumentationOptions.AddFlags(fs)
@@ -85,8 +105,18 @@ func bindOptions(fs *flag.FlagSet) *options {
 	fs.StringVar(&o.dataDir, "data-dir", "", "Local directory to cache UI data into.")
 	fs.StringVar(&o.cacheBucket, "cache-bucket", "", "GCS bucket name holding cached Prometheus data.")
 	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "File where GCS credentials are stored.")
+	fs.StringVar(&o.cacheS3Bucket, "cache-s3-bucket", "", "S3 bucket name holding cached Prometheus data.")
+	fs.StringVar(&o.cacheS3Endpoint, "cache-s3-endpoint", "", "Endpoint of S3-compatible storage holding the bucket, if not AWS S3.")
+	fs.StringVar(&o.cacheS3Region, "cache-s3-region", "us-east-1", "Region of the S3 bucket.")
+	fs.StringVar(&o.s3CredentialsFile, "s3-credentials-file", "", "File where S3 credentials are stored, in the AWS shared credentials format. If unset, credentials are taken from the environment.")
 	fs.Int64Var(&o.cpuCap, "cpu-cap", 10, "The maximum CPU request value, ex: 10")
 	fs.StringVar(&o.memoryCap, "memory-cap", "20Gi", "The maximum memory request value, ex: '20Gi'")
+	fs.StringVar(&o.ephemeralStorageCap, "ephemeral-storage-cap", "100Gi", "The maximum ephemeral storage request value, ex: '100Gi'")
+	fs.StringVar(&o.simulateConfigPath, "simulate-config", "", "Path to the ci-operator configuration to simulate the admission of.")
+	fs.StringVar(&o.simulateRegistryPath, "simulate-registry", "", "Path to the step registry to resolve the simulated configuration with.")
+	fs.StringVar(&o.simulateVersionsPath, "simulate-registry-versions", "", "Optional directory with versions of the step registry the simulated configuration can pin components to.")
+	fs.Var(&o.rollbackMetrics, "rollback-metric", "Cache to roll back, like 'pods/container_memory_working_set_bytes'. Can be passed multiple times. If unset, all caches are rolled back.")
+	fs.Int64Var(&o.rollbackGeneration, "rollback-generation", 0, "Generation of cached data to restore. If unset, the generation produced before the one currently served is restored.")
 	fs.Int64Var(&o.cpuPriorityScheduling, "cpu-priority-scheduling", 8, "Pods with CPU requests at, or above, this value will be admitted with priority scheduling")
 	o.resultsOptions.Bind(fs)
 	return &o
@@ -108,6 +138,9 @@ func (o *options) validate() error {
 		if o.dataDir == "" {
 			return errors.New("--data-dir is required")
 		}
+		if err := o.validateCaps(); err != nil {
+			return err
+		}
 	case "consumer.admission":
 		if o.port == 0 {
 			return errors.New("--port is required")
@@ -115,22 +148,39 @@ func (o *options) validate() error {
 		if o.certDir == "" {
 			return errors.New("--serving-cert-dir is required")
 		}
-		if cpuCap := resource.NewQuantity(o.cpuCap, resource.DecimalSI); cpuCap.Sign() <= 0 {
-			return errors.New("--cpu-cap must be greater than 0")
-		}
-		if memoryCap := resource.MustParse(o.memoryCap); memoryCap.Sign() <= 0 {
-			return errors.New("--memory-cap must be greater than 0")
+		if err := o.validateCaps(); err != nil {
+			return err
 		}
 		if err := o.resultsOptions.Validate(); err != nil {
 			return err
 		}
+	case "simulate":
+		if o.simulateConfigPath == "" {
+			return errors.New("--simulate-config is required")
+		}
+		if err := o.validateCaps(); err != nil {
+			return err
+		}
+	case "rollback":
+		if o.rollbackGeneration < 0 {
+			return errors.New("--rollback-generation must not be negative")
+		}
+		cached := queriesByMetric()
+		for _, metric := range o.rollbackMetrics.Strings() {
+			if _, ok := cached[metric]; !ok {
+				return fmt.Errorf("--rollback-metric %s is not a cache", metric)
+			}
+		}
 
 	default:
-		return errors.New("--mode must be either \"producer\", \"consumer.ui\", or \"consumer.admission\"")
+		return errors.New("--mode must be either \"producer\", \"consumer.ui\", \"consumer.admission\", \"simulate\", or \"rollback\"")
 	}
-	if o.cacheDir == "" {
+	if o.cacheBucket != "" && o.cacheS3Bucket != "" {
+		return errors.New("--cache-bucket and --cache-s3-bucket are mutually exclusive")
+	}
+	if o.cacheDir == "" && o.cacheS3Bucket == "" {
 		if o.cacheBucket == "" {
-			return errors.New("--cache-bucket is required")
+			return errors.New("one of --cache-dir, --cache-bucket or --cache-s3-bucket is required")
 		}
 		if o.gcsCredentialsFile == "" {
 			return errors.New("--gcs-credentials-file is required")
@@ -148,6 +198,19 @@ func (o *options) validate() error {
 	return o.instrumentationOptions.Validate(false)
 }
 
+func (o *options) validateCaps() error {
+	if cpuCap := resource.NewQuantity(o.cpuCap, resource.DecimalSI); cpuCap.Sign() <= 0 {
+		return errors.New("--cpu-cap must be greater than 0")
+	}
+	if memoryCap := resource.MustParse(o.memoryCap); memoryCap.Sign() <= 0 {
+		return errors.New("--memory-cap must be greater than 0")
+	}
+	if ephemeralStorageCap, err := resource.ParseQuantity(o.ephemeralStorageCap); err != nil || ephemeralStorageCap.Sign() <= 0 {
+		return errors.New("--ephemeral-storage-cap must be a quantity greater than 0")
+	}
+	return nil
+}
+
 func main() {
 	flagSet := flag.NewFlagSet("", flag.ExitOnError)
 	opts := bindOptions(flagSet)
@@ -176,6 +239,12 @@ func main() {
 	var cache cache
 	if opts.cacheDir != "" {
 		cache = &localCache{dir: opts.cacheDir}
+	} else if opts.cacheS3Bucket != "" {
+		s3Cache, err := newS3Cache(opts.cacheS3Bucket, opts.cacheS3Endpoint, opts.cacheS3Region, opts.s3CredentialsFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not initialize S3 client.")
+		}
+		cache = s3Cache
 	} else {
 		gcsClient, err := storage.NewClient(interrupts.Context(), option.WithCredentialsFile(opts.gcsCredentialsFile))
 		if err != nil {
@@ -192,6 +261,12 @@ func main() {
 		mainUI(opts, cache)
 	case "consumer.admission":
 		mainAdmission(opts, cache)
+	case "simulate":
+		mainSimulate(opts, cache)
+		return
+	case "rollback":
+		mainRollback(opts, cache)
+		return
 	}
 	if !opts.once {
 		interrupts.WaitForGracefulShutdown()
@@ -240,7 +315,12 @@ func mainProduce(opts *options, cache cache) {
 }
 
 func mainUI(opts *options, cache cache) {
-	go serveUI(opts.uiPort, opts.instrumentationOptions.HealthPort, opts.dataDir, loaders(cache))
+	go serveUI(opts.uiPort, opts.instrumentationOptions.HealthPort, opts.dataDir, loaders(cache, MetricNameCPUUsage, MetricNameMemoryWorkingSet, MetricNameEphemeralStorageUsage, MetricNameOOMKilled), simulator{
+		mutateResourceLimits: opts.mutateResourceLimits,
+		cpuCap:               opts.cpuCap,
+		memoryCap:            opts.memoryCap,
+		ephemeralStorageCap:  opts.ephemeralStorageCap,
+	})
 }
 
 func mainAdmission(opts *options, cache cache) {
@@ -259,14 +339,41 @@ func mainAdmission(opts *options, cache cache) {
 		logrus.WithError(err).Fatal("Failed to create pod-scaler reporter.")
 	}
 
-	go admit(opts.port, opts.instrumentationOptions.HealthPort, opts.certDir, client, loaders(cache), opts.mutateResourceLimits, opts.cpuCap, opts.memoryCap, opts.cpuPriorityScheduling, reporter)
+	go admit(opts.port, opts.instrumentationOptions.HealthPort, opts.certDir, client, loaders(cache, MetricNameCPUUsage, MetricNameMemoryWorkingSet, MetricNameEphemeralStorageUsage, MetricNameOOMKilled), opts.mutateResourceLimits, opts.cpuCap, opts.memoryCap, opts.ephemeralStorageCap, opts.cpuPriorityScheduling, reporter)
 }
 
-func loaders(cache cache) map[string][]*cacheReloader {
+func mainRollback(opts *options, cache cache) {
+	names := opts.rollbackMetrics.Strings()
+	if len(names) == 0 {
+		for metric := range queriesByMetric() {
+			names = append(names, metric)
+		}
+		sort.Strings(names)
+	}
+	var failed bool
+	for _, metric := range names {
+		logger := logrus.WithField("metric", metric)
+		restored, err := rollback(cache, metric, opts.rollbackGeneration, time.Now(), logger)
+		if err != nil {
+			logger.WithError(err).Error("Failed to roll back cached data.")
+			failed = true
+			continue
+		}
+		logger.WithField("generation", restored.Generation).Infof("Restored data from generation %d.", restored.RestoredFrom)
+	}
+	if failed {
+		logrus.Fatal("Failed to roll back cached data.")
+	}
+}
+
+// loaders creates reloaders for the metrics a consumer digests, as reloaders without
+// subscribers would load data for nothing
+func loaders(cache cache, metricNames ...string) map[string][]*cacheReloader {
 	l := map[string][]*cacheReloader{}
 	for _, prefix := range []string{prowjobsCachePrefix, podsCachePrefix, stepsCachePrefix} {
-		l[MetricNameCPUUsage] = append(l[MetricNameCPUUsage], newReloader(prefix+"/"+MetricNameCPUUsage, cache))
-		l[MetricNameMemoryWorkingSet] = append(l[MetricNameMemoryWorkingSet], newReloader(prefix+"/"+MetricNameMemoryWorkingSet, cache))
+		for _, metric := range metricNames {
+			l[metric] = append(l[metric], newReloader(prefix+"/"+metric, cache))
+		}
 	}
 	return l
 }
@@ -21,10 +21,15 @@ import (
 )
 
 const (
-	MetricNameCPUUsage         = `container_cpu_usage_seconds_total`
-	MetricNameMemoryWorkingSet = `container_memory_working_set_bytes`
+	MetricNameCPUUsage              = `container_cpu_usage_seconds_total`
+	MetricNameMemoryWorkingSet      = `container_memory_working_set_bytes`
+	MetricNameEphemeralStorageUsage = `container_fs_usage_bytes`
+	// MetricNameOOMKilled only has series for containers that were OOMKilled, which
+	// share their fingerprint with the usage series of the same container
+	MetricNameOOMKilled = `kube_pod_container_status_last_terminated_reason`
 
 	containerFilter = `{container!="POD",container!=""}`
+	oomKilledFilter = `{container!="POD",container!="",reason="OOMKilled"}`
 
 	// MaxSamplesPerRequest is the maximum number of samples that Prometheus will allow a client to ask for in
 	// one request. We also use this to approximate the maximum number of samples we should be asking any one
@@ -61,8 +66,10 @@ func queriesByMetric() map[string]string {
 		},
 	} {
 		for name, metric := range map[string]string{
-			MetricNameCPUUsage:         `rate(` + MetricNameCPUUsage + containerFilter + `[3m])`,
-			MetricNameMemoryWorkingSet: MetricNameMemoryWorkingSet + containerFilter,
+			MetricNameCPUUsage:              `rate(` + MetricNameCPUUsage + containerFilter + `[3m])`,
+			MetricNameMemoryWorkingSet:      MetricNameMemoryWorkingSet + containerFilter,
+			MetricNameEphemeralStorageUsage: MetricNameEphemeralStorageUsage + containerFilter,
+			MetricNameOOMKilled:             MetricNameOOMKilled + oomKilledFilter,
 		} {
 			queries[fmt.Sprintf("%s/%s", info.prefix, name)] = queryFor(metric, info.selector, info.labels)
 		}
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/openhistogram/circonusllhist"
+	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 
 	corev1 "k8s.io/api/core/v1"
@@ -14,18 +15,30 @@ import (
 )
 
 func newResourceServer(loaders map[string][]*cacheReloader, health *pjutil.Health) *resourceServer {
-	logger := logrus.WithField("component", "pod-scaler request server")
-	server := &resourceServer{
-		logger:     logger,
+	server := emptyResourceServer()
+	digestAll(loaders, server.digesters(), health, server.logger)
+
+	return server
+}
+
+func emptyResourceServer() *resourceServer {
+	return &resourceServer{
+		logger:     logrus.WithField("component", "pod-scaler request server"),
 		lock:       sync.RWMutex{},
 		byMetaData: map[pod_scaler.FullMetadata]corev1.ResourceRequirements{},
+		runs:       map[pod_scaler.FullMetadata][]model.Fingerprint{},
+		oomKills:   map[pod_scaler.FullMetadata][]model.Fingerprint{},
 	}
-	digestAll(loaders, map[string]digester{
-		MetricNameCPUUsage:         server.digestCPU,
-		MetricNameMemoryWorkingSet: server.digestMemory,
-	}, health, logger)
+}
 
-	return server
+// digesters returns the digester for every metric the server recommends requests from
+func (s *resourceServer) digesters() map[string]digester {
+	return map[string]digester{
+		MetricNameCPUUsage:              s.digestCPU,
+		MetricNameMemoryWorkingSet:      s.digestMemory,
+		MetricNameEphemeralStorageUsage: s.digestEphemeralStorage,
+		MetricNameOOMKilled:             s.digestOOMKills,
+	}
 }
 
 type resourceServer struct {
@@ -34,6 +47,12 @@ type resourceServer struct {
 	// byMetaData caches resource requirements calculated for the full assortment of
 	// metadata labels.
 	byMetaData map[pod_scaler.FullMetadata]corev1.ResourceRequirements
+	// runs holds the fingerprints of the recent runs we have memory usage for and
+	// oomKills the fingerprints of the runs that were OOMKilled. Fingerprints identify
+	// the container of one run across metrics, so the OOMKills of runs that were pruned
+	// from the usage data no longer count.
+	runs     map[pod_scaler.FullMetadata][]model.Fingerprint
+	oomKills map[pod_scaler.FullMetadata][]model.Fingerprint
 }
 
 const (
@@ -66,8 +85,41 @@ func formatMemory() toQuantity {
 func (s *resourceServer) digestMemory(data *pod_scaler.CachedQuery) {
 	s.logger.Debugf("Digesting new memory consumption metrics.")
 	s.digestData(data, memRequestQuantile, corev1.ResourceMemory, formatMemory())
+	s.lock.Lock()
+	for meta, fingerprints := range data.DataByMetaData {
+		s.runs[meta] = fingerprints
+	}
+	s.lock.Unlock()
 }
 
+const (
+	// ephemeralStorageRequestQuantile is the quantile of ephemeral storage usage data to use as the ephemeral storage request
+	ephemeralStorageRequestQuantile = 0.8
+)
+
+func (s *resourceServer) digestEphemeralStorage(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new ephemeral storage consumption metrics.")
+	s.digestData(data, ephemeralStorageRequestQuantile, corev1.ResourceEphemeralStorage, formatMemory())
+}
+
+func (s *resourceServer) digestOOMKills(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new OOMKill metrics.")
+	s.lock.Lock()
+	for meta, fingerprints := range data.DataByMetaData {
+		s.oomKills[meta] = fingerprints
+	}
+	s.lock.Unlock()
+}
+
+const (
+	// memoryBumpPerOOMKill is how much the memory request grows over the quantile of the
+	// historical usage for every recent run that was OOMKilled: the usage of those runs
+	// was cut short by their limit, so the quantile underestimates what they need
+	memoryBumpPerOOMKill = 0.25
+	// maxOOMKillsBumped limits the bump to doubling the memory request
+	maxOOMKillsBumped = 4
+)
+
 type toQuantity func(valueAtQuantile float64) (quantity *resource.Quantity)
 
 func (s *resourceServer) digestData(data *pod_scaler.CachedQuery, quantile float64, request corev1.ResourceName, quantity toQuantity) {
@@ -102,5 +154,33 @@ func (s *resourceServer) recommendedRequestFor(meta pod_scaler.FullMetadata) (co
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	data, ok := s.byMetaData[meta]
+	if !ok {
+		return data, ok
+	}
+	memory, hasMemory := data.Requests[corev1.ResourceMemory]
+	if kills := s.recentOOMKills(meta); hasMemory && kills > 0 {
+		if kills > maxOOMKillsBumped {
+			kills = maxOOMKillsBumped
+		}
+		// the cached requirements are shared, so we must not mutate them
+		data = *data.DeepCopy()
+		bumped := float64(memory.Value()) * (1 + memoryBumpPerOOMKill*float64(kills))
+		data.Requests[corev1.ResourceMemory] = *formatMemory()(bumped)
+	}
 	return data, ok
 }
+
+// recentOOMKills counts the recent runs that were OOMKilled, the caller must hold the lock
+func (s *resourceServer) recentOOMKills(meta pod_scaler.FullMetadata) int {
+	killed := map[model.Fingerprint]bool{}
+	for _, fingerprint := range s.oomKills[meta] {
+		killed[fingerprint] = true
+	}
+	var kills int
+	for _, fingerprint := range s.runs[meta] {
+		if killed[fingerprint] {
+			kills++
+		}
+	}
+	return kills
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+func TestRecommendedRequestFor(t *testing.T) {
+	meta := pod_scaler.FullMetadata{
+		Metadata:  api.Metadata{Org: "org", Repo: "repo", Branch: "branch"},
+		Target:    "target",
+		Step:      "step",
+		Container: "test",
+	}
+	recommendation := func() corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:              *resource.NewQuantity(1, resource.DecimalSI),
+				corev1.ResourceMemory:           *resource.NewQuantity(1e9, resource.BinarySI),
+				corev1.ResourceEphemeralStorage: *resource.NewQuantity(1e10, resource.BinarySI),
+			},
+			Limits: corev1.ResourceList{},
+		}
+	}
+	withMemory := func(memory int64) corev1.ResourceRequirements {
+		r := recommendation()
+		r.Requests[corev1.ResourceMemory] = *resource.NewQuantity(memory, resource.BinarySI)
+		return r
+	}
+	var testCases = []struct {
+		name     string
+		runs     []model.Fingerprint
+		oomKills []model.Fingerprint
+		expected corev1.ResourceRequirements
+	}{
+		{
+			name:     "no OOMKills",
+			runs:     []model.Fingerprint{1, 2, 3},
+			expected: recommendation(),
+		},
+		{
+			name:     "one recent OOMKill",
+			runs:     []model.Fingerprint{1, 2, 3},
+			oomKills: []model.Fingerprint{2},
+			expected: withMemory(1.25e9),
+		},
+		{
+			name:     "OOMKills of runs that were pruned do not count",
+			runs:     []model.Fingerprint{4, 5, 6},
+			oomKills: []model.Fingerprint{1, 2, 3, 5},
+			expected: withMemory(1.25e9),
+		},
+		{
+			name:     "bump is limited",
+			runs:     []model.Fingerprint{1, 2, 3, 4, 5, 6},
+			oomKills: []model.Fingerprint{1, 2, 3, 4, 5, 6},
+			expected: withMemory(2e9),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := &resourceServer{
+				logger:     logrus.WithField("test", testCase.name),
+				byMetaData: map[pod_scaler.FullMetadata]corev1.ResourceRequirements{meta: recommendation()},
+				runs:       map[pod_scaler.FullMetadata][]model.Fingerprint{meta: testCase.runs},
+				oomKills:   map[pod_scaler.FullMetadata][]model.Fingerprint{meta: testCase.oomKills},
+			}
+			actual, ok := server.recommendedRequestFor(meta)
+			if !ok {
+				t.Fatal("expected a recommendation")
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect recommendation: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(recommendation(), server.byMetaData[meta]); diff != "" {
+				t.Errorf("%s: recommendation was mutated: %v", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/test-infra/prow/metrics"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load"
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// simulatedContainer shows what the admission webhook would do to the resources of a container
+type simulatedContainer struct {
+	Metadata    pod_scaler.FullMetadata     `json:"metadata"`
+	Configured  corev1.ResourceRequirements `json:"configured"`
+	Recommended corev1.ResourceRequirements `json:"recommended"`
+	Applied     corev1.ResourceRequirements `json:"applied"`
+	// Capped lists the requests that were lowered to the caps to keep the Pod schedulable
+	Capped []corev1.ResourceName `json:"capped,omitempty"`
+	// RequestChange is how much more of each resource the applied requests reserve than the configured
+	// ones. It is a change in quantities, not a cost.
+	RequestChange corev1.ResourceList `json:"requestChange,omitempty"`
+}
+
+// simulator determines the resources the admission webhook would apply to the containers of a configuration
+type simulator struct {
+	resources            *resourceServer
+	mutateResourceLimits bool
+	cpuCap               int64
+	memoryCap            string
+	ephemeralStorageCap  string
+}
+
+// discardingReporter does not report, as nothing is admitted in a simulation
+type discardingReporter struct{}
+
+func (discardingReporter) ReportResourceConfigurationWarning(string, string, string, string, string) {
+}
+
+// simulate mirrors mutatePodResources for every container
+func (s *simulator) simulate(containers []pod_scaler.ConfiguredContainer, logger *logrus.Entry) []simulatedContainer {
+	var simulated []simulatedContainer
+	for _, container := range containers {
+		result := simulatedContainer{Metadata: container.Metadata, Configured: container.Resources}
+		applied := container.Resources.DeepCopy()
+		if recommended, ok := s.resources.recommendedRequestFor(container.Metadata); ok {
+			result.Recommended = *recommended.DeepCopy()
+			useOursIfLarger(&recommended, applied, container.Metadata.String(), WorkloadTypeUndefined, discardingReporter{}, logger)
+			if s.mutateResourceLimits {
+				reconcileLimits(applied)
+			}
+		}
+		uncapped := applied.DeepCopy()
+		preventUnschedulable(applied, s.cpuCap, s.memoryCap, s.ephemeralStorageCap, logger)
+		for _, name := range sortedNames(applied.Requests) {
+			capped, requested := applied.Requests[name], uncapped.Requests[name]
+			if capped.Cmp(requested) == -1 {
+				result.Capped = append(result.Capped, name)
+			}
+		}
+		for _, name := range sortedNames(container.Resources.Requests, applied.Requests) {
+			change := applied.Requests[name].DeepCopy()
+			change.Sub(container.Resources.Requests[name])
+			if !change.IsZero() {
+				if result.RequestChange == nil {
+					result.RequestChange = corev1.ResourceList{}
+				}
+				result.RequestChange[name] = change
+			}
+		}
+		result.Applied = *applied
+		simulated = append(simulated, result)
+	}
+	return simulated
+}
+
+func sortedNames(lists ...corev1.ResourceList) []corev1.ResourceName {
+	seen := map[corev1.ResourceName]bool{}
+	var names []corev1.ResourceName
+	for _, list := range lists {
+		for name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// ServeHTTP simulates the admission of the containers of a resolved configuration posted as YAML or JSON.
+// Configurations with unresolved multi-stage tests are rejected.
+func (s *simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotImplemented)
+		_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+		return
+	}
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		metrics.RecordError("failed to read request", uiMetrics.ErrorRate)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "failed to read request: %v", err)
+		return
+	}
+	var config api.ReleaseBuildConfiguration
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		metrics.RecordError("invalid configuration", uiMetrics.ErrorRate)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "failed to unmarshal configuration: %v", err)
+		return
+	}
+	logger := logrus.WithFields(api.LogFieldsFor(config.Metadata))
+	containers, err := pod_scaler.ContainersFor(config)
+	if err != nil {
+		metrics.RecordError("invalid configuration", uiMetrics.ErrorRate)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "failed to determine containers: %v", err)
+		return
+	}
+	response, err := json.Marshal(s.simulate(containers, logger))
+	if err != nil {
+		metrics.RecordError("failed to marshal data", uiMetrics.ErrorRate)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "failed to marshal data to JSON: %v", err)
+		logger.WithError(err).Errorf("Failed to marshal data to JSON.")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(response); err != nil {
+		logger.WithError(err).Error("Failed to write response")
+	}
+}
+
+// printSimulation writes a table of the requests of every container
+func printSimulation(out io.Writer, simulated []simulatedContainer) error {
+	format := func(list corev1.ResourceList, name corev1.ResourceName) string {
+		if quantity, ok := list[name]; ok {
+			return quantity.String()
+		}
+		return "-"
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tRESOURCE\tCONFIGURED\tRECOMMENDED\tAPPLIED\tCHANGE")
+	for _, container := range simulated {
+		capped := map[corev1.ResourceName]bool{}
+		for _, name := range container.Capped {
+			capped[name] = true
+		}
+		for _, name := range sortedNames(container.Configured.Requests, container.Recommended.Requests, container.Applied.Requests) {
+			applied := format(container.Applied.Requests, name)
+			if capped[name] {
+				applied += " (capped)"
+			}
+			change := format(container.RequestChange, name)
+			if quantity, ok := container.RequestChange[name]; ok && quantity.Sign() > 0 {
+				change = "+" + change
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", container.Metadata.String(), name, format(container.Configured.Requests, name), format(container.Recommended.Requests, name), applied, change)
+		}
+	}
+	return w.Flush()
+}
+
+// loadResourceServer digests all cached data at once, as a simulation is not long-running
+func loadResourceServer(cache cache) (*resourceServer, error) {
+	server := emptyResourceServer()
+	for metric, digest := range server.digesters() {
+		for _, prefix := range []string{prowjobsCachePrefix, podsCachePrefix, stepsCachePrefix} {
+			name := prefix + "/" + metric
+			data, err := loadCache(cache, name, server.logger.WithField("metric", name))
+			if errors.Is(err, notExist{}) {
+				server.logger.WithField("metric", name).Warn("No data cached for metric.")
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to load data for %s: %w", name, err)
+			}
+			digest(data)
+		}
+	}
+	return server, nil
+}
+
+func mainSimulate(opts *options, cache cache) {
+	raw, err := os.ReadFile(opts.simulateConfigPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read configuration.")
+	}
+	var config api.ReleaseBuildConfiguration
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		logrus.WithError(err).Fatal("Failed to unmarshal configuration.")
+	}
+	if opts.simulateRegistryPath != "" {
+		refs, chains, workflows, _, _, observers, err := load.Registry(opts.simulateRegistryPath, load.RegistryFlag(0))
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to load registry.")
+		}
+		var versions registry.VersionByName
+		if opts.simulateVersionsPath != "" {
+			if versions, err = load.RegistryVersions(opts.simulateVersionsPath, load.RegistryFlag(0), nil); err != nil {
+				logrus.WithError(err).Fatal("Failed to load registry versions.")
+			}
+		}
+		if config, err = registry.ResolveConfig(registry.NewVersionedResolver(refs, chains, workflows, observers, versions), config); err != nil {
+			logrus.WithError(err).Fatal("Failed to resolve configuration.")
+		}
+	}
+	containers, err := pod_scaler.ContainersFor(config)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to determine containers.")
+	}
+	resources, err := loadResourceServer(cache)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load cached data.")
+	}
+	s := &simulator{
+		resources:            resources,
+		mutateResourceLimits: opts.mutateResourceLimits,
+		cpuCap:               opts.cpuCap,
+		memoryCap:            opts.memoryCap,
+		ephemeralStorageCap:  opts.ephemeralStorageCap,
+	}
+	if err := printSimulation(os.Stdout, s.simulate(containers, logrus.WithField("component", "pod-scaler simulator"))); err != nil {
+		logrus.WithError(err).Fatal("Failed to print simulation.")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+func TestSimulate(t *testing.T) {
+	meta := func(step string) pod_scaler.FullMetadata {
+		return pod_scaler.FullMetadata{
+			Metadata:  api.Metadata{Org: "org", Repo: "repo", Branch: "branch"},
+			Target:    "e2e",
+			Step:      step,
+			Pod:       "e2e-" + step,
+			Container: "test",
+		}
+	}
+	s := &simulator{
+		resources: &resourceServer{
+			logger: logrus.WithField("test", t.Name()),
+			byMetaData: map[pod_scaler.FullMetadata]corev1.ResourceRequirements{
+				meta("small"): {Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				}, Limits: corev1.ResourceList{}},
+				meta("large"): {Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("16"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				}, Limits: corev1.ResourceList{}},
+			},
+		},
+		cpuCap:              10,
+		memoryCap:           "20Gi",
+		ephemeralStorageCap: "100Gi",
+	}
+	containers := []pod_scaler.ConfiguredContainer{
+		{
+			Metadata: meta("small"),
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			}},
+		},
+		{
+			Metadata: meta("large"),
+		},
+		{
+			Metadata: meta("unknown"),
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("100m"),
+			}},
+		},
+	}
+	expected := []simulatedContainer{
+		{
+			Metadata: meta("small"),
+			Configured: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			}},
+			Recommended: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}, Limits: corev1.ResourceList{}},
+			Applied: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}, Limits: corev1.ResourceList{}},
+			RequestChange: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		},
+		{
+			Metadata: meta("large"),
+			Recommended: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("16"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}, Limits: corev1.ResourceList{}},
+			Applied: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}, Limits: corev1.ResourceList{}},
+			Capped: []corev1.ResourceName{corev1.ResourceCPU},
+			RequestChange: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		{
+			Metadata: meta("unknown"),
+			Configured: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("100m"),
+			}},
+			Applied: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("100m"),
+			}},
+		},
+	}
+	actual := s.simulate(containers, logrus.WithField("test", t.Name()))
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Fatalf("got incorrect simulation: %v", diff)
+	}
+
+	out := &bytes.Buffer{}
+	if err := printSimulation(out, actual); err != nil {
+		t.Fatalf("failed to print simulation: %v", err)
+	}
+	expectedOut := `CONTAINER                            RESOURCE  CONFIGURED  RECOMMENDED  APPLIED      CHANGE
+org/repo@branch e2e - small[test]    cpu       1           500m         1            -
+org/repo@branch e2e - small[test]    memory    512Mi       1Gi          1Gi          +512Mi
+org/repo@branch e2e - large[test]    cpu       -           16           10 (capped)  +10
+org/repo@branch e2e - large[test]    memory    -           1Gi          1Gi          +1Gi
+org/repo@branch e2e - unknown[test]  cpu       100m        -            100m         -
+`
+	if diff := cmp.Diff(expectedOut, out.String()); diff != "" {
+		t.Errorf("got incorrect output: %v", diff)
+	}
+}
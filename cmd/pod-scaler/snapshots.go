@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/interrupts"
+)
+
+const (
+	// maxSnapshots is how many generations of data we retain for every metric to roll back to
+	maxSnapshots = 10
+
+	snapshotsPrefix = "snapshots"
+	blobsPrefix     = "blobs/sha256"
+)
+
+// snapshot identifies one generation of the cached data for a metric. The data itself
+// is stored under its digest, so rolling back only needs to point at older data again.
+// Data cached before we kept snapshots is served as generation zero.
+type snapshot struct {
+	Generation int64     `json:"generation"`
+	Digest     string    `json:"digest,omitempty"`
+	Created    time.Time `json:"created"`
+	// RestoredFrom is the generation whose data a rollback restored
+	RestoredFrom int64 `json:"restored_from,omitempty"`
+}
+
+// snapshotIndex lists the retained generations of data for a metric, oldest first
+type snapshotIndex struct {
+	Snapshots []snapshot `json:"snapshots"`
+}
+
+func (i *snapshotIndex) latest() (snapshot, bool) {
+	if len(i.Snapshots) == 0 {
+		return snapshot{}, false
+	}
+	return i.Snapshots[len(i.Snapshots)-1], true
+}
+
+func (i *snapshotIndex) nextGeneration() int64 {
+	current, _ := i.latest()
+	return current.Generation + 1
+}
+
+// rollbackTarget determines the snapshot to restore: the given generation, or when none
+// is given, the newest one produced before the data that is currently served
+func (i *snapshotIndex) rollbackTarget(generation int64) (snapshot, error) {
+	if generation != 0 {
+		for _, item := range i.Snapshots {
+			if item.Generation == generation {
+				return item, nil
+			}
+		}
+		return snapshot{}, fmt.Errorf("generation %d is not retained", generation)
+	}
+	current, ok := i.latest()
+	if !ok {
+		return snapshot{}, errors.New("no generations are retained")
+	}
+	served := current.Generation
+	if current.RestoredFrom != 0 {
+		served = current.RestoredFrom
+	}
+	for j := len(i.Snapshots) - 1; j >= 0; j-- {
+		if item := i.Snapshots[j]; item.Generation < served && item.RestoredFrom == 0 {
+			return item, nil
+		}
+	}
+	return snapshot{}, fmt.Errorf("no generation before %d is retained", served)
+}
+
+// add records a new snapshot and returns the digests no retained snapshot refers to anymore
+func (i *snapshotIndex) add(next snapshot) []string {
+	i.Snapshots = append(i.Snapshots, next)
+	if len(i.Snapshots) <= maxSnapshots {
+		return nil
+	}
+	pruned := i.Snapshots[:len(i.Snapshots)-maxSnapshots]
+	i.Snapshots = i.Snapshots[len(i.Snapshots)-maxSnapshots:]
+	retained := sets.NewString()
+	for _, item := range i.Snapshots {
+		retained.Insert(item.Digest)
+	}
+	orphaned := sets.NewString()
+	for _, item := range pruned {
+		if !retained.Has(item.Digest) {
+			orphaned.Insert(item.Digest)
+		}
+	}
+	return orphaned.List()
+}
+
+func indexName(metricName string) string {
+	return snapshotsPrefix + "/" + metricName
+}
+
+func blobName(digest string) string {
+	return blobsPrefix + "/" + digest
+}
+
+func digestOf(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func loadIndex(loader loader, metricName string) (*snapshotIndex, error) {
+	raw, err := loadFrom(loader, indexName(metricName))
+	if err != nil {
+		return nil, err
+	}
+	var index snapshotIndex
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return nil, fmt.Errorf("could not unmarshal snapshot index: %w", err)
+	}
+	return &index, nil
+}
+
+// loadSnapshot loads the data of the latest generation for a metric. We resolve the
+// generation from the index and then load its data by digest, so the generation we
+// report is always the one we loaded, even if a producer stores a new one meanwhile.
+func loadSnapshot(loader loader, metricName string) (snapshot, []byte, error) {
+	index, err := loadIndex(loader, metricName)
+	if err != nil && !errors.Is(err, notExist{}) {
+		return snapshot{}, nil, fmt.Errorf("could not load snapshot index: %w", err)
+	}
+	var current snapshot
+	var ok bool
+	if index != nil {
+		current, ok = index.latest()
+	}
+	if !ok {
+		data, err := loadFrom(loader, metricName)
+		return snapshot{}, data, err
+	}
+	data, err := loadBlob(loader, current.Digest)
+	return current, data, err
+}
+
+func loadBlob(loader loader, digest string) ([]byte, error) {
+	data, err := loadFrom(loader, blobName(digest))
+	if err != nil {
+		return nil, err
+	}
+	if actual := digestOf(data); actual != digest {
+		return nil, fmt.Errorf("data stored for digest %s has digest %s", digest, actual)
+	}
+	return data, nil
+}
+
+// storeSnapshot stores data as the next generation for a metric
+func storeSnapshot(dataCache cache, metricName string, data []byte, now time.Time, logger *logrus.Entry) (snapshot, error) {
+	index, err := loadIndex(dataCache, metricName)
+	if errors.Is(err, notExist{}) {
+		index, err = &snapshotIndex{}, nil
+	}
+	if err != nil {
+		return snapshot{}, fmt.Errorf("could not load snapshot index: %w", err)
+	}
+	digest := digestOf(data)
+	if err := storeTo(dataCache, blobName(digest), data); err != nil {
+		return snapshot{}, fmt.Errorf("could not store snapshot data: %w", err)
+	}
+	next := snapshot{Generation: index.nextGeneration(), Digest: digest, Created: now}
+	return next, commitSnapshot(dataCache, metricName, index, next, data, logger)
+}
+
+// rollback restores the data of an older generation for a metric as the next generation,
+// so consumers pick it up the same way they pick up new data
+func rollback(dataCache cache, metricName string, generation int64, now time.Time, logger *logrus.Entry) (snapshot, error) {
+	index, err := loadIndex(dataCache, metricName)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("could not load snapshot index: %w", err)
+	}
+	target, err := index.rollbackTarget(generation)
+	if err != nil {
+		return snapshot{}, err
+	}
+	data, err := loadBlob(dataCache, target.Digest)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("could not load data for generation %d: %w", target.Generation, err)
+	}
+	next := snapshot{Generation: index.nextGeneration(), Digest: target.Digest, Created: now, RestoredFrom: target.Generation}
+	return next, commitSnapshot(dataCache, metricName, index, next, data, logger)
+}
+
+// commitSnapshot records the snapshot in the index before overwriting the latest data, as
+// consumers reload once the latest data changes and need to find the new generation then.
+// The latest data is still written in full for consumers that do not know about snapshots.
+func commitSnapshot(dataCache cache, metricName string, index *snapshotIndex, next snapshot, data []byte, logger *logrus.Entry) error {
+	orphaned := index.add(next)
+	raw, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("could not marshal snapshot index: %w", err)
+	}
+	if err := storeTo(dataCache, indexName(metricName), raw); err != nil {
+		return fmt.Errorf("could not store snapshot index: %w", err)
+	}
+	if err := storeTo(dataCache, metricName, data); err != nil {
+		return fmt.Errorf("could not store latest data: %w", err)
+	}
+	for _, digest := range orphaned {
+		if err := removeFrom(dataCache, blobName(digest)); err != nil && !errors.Is(err, notExist{}) {
+			logger.WithError(err).WithField("digest", digest).Warn("Failed to remove data no snapshot refers to.")
+		}
+	}
+	return nil
+}
+
+func removeFrom(deleter deleter, name string) error {
+	ctx, cancel := context.WithTimeout(interrupts.Context(), 5*time.Minute)
+	defer func() { cancel() }()
+	return deleter.remove(ctx, name+".json")
+}
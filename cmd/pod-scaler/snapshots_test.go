@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRollbackTarget(t *testing.T) {
+	index := snapshotIndex{Snapshots: []snapshot{
+		{Generation: 1, Digest: "a"},
+		{Generation: 2, Digest: "b"},
+		{Generation: 3, Digest: "c"},
+		{Generation: 4, Digest: "b", RestoredFrom: 2},
+	}}
+	var testCases = []struct {
+		name          string
+		index         snapshotIndex
+		generation    int64
+		expected      snapshot
+		expectedError error
+	}{
+		{
+			name:     "previous generation of data that was produced",
+			index:    snapshotIndex{Snapshots: index.Snapshots[:3]},
+			expected: snapshot{Generation: 2, Digest: "b"},
+		},
+		{
+			name:     "previous generation of data that was restored",
+			index:    index,
+			expected: snapshot{Generation: 1, Digest: "a"},
+		},
+		{
+			name:       "explicit generation",
+			index:      index,
+			generation: 3,
+			expected:   snapshot{Generation: 3, Digest: "c"},
+		},
+		{
+			name:          "generation that is not retained",
+			index:         index,
+			generation:    7,
+			expectedError: errors.New("generation 7 is not retained"),
+		},
+		{
+			name:          "nothing before the first generation",
+			index:         snapshotIndex{Snapshots: index.Snapshots[:1]},
+			expectedError: errors.New("no generation before 1 is retained"),
+		},
+		{
+			name:          "no generations",
+			expectedError: errors.New("no generations are retained"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := testCase.index.rollbackTarget(testCase.generation)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("%s: got incorrect error: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect target: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	dataCache := &localCache{dir: dir}
+	logger := logrus.WithField("test", t.Name())
+	metric := "pods/metric"
+	created := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	expectServed := func(expected snapshot, expectedData string) {
+		t.Helper()
+		current, data, err := loadSnapshot(dataCache, metric)
+		if err != nil {
+			t.Fatalf("failed to load snapshot: %v", err)
+		}
+		if diff := cmp.Diff(expected, current); diff != "" {
+			t.Errorf("got incorrect snapshot: %v", diff)
+		}
+		if diff := cmp.Diff(expectedData, string(data)); diff != "" {
+			t.Errorf("got incorrect data: %v", diff)
+		}
+		latest, err := loadFrom(dataCache, metric)
+		if err != nil {
+			t.Fatalf("failed to load latest data: %v", err)
+		}
+		if diff := cmp.Diff(expectedData, string(latest)); diff != "" {
+			t.Errorf("got incorrect latest data: %v", diff)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "pods"), 0777); err != nil {
+		t.Fatalf("failed to seed cache: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, metric+".json"), []byte("legacy"), 0666); err != nil {
+		t.Fatalf("failed to seed cache: %v", err)
+	}
+	expectServed(snapshot{}, "legacy")
+
+	for i := 1; i <= 3; i++ {
+		stored, err := storeSnapshot(dataCache, metric, []byte(fmt.Sprintf("data-%d", i)), created, logger)
+		if err != nil {
+			t.Fatalf("failed to store snapshot: %v", err)
+		}
+		expectServed(stored, fmt.Sprintf("data-%d", i))
+	}
+	current, _, _ := loadSnapshot(dataCache, metric)
+	if diff := cmp.Diff(snapshot{Generation: 3, Digest: digestOf([]byte("data-3")), Created: created}, current); diff != "" {
+		t.Errorf("got incorrect snapshot: %v", diff)
+	}
+
+	restored, err := rollback(dataCache, metric, 0, created, logger)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	expectServed(snapshot{Generation: 4, Digest: digestOf([]byte("data-2")), Created: created, RestoredFrom: 2}, "data-2")
+	if restored.Generation != 4 {
+		t.Errorf("expected to restore into generation 4, got %d", restored.Generation)
+	}
+	if _, err := rollback(dataCache, metric, 0, created, logger); err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	expectServed(snapshot{Generation: 5, Digest: digestOf([]byte("data-1")), Created: created, RestoredFrom: 1}, "data-1")
+	if _, err := rollback(dataCache, metric, 0, created, logger); err == nil {
+		t.Error("expected to fail rolling back past the first generation")
+	}
+	if _, err := rollback(dataCache, metric, 3, created, logger); err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	expectServed(snapshot{Generation: 6, Digest: digestOf([]byte("data-3")), Created: created, RestoredFrom: 3}, "data-3")
+
+	// the first generations are pruned, along with the data only they referred to
+	for i := 4; i <= 13; i++ {
+		if _, err := storeSnapshot(dataCache, metric, []byte(fmt.Sprintf("data-%d", i)), created, logger); err != nil {
+			t.Fatalf("failed to store snapshot: %v", err)
+		}
+	}
+	index, err := loadIndex(dataCache, metric)
+	if err != nil {
+		t.Fatalf("failed to load index: %v", err)
+	}
+	if len(index.Snapshots) != maxSnapshots {
+		t.Errorf("expected %d snapshots to be retained, got %d", maxSnapshots, len(index.Snapshots))
+	}
+	for data, expected := range map[string]bool{"data-1": false, "data-2": false, "data-3": false, "data-4": true, "data-13": true} {
+		_, err := loadBlob(dataCache, digestOf([]byte(data)))
+		if exists := err == nil; exists != expected {
+			t.Errorf("%s: expected data to be retained: %v, got error: %v", data, expected, err)
+		}
+	}
+}
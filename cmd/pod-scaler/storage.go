@@ -7,12 +7,20 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/sirupsen/logrus"
 
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -26,6 +34,7 @@ type cache interface {
 	loader
 	storer
 	attributeResolver
+	deleter
 }
 
 // loader closes over how we load cached data
@@ -43,6 +52,11 @@ type attributeResolver interface {
 	lastUpdated(ctx context.Context, name string) (time.Time, error)
 }
 
+// deleter closes over how we remove cached data
+type deleter interface {
+	remove(ctx context.Context, name string) error
+}
+
 type bucketCache struct {
 	bucket *storage.BucketHandle
 }
@@ -72,6 +86,114 @@ func (b *bucketCache) lastUpdated(ctx context.Context, name string) (time.Time,
 	return attrs.Updated, nil
 }
 
+func (b *bucketCache) remove(ctx context.Context, name string) error {
+	err := b.bucket.Object(name).Delete(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		err = notExist{wrapped: err}
+	}
+	return err
+}
+
+// s3Client is the subset of the S3 API we use, so that any S3-compatible storage will do
+type s3Client interface {
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error)
+	DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error)
+}
+
+type s3Cache struct {
+	bucket   string
+	client   s3Client
+	uploader *s3manager.Uploader
+}
+
+var _ cache = &s3Cache{}
+
+// newS3Cache creates a cache in an S3 bucket. When an endpoint is given, requests are sent
+// there using path-style addressing, as S3-compatible storage like MinIO expects.
+func newS3Cache(bucket, endpoint, region, credentialsFile string) (*s3Cache, error) {
+	config := &aws.Config{Region: aws.String(region)}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	if credentialsFile != "" {
+		config.Credentials = credentials.NewSharedCredentials(credentialsFile, "")
+	}
+	awsSession, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create AWS session: %w", err)
+	}
+	client := s3.New(awsSession)
+	return &s3Cache{bucket: bucket, client: client, uploader: s3manager.NewUploaderWithClient(client)}, nil
+}
+
+// s3NotExist determines if the error from S3 means that the object does not exist. Only
+// GetObject responses carry the NoSuchKey code, others just have the HTTP status.
+func s3NotExist(err error) bool {
+	var awsErr awserr.RequestFailure
+	if errors.As(err, &awsErr) {
+		return awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.StatusCode() == http.StatusNotFound
+	}
+	return false
+}
+
+func (s *s3Cache) load(ctx context.Context, name string) (io.ReadCloser, error) {
+	output, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(name)})
+	if s3NotExist(err) {
+		return nil, notExist{wrapped: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (s *s3Cache) store(ctx context.Context, name string) (io.WriteCloser, error) {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{Bucket: aws.String(s.bucket), Key: aws.String(name), Body: reader})
+		// unblock the writer if the upload failed before consuming everything
+		reader.CloseWithError(err)
+		done <- err
+	}()
+	return &s3Writer{writer: writer, done: done}, nil
+}
+
+func (s *s3Cache) lastUpdated(ctx context.Context, name string) (time.Time, error) {
+	output, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(name)})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not query cache for attributes: %w", err)
+	}
+	return aws.TimeValue(output.LastModified), nil
+}
+
+func (s *s3Cache) remove(ctx context.Context, name string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(name)})
+	if s3NotExist(err) {
+		err = notExist{wrapped: err}
+	}
+	return err
+}
+
+// s3Writer streams data to an upload, which is only complete once the writer is closed
+type s3Writer struct {
+	writer *io.PipeWriter
+	done   <-chan error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
 type localCache struct {
 	dir string
 }
@@ -102,6 +224,14 @@ func (l *localCache) lastUpdated(_ context.Context, name string) (time.Time, err
 	return info.ModTime(), nil
 }
 
+func (l *localCache) remove(_ context.Context, name string) error {
+	err := os.Remove(path.Join(l.dir, name))
+	if os.IsNotExist(err) {
+		err = notExist{wrapped: err}
+	}
+	return err
+}
+
 // notExist closes over the different ways in which storage libraries may expose a nonexistent file
 type notExist struct {
 	wrapped error
@@ -122,29 +252,36 @@ func (e notExist) Unwrap() error {
 
 // loadCache loads cached query data from the given storage loader.
 func loadCache(loader loader, metricName string, logger *logrus.Entry) (*pod_scaler.CachedQuery, error) {
+	cache, _, err := loadCacheSnapshot(loader, metricName, logger)
+	return cache, err
+}
+
+// loadCacheSnapshot loads the latest generation of cached query data from the given storage loader.
+func loadCacheSnapshot(loader loader, metricName string, logger *logrus.Entry) (*pod_scaler.CachedQuery, snapshot, error) {
 	readStart := time.Now()
 	logger.Info("Reading Prometheus data from cache.")
 	logger.Debug("Loading Prometheus data from storage.")
+	var current snapshot
 	var data []byte
 	for i := 0; i < 5; i++ {
 		var readErr error
-		data, readErr = loadFrom(loader, metricName)
+		current, data, readErr = loadSnapshot(loader, metricName)
 		if errors.Is(readErr, context.DeadlineExceeded) {
 			logger.Debug("Failed to load data before deadline, trying again.")
 			continue
 		}
 		if readErr != nil {
-			return nil, fmt.Errorf("could not read cached data: %w", readErr)
+			return nil, snapshot{}, fmt.Errorf("could not read cached data: %w", readErr)
 		}
 		break
 	}
 	logger.Debugf("Read Prometheus data from storage after %s.", time.Since(readStart).Round(time.Second))
 	var cache pod_scaler.CachedQuery
 	if err := json.Unmarshal(data, &cache); err != nil {
-		return nil, fmt.Errorf("could not unmarshal cached data: %w", err)
+		return nil, snapshot{}, fmt.Errorf("could not unmarshal cached data: %w", err)
 	}
-	logger.Infof("Loaded %d distributions for %d identifiers after %s.", len(cache.Data), len(cache.DataByMetaData), time.Since(readStart).Round(time.Second))
-	return &cache, nil
+	logger.Infof("Loaded %d distributions for %d identifiers from generation %d after %s.", len(cache.Data), len(cache.DataByMetaData), current.Generation, time.Since(readStart).Round(time.Second))
+	return &cache, current, nil
 }
 
 func loadFrom(loader loader, metricName string) ([]byte, error) {
@@ -161,8 +298,8 @@ func loadFrom(loader loader, metricName string) ([]byte, error) {
 	return data, readErr
 }
 
-// storeCache prunes and stores cached query data to the given storage storer.
-func storeCache(storer storer, metricName string, data *pod_scaler.CachedQuery, logger *logrus.Entry) error {
+// storeCache prunes and stores cached query data as a new generation in the given cache.
+func storeCache(dataCache cache, metricName string, data *pod_scaler.CachedQuery, logger *logrus.Entry) error {
 	pruneStart := time.Now()
 	logger.Debug("Pruning cached Prometheus data.")
 	data.Prune()
@@ -175,7 +312,7 @@ func storeCache(storer storer, metricName string, data *pod_scaler.CachedQuery,
 		return fmt.Errorf("could not marshal cached data: %w", err)
 	}
 	for i := 0; i < 5; i++ {
-		storeErr := storeTo(storer, metricName, raw)
+		stored, storeErr := storeSnapshot(dataCache, metricName, raw, time.Now(), logger)
 		if errors.Is(storeErr, context.DeadlineExceeded) {
 			logger.Debug("Failed to store data before deadline, trying again.")
 			continue
@@ -183,6 +320,7 @@ func storeCache(storer storer, metricName string, data *pod_scaler.CachedQuery,
 		if storeErr != nil {
 			return fmt.Errorf("could not write cached data: %w", storeErr)
 		}
+		logger = logger.WithField("generation", stored.Generation)
 		break
 	}
 	logger.Infof("Flushed Prometheus data to cache after %s.", time.Since(flushStart).Round(time.Second))
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeS3 is a stand-in for S3-compatible storage like MinIO, serving objects in a bucket
+// with path-style addressing
+type fakeS3 struct {
+	bucket   string
+	modified time.Time
+
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if key == r.URL.Path {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchBucket</Code></Error>`)
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	data, exists := f.objects[key]
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[key] = body
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("Last-Modified", f.modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestS3Cache(t *testing.T) {
+	backend := &fakeS3{bucket: "bucket", modified: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC), objects: map[string][]byte{}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	credentialsFile := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(credentialsFile, []byte("[default]\naws_access_key_id = access\naws_secret_access_key = secret\n"), 0600); err != nil {
+		t.Fatalf("failed to write credentials: %v", err)
+	}
+	dataCache, err := newS3Cache("bucket", server.URL, "us-east-1", credentialsFile)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := dataCache.load(ctx, "missing.json"); !errors.Is(err, notExist{}) {
+		t.Errorf("expected a missing object not to exist, got %v", err)
+	}
+	if _, err := dataCache.lastUpdated(ctx, "missing.json"); err == nil {
+		t.Error("expected to fail to query a missing object")
+	}
+	if err := storeTo(dataCache, "pods/metric", []byte("data")); err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+	if diff := cmp.Diff(map[string][]byte{"pods/metric.json": []byte("data")}, backend.objects); diff != "" {
+		t.Errorf("got incorrect objects: %v", diff)
+	}
+	data, err := loadFrom(dataCache, "pods/metric")
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if diff := cmp.Diff("data", string(data)); diff != "" {
+		t.Errorf("got incorrect data: %v", diff)
+	}
+	updated, err := lastUpdated(dataCache, "pods/metric")
+	if err != nil {
+		t.Fatalf("failed to query last update: %v", err)
+	}
+	if !updated.Equal(backend.modified) {
+		t.Errorf("expected last update at %s, got %s", backend.modified, updated)
+	}
+	if err := removeFrom(dataCache, "pods/metric"); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	if _, err := loadFrom(dataCache, "pods/metric"); !errors.Is(err, notExist{}) {
+		t.Errorf("expected a removed object not to exist, got %v", err)
+	}
+
+	stored, err := storeSnapshot(dataCache, "pods/metric", []byte("snapshot"), backend.modified, logrus.WithField("test", t.Name()))
+	if err != nil {
+		t.Fatalf("failed to store snapshot: %v", err)
+	}
+	current, data, err := loadSnapshot(dataCache, "pods/metric")
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if diff := cmp.Diff(stored, current); diff != "" {
+		t.Errorf("got incorrect snapshot: %v", diff)
+	}
+	if diff := cmp.Diff("snapshot", string(data)); diff != "" {
+		t.Errorf("got incorrect snapshot data: %v", diff)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/test-infra/prow/flagutil"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/promotion"
+	"github.com/openshift/ci-tools/pkg/promotion/history"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+const (
+	actionRollback = "rollback"
+	actionPin      = "pin"
+	actionUnpin    = "unpin"
+	actionHistory  = "history"
+)
+
+type options struct {
+	action     string
+	namespace  string
+	name       string
+	tags       flagutil.Strings
+	image      string
+	generation int
+	pin        bool
+	dryRun     bool
+}
+
+func gatherOptions() options {
+	o := options{}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.StringVar(&o.action, "action", actionRollback, fmt.Sprintf("What to do with the tags: %s restores previous images, %s and %s control if promotions may replace them, %s shows the images they pointed to.", actionRollback, actionPin, actionUnpin, actionHistory))
+	fs.StringVar(&o.namespace, "namespace", "", "The namespace of the imagestream.")
+	fs.StringVar(&o.name, "name", "", "The name of the imagestream.")
+	fs.Var(&o.tags, "tag", "A tag of the imagestream. Can be passed multiple times.")
+	fs.StringVar(&o.image, "image", "", "The digest of the image to roll back to. If not set, --generation selects the image from the promotion history of each tag.")
+	fs.IntVar(&o.generation, "generation", 1, "How many replaced images to go back in the promotion history of each tag, where 1 is the image replaced by the latest promotion.")
+	fs.BoolVar(&o.pin, "pin", false, "Pin the tags after rolling them back, so that promotions do not replace them until they are unpinned.")
+	fs.BoolVar(&o.dryRun, "dry-run", true, "Only print what would be rolled back.")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatal("could not parse input")
+	}
+	return o
+}
+
+func (o *options) validate() error {
+	switch o.action {
+	case actionRollback, actionPin, actionUnpin, actionHistory:
+	default:
+		return fmt.Errorf("--action must be one of %s, %s, %s, %s", actionRollback, actionPin, actionUnpin, actionHistory)
+	}
+	if o.namespace == "" || o.name == "" {
+		return fmt.Errorf("--namespace and --name are required")
+	}
+	if o.action != actionHistory && len(o.tags.Strings()) == 0 {
+		return fmt.Errorf("--tag is required")
+	}
+	if o.action == actionRollback && o.image == "" && o.generation < 1 {
+		return fmt.Errorf("--generation must be positive")
+	}
+	return nil
+}
+
+func main() {
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("failed to validate options")
+	}
+	logger := logrus.WithField("imagestream", fmt.Sprintf("%s/%s", o.namespace, o.name))
+
+	if err := imagev1.AddToScheme(scheme.Scheme); err != nil {
+		logrus.WithError(err).Fatal("failed to add imagev1 to scheme")
+	}
+	clusterConfig, err := util.LoadClusterConfig()
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to load cluster config")
+	}
+	client, err := ctrlruntimeclient.New(clusterConfig, ctrlruntimeclient.Options{})
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to create client")
+	}
+	ctx := context.Background()
+
+	switch o.action {
+	case actionHistory:
+		if err := printHistory(ctx, client, o); err != nil {
+			logger.WithError(err).Fatal("failed to print the promotion history")
+		}
+	case actionPin, actionUnpin:
+		if o.dryRun {
+			logger.WithField("tags", o.tags.Strings()).Infof("Would %s the tags", o.action)
+			return
+		}
+		if err := promotion.SetPinned(ctx, client, o.namespace, o.name, o.tags.Strings(), o.action == actionPin); err != nil {
+			logger.WithError(err).Fatalf("failed to %s the tags", o.action)
+		}
+		logger.WithField("tags", o.tags.Strings()).Infof("Finished to %s the tags", o.action)
+	case actionRollback:
+		by := os.Getenv("USER")
+		if by == "" {
+			by = "promotion-rollback"
+		}
+		rolledBack, err := promotion.RollbackTags(ctx, client, promotion.Rollback{
+			Namespace:  o.namespace,
+			Name:       o.name,
+			Tags:       o.tags.Strings(),
+			Image:      o.image,
+			Generation: o.generation,
+			Pin:        o.pin,
+			By:         by,
+		}, o.dryRun, time.Now(), logger)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to roll back the tags")
+		}
+		for _, tag := range rolledBack {
+			fmt.Printf("%s:%s %s -> %s\n", o.name, tag.Tag, tag.From, tag.To)
+		}
+		if o.dryRun {
+			logger.Info("Dry run, nothing was changed")
+		}
+	}
+}
+
+func printHistory(ctx context.Context, client ctrlruntimeclient.Client, o options) error {
+	is := &imagev1.ImageStream{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: o.namespace, Name: o.name}, is); err != nil {
+		return fmt.Errorf("failed to get imagestream: %w", err)
+	}
+	tagHistory, err := history.For(is)
+	if err != nil {
+		return err
+	}
+	tags := o.tags.Strings()
+	if len(tags) == 0 {
+		for tag := range tagHistory {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+	}
+	pinned := history.PinnedTags(is)
+	for _, tag := range tags {
+		state := ""
+		if pinned.Has(tag) {
+			state = " (pinned)"
+		}
+		fmt.Printf("%s:%s%s %s\n", o.name, tag, state, history.CurrentImage(is, tag))
+		for i, record := range tagHistory[tag] {
+			fmt.Printf("  %d: %s replaced at %s by %s\n", i+1, record.Image, record.Replaced.Format(time.RFC3339), record.By)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/flagutil"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		given    options
+		expected error
+	}{
+		{
+			name:  "roll back a tag",
+			given: options{action: actionRollback, namespace: "ocp", name: "4.12", tags: flagutil.NewStrings("cli"), generation: 1},
+		},
+		{
+			name:  "history of all tags",
+			given: options{action: actionHistory, namespace: "ocp", name: "4.12"},
+		},
+		{
+			name:     "unknown action",
+			given:    options{action: "forward", namespace: "ocp", name: "4.12", tags: flagutil.NewStrings("cli")},
+			expected: fmt.Errorf("--action must be one of rollback, pin, unpin, history"),
+		},
+		{
+			name:     "no imagestream",
+			given:    options{action: actionPin, tags: flagutil.NewStrings("cli")},
+			expected: fmt.Errorf("--namespace and --name are required"),
+		},
+		{
+			name:     "no tags",
+			given:    options{action: actionUnpin, namespace: "ocp", name: "4.12"},
+			expected: fmt.Errorf("--tag is required"),
+		},
+		{
+			name:     "no generation",
+			given:    options{action: actionRollback, namespace: "ocp", name: "4.12", tags: flagutil.NewStrings("cli")},
+			expected: fmt.Errorf("--generation must be positive"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, tc.given.validate(), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("error differs from expected:\n%s", diff)
+			}
+		})
+	}
+}
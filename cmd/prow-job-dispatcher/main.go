@@ -59,6 +59,12 @@ type options struct {
 	disableClusters flagutil.Strings
 	defaultCluster  string
 
+	loadAware            bool
+	clusterPrometheusURL flagutil.Strings
+	maxMoves             int
+	degradedScore        float64
+	planPath             string
+
 	bumper.GitAuthorOptions
 	dispatcher.PrometheusOptions
 	prcreation.PRCreationOptions
@@ -82,6 +88,12 @@ func gatherOptions() options {
 	fs.Var(&o.disableClusters, "disable-cluster", "Disable this cluster. Does nothing if the cluster is disabled. Can be passed multiple times and must be disjoint with all --enable-cluster values.")
 	fs.StringVar(&o.defaultCluster, "default-cluster", "", "If passed, changes the default cluster to the specified value.")
 
+	fs.BoolVar(&o.loadAware, "load-aware", false, "Instead of dispatching all jobs, move a bounded number of job config files between the clusters of the build farm according to their current load.")
+	fs.Var(&o.clusterPrometheusURL, "cluster-prometheus-url", "The Prometheus URL of a build farm cluster in the form cluster=URL, used in --load-aware mode. Can be passed multiple times.")
+	fs.IntVar(&o.maxMoves, "max-moves", 10, "Maximum number of job config files to move in --load-aware mode.")
+	fs.Float64Var(&o.degradedScore, "degraded-score", 0.05, "In --load-aware mode, clusters whose load score is below this value are considered degraded and their jobs are moved away.")
+	fs.StringVar(&o.planPath, "plan-path", "", "If passed, the plan of the --load-aware mode is written to this file.")
+
 	o.GitAuthorOptions.AddFlags(fs)
 	o.PrometheusOptions.AddFlags(fs)
 	o.PRCreationOptions.AddFlags(fs)
@@ -118,6 +130,18 @@ func (o *options) validate() error {
 		return fmt.Errorf("--default-cluster value cannot be also be in --disable-cluster")
 	}
 
+	if o.loadAware {
+		if _, err := o.clusterPrometheusURLs(); err != nil {
+			return err
+		}
+		if o.maxMoves < 1 {
+			return fmt.Errorf("--max-moves must be positive")
+		}
+		if o.degradedScore < 0 || o.degradedScore > 1 {
+			return fmt.Errorf("--degraded-score must be between 0 and 1")
+		}
+	}
+
 	if o.createPR {
 		if o.githubLogin == "" {
 			return fmt.Errorf("--github-login cannot be empty string")
@@ -138,6 +162,22 @@ func (o *options) validate() error {
 	return o.PrometheusOptions.Validate()
 }
 
+// clusterPrometheusURLs returns the Prometheus URL of each cluster from --cluster-prometheus-url
+func (o *options) clusterPrometheusURLs() (map[api.Cluster]string, error) {
+	urls := map[api.Cluster]string{}
+	for _, value := range o.clusterPrometheusURL.Strings() {
+		cluster, url, found := strings.Cut(value, "=")
+		if !found || cluster == "" || url == "" {
+			return nil, fmt.Errorf("--cluster-prometheus-url must be in the form cluster=URL, got %q", value)
+		}
+		urls[api.Cluster(cluster)] = url
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("--cluster-prometheus-url must be set in --load-aware mode")
+	}
+	return urls, nil
+}
+
 // getCloudProvidersForE2ETests returns a set of cloud providers where a cluster is hosted for an e2e test defined in the given Prow job config.
 func getCloudProvidersForE2ETests(jc *prowconfig.JobConfig) sets.String {
 	cloudProviders := sets.NewString()
@@ -370,6 +410,122 @@ func dispatchJobs(ctx context.Context, prowJobConfigDir string, maxConcurrency i
 	return utilerrors.NewAggregate(errs)
 }
 
+// loadFiles returns the load that the relocatable jobs of each Prow job config file put on their cluster
+func loadFiles(prowJobConfigDir string, config *dispatcher.Config, jobVolumes map[string]float64) (map[string]dispatcher.FileLoad, error) {
+	files := map[string]dispatcher.FileLoad{}
+	var errs []error
+	if err := filepath.WalkDir(prowJobConfigDir, func(path string, info fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk file/directory '%s'", path)
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".yaml") {
+			return nil
+		}
+		data, err := gzip.ReadFileMaybeGZIP(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read file %q: %w", path, err))
+			return nil
+		}
+		jobConfig := &prowconfig.JobConfig{}
+		if err := yaml.Unmarshal(data, jobConfig); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unmarshal file %q: %w", path, err))
+			return nil
+		}
+
+		var jobs []prowconfig.JobBase
+		for _, presubmits := range jobConfig.PresubmitsStatic {
+			for _, job := range presubmits {
+				jobs = append(jobs, job.JobBase)
+			}
+		}
+		for _, postsubmits := range jobConfig.PostsubmitsStatic {
+			for _, job := range postsubmits {
+				jobs = append(jobs, job.JobBase)
+			}
+		}
+		for _, job := range jobConfig.Periodics {
+			jobs = append(jobs, job.JobBase)
+		}
+
+		var file dispatcher.FileLoad
+		for _, job := range jobs {
+			_, relocatable, err := config.DetermineClusterForJob(job, path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to determine cluster for the job %s in path %q: %w", job.Name, path, err))
+				continue
+			}
+			if relocatable {
+				file.AddJob(job, jobVolumes)
+			}
+		}
+		files[info.Name()] = file
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load Prow jobs: %w", err)
+	}
+	return files, utilerrors.NewAggregate(errs)
+}
+
+// getClusterLoads gets the current load of each cluster from its Prometheus
+func getClusterLoads(ctx context.Context, o options) (map[api.Cluster]dispatcher.ClusterLoad, error) {
+	urls, err := o.clusterPrometheusURLs()
+	if err != nil {
+		return nil, err
+	}
+	loads := map[api.Cluster]dispatcher.ClusterLoad{}
+	var errs []error
+	for cluster, url := range urls {
+		prometheusOptions := o.PrometheusOptions
+		prometheusOptions.PrometheusURL = url
+		client, err := prometheusOptions.NewPrometheusClient(secret.GetSecret)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create prometheus client for cluster %s: %w", cluster, err))
+			continue
+		}
+		queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		load, err := dispatcher.GetClusterLoadFromPrometheus(queryCtx, prometheusapi.NewAPI(client), time.Now())
+		cancel()
+		if err != nil {
+			// A cluster without a known load keeps its jobs
+			logrus.WithError(err).WithField("cluster", cluster).Warn("Failed to get the load of the cluster")
+			continue
+		}
+		logrus.WithField("cluster", cluster).WithField("load", load).WithField("score", load.Score()).Info("Got the load of the cluster")
+		loads[cluster] = load
+	}
+	return loads, utilerrors.NewAggregate(errs)
+}
+
+// redispatchJobs moves the job config files between clusters according to their current load
+func redispatchJobs(ctx context.Context, o options, config *dispatcher.Config, jobVolumes map[string]float64) error {
+	files, err := loadFiles(o.prowJobConfigDir, config, jobVolumes)
+	if err != nil {
+		return err
+	}
+	loads, err := getClusterLoads(ctx, o)
+	if err != nil {
+		return err
+	}
+	plan := config.PlanRedispatch(loads, files, o.maxMoves, o.degradedScore)
+	for _, move := range plan.Moves {
+		logrus.WithField("filename", move.Filename).WithField("from", move.From).WithField("to", move.To).WithField("volume", move.Volume).Info("Moving the jobs")
+	}
+	if len(plan.Stuck) > 0 {
+		logrus.WithField("degraded", plan.Degraded).WithField("files", plan.Stuck).Warn("Jobs are left on degraded clusters")
+	}
+	plan.Apply(config)
+	if o.planPath != "" {
+		raw, err := yaml.Marshal(plan)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the plan: %w", err)
+		}
+		if err := os.WriteFile(o.planPath, raw, 0644); err != nil {
+			return fmt.Errorf("failed to write the plan to %s: %w", o.planPath, err)
+		}
+	}
+	return nil
+}
+
 // getClusterProvider gets information using get request what is the current cloud provider for the given cluster
 func getClusterProvider(cluster string) (api.Cloud, error) {
 	type pageData struct {
@@ -505,9 +661,16 @@ func main() {
 	}
 	addEnabledClusters(config, enabled, getClusterProvider)
 
-	logrus.Info("Dispatching ...")
-	if err := dispatchJobs(context.TODO(), o.prowJobConfigDir, o.maxConcurrency, config, jobVolumes); err != nil {
-		logrus.WithError(err).Fatal("Failed to dispatch")
+	if o.loadAware {
+		logrus.Info("Redispatching by load ...")
+		if err := redispatchJobs(context.TODO(), o, config, jobVolumes); err != nil {
+			logrus.WithError(err).Fatal("Failed to redispatch")
+		}
+	} else {
+		logrus.Info("Dispatching ...")
+		if err := dispatchJobs(context.TODO(), o.prowJobConfigDir, o.maxConcurrency, config, jobVolumes); err != nil {
+			logrus.WithError(err).Fatal("Failed to dispatch")
+		}
 	}
 	if err := dispatcher.SaveConfig(config, o.configPath); err != nil {
 		logrus.WithError(err).Fatalf("Failed to save config file to %s", o.configPath)
@@ -13,6 +13,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowconfig "k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/flagutil"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/dispatcher"
@@ -78,6 +79,41 @@ func TestValidate(t *testing.T) {
 			},
 			expected: fmt.Errorf("--prometheus-days-before must be between 1 and 15"),
 		},
+		{
+			name: "load aware without cluster prometheus URLs",
+			given: &options{
+				prowJobConfigDir:     "prow-jobs-dir",
+				configPath:           "some-path",
+				prometheusDaysBefore: 1,
+				loadAware:            true,
+				maxMoves:             10,
+			},
+			expected: fmt.Errorf("--cluster-prometheus-url must be set in --load-aware mode"),
+		},
+		{
+			name: "load aware with a malformed cluster prometheus URL",
+			given: &options{
+				prowJobConfigDir:     "prow-jobs-dir",
+				configPath:           "some-path",
+				prometheusDaysBefore: 1,
+				loadAware:            true,
+				maxMoves:             10,
+				clusterPrometheusURL: flagutil.NewStrings("https://prometheus.build01"),
+			},
+			expected: fmt.Errorf("--cluster-prometheus-url must be in the form cluster=URL, got \"https://prometheus.build01\""),
+		},
+		{
+			name: "load aware",
+			given: &options{
+				prowJobConfigDir:     "prow-jobs-dir",
+				configPath:           "some-path",
+				prometheusDaysBefore: 1,
+				loadAware:            true,
+				maxMoves:             10,
+				degradedScore:        0.05,
+				clusterPrometheusURL: flagutil.NewStrings("build01=https://prometheus.build01", "build02=https://prometheus.build02"),
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
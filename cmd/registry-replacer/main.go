@@ -52,6 +52,7 @@ type options struct {
 	applyReplacements                            bool
 	ensureCorrectPromotionDockerfileIngoredRepos *flagutil.Strings
 	registryPath                                 string
+	versionsPath                                 string
 	flagutil.GitHubOptions
 }
 
@@ -72,6 +73,7 @@ func gatherOptions() (*options, error) {
 	flag.BoolVar(&o.applyReplacements, "apply-replacements", true, "If we should apply Dockerfile image replacements. You will probably always leave this as the default, and it's mostly used by tests that validate that base image pruning doesn't botch things. Note: If not applying replacements we will also skip unused replacement pruning.")
 	flag.BoolVar(&o.pruneOCPBuilderReplacements, "prune-ocp-builder-replacements", false, "If all replacements that target the ocp/builder imagestream should be removed")
 	flag.StringVar(&o.registryPath, "registry", "", "Path to the step registry directory")
+	flag.StringVar(&o.versionsPath, "registry-versions", "", "Optional directory with versions of the step registry tests can pin components to")
 	flag.Parse()
 
 	var errs []error
@@ -138,7 +140,7 @@ func main() {
 		}
 	}
 
-	resolver, err := loadResolver(opts.registryPath)
+	resolver, err := loadResolver(opts.registryPath, opts.versionsPath)
 	if err != nil {
 		logrus.WithError(err).Fatal("failed to load resolver")
 	}
@@ -199,7 +201,7 @@ func main() {
 	}
 }
 
-func loadResolver(path string) (registry.Resolver, error) {
+func loadResolver(path, versionsPath string) (registry.Resolver, error) {
 	if path == "" {
 		return nil, nil
 	}
@@ -207,7 +209,13 @@ func loadResolver(path string) (registry.Resolver, error) {
 	if err != nil {
 		return nil, err
 	}
-	return registry.NewResolver(refs, chains, workflows, observers), nil
+	var versions registry.VersionByName
+	if versionsPath != "" {
+		if versions, err = load.RegistryVersions(versionsPath, load.RegistryFlag(0), nil); err != nil {
+			return nil, err
+		}
+	}
+	return registry.NewVersionedResolver(refs, chains, workflows, observers, versions), nil
 }
 
 type usernameToken struct {
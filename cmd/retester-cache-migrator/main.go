@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/retester"
+)
+
+type options struct {
+	dryRun bool
+
+	cacheFile         string
+	cacheFileOnS3     bool
+	cacheRecordAgeRaw string
+	cacheRecordAge    time.Duration
+
+	configMapNamespace string
+	configMapName      string
+}
+
+func gatherOptions() options {
+	o := options{}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	fs.BoolVar(&o.dryRun, "dry-run", true, "Dry run for testing. Loads the caches but does not write the ConfigMap.")
+	fs.StringVar(&o.cacheFile, "cache-file", "", "File of the backoff cache to migrate.")
+	fs.BoolVar(&o.cacheFileOnS3, "cache-file-on-s3", false, "If true, the cache file is read from the aws s3 bucket of the retester.")
+	fs.StringVar(&o.cacheRecordAgeRaw, "cache-record-age", "168h", "Parseable duration string that specifies how long a cache record lives in cache after the last time it was considered. Older records are not migrated.")
+	fs.StringVar(&o.configMapNamespace, "configmap-namespace", "ci", "The namespace of the cache ConfigMap to migrate into.")
+	fs.StringVar(&o.configMapName, "configmap-name", "", "The name of the cache ConfigMap to migrate into.")
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatal("could not parse input")
+	}
+	return o
+}
+
+func (o *options) complete() error {
+	var err error
+	o.cacheRecordAge, err = time.ParseDuration(o.cacheRecordAgeRaw)
+	if err != nil {
+		return fmt.Errorf("invalid --cache-record-age: %w", err)
+	}
+	return nil
+}
+
+func (o *options) validate() error {
+	if o.cacheFile == "" {
+		return fmt.Errorf("--cache-file is required")
+	}
+	if o.configMapNamespace == "" || o.configMapName == "" {
+		return fmt.Errorf("--configmap-namespace and --configmap-name are required")
+	}
+	return nil
+}
+
+func main() {
+	o := gatherOptions()
+	if err := o.complete(); err != nil {
+		logrus.WithError(err).Fatal("failed to complete options")
+	}
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("failed to validate options")
+	}
+
+	var awsSession *session.Session
+	if o.cacheFileOnS3 {
+		var err error
+		awsSession, err = session.NewSession(&aws.Config{Region: aws.String("us-east-1")})
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to create AWS session.")
+		}
+		if _, err := awsSession.Config.Credentials.Get(); err != nil {
+			logrus.WithError(err).Fatal("Error getting AWS credentials.")
+		}
+	}
+
+	kubeConfig, err := controllerruntime.GetConfig()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load kube config.")
+	}
+	kubeClient, err := ctrlruntimeclient.New(kubeConfig, ctrlruntimeclient.Options{})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to construct kube client.")
+	}
+	if o.dryRun {
+		kubeClient = ctrlruntimeclient.NewDryRunClient(kubeClient)
+	}
+
+	configMap := types.NamespacedName{Namespace: o.configMapNamespace, Name: o.configMapName}
+	if err := retester.MigrateBackoffCache(o.cacheFile, o.cacheRecordAge, awsSession, kubeClient, configMap); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate the backoff cache.")
+	}
+	logrus.WithField("configmap", configMap.String()).Info("Migrated the backoff cache")
+}
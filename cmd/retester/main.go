@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/sirupsen/logrus"
 
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/test-infra/pkg/flagutil"
 	prowConfig "k8s.io/test-infra/prow/config"
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
@@ -17,6 +19,9 @@ import (
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/interrupts"
 	"k8s.io/test-infra/prow/metrics"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/openshift/ci-tools/pkg/retester"
 )
@@ -35,6 +40,8 @@ type options struct {
 
 	cacheFile      string
 	cacheFileOnS3  bool
+	cacheConfigMap string
+	cacheNamespace string
 	cacheRecordAge time.Duration
 
 	configFile string
@@ -52,6 +59,14 @@ func (o *options) Validate() error {
 	if o.cacheFileOnS3 && o.cacheFile == "" {
 		return fmt.Errorf("--cache-file is required if --cache-file-on-s3 is set to true")
 	}
+	if o.cacheConfigMap != "" {
+		if o.cacheFile != "" {
+			return fmt.Errorf("--cache-file and --cache-configmap are mutually exclusive")
+		}
+		if o.cacheNamespace == "" {
+			return fmt.Errorf("--cache-namespace is required if --cache-configmap is set")
+		}
+	}
 	return nil
 }
 
@@ -75,6 +90,8 @@ func gatherOptions() options {
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Dry run for testing. Uses API tokens but does not mutate.")
 	fs.BoolVar(&o.runOnce, "run-once", false, "If true, run only once then quit.")
 	fs.BoolVar(&o.cacheFileOnS3, "cache-file-on-s3", false, "If true, use aws s3 bucket to store the cache file.")
+	fs.StringVar(&o.cacheConfigMap, "cache-configmap", "", "Name of the ConfigMap in --cache-namespace to persist cache in. If set, the retester runs with leader election, so multiple replicas can share the cache.")
+	fs.StringVar(&o.cacheNamespace, "cache-namespace", "ci", "The namespace of the cache ConfigMap and the leader election lock.")
 	fs.StringVar(&o.intervalRaw, "interval", "1h", "Parseable duration string that specifies the sync period")
 	fs.StringVar(&o.cacheFile, "cache-file", "", "File to persist cache. No persistence of cache if not set")
 	fs.StringVar(&o.cacheRecordAgeRaw, "cache-record-age", "168h", "Parseable duration string that specifies how long a cache record lives in cache after the last time it was considered")
@@ -132,8 +149,6 @@ func main() {
 		}
 	}
 
-	c := retester.NewController(gc, configAgent.Config, git.ClientFactoryFrom(gitClient), o.github.AppPrivateKeyPath != "", o.cacheFile, o.cacheRecordAge, config, awsSession)
-
 	metrics.ExposeMetrics("retester", prowConfig.PushGateway{}, prowflagutil.DefaultMetricsPort)
 
 	interrupts.OnInterrupt(func() {
@@ -142,6 +157,16 @@ func main() {
 		}
 	})
 
+	if o.cacheConfigMap != "" {
+		cacheConfigMap := types.NamespacedName{Namespace: o.cacheNamespace, Name: o.cacheConfigMap}
+		runWithLeaderElection(o, func(kubeClient ctrlruntimeclient.Client) *retester.RetestController {
+			return retester.NewController(gc, configAgent.Config, git.ClientFactoryFrom(gitClient), o.github.AppPrivateKeyPath != "", o.cacheFile, o.cacheRecordAge, config, nil, kubeClient, cacheConfigMap)
+		})
+		return
+	}
+
+	c := retester.NewController(gc, configAgent.Config, git.ClientFactoryFrom(gitClient), o.github.AppPrivateKeyPath != "", o.cacheFile, o.cacheRecordAge, config, awsSession, nil, types.NamespacedName{})
+
 	execute(c)
 	if o.runOnce {
 		return
@@ -158,6 +183,56 @@ func main() {
 	interrupts.WaitForGracefulShutdown()
 }
 
+// runWithLeaderElection runs the controller in the replica holding the leader election lock, so only one
+// replica at a time writes to the cache ConfigMap. The controller is created only once the lock is acquired,
+// so the cache written by the previous leader is loaded.
+func runWithLeaderElection(o options, newController func(kubeClient ctrlruntimeclient.Client) *retester.RetestController) {
+	kubeConfig, err := controllerruntime.GetConfig()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load kube config.")
+	}
+	kubeClient, err := ctrlruntimeclient.New(kubeConfig, ctrlruntimeclient.Options{})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to construct kube client.")
+	}
+	mgr, err := controllerruntime.NewManager(kubeConfig, controllerruntime.Options{
+		LeaderElection:                true,
+		LeaderElectionReleaseOnCancel: true,
+		LeaderElectionNamespace:       o.cacheNamespace,
+		LeaderElectionID:              "retester",
+		MetricsBindAddress:            "0",
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to construct manager.")
+	}
+
+	ctx, cancel := context.WithCancel(interrupts.Context())
+	defer cancel()
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		c := newController(kubeClient)
+		execute(c)
+		if o.runOnce {
+			cancel()
+			return nil
+		}
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				execute(c)
+			}
+		}
+	})); err != nil {
+		logrus.WithError(err).Fatal("Failed to add retester to manager.")
+	}
+	if err := mgr.Start(ctx); err != nil {
+		logrus.WithError(err).Fatal("Manager ended with error.")
+	}
+}
+
 func execute(c *retester.RetestController) {
 	if err := c.Run(); err != nil {
 		logrus.WithError(err).Error("Error running")
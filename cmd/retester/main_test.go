@@ -117,6 +117,45 @@ func TestValidate(t *testing.T) {
 			},
 			expected: errors.New("--cache-file is required if --cache-file-on-s3 is set to true"),
 		},
+		{
+			name: "cache namespace not set when using configmap",
+			o: options{
+				config:         flagutil.ConfigOptions{ConfigPath: "/etc/config/config.yaml"},
+				configFile:     "/etc/retester/config.yaml",
+				dryRun:         true,
+				interval:       time.Hour,
+				cacheRecordAge: sevenDays,
+				cacheConfigMap: "retester-backoff",
+			},
+			expected: errors.New("--cache-namespace is required if --cache-configmap is set"),
+		},
+		{
+			name: "configmap and file are mutually exclusive",
+			o: options{
+				config:         flagutil.ConfigOptions{ConfigPath: "/etc/config/config.yaml"},
+				configFile:     "/etc/retester/config.yaml",
+				dryRun:         true,
+				interval:       time.Hour,
+				cacheRecordAge: sevenDays,
+				cacheFile:      "retester-backoff",
+				cacheFileOnS3:  true,
+				cacheConfigMap: "retester-backoff",
+				cacheNamespace: "ci",
+			},
+			expected: errors.New("--cache-file and --cache-configmap are mutually exclusive"),
+		},
+		{
+			name: "configmap",
+			o: options{
+				config:         flagutil.ConfigOptions{ConfigPath: "/etc/config/config.yaml"},
+				configFile:     "/etc/retester/config.yaml",
+				dryRun:         true,
+				interval:       time.Hour,
+				cacheRecordAge: sevenDays,
+				cacheConfigMap: "retester-backoff",
+				cacheNamespace: "ci",
+			},
+		},
 		{
 			name: "cache-file not set when using local file cache",
 			o: options{
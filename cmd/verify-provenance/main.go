@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/test-infra/prow/flagutil"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/provenance"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+type options struct {
+	namespace     string
+	name          string
+	tags          flagutil.Strings
+	publicKeyPath string
+}
+
+func gatherOptions() options {
+	o := options{}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.StringVar(&o.namespace, "namespace", "", "The namespace of the imagestream.")
+	fs.StringVar(&o.name, "name", "", "The name of the imagestream.")
+	fs.Var(&o.tags, "tag", "A tag of the imagestream to verify. Can be passed multiple times. If not set, all tags are verified.")
+	fs.StringVar(&o.publicKeyPath, "public-key", "", "The PEM encoded ECDSA public key of the key ci-operator signs the provenance with.")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatal("could not parse input")
+	}
+	return o
+}
+
+func (o *options) validate() error {
+	if o.namespace == "" || o.name == "" {
+		return fmt.Errorf("--namespace and --name are required")
+	}
+	if o.publicKeyPath == "" {
+		return fmt.Errorf("--public-key is required")
+	}
+	return nil
+}
+
+func main() {
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("failed to validate options")
+	}
+	logger := logrus.WithField("imagestream", fmt.Sprintf("%s/%s", o.namespace, o.name))
+
+	verifier, err := provenance.LoadVerifier(o.publicKeyPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to load public key")
+	}
+	if err := imagev1.AddToScheme(scheme.Scheme); err != nil {
+		logrus.WithError(err).Fatal("failed to add imagev1 to scheme")
+	}
+	clusterConfig, err := util.LoadClusterConfig()
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to load cluster config")
+	}
+	client, err := ctrlruntimeclient.New(clusterConfig, ctrlruntimeclient.Options{})
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to create client")
+	}
+
+	verifications, err := provenance.VerifyImageStream(context.Background(), client, verifier, o.namespace, o.name, o.tags.Strings())
+	if err != nil {
+		logger.WithError(err).Fatal("failed to verify the provenance")
+	}
+	var failed int
+	for _, verification := range verifications {
+		if verification.Error != nil {
+			failed++
+			fmt.Printf("%s:%s %s FAILED: %v\n", o.name, verification.Tag, verification.Image, verification.Error)
+			continue
+		}
+		fmt.Printf("%s:%s %s verified\n", o.name, verification.Tag, verification.Image)
+	}
+	if failed > 0 {
+		logger.Fatalf("%d of %d tags failed the verification", failed, len(verifications))
+	}
+	logger.Infof("The provenance of all %d tags is verified", len(verifications))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		given    options
+		expected error
+	}{
+		{
+			name:  "all tags",
+			given: options{namespace: "ocp", name: "4.12", publicKeyPath: "key.pub"},
+		},
+		{
+			name:     "no imagestream",
+			given:    options{name: "4.12", publicKeyPath: "key.pub"},
+			expected: fmt.Errorf("--namespace and --name are required"),
+		},
+		{
+			name:     "no public key",
+			given:    options{namespace: "ocp", name: "4.12"},
+			expected: fmt.Errorf("--public-key is required"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, tc.given.validate(), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("error differs from expected:\n%s", diff)
+			}
+		})
+	}
+}
@@ -28,6 +28,10 @@ const (
 	NoBuildsLabel = "ci.openshift.io/no-builds"
 	NoBuildsValue = "true"
 
+	// CapabilityLabelPrefix prefixes the labels of a job naming the capabilities
+	// that the cluster where the job runs must have
+	CapabilityLabelPrefix = "capabilities.ci.openshift.io/"
+
 	// HiveCluster is the cluster where Hive is deployed
 	HiveCluster = ClusterHive
 
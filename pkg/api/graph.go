@@ -565,6 +565,9 @@ func mergeSteps(into, from CIOperatorStepDetails) CIOperatorStepDetails {
 	if into.Failed == nil {
 		into.Failed = from.Failed
 	}
+	if into.Profile == nil {
+		into.Profile = from.Profile
+	}
 	if into.Substeps == nil {
 		into.Substeps = from.Substeps
 	}
@@ -589,6 +592,45 @@ type CIOperatorStepDetailInfo struct {
 	Manifests    []ctrlruntimeclient.Object `json:"manifests,omitempty"`
 	LogURL       string                     `json:"log_url,omitempty"`
 	Failed       *bool                      `json:"failed,omitempty"`
+	// Profile is the execution profile of the step, when the step
+	// was run by the entrypoint-wrapper
+	Profile *StepProfile `json:"profile,omitempty"`
+}
+
+// StepProfileFilename is the name of the file the entrypoint-wrapper writes the
+// execution profile of a step to, in the artifacts of the step
+const StepProfileFilename = "step-profile.json"
+
+// StepProfile is the execution profile of a step, recorded by the entrypoint-wrapper
+// +k8s:deepcopy-gen=false
+type StepProfile struct {
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	WallTime   time.Duration `json:"wall_time"`
+	// MaxProcessRSSBytes is the peak resident set size of the largest single process
+	// the step ran. Processes running at the same time may have used more together.
+	MaxProcessRSSBytes int64 `json:"max_process_rss_bytes"`
+	// SharedDirModifiedBytes is the total size of the files in SHARED_DIR that were
+	// created or modified while the step ran, not the number of bytes it wrote
+	SharedDirModifiedBytes int64 `json:"shared_dir_modified_bytes"`
+	// ArtifactModifiedBytes is the total size of the files in ARTIFACT_DIR that were
+	// created or modified while the step ran, not the number of bytes it wrote
+	ArtifactModifiedBytes int64 `json:"artifact_modified_bytes"`
+	// Processes are the processes the step ran, ordered by the time they started.
+	// The first one is the command of the step, all others are its descendants.
+	// Processes are sampled, so short-lived ones may be missing.
+	Processes []ProcessProfile `json:"processes,omitempty"`
+}
+
+// ProcessProfile describes a process a step ran
+// +k8s:deepcopy-gen=false
+type ProcessProfile struct {
+	PID int `json:"pid"`
+	// Parent is the PID of the parent process, unset for the command of the step
+	Parent    int           `json:"parent,omitempty"`
+	Command   string        `json:"command"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
 }
 
 func (c *CIOperatorStepDetailInfo) UnmarshalJSON(data []byte) error {
@@ -11,4 +11,7 @@ const (
 	// AnnotationNamespaceLastActive contains time.RFC3339 timestamp at which the namespace was last in active use. We
 	// update this every ten minutes.
 	AnnotationNamespaceLastActive = "ci.openshift.io/active"
+	// AnnotationProwJobID contains the ID of the ProwJob that last used the namespace, which lets the namespace
+	// reaper find out if the namespace was abandoned
+	AnnotationProwJobID = "ci.openshift.io/prowjob-id"
 )
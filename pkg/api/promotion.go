@@ -88,10 +88,15 @@ func tagsInQuay(image string, tag ImageStreamTagReference, date string) ([]strin
 	digest := splits[1]
 	return []string{
 		fmt.Sprintf("%s:%s_sha256_%s", QuayOpenShiftCIRepo, date, digest),
-		fmt.Sprintf("%s:%s_%s_%s", QuayOpenShiftCIRepo, tag.Namespace, tag.Name, tag.Tag),
+		QuayImage(tag),
 	}, nil
 }
 
+// QuayImage returns the pull spec in quay.io the image promoted to the ImageStream tag is mirrored to
+func QuayImage(tag ImageStreamTagReference) string {
+	return fmt.Sprintf("%s:%s_%s_%s", QuayOpenShiftCIRepo, tag.Namespace, tag.Name, tag.Tag)
+}
+
 var (
 	// DefaultMirrorFunc is the default mirroring function
 	DefaultMirrorFunc = func(source, target string, _ ImageStreamTagReference, _ string, mirror map[string]string) {
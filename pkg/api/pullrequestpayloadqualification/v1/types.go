@@ -9,6 +9,11 @@ import (
 
 const (
 	PullRequestPayloadQualificationRunLabel = "pullrequestpayloadqualificationruns.ci.openshift.io"
+
+	// ConditionComparedToBase is the type of the condition that holds the comparison of the
+	// results of the jobs with the results of the same jobs on the base payload. It is set
+	// once all jobs finished, and it is false when any of the jobs regressed.
+	ConditionComparedToBase = "ComparedToBase"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -30,6 +35,12 @@ type PullRequestPayloadQualificationRun struct {
 type PullRequestPayloadTestSpec struct {
 	// PullRequest specifies the code to be tested. Immutable and required.
 	PullRequest PullRequestUnderTest `json:"pullRequest"`
+	// PullRequests specifies the code to be tested when the run was requested for
+	// multiple PRs that are built into a single payload. The PRs may target other
+	// repositories and branches than PullRequest: the PRs are grouped by the
+	// repository and branch they target and the images of every repository are
+	// built. When set, the first item must be the same as PullRequest. Immutable.
+	PullRequests []PullRequestUnderTest `json:"pullRequests,omitempty"`
 	// Jobs specifies the jobs to be executed. Immutable.
 	Jobs PullRequestPayloadJobSpec `json:"jobs"`
 }
@@ -125,8 +136,32 @@ type PullRequestPayloadJobStatus struct {
 	ProwJob string `json:"prowJob"`
 
 	Status prowv1.ProwJobStatus `json:"status,omitempty"`
+
+	// BasePayload is the payload the code under test is compared against. It is inferred
+	// rather than recorded by the job under test: it is the latest payload the same job
+	// started to run on before the job under test started, which is usually, but not
+	// necessarily, the payload the job under test was resolved to
+	BasePayload string `json:"basePayload,omitempty"`
+	// BaseStatus is the status of the latest completed run of the same job on the
+	// base payload, i.e. without the code under test
+	BaseStatus *prowv1.ProwJobStatus `json:"baseStatus,omitempty"`
+	// Comparison compares Status with BaseStatus once both are known
+	Comparison PayloadJobComparison `json:"comparison,omitempty"`
 }
 
+// PayloadJobComparison compares the result of a job with the result of the same job
+// on the base payload
+type PayloadJobComparison string
+
+const (
+	// PayloadJobRegressed means the job fails while it passes on the base payload
+	PayloadJobRegressed PayloadJobComparison = "Regressed"
+	// PayloadJobFixed means the job passes while it fails on the base payload
+	PayloadJobFixed PayloadJobComparison = "Fixed"
+	// PayloadJobSameAsBase means the job has the same result as on the base payload
+	PayloadJobSameAsBase PayloadJobComparison = "SameAsBase"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // PullRequestPayloadQualificationRunList is a list of PullRequestPayloadQualificationRun resources
@@ -137,6 +172,15 @@ type PullRequestPayloadQualificationRunList struct {
 	Items []PullRequestPayloadQualificationRun `json:"items"`
 }
 
+// PullRequestsUnderTest returns all PRs under test, starting with the one the run was
+// requested for
+func (s PullRequestPayloadTestSpec) PullRequestsUnderTest() []PullRequestUnderTest {
+	if len(s.PullRequests) > 0 {
+		return s.PullRequests
+	}
+	return []PullRequestUnderTest{s.PullRequest}
+}
+
 // JobName maps the name in the spec to the corresponding Prow job name.
 // It matches the `ReleaseJobName` value in the status.
 func (s *ReleaseJobSpec) JobName(prefix string) string {
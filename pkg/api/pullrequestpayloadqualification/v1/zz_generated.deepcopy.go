@@ -8,6 +8,7 @@ package v1
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	prowjobsv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -65,6 +66,11 @@ func (in *PullRequestPayloadJobSpec) DeepCopy() *PullRequestPayloadJobSpec {
 func (in *PullRequestPayloadJobStatus) DeepCopyInto(out *PullRequestPayloadJobStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
+	if in.BaseStatus != nil {
+		in, out := &in.BaseStatus, &out.BaseStatus
+		*out = new(prowjobsv1.ProwJobStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullRequestPayloadJobStatus.
@@ -140,6 +146,11 @@ func (in *PullRequestPayloadQualificationRunList) DeepCopyObject() runtime.Objec
 func (in *PullRequestPayloadTestSpec) DeepCopyInto(out *PullRequestPayloadTestSpec) {
 	*out = *in
 	out.PullRequest = in.PullRequest
+	if in.PullRequests != nil {
+		in, out := &in.PullRequests, &out.PullRequests
+		*out = make([]PullRequestUnderTest, len(*in))
+		copy(*out, *in)
+	}
 	in.Jobs.DeepCopyInto(&out.Jobs)
 }
 
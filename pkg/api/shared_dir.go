@@ -0,0 +1,30 @@
+package api
+
+// SharedDirStorage configures a volume that backs SHARED_DIR. When the contents
+// of SHARED_DIR would exceed the size limit of the secret that holds them, the
+// largest files are moved to the volume and restored for the following steps.
+type SharedDirStorage struct {
+	// Size is the size of the volume, like `5Gi`.
+	Size string `json:"size"`
+	// StorageClassName is the storage class of the volume. The default storage
+	// class of the cluster is used when unset.
+	StorageClassName string `json:"storage_class_name,omitempty"`
+}
+
+// SharedDirStoreIndexKey is the key in the SHARED_DIR secret that lists the
+// files moved to the backing volume
+const SharedDirStoreIndexKey = ".shared-dir-store.json"
+
+// SharedDirStoreIndex lists the files of SHARED_DIR moved to the backing
+// volume, by name
+// +k8s:deepcopy-gen=false
+type SharedDirStoreIndex map[string]SharedDirStoreEntry
+
+// SharedDirStoreEntry locates a file of SHARED_DIR in the backing volume, where
+// files are stored under the SHA256 digest of their contents, so the versions
+// of a file written by different steps never overwrite each other
+// +k8s:deepcopy-gen=false
+type SharedDirStoreEntry struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
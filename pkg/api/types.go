@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -628,6 +629,11 @@ type TestStepConfiguration struct {
 	// Cluster specifies the name of the cluster where the test runs.
 	Cluster Cluster `json:"cluster,omitempty"`
 
+	// Capabilities are the capabilities that the cluster where the test runs must have,
+	// e.g. arm64 or vpn. The capabilities of each cluster are declared in the configuration
+	// of the prow-job-dispatcher.
+	Capabilities []string `json:"capabilities,omitempty"`
+
 	// Secret is an optional secret object which
 	// will be mounted inside the test container.
 	// You cannot set the Secret and Secrets attributes
@@ -795,6 +801,8 @@ type RegistryChain struct {
 	Environment []StepParameter `json:"env,omitempty"`
 	// Leases lists resources that should be acquired for the test.
 	Leases []StepLease `json:"leases,omitempty"`
+	// Deprecation marks the chain as deprecated.
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
 }
 
 // RegistryWorkflowConfig is the struct that workflow references are unmarshalled into.
@@ -840,6 +848,51 @@ type RegistryInfo struct {
 	Owners repoowners.Config `json:"owners,omitempty"`
 }
 
+// Deprecation describes why a registry component is deprecated and what
+// replaces it.
+type Deprecation struct {
+	// Message explains why the component is deprecated and how to migrate.
+	Message string `json:"message,omitempty"`
+	// ReplacedBy is the name of the component of the same type that replaces
+	// the deprecated one.
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// RemovalDate is the date after which the component may be removed from
+	// the registry, formatted as YYYY-MM-DD.
+	RemovalDate string `json:"removal_date,omitempty"`
+}
+
+// DeprecationDateFormat is the format of removal dates of deprecated components
+const DeprecationDateFormat = "2006-01-02"
+
+// DeprecatedComponent identifies a deprecated registry component used by a test.
+type DeprecatedComponent struct {
+	// Type is the type of the component: reference, chain or workflow.
+	Type string `json:"type"`
+	// Name is the name of the component.
+	Name        string `json:"name"`
+	Deprecation `json:",inline"`
+}
+
+// Warning describes the deprecation to users of the component at the given time
+func (c DeprecatedComponent) Warning(now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s is deprecated", c.Type, c.Name)
+	if c.RemovalDate != "" {
+		if removal, err := time.Parse(DeprecationDateFormat, c.RemovalDate); err == nil && now.After(removal) {
+			fmt.Fprintf(&b, " and may be removed at any time since %s", c.RemovalDate)
+		} else {
+			fmt.Fprintf(&b, " and will be removed after %s", c.RemovalDate)
+		}
+	}
+	if c.ReplacedBy != "" {
+		fmt.Fprintf(&b, ", use %s %s instead", c.Type, c.ReplacedBy)
+	}
+	if c.Message != "" {
+		fmt.Fprintf(&b, ": %s", c.Message)
+	}
+	return b.String()
+}
+
 // Observer is the configuration for an observer Pod that will run in parallel
 // with a multi-stage test job.
 type Observer struct {
@@ -921,6 +974,9 @@ type LiteralTestStep struct {
 	// RunAsScript defines if this step should be executed as a script mounted
 	// in the test container instead of being executed directly via bash
 	RunAsScript *bool `json:"run_as_script,omitempty"`
+	// Deprecation marks a step in the registry as deprecated. It can only be
+	// set in the registry.
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
 }
 
 // StepParameter is a variable set by the test, with an optional default.
@@ -990,12 +1046,28 @@ func (s *LiteralTestStep) FromImageTag() (PipelineImageStreamTagReference, bool)
 type TestStep struct {
 	// LiteralTestStep is a full test step definition.
 	*LiteralTestStep `json:",inline,omitempty"`
-	// Reference is the name of a step reference.
+	// Reference is the name of a step reference. The name can be pinned to
+	// a version of the registry, like `ipi-install@v3`.
 	Reference *string `json:"ref,omitempty"`
-	// Chain is the name of a step chain reference.
+	// Chain is the name of a step chain reference. The name can be pinned to
+	// a version of the registry, like `ipi-aws-pre@v3`.
 	Chain *string `json:"chain,omitempty"`
 }
 
+// RegistryVersionSeparator separates the name of a registry component from the
+// version of the registry the component is pinned to.
+const RegistryVersionSeparator = "@"
+
+// SplitRegistryVersion splits the name of a registry component pinned to a
+// version of the registry, like `ipi-install@v3`, into the name and the version.
+// The version is empty when the component is not pinned.
+func SplitRegistryVersion(name string) (string, string) {
+	if i := strings.LastIndex(name, RegistryVersionSeparator); i != -1 {
+		return name[:i], name[i+len(RegistryVersionSeparator):]
+	}
+	return name, ""
+}
+
 // MultiStageTestConfiguration is a flexible configuration mode that allows tighter control over
 // the multiple stages of end to end tests.
 type MultiStageTestConfiguration struct {
@@ -1011,6 +1083,8 @@ type MultiStageTestConfiguration struct {
 	Post []TestStep `json:"post,omitempty"`
 	// Workflow is the name of the workflow to be used for this configuration. For fields defined in both
 	// the config and the workflow, the fields from the config will override what is set in Workflow.
+	// The name can be pinned to a version of the registry, like `ipi-aws@v3`, in which case all
+	// steps and chains of the workflow are resolved from that version as well.
 	Workflow *string `json:"workflow,omitempty"`
 	// Environment has the values of parameters for the steps.
 	Environment TestEnvironment `json:"env,omitempty"`
@@ -1033,6 +1107,12 @@ type MultiStageTestConfiguration struct {
 	// DependencyOverrides allows a step to override a dependency with a fully-qualified pullspec. This will probably only ever
 	// be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.
 	DependencyOverrides DependencyOverrides `json:"dependency_overrides,omitempty"`
+	// SharedDirStorage configures a volume for the files in SHARED_DIR that do
+	// not fit in the secret that holds it.
+	SharedDirStorage *SharedDirStorage `json:"shared_dir_storage,omitempty"`
+	// Deprecation marks a workflow in the registry as deprecated. It can only
+	// be set in the registry.
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
 }
 type DependencyOverrides map[string]string
 
@@ -1071,8 +1151,15 @@ type MultiStageTestConfigurationLiteral struct {
 	// be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.
 	DependencyOverrides DependencyOverrides `json:"dependency_overrides,omitempty"`
 
+	// SharedDirStorage configures a volume for the files in SHARED_DIR that do
+	// not fit in the secret that holds it.
+	SharedDirStorage *SharedDirStorage `json:"shared_dir_storage,omitempty"`
+
 	// Override job timeout
 	Timeout *prowv1.Duration `json:"timeout,omitempty"`
+
+	// Deprecated lists the deprecated registry components the test uses.
+	Deprecated []DeprecatedComponent `json:"deprecated,omitempty"`
 }
 
 // TestEnvironment has the values of parameters for multi-stage tests.
@@ -1829,6 +1916,21 @@ const (
 	PipelineImageStreamTagReferenceRPMs         PipelineImageStreamTagReference = "rpms"
 )
 
+// RefString identifies a repository cloned for a job, as used by
+// the Ref fields of the steps building from its source
+func RefString(org, repo string) string {
+	return fmt.Sprintf("%s.%s", org, repo)
+}
+
+// PipelineImageStreamTagReferenceForRef returns the pipeline tag that holds
+// the image for the given repository, e.g. `src-org.repo` for `src`
+func PipelineImageStreamTagReferenceForRef(tag PipelineImageStreamTagReference, ref string) PipelineImageStreamTagReference {
+	if ref == "" {
+		return tag
+	}
+	return PipelineImageStreamTagReference(fmt.Sprintf("%s-%s", tag, ref))
+}
+
 // The fields in ReleaseBuildConfiguration which originate each pipeline image
 const (
 	PipelineImageStreamTagSourceRoot         = "build_root"
@@ -1850,6 +1952,10 @@ type SourceStepConfiguration struct {
 	// ClonerefsPath is the path in the above image where the
 	// clonerefs tool is placed
 	ClonerefsPath string `json:"clonerefs_path"`
+
+	// Ref restricts the clone to the repository identified by
+	// RefString. When unset, every ref of the job is cloned.
+	Ref string `json:"ref,omitempty"`
 }
 
 func (config SourceStepConfiguration) TargetName() string {
@@ -1985,6 +2091,11 @@ type ProjectDirectoryImageBuildStepConfiguration struct {
 	// promoted unless explicitly targeted. Use for builds which
 	// are invoked only when testing certain parts of the repo.
 	Optional bool `json:"optional,omitempty"`
+
+	// Ref identifies, as with RefString, the repository whose
+	// source image is the build context. When unset, the source
+	// image of the repository under test is used.
+	Ref string `json:"ref,omitempty"`
 }
 
 func (config ProjectDirectoryImageBuildStepConfiguration) TargetName() string {
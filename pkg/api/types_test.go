@@ -3,6 +3,7 @@ package api
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/google/go-cmp/cmp"
@@ -343,3 +344,61 @@ func TestInputImageTagStepConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestDeprecatedComponentWarning(t *testing.T) {
+	now := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		name      string
+		component DeprecatedComponent
+		expected  string
+	}{
+		{
+			name:      "only deprecated",
+			component: DeprecatedComponent{Type: "reference", Name: "old"},
+			expected:  "reference old is deprecated",
+		},
+		{
+			name: "removal in the future with a replacement",
+			component: DeprecatedComponent{Type: "chain", Name: "old", Deprecation: Deprecation{
+				Message: "the installer moved", ReplacedBy: "new", RemovalDate: "2022-12-01",
+			}},
+			expected: "chain old is deprecated and will be removed after 2022-12-01, use chain new instead: the installer moved",
+		},
+		{
+			name:      "removal date passed",
+			component: DeprecatedComponent{Type: "workflow", Name: "old", Deprecation: Deprecation{RemovalDate: "2022-10-01"}},
+			expected:  "workflow old is deprecated and may be removed at any time since 2022-10-01",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if diff := cmp.Diff(testCase.expected, testCase.component.Warning(now)); diff != "" {
+				t.Errorf("%s: got incorrect warning: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestSplitRegistryVersion(t *testing.T) {
+	var testCases = []struct {
+		name            string
+		input           string
+		expectedName    string
+		expectedVersion string
+	}{
+		{name: "unpinned", input: "ipi-install", expectedName: "ipi-install"},
+		{name: "version tag", input: "ipi-install@v3", expectedName: "ipi-install", expectedVersion: "v3"},
+		{name: "revision", input: "ipi-install@0123abc", expectedName: "ipi-install", expectedVersion: "0123abc"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			name, version := SplitRegistryVersion(testCase.input)
+			if diff := cmp.Diff(testCase.expectedName, name); diff != "" {
+				t.Errorf("%s: got incorrect name: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(testCase.expectedVersion, version); diff != "" {
+				t.Errorf("%s: got incorrect version: %v", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ type options struct {
 	ciOperatorConfigPath string
 	jobConfigPath        string
 	registryPath         string
+	versionsPath         string
 	// Dynamic, optional state.
 	refs      registry.ReferenceByName
 	chains    registry.ChainByName
@@ -38,6 +39,7 @@ directly or as the base for higher-level programs and scripts.`,
 	flags.StringVar(&o.ciOperatorConfigPath, "config-dir", "", fmt.Sprintf(`path to the ci-operator configuration directory (default: %q under the root directory)`, config.CiopConfigInRepoPath))
 	flags.StringVar(&o.jobConfigPath, "job-config", "", fmt.Sprintf(`path to the Prow job configuration directory (default: %q under the root directory)`, config.JobConfigInRepoPath))
 	flags.StringVar(&o.registryPath, "registry", "", fmt.Sprintf(`path to the registry directory (default: %q under the root directory)`, config.RegistryPath))
+	flags.StringVar(&o.versionsPath, "registry-versions", "", `path to a directory with versions of the registry tests can pin components to (optional)`)
 	ret.AddCommand(newConfigCommand(&o))
 	ret.AddCommand(newJobCommand(&o))
 	ret.AddCommand(newRegistryCommand(&o))
@@ -42,7 +42,13 @@ func (o *options) loadRegistry() error {
 	if err != nil {
 		return fmt.Errorf("failed to load registry: %w", err)
 	}
-	o.resolver = registry.NewResolver(o.refs, o.chains, o.workflows, nil)
+	var versions registry.VersionByName
+	if o.versionsPath != "" {
+		if versions, err = load.RegistryVersions(joinPath(o.rootPath, o.versionsPath), load.RegistryFlag(0), nil); err != nil {
+			return fmt.Errorf("failed to load registry versions: %w", err)
+		}
+	}
+	o.resolver = registry.NewVersionedResolver(o.refs, o.chains, o.workflows, nil, versions)
 	return nil
 }
 
@@ -0,0 +1,405 @@
+package imagestreamtaggc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	testimagesdistributor "github.com/openshift/ci-tools/pkg/controller/test-images-distributor"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const ControllerName = "imagestreamtag_gc"
+
+// ReportPath is where dptp-controller-manager serves the latest report and answers why a tag is kept
+const ReportPath = "/imagestreamtag-gc"
+
+// UnreferencedSinceAnnotation is set on the spec tags of ImageStreams when the garbage collector
+// first finds them unreferenced. Tags are deleted once they were unreferenced for the grace period.
+const UnreferencedSinceAnnotation = "ci.openshift.io/unreferenced-since"
+
+// ImportedForTestsLabel is set on the ImageStreams that tags for test images are imported into
+// by other means than the test-images-distributor, like the ImageStreams on app.ci that import
+// the `from_image` of steps from external registries. Their imported tags are collected like the
+// ones of the ImageStreams the test-images-distributor labels.
+const ImportedForTestsLabel = "ci.openshift.io/imported-for-tests"
+
+type Options struct {
+	DryRun        bool
+	ConfigAgent   agents.ConfigAgent
+	RegistryAgent agents.RegistryAgent
+	// AdditionalImageStreamTags, AdditionalImageStreams and AdditionalImageStreamNamespaces are
+	// kept even if no configuration references them, like the test-images-distributor distributes them.
+	AdditionalImageStreamTags       sets.String
+	AdditionalImageStreams          sets.String
+	AdditionalImageStreamNamespaces sets.String
+	// IgnoredImageStreamTags match tags in namespace/name:tag format that are never deleted
+	IgnoredImageStreamTags []*regexp.Regexp
+	GracePeriod            time.Duration
+	Interval               time.Duration
+}
+
+// ReportEntry describes an imported tag that is not referenced anymore
+type ReportEntry struct {
+	Cluster           string    `json:"cluster"`
+	ImageStreamTag    string    `json:"imageStreamTag"`
+	UnreferencedSince time.Time `json:"unreferencedSince"`
+	Deleted           bool      `json:"deleted"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// Report lists the imported tags that are not referenced anymore
+type Report struct {
+	Generated time.Time     `json:"generated"`
+	DryRun    bool          `json:"dryRun"`
+	Entries   []ReportEntry `json:"entries"`
+}
+
+// Explanation lists why a tag is kept
+type Explanation struct {
+	ImageStreamTag string   `json:"imageStreamTag"`
+	References     []string `json:"references"`
+}
+
+type collector struct {
+	log     *logrus.Entry
+	clients map[string]ctrlruntimeclient.Client
+	opts    Options
+	now     func() time.Time
+
+	// the references from the configurations only change with the configurations and the registry
+	configGeneration, registryGeneration int
+	fromConfigs                          *references
+
+	lock   sync.RWMutex
+	latest *Report
+	refs   *references
+
+	unreferenced *prometheus.GaugeVec
+	deleted      *prometheus.CounterVec
+}
+
+func newMetrics() (*prometheus.GaugeVec, *prometheus.CounterVec) {
+	unreferenced := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: ControllerName,
+		Name:      "unreferenced_tags",
+		Help:      "The number of imported tags no configuration references",
+	}, []string{"cluster"})
+	deleted := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ControllerName,
+		Name:      "deleted_tags_total",
+		Help:      "The number of unreferenced tags that were deleted",
+	}, []string{"cluster"})
+	return unreferenced, deleted
+}
+
+// AddToManager adds the garbage collector for imported ImageStreamTags on all clusters
+func AddToManager(mgr manager.Manager, allManagers map[string]manager.Manager, opts Options) error {
+	unreferenced, deleted := newMetrics()
+	for _, c := range []prometheus.Collector{unreferenced, deleted} {
+		if err := metrics.Registry.Register(c); err != nil {
+			return fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+	c := &collector{
+		log:          logrus.WithField("controller", ControllerName),
+		clients:      map[string]ctrlruntimeclient.Client{},
+		opts:         opts,
+		now:          time.Now,
+		unreferenced: unreferenced,
+		deleted:      deleted,
+	}
+	for cluster, clusterManager := range allManagers {
+		c.clients[cluster] = clusterManager.GetClient()
+	}
+	if err := mgr.Add(c); err != nil {
+		return fmt.Errorf("failed to add the garbage collector to the manager: %w", err)
+	}
+	if err := mgr.AddMetricsExtraHandler(ReportPath, c); err != nil {
+		return fmt.Errorf("failed to serve the report: %w", err)
+	}
+	return nil
+}
+
+// Start collects garbage every interval until the context is cancelled
+func (c *collector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		c.update(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *collector) update(ctx context.Context) {
+	start := c.now()
+	refs, err := c.references(ctx)
+	if err != nil {
+		c.log.WithError(err).Error("Failed to determine the referenced tags")
+		return
+	}
+	report := &Report{Generated: start, DryRun: c.opts.DryRun}
+	clusters := sets.StringKeySet(c.clients).List()
+	for _, cluster := range clusters {
+		entries, err := c.collect(ctx, cluster, refs)
+		if err != nil {
+			c.log.WithError(err).WithField("cluster", cluster).Error("Failed to collect unreferenced tags")
+		}
+		report.Entries = append(report.Entries, entries...)
+	}
+	c.lock.Lock()
+	c.latest, c.refs = report, refs
+	c.lock.Unlock()
+	c.recordMetrics(report)
+	c.log.WithField("entries", len(report.Entries)).WithField("duration", time.Since(start)).Info("Collected unreferenced tags")
+}
+
+func (c *collector) recordMetrics(report *Report) {
+	c.unreferenced.Reset()
+	for cluster := range c.clients {
+		c.unreferenced.WithLabelValues(cluster)
+	}
+	for _, entry := range report.Entries {
+		if entry.Deleted {
+			c.deleted.WithLabelValues(entry.Cluster).Inc()
+		} else {
+			c.unreferenced.WithLabelValues(entry.Cluster).Inc()
+		}
+	}
+}
+
+func (c *collector) references(ctx context.Context) (*references, error) {
+	configGeneration, registryGeneration := c.opts.ConfigAgent.GetGeneration(), c.opts.RegistryAgent.GetGeneration()
+	if c.fromConfigs == nil || configGeneration != c.configGeneration || registryGeneration != c.registryGeneration {
+		steps, _, _, _, _ := c.opts.RegistryAgent.GetRegistryComponents()
+		c.fromConfigs = c.referencesFromConfigs(c.opts.ConfigAgent.GetAll(), c.opts.RegistryAgent.ResolveConfig, steps)
+		c.configGeneration, c.registryGeneration = configGeneration, registryGeneration
+	}
+	return c.referencesWithImports(ctx)
+}
+
+// referencesWithImports adds the tags requested by TestImageStreamTagImports to a copy of the references from the configurations
+func (c *collector) referencesWithImports(ctx context.Context) (*references, error) {
+	refs := newReferences(c.opts.IgnoredImageStreamTags)
+	for _, m := range []struct{ from, into map[string]sets.String }{
+		{from: c.fromConfigs.tags, into: refs.tags},
+		{from: c.fromConfigs.streams, into: refs.streams},
+		{from: c.fromConfigs.namespaces, into: refs.namespaces},
+	} {
+		for key, reasons := range m.from {
+			m.into[key] = sets.NewString(reasons.UnsortedList()...)
+		}
+	}
+	if err := referencesFromImports(ctx, refs, c.clients); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (c *collector) referencesFromConfigs(configs config.ByOrgRepo, resolve resolveFunc, steps registry.ReferenceByName) *references {
+	refs := newReferences(c.opts.IgnoredImageStreamTags)
+	referencesFromConfigs(refs, configs, resolve, steps, c.log)
+	for _, tag := range c.opts.AdditionalImageStreamTags.List() {
+		if namespace, name, err := splitNamespacedName(tag); err == nil {
+			refs.addTag(tagReferenceFor(namespace, name), "configured additional tag")
+		}
+	}
+	for _, stream := range c.opts.AdditionalImageStreams.List() {
+		if namespace, name, err := splitNamespacedName(stream); err == nil {
+			refs.addStream(namespace, name, "configured additional imagestream")
+		}
+	}
+	for _, namespace := range c.opts.AdditionalImageStreamNamespaces.List() {
+		refs.addNamespace(namespace, "configured additional namespace")
+	}
+	return refs
+}
+
+func splitNamespacedName(raw string) (string, string, error) {
+	namespace, name, found := strings.Cut(raw, "/")
+	if !found {
+		return "", "", fmt.Errorf("%s is not in namespace/name format", raw)
+	}
+	return namespace, name, nil
+}
+
+// collect marks imported tags that are not referenced anymore, unmarks the ones that
+// are referenced again and deletes the ones that were unreferenced for the grace period
+func (c *collector) collect(ctx context.Context, cluster string, refs *references) ([]ReportEntry, error) {
+	client := c.clients[cluster]
+	now := c.now()
+	var entries []ReportEntry
+	var errs []error
+	for _, namespace := range refs.scope() {
+		streams, err := importedImageStreams(ctx, client, namespace)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range streams {
+			stream := &streams[i]
+			log := c.log.WithField("cluster", cluster).WithField("imagestream", stream.Namespace+"/"+stream.Name)
+			// indexes of the entries to delete, by tag
+			toDelete := map[string]int{}
+			// the new marks, by tag, nil to remove the mark
+			marks := map[string]*string{}
+			for j := range stream.Spec.Tags {
+				tag := &stream.Spec.Tags[j]
+				if tag.From == nil || tag.From.Kind != "DockerImage" {
+					continue
+				}
+				ref := api.ImageStreamTagReference{Namespace: stream.Namespace, Name: stream.Name, Tag: tag.Name}
+				_, marked := tag.Annotations[UnreferencedSinceAnnotation]
+				if len(refs.explain(ref)) > 0 {
+					if marked {
+						marks[tag.Name] = nil
+					}
+					continue
+				}
+				since := now
+				if marked {
+					parsed, err := time.Parse(time.RFC3339, tag.Annotations[UnreferencedSinceAnnotation])
+					if err != nil {
+						log.WithError(err).WithField("tag", tag.Name).Warn("Replacing invalid mark")
+						marked = false
+					} else {
+						since = parsed
+					}
+				}
+				if !marked {
+					mark := since.Format(time.RFC3339)
+					marks[tag.Name] = &mark
+				}
+				entries = append(entries, ReportEntry{Cluster: cluster, ImageStreamTag: ref.ISTagName(), UnreferencedSince: since})
+				if now.Sub(since) >= c.opts.GracePeriod {
+					toDelete[tag.Name] = len(entries) - 1
+				}
+			}
+			if c.opts.DryRun {
+				continue
+			}
+			if len(marks) > 0 {
+				if err := markTags(ctx, client, stream, marks); err != nil {
+					errs = append(errs, fmt.Errorf("failed to mark the tags of imagestream %s/%s: %w", stream.Namespace, stream.Name, err))
+					continue
+				}
+			}
+			for tag, index := range toDelete {
+				entry := &entries[index]
+				log.WithField("tag", tag).Info("Deleting unreferenced tag")
+				istag := &imagev1.ImageStreamTag{ObjectMeta: metav1.ObjectMeta{Namespace: stream.Namespace, Name: stream.Name + ":" + tag}}
+				if err := client.Delete(ctx, istag); err != nil && !apierrors.IsNotFound(err) {
+					entry.Error = err.Error()
+					errs = append(errs, fmt.Errorf("failed to delete imagestreamtag %s: %w", entry.ImageStreamTag, err))
+					continue
+				}
+				entry.Deleted = true
+			}
+		}
+	}
+	return entries, utilerrors.NewAggregate(errs)
+}
+
+// importedImageStreams lists the ImageStreams of the namespace that tags for test images are imported
+// into. Only those are ours to clean up, everything else, like the release ImageStreams on app.ci, is not.
+func importedImageStreams(ctx context.Context, client ctrlruntimeclient.Client, namespace string) ([]imagev1.ImageStream, error) {
+	var imported []imagev1.ImageStream
+	seen := sets.NewString()
+	for _, label := range []string{testimagesdistributor.DistributedLabel, ImportedForTestsLabel} {
+		streams := &imagev1.ImageStreamList{}
+		if err := client.List(ctx, streams, ctrlruntimeclient.InNamespace(namespace), ctrlruntimeclient.MatchingLabels{label: "true"}); err != nil {
+			return nil, fmt.Errorf("failed to list imagestreams in namespace %s: %w", namespace, err)
+		}
+		for _, stream := range streams.Items {
+			if !seen.Has(stream.Name) {
+				seen.Insert(stream.Name)
+				imported = append(imported, stream)
+			}
+		}
+	}
+	return imported, nil
+}
+
+// markTags sets or removes the marks on the spec tags with a strategic merge patch, which
+// merges the tags by name, so we do not overwrite the ImageStream if it changed meanwhile
+func markTags(ctx context.Context, client ctrlruntimeclient.Client, stream *imagev1.ImageStream, marks map[string]*string) error {
+	type tagPatch struct {
+		Name        string             `json:"name"`
+		Annotations map[string]*string `json:"annotations"`
+	}
+	var tags []tagPatch
+	for _, name := range sets.StringKeySet(marks).List() {
+		tags = append(tags, tagPatch{Name: name, Annotations: map[string]*string{UnreferencedSinceAnnotation: marks[name]}})
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"tags": tags}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	return client.Patch(ctx, stream, ctrlruntimeclient.RawPatch(types.StrategicMergePatchType, patch))
+}
+
+// ServeHTTP serves the latest report as JSON, optionally filtered by the cluster query parameter.
+// With the explain query parameter set to a tag in namespace/name:tag format, it lists why the tag is kept.
+func (c *collector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.lock.RLock()
+	latest, refs := c.latest, c.refs
+	c.lock.RUnlock()
+	if latest == nil {
+		http.Error(w, "no garbage was collected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	var response interface{}
+	if explain := req.URL.Query().Get("explain"); explain != "" {
+		namespace, name, err := splitNamespacedName(explain)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("explain must be in namespace/name:tag format: %v", err), http.StatusBadRequest)
+			return
+		}
+		ref := tagReferenceFor(namespace, name)
+		response = Explanation{ImageStreamTag: ref.ISTagName(), References: refs.explain(ref)}
+	} else {
+		cluster := req.URL.Query().Get("cluster")
+		filtered := Report{Generated: latest.Generated, DryRun: latest.DryRun, Entries: []ReportEntry{}}
+		for _, entry := range latest.Entries {
+			if cluster == "" || entry.Cluster == cluster {
+				filtered.Entries = append(filtered.Entries, entry)
+			}
+		}
+		sort.SliceStable(filtered.Entries, func(i, j int) bool {
+			return filtered.Entries[i].UnreferencedSince.Before(filtered.Entries[j].UnreferencedSince)
+		})
+		response = filtered
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		c.log.WithError(err).Warn("Failed to serve the report")
+	}
+}
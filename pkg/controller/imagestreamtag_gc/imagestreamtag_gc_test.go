@@ -0,0 +1,303 @@
+package imagestreamtaggc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	testimagestreamtagimportv1 "github.com/openshift/ci-tools/pkg/api/testimagestreamtagimport/v1"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+func TestReferences(t *testing.T) {
+	configs := config.ByOrgRepo{"org": {"repo": []api.ReleaseBuildConfiguration{
+		{
+			Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+			Tests: []api.TestStepConfiguration{{
+				As: "e2e",
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+					Test: []api.LiteralTestStep{{As: "test", FromImage: &api.ImageStreamTagReference{Namespace: "ci", Name: "tools", Tag: "latest"}}},
+				},
+			}},
+			InputConfiguration: api.InputConfiguration{
+				BaseImages: map[string]api.ImageStreamTagReference{"base": {Namespace: "ocp", Name: "builder", Tag: "golang-1.19"}},
+				Releases:   map[string]api.UnresolvedRelease{"latest": {Integration: &api.Integration{Namespace: "ocp", Name: "4.12"}}},
+			},
+			PromotionConfiguration: &api.PromotionConfiguration{Namespace: "ocp", Name: "4.12"},
+			Images:                 []api.ProjectDirectoryImageBuildStepConfiguration{{To: "cli"}},
+		},
+		{
+			Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "broken"},
+			InputConfiguration: api.InputConfiguration{
+				BaseImages: map[string]api.ImageStreamTagReference{"base": {Namespace: "broken", Name: "base", Tag: "latest"}},
+			},
+		},
+	}}}
+	resolve := func(cfg api.ReleaseBuildConfiguration) (api.ReleaseBuildConfiguration, error) {
+		if cfg.Metadata.Branch == "broken" {
+			return cfg, errors.New("injected")
+		}
+		return cfg, nil
+	}
+	steps := registry.ReferenceByName{"upgrade": {As: "upgrade", FromImage: &api.ImageStreamTagReference{Namespace: "ci", Name: "upgrade", Tag: "latest"}}}
+
+	refs := newReferences([]*regexp.Regexp{regexp.MustCompile(`^ci/keep:.*`)})
+	referencesFromConfigs(refs, configs, resolve, steps, logrus.NewEntry(logrus.StandardLogger()))
+
+	testCases := []struct {
+		tag      api.ImageStreamTagReference
+		expected []string
+	}{
+		{tag: api.ImageStreamTagReference{Namespace: "ocp", Name: "builder", Tag: "golang-1.19"}, expected: []string{"test input of org/repo@master"}},
+		{tag: api.ImageStreamTagReference{Namespace: "ocp", Name: "builder", Tag: "golang-1.18"}},
+		{tag: api.ImageStreamTagReference{Namespace: "ci", Name: "tools", Tag: "latest"}, expected: []string{"test input of org/repo@master"}},
+		{tag: api.ImageStreamTagReference{Namespace: "ci", Name: "upgrade", Tag: "latest"}, expected: []string{"from_image of step upgrade"}},
+		{tag: api.ImageStreamTagReference{Namespace: "ci", Name: "keep", Tag: "anything"}, expected: []string{"ignored by ^ci/keep:.*"}},
+		{tag: api.ImageStreamTagReference{Namespace: "ocp", Name: "4.12", Tag: "cli"}, expected: []string{"promoted by org/repo@master", "test input of org/repo@master"}},
+		{tag: api.ImageStreamTagReference{Namespace: "ocp", Name: "4.12", Tag: "etcd"}, expected: []string{"test input of org/repo@master"}},
+		{tag: api.ImageStreamTagReference{Namespace: "broken", Name: "other", Tag: "latest"}, expected: []string{"unresolvable org/repo@broken"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.tag.ISTagName(), func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, refs.explain(tc.tag), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("explanation differs from expected:\n%s", diff)
+			}
+		})
+	}
+	if diff := cmp.Diff([]string{"ci", "ocp"}, refs.scope()); diff != "" {
+		t.Errorf("scope differs from expected:\n%s", diff)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	now := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	imported := func(name string, since *time.Time) imagev1.TagReference {
+		tag := imagev1.TagReference{Name: name, From: &corev1.ObjectReference{Kind: "DockerImage", Name: "registry.ci.openshift.org/ci/tools:" + name}}
+		if since != nil {
+			tag.Annotations = map[string]string{UnreferencedSinceAnnotation: since.Format(time.RFC3339)}
+		}
+		return tag
+	}
+	longAgo, recently := now.Add(-30*24*time.Hour), now.Add(-time.Hour)
+	stream := func() *imagev1.ImageStream {
+		return &imagev1.ImageStream{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "tools", Labels: map[string]string{"ci.openshift.io/test-images-distributor": "true"}},
+			Spec: imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{
+				imported("referenced", nil),
+				imported("referenced-again", &longAgo),
+				imported("new", nil),
+				imported("marked", &recently),
+				imported("expired", &longAgo),
+				imported("imported-for-a-test", &longAgo),
+				{Name: "local", From: &corev1.ObjectReference{Kind: "ImageStreamImage", Name: "tools@sha256:local"}},
+			}},
+		}
+	}
+	// tags for the steps imported on app.ci rather than by the test-images-distributor
+	importedForTests := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "step-images", Labels: map[string]string{"ci.openshift.io/imported-for-tests": "true"}},
+		Spec:       imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{imported("expired", &longAgo)}},
+	}
+	// not distributed by the test-images-distributor, so never touched
+	notDistributed := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "release"},
+		Spec:       imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{imported("unreferenced", nil)}},
+	}
+	istag := func(tag string) ctrlruntimeclient.Object {
+		return &imagev1.ImageStreamTag{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "tools:" + tag}}
+	}
+	testImport := &testimagestreamtagimportv1.TestImageStreamTagImport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "import"},
+		Spec:       testimagestreamtagimportv1.TestImageStreamTagImportSpec{ClusterName: "build01", Namespace: "ci", Name: "tools:imported-for-a-test"},
+	}
+
+	testCases := []struct {
+		name            string
+		dryRun          bool
+		expectedEntries []ReportEntry
+		expectedMarks   map[string]string
+		expectedIsTags  sets.String
+	}{
+		{
+			name: "unreferenced tags are marked and deleted after the grace period",
+			expectedEntries: []ReportEntry{
+				{Cluster: "build01", ImageStreamTag: "ci/tools:new", UnreferencedSince: now},
+				{Cluster: "build01", ImageStreamTag: "ci/tools:marked", UnreferencedSince: recently},
+				{Cluster: "build01", ImageStreamTag: "ci/tools:expired", UnreferencedSince: longAgo, Deleted: true},
+				{Cluster: "build01", ImageStreamTag: "ci/step-images:expired", UnreferencedSince: longAgo, Deleted: true},
+			},
+			expectedMarks: map[string]string{
+				"new":     now.Format(time.RFC3339),
+				"marked":  recently.Format(time.RFC3339),
+				"expired": longAgo.Format(time.RFC3339),
+			},
+			expectedIsTags: sets.NewString("tools:referenced", "tools:imported-for-a-test", "release:unreferenced"),
+		},
+		{
+			name:   "dry run changes nothing",
+			dryRun: true,
+			expectedEntries: []ReportEntry{
+				{Cluster: "build01", ImageStreamTag: "ci/tools:new", UnreferencedSince: now},
+				{Cluster: "build01", ImageStreamTag: "ci/tools:marked", UnreferencedSince: recently},
+				{Cluster: "build01", ImageStreamTag: "ci/tools:expired", UnreferencedSince: longAgo},
+				{Cluster: "build01", ImageStreamTag: "ci/step-images:expired", UnreferencedSince: longAgo},
+			},
+			expectedMarks: map[string]string{
+				"referenced-again":    longAgo.Format(time.RFC3339),
+				"marked":              recently.Format(time.RFC3339),
+				"expired":             longAgo.Format(time.RFC3339),
+				"imported-for-a-test": longAgo.Format(time.RFC3339),
+			},
+			expectedIsTags: sets.NewString("tools:referenced", "tools:expired", "tools:imported-for-a-test", "release:unreferenced", "step-images:expired"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := imagev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to register imagev1 scheme: %v", err)
+			}
+			if err := testimagestreamtagimportv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to register testimagestreamtagimportv1 scheme: %v", err)
+			}
+			client := fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(stream(), importedForTests.DeepCopy(), notDistributed.DeepCopy(), &imagev1.ImageStreamTag{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "step-images:expired"}}, istag("referenced"), istag("expired"), istag("imported-for-a-test"), &imagev1.ImageStreamTag{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "release:unreferenced"}}, testImport).Build()
+			c := &collector{
+				log:     logrus.NewEntry(logrus.StandardLogger()),
+				clients: map[string]ctrlruntimeclient.Client{"build01": client},
+				opts:    Options{DryRun: tc.dryRun, GracePeriod: 7 * 24 * time.Hour},
+				now:     func() time.Time { return now },
+			}
+			c.fromConfigs = newReferences(nil)
+			for _, tag := range []string{"referenced", "referenced-again"} {
+				c.fromConfigs.addTag(api.ImageStreamTagReference{Namespace: "ci", Name: "tools", Tag: tag}, "test input of org/repo@master")
+			}
+
+			refs, err := c.referencesWithImports(context.Background())
+			if err != nil {
+				t.Fatalf("failed to determine references: %v", err)
+			}
+			entries, err := c.collect(context.Background(), "build01", refs)
+			if err != nil {
+				t.Fatalf("failed to collect: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedEntries, entries); diff != "" {
+				t.Errorf("entries differ from expected:\n%s", diff)
+			}
+
+			actual := &imagev1.ImageStream{}
+			if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "ci", Name: "tools"}, actual); err != nil {
+				t.Fatalf("failed to get imagestream: %v", err)
+			}
+			marks := map[string]string{}
+			for _, tag := range actual.Spec.Tags {
+				if mark, ok := tag.Annotations[UnreferencedSinceAnnotation]; ok {
+					marks[tag.Name] = mark
+				}
+			}
+			if diff := cmp.Diff(tc.expectedMarks, marks); diff != "" {
+				t.Errorf("marks differ from expected:\n%s", diff)
+			}
+			release := &imagev1.ImageStream{}
+			if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "ci", Name: "release"}, release); err != nil {
+				t.Fatalf("failed to get imagestream: %v", err)
+			}
+			if diff := cmp.Diff(notDistributed.Spec, release.Spec); diff != "" {
+				t.Errorf("imagestream that is not distributed was changed:\n%s", diff)
+			}
+			istags := &imagev1.ImageStreamTagList{}
+			if err := client.List(context.Background(), istags); err != nil {
+				t.Fatalf("failed to list imagestreamtags: %v", err)
+			}
+			names := sets.NewString()
+			for _, item := range istags.Items {
+				names.Insert(item.Name)
+			}
+			if diff := cmp.Diff(tc.expectedIsTags, names); diff != "" {
+				t.Errorf("imagestreamtags differ from expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	refs := newReferences(nil)
+	refs.addTag(api.ImageStreamTagReference{Namespace: "ci", Name: "tools", Tag: "latest"}, "test input of org/repo@master")
+	c := &collector{log: logrus.NewEntry(logrus.StandardLogger())}
+
+	testCases := []struct {
+		name             string
+		query            string
+		latest           *Report
+		expectedCode     int
+		expectedResponse string
+	}{
+		{
+			name:             "no report yet",
+			expectedCode:     http.StatusServiceUnavailable,
+			expectedResponse: "no garbage was collected yet\n",
+		},
+		{
+			name: "report filtered by cluster",
+			latest: &Report{Entries: []ReportEntry{
+				{Cluster: "build01", ImageStreamTag: "ci/tools:old", Deleted: true},
+				{Cluster: "build02", ImageStreamTag: "ci/tools:old"},
+			}},
+			query:            "cluster=build01",
+			expectedCode:     http.StatusOK,
+			expectedResponse: `{"generated":"0001-01-01T00:00:00Z","dryRun":false,"entries":[{"cluster":"build01","imageStreamTag":"ci/tools:old","unreferencedSince":"0001-01-01T00:00:00Z","deleted":true}]}` + "\n",
+		},
+		{
+			name:             "explain a referenced tag",
+			latest:           &Report{},
+			query:            "explain=ci/tools:latest",
+			expectedCode:     http.StatusOK,
+			expectedResponse: `{"imageStreamTag":"ci/tools:latest","references":["test input of org/repo@master"]}` + "\n",
+		},
+		{
+			name:             "explain an unreferenced tag",
+			latest:           &Report{},
+			query:            "explain=ci/tools:old",
+			expectedCode:     http.StatusOK,
+			expectedResponse: `{"imageStreamTag":"ci/tools:old","references":[]}` + "\n",
+		},
+		{
+			name:             "explain an invalid tag",
+			latest:           &Report{},
+			query:            "explain=tools",
+			expectedCode:     http.StatusBadRequest,
+			expectedResponse: "explain must be in namespace/name:tag format: tools is not in namespace/name format\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c.latest, c.refs = tc.latest, refs
+			recorder := httptest.NewRecorder()
+			c.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ReportPath+"?"+tc.query, nil))
+			if recorder.Code != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, recorder.Code)
+			}
+			if diff := cmp.Diff(tc.expectedResponse, recorder.Body.String()); diff != "" {
+				t.Errorf("response differs from expected:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package imagestreamtaggc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	apihelper "github.com/openshift/ci-tools/pkg/api/helper"
+	testimagestreamtagimportv1 "github.com/openshift/ci-tools/pkg/api/testimagestreamtagimport/v1"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/steps/release"
+)
+
+// references records why ImageStreamTags must be kept. A tag is referenced if it is
+// referenced itself, if its whole ImageStream is or if its whole namespace is.
+type references struct {
+	tags       map[string]sets.String
+	streams    map[string]sets.String
+	namespaces map[string]sets.String
+	ignored    []*regexp.Regexp
+}
+
+func newReferences(ignored []*regexp.Regexp) *references {
+	return &references{tags: map[string]sets.String{}, streams: map[string]sets.String{}, namespaces: map[string]sets.String{}, ignored: ignored}
+}
+
+func add(into map[string]sets.String, key, reason string) {
+	if _, ok := into[key]; !ok {
+		into[key] = sets.NewString()
+	}
+	into[key].Insert(reason)
+}
+
+func (r *references) addTag(tag api.ImageStreamTagReference, reason string) {
+	add(r.tags, tag.ISTagName(), reason)
+}
+
+func (r *references) addStream(namespace, name, reason string) {
+	add(r.streams, namespace+"/"+name, reason)
+}
+
+func (r *references) addNamespace(namespace, reason string) {
+	add(r.namespaces, namespace, reason)
+}
+
+// explain returns why the tag must be kept, which is nothing if it can be deleted
+func (r *references) explain(tag api.ImageStreamTagReference) []string {
+	reasons := sets.NewString()
+	reasons.Insert(r.tags[tag.ISTagName()].UnsortedList()...)
+	reasons.Insert(r.streams[tag.Namespace+"/"+tag.Name].UnsortedList()...)
+	reasons.Insert(r.namespaces[tag.Namespace].UnsortedList()...)
+	for _, re := range r.ignored {
+		if re.MatchString(tag.ISTagName()) {
+			reasons.Insert(fmt.Sprintf("ignored by %s", re.String()))
+		}
+	}
+	return reasons.List()
+}
+
+// scope returns the namespaces that hold referenced tags: tags imported for test images
+// live in the namespaces of the tags that are still referenced, and we do not want to
+// touch any other namespaces. Within them, only distributed ImageStreams are collected.
+func (r *references) scope() []string {
+	namespaces := sets.NewString()
+	for _, m := range []map[string]sets.String{r.tags, r.streams} {
+		for key := range m {
+			namespaces.Insert(strings.SplitN(key, "/", 2)[0])
+		}
+	}
+	return namespaces.Difference(sets.StringKeySet(r.namespaces)).List()
+}
+
+type resolveFunc func(api.ReleaseBuildConfiguration) (api.ReleaseBuildConfiguration, error)
+
+// referencesFromConfigs collects the test input images of all configurations, resolved
+// against the step registry, the images the step registry references and all promoted tags
+func referencesFromConfigs(into *references, configs config.ByOrgRepo, resolve resolveFunc, steps registry.ReferenceByName, log *logrus.Entry) {
+	var all []api.ReleaseBuildConfiguration
+	for _, repos := range configs {
+		for _, repoConfigs := range repos {
+			all = append(all, repoConfigs...)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Metadata.AsString() < all[j].Metadata.AsString()
+	})
+	for _, cfg := range all {
+		reason := fmt.Sprintf("test input of %s", cfg.Metadata.AsString())
+		for _, tag := range release.PromotedTags(&cfg) {
+			into.addTag(tag, fmt.Sprintf("promoted by %s", cfg.Metadata.AsString()))
+		}
+		resolved, err := resolve(cfg)
+		if err != nil {
+			// a configuration we cannot resolve still references its images, so keep everything
+			// in the namespaces it may use instead of deleting tags it needs
+			log.WithError(err).WithField("config", cfg.Metadata.AsString()).Warn("Failed to resolve configuration, keeping the namespaces of its base images")
+			for _, tag := range cfg.BaseImages {
+				into.addNamespace(tag.Namespace, fmt.Sprintf("unresolvable %s", cfg.Metadata.AsString()))
+			}
+			continue
+		}
+		tags, err := apihelper.TestInputImageStreamTagsFromResolvedConfig(resolved)
+		if err != nil {
+			log.WithError(err).WithField("config", cfg.Metadata.AsString()).Warn("Failed to get the test input images of configuration")
+		}
+		for _, tag := range tags {
+			into.addTag(tagReferenceFor(tag.Namespace, tag.Name), reason)
+		}
+		for _, stream := range apihelper.TestInputImageStreamsFromResolvedConfig(resolved) {
+			into.addStream(stream.Namespace, stream.Name, reason)
+		}
+	}
+	for name, step := range steps {
+		if step.FromImage != nil {
+			into.addTag(*step.FromImage, fmt.Sprintf("from_image of step %s", name))
+		}
+	}
+}
+
+// referencesFromImports records the tags jobs requested with TestImageStreamTagImports: those
+// are kept for a week and cover tags used by rehearsals and other jobs with configurations
+// that are not merged yet
+func referencesFromImports(ctx context.Context, into *references, clients map[string]ctrlruntimeclient.Client) error {
+	for cluster, client := range clients {
+		imports := &testimagestreamtagimportv1.TestImageStreamTagImportList{}
+		if err := client.List(ctx, imports); err != nil {
+			return fmt.Errorf("failed to list testimagestreamtagimports on cluster %s: %w", cluster, err)
+		}
+		for _, item := range imports.Items {
+			into.addTag(tagReferenceFor(item.Spec.Namespace, item.Spec.Name), fmt.Sprintf("imported for a test on %s", cluster))
+		}
+	}
+	return nil
+}
+
+// tagReferenceFor converts the namespace and name:tag of an ImageStreamTag
+func tagReferenceFor(namespace, name string) api.ImageStreamTagReference {
+	ref := api.ImageStreamTagReference{Namespace: namespace, Name: name}
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		ref.Name, ref.Tag = name[:i], name[i+1:]
+	}
+	return ref
+}
@@ -0,0 +1,49 @@
+package namespacereaper
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
+
+	"github.com/openshift/ci-tools/pkg/api/nsttl"
+)
+
+// NewClusterClaimHook returns a hook that releases the clusters a job claimed from a cluster pool.
+// ci-operator names the claims after the ProwJob ID and releases them when the test ends, but they
+// leak until their lifetime runs out when ci-operator does not get to do so.
+func NewClusterClaimHook(hiveClient ctrlruntimeclient.Client) CleanupHook {
+	return &clusterClaimHook{client: hiveClient}
+}
+
+type clusterClaimHook struct {
+	client ctrlruntimeclient.Client
+}
+
+func (h *clusterClaimHook) Name() string {
+	return "cluster_claims"
+}
+
+func (h *clusterClaimHook) Cleanup(ctx context.Context, _ string, ns *corev1.Namespace) error {
+	prowJobID := ns.Annotations[nsttl.AnnotationProwJobID]
+	if prowJobID == "" {
+		return nil
+	}
+	claims := &hivev1.ClusterClaimList{}
+	if err := h.client.List(ctx, claims); err != nil {
+		return fmt.Errorf("failed to list cluster claims: %w", err)
+	}
+	for i := range claims.Items {
+		if claims.Items[i].Name != prowJobID {
+			continue
+		}
+		if err := h.client.Delete(ctx, &claims.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete cluster claim %s/%s: %w", claims.Items[i].Namespace, claims.Items[i].Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package namespacereaper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openshift/ci-tools/pkg/api/nsttl"
+)
+
+const ControllerName = "namespace_reaper"
+
+// Reason describes why a namespace got reaped
+type Reason string
+
+const (
+	// ReasonHardTTL is used when the namespace has not been active for longer than its hard TTL
+	ReasonHardTTL Reason = "hard_ttl"
+	// ReasonSoftTTL is used when all pods in the namespace completed longer than its soft TTL ago
+	ReasonSoftTTL Reason = "soft_ttl"
+	// ReasonAbandoned is used when the job that used the namespace is gone without the namespace
+	// having expired, e.g. because ci-operator got killed before it could set the TTLs
+	ReasonAbandoned Reason = "abandoned"
+)
+
+// maxRequeue bounds how long we wait before looking at a namespace again: pods completing
+// move the soft TTL deadline and we do not watch pods.
+const maxRequeue = 10 * time.Minute
+
+// CleanupHook frees resources outside of the namespace that a job may have leaked. All hooks
+// are run before a namespace gets deleted. If a hook fails, the namespace is kept and retried,
+// so hooks must be idempotent.
+type CleanupHook interface {
+	Name() string
+	Cleanup(ctx context.Context, cluster string, ns *corev1.Namespace) error
+}
+
+type Options struct {
+	// ProwJobClient is a client for the cluster the ProwJobs live in.
+	ProwJobClient ctrlruntimeclient.Client
+	ConfigGetter  prowconfig.Getter
+	// AbandonedAfter is how long the namespace must have had no heartbeat from ci-operator before
+	// it is considered abandoned if its ProwJob finished.
+	AbandonedAfter time.Duration
+	Hooks          []CleanupHook
+}
+
+var (
+	deletedNamespaces = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "namespace_reaper_deleted_namespaces_total",
+		Help: "The number of namespaces the namespace reaper deleted",
+	}, []string{"cluster", "reason"})
+	reclaimedCPU = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "namespace_reaper_reclaimed_cpu_cores_total",
+		Help: "The CPU requested by pods that were still running in reaped namespaces",
+	}, []string{"cluster", "reason"})
+	reclaimedMemory = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "namespace_reaper_reclaimed_memory_bytes_total",
+		Help: "The memory requested by pods that were still running in reaped namespaces",
+	}, []string{"cluster", "reason"})
+	reclaimedStorage = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "namespace_reaper_reclaimed_storage_bytes_total",
+		Help: "The storage requested by persistent volume claims in reaped namespaces",
+	}, []string{"cluster", "reason"})
+	hookFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "namespace_reaper_cleanup_hook_failures_total",
+		Help: "The number of times a cleanup hook failed",
+	}, []string{"cluster", "hook"})
+)
+
+func AddToManager(mgr manager.Manager, allManagers map[string]manager.Manager, opts Options) error {
+	for _, collector := range []prometheus.Collector{deletedNamespaces, reclaimedCPU, reclaimedMemory, reclaimedStorage, hookFailures} {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+
+	managed := predicate.NewPredicateFuncs(func(o ctrlruntimeclient.Object) bool {
+		_, ok := o.GetAnnotations()[nsttl.AnnotationNamespaceLastActive]
+		return ok
+	})
+	for clusterName, clusterManager := range allManagers {
+		r := &reconciler{
+			log:            logrus.WithField("controller", ControllerName).WithField("cluster", clusterName),
+			cluster:        clusterName,
+			client:         clusterManager.GetClient(),
+			prowJobClient:  opts.ProwJobClient,
+			configGetter:   opts.ConfigGetter,
+			abandonedAfter: opts.AbandonedAfter,
+			hooks:          opts.Hooks,
+			now:            time.Now,
+		}
+		c, err := controller.New(ControllerName+"_"+clusterName, mgr, controller.Options{
+			Reconciler:              r,
+			MaxConcurrentReconciles: 10,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to construct controller for cluster %s: %w", clusterName, err)
+		}
+		if err := c.Watch(source.Kind(clusterManager.GetCache(), &corev1.Namespace{}), &handler.EnqueueRequestForObject{}, managed); err != nil {
+			return fmt.Errorf("failed to watch namespaces in cluster %s: %w", clusterName, err)
+		}
+	}
+
+	return nil
+}
+
+type reconciler struct {
+	log            *logrus.Entry
+	cluster        string
+	client         ctrlruntimeclient.Client
+	prowJobClient  ctrlruntimeclient.Client
+	configGetter   prowconfig.Getter
+	abandonedAfter time.Duration
+	hooks          []CleanupHook
+	now            func() time.Time
+}
+
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := r.log.WithField("namespace", req.Name)
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(ctx, req.NamespacedName, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("failed to get namespace %s: %w", req.Name, err)
+	}
+	if ns.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.client.List(ctx, pods, ctrlruntimeclient.InNamespace(ns.Name)); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to list pods in namespace %s: %w", ns.Name, err)
+	}
+	reason, requeueAfter, err := r.decide(ctx, ns, pods.Items, log)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if reason == "" {
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	log = log.WithField("reason", reason)
+	for _, hook := range r.hooks {
+		if err := hook.Cleanup(ctx, r.cluster, ns); err != nil {
+			hookFailures.WithLabelValues(r.cluster, hook.Name()).Inc()
+			return reconcile.Result{}, fmt.Errorf("cleanup hook %s failed for namespace %s: %w", hook.Name(), ns.Name, err)
+		}
+	}
+	reclaimed, err := r.reclaimable(ctx, ns.Name, pods.Items)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	log.Info("Deleting namespace")
+	if err := r.client.Delete(ctx, ns); err != nil && !apierrors.IsNotFound(err) {
+		return reconcile.Result{}, fmt.Errorf("failed to delete namespace %s: %w", ns.Name, err)
+	}
+	deletedNamespaces.WithLabelValues(r.cluster, string(reason)).Inc()
+	reclaimedCPU.WithLabelValues(r.cluster, string(reason)).Add(reclaimed.cpu)
+	reclaimedMemory.WithLabelValues(r.cluster, string(reason)).Add(reclaimed.memory)
+	reclaimedStorage.WithLabelValues(r.cluster, string(reason)).Add(reclaimed.storage)
+	return reconcile.Result{}, nil
+}
+
+// decide returns why the namespace must be reaped or, if it must not be reaped yet, when to look at it again
+func (r *reconciler) decide(ctx context.Context, ns *corev1.Namespace, pods []corev1.Pod, log *logrus.Entry) (Reason, time.Duration, error) {
+	now := r.now()
+	lastActive := ns.CreationTimestamp.Time
+	if raw, ok := ns.Annotations[nsttl.AnnotationNamespaceLastActive]; ok {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			log.WithError(err).Warnf("Ignoring invalid %s annotation", nsttl.AnnotationNamespaceLastActive)
+		} else {
+			lastActive = parsed
+		}
+	}
+
+	requeueAfter := maxRequeue
+	expiresAt := func(deadline time.Time) bool {
+		if !now.Before(deadline) {
+			return true
+		}
+		if until := deadline.Sub(now); until < requeueAfter {
+			requeueAfter = until
+		}
+		return false
+	}
+
+	if hard, ok := durationAnnotation(ns, nsttl.AnnotationCleanupDurationTTL, log); ok && expiresAt(lastActive.Add(hard)) {
+		return ReasonHardTTL, 0, nil
+	}
+
+	idleSince, running := lastActive, false
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			running = true
+			break
+		}
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				if status.State.Terminated != nil && status.State.Terminated.FinishedAt.After(idleSince) {
+					idleSince = status.State.Terminated.FinishedAt.Time
+				}
+			}
+		}
+	}
+	if running {
+		return "", requeueAfter, nil
+	}
+
+	if soft, ok := durationAnnotation(ns, nsttl.AnnotationIdleCleanupDurationTTL, log); ok && expiresAt(idleSince.Add(soft)) {
+		return ReasonSoftTTL, 0, nil
+	}
+
+	// ci-operator refreshes the heartbeat for as long as it runs and holds its leases, so a stale
+	// heartbeat together with a finished ProwJob means nobody is coming back for this namespace.
+	prowJobID := ns.Annotations[nsttl.AnnotationProwJobID]
+	if prowJobID == "" || r.abandonedAfter <= 0 || !expiresAt(lastActive.Add(r.abandonedAfter)) {
+		return "", requeueAfter, nil
+	}
+	prowJob := &prowv1.ProwJob{}
+	if err := r.prowJobClient.Get(ctx, types.NamespacedName{Namespace: r.configGetter().ProwJobNamespace, Name: prowJobID}, prowJob); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ReasonAbandoned, 0, nil
+		}
+		return "", 0, fmt.Errorf("failed to get prowjob %s: %w", prowJobID, err)
+	}
+	if prowJob.Complete() {
+		return ReasonAbandoned, 0, nil
+	}
+	return "", requeueAfter, nil
+}
+
+func durationAnnotation(ns *corev1.Namespace, annotation string, log *logrus.Entry) (time.Duration, bool) {
+	raw, ok := ns.Annotations[annotation]
+	if !ok {
+		return 0, false
+	}
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		log.WithError(err).Warnf("Ignoring invalid %s annotation", annotation)
+		return 0, false
+	}
+	return duration, true
+}
+
+type quota struct {
+	cpu, memory, storage float64
+}
+
+// reclaimable sums up the capacity the namespace still holds: the requests of pods that did not
+// complete and the storage of persistent volume claims
+func (r *reconciler) reclaimable(ctx context.Context, namespace string, pods []corev1.Pod) (quota, error) {
+	var q quota
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			q.cpu += container.Resources.Requests.Cpu().AsApproximateFloat64()
+			q.memory += container.Resources.Requests.Memory().AsApproximateFloat64()
+		}
+	}
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := r.client.List(ctx, claims, ctrlruntimeclient.InNamespace(namespace)); err != nil {
+		return q, fmt.Errorf("failed to list persistent volume claims in namespace %s: %w", namespace, err)
+	}
+	for _, claim := range claims.Items {
+		q.storage += claim.Spec.Resources.Requests.Storage().AsApproximateFloat64()
+	}
+	return q, nil
+}
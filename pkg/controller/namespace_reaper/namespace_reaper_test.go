@@ -0,0 +1,287 @@
+package namespacereaper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/openshift/ci-tools/pkg/api/nsttl"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+type fakeHook struct {
+	err    error
+	called []string
+}
+
+func (h *fakeHook) Name() string { return "fake" }
+
+func (h *fakeHook) Cleanup(_ context.Context, cluster string, ns *corev1.Namespace) error {
+	h.called = append(h.called, cluster+"/"+ns.Name)
+	return h.err
+}
+
+func TestReconcile(t *testing.T) {
+	now := time.Date(2022, 2, 2, 12, 0, 0, 0, time.UTC)
+	namespace := func(annotations map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:              "ci-op-1234",
+			CreationTimestamp: metav1.Time{Time: now.Add(-24 * time.Hour)},
+			Annotations:       annotations,
+		}}
+	}
+	activeAgo := func(d time.Duration) string { return now.Add(-d).Format(time.RFC3339) }
+	pod := func(phase corev1.PodPhase, finishedAgo time.Duration) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci-op-1234", Name: string(phase)},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if finishedAgo != 0 {
+			p.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: now.Add(-finishedAgo)}}}}}
+		}
+		return p
+	}
+	prowJob := func(state prowv1.ProwJobState) *prowv1.ProwJob {
+		pj := &prowv1.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "uuid"},
+			Status:     prowv1.ProwJobStatus{State: state},
+		}
+		if state == prowv1.SuccessState {
+			pj.Status.CompletionTime = &metav1.Time{Time: now.Add(-2 * time.Hour)}
+		}
+		return pj
+	}
+
+	testCases := []struct {
+		name     string
+		objects  []ctrlruntimeclient.Object
+		prowJobs []ctrlruntimeclient.Object
+		hookErr  error
+
+		expectedResult    reconcile.Result
+		expectedErr       error
+		expectedDeletion  bool
+		expectedHookCalls []string
+	}{
+		{
+			name: "namespace not found is swallowed",
+		},
+		{
+			name: "no TTL expired yet, requeue when the hard TTL expires",
+			objects: []ctrlruntimeclient.Object{namespace(map[string]string{
+				nsttl.AnnotationNamespaceLastActive: activeAgo(time.Hour),
+				nsttl.AnnotationCleanupDurationTTL:  "65m",
+			})},
+			expectedResult: reconcile.Result{RequeueAfter: 5 * time.Minute},
+		},
+		{
+			name: "hard TTL expired",
+			objects: []ctrlruntimeclient.Object{
+				namespace(map[string]string{
+					nsttl.AnnotationNamespaceLastActive: activeAgo(2 * time.Hour),
+					nsttl.AnnotationCleanupDurationTTL:  "1h",
+				}),
+				pod(corev1.PodRunning, 0),
+			},
+			expectedDeletion:  true,
+			expectedHookCalls: []string{"build01/ci-op-1234"},
+		},
+		{
+			name: "soft TTL is not considered while pods are running",
+			objects: []ctrlruntimeclient.Object{
+				namespace(map[string]string{
+					nsttl.AnnotationNamespaceLastActive:    activeAgo(2 * time.Hour),
+					nsttl.AnnotationIdleCleanupDurationTTL: "1h",
+				}),
+				pod(corev1.PodRunning, 0),
+			},
+			expectedResult: reconcile.Result{RequeueAfter: maxRequeue},
+		},
+		{
+			name: "soft TTL counts from the last pod that completed",
+			objects: []ctrlruntimeclient.Object{
+				namespace(map[string]string{
+					nsttl.AnnotationNamespaceLastActive:    activeAgo(2 * time.Hour),
+					nsttl.AnnotationIdleCleanupDurationTTL: "1h",
+				}),
+				pod(corev1.PodSucceeded, 58*time.Minute),
+			},
+			expectedResult: reconcile.Result{RequeueAfter: 2 * time.Minute},
+		},
+		{
+			name: "soft TTL expired",
+			objects: []ctrlruntimeclient.Object{
+				namespace(map[string]string{
+					nsttl.AnnotationNamespaceLastActive:    activeAgo(2 * time.Hour),
+					nsttl.AnnotationIdleCleanupDurationTTL: "1h",
+				}),
+				pod(corev1.PodFailed, 90*time.Minute),
+			},
+			expectedDeletion:  true,
+			expectedHookCalls: []string{"build01/ci-op-1234"},
+		},
+		{
+			name: "invalid TTL is ignored",
+			objects: []ctrlruntimeclient.Object{namespace(map[string]string{
+				nsttl.AnnotationNamespaceLastActive: activeAgo(2 * time.Hour),
+				nsttl.AnnotationCleanupDurationTTL:  "forever",
+			})},
+			expectedResult: reconcile.Result{RequeueAfter: maxRequeue},
+		},
+		{
+			name: "abandoned: heartbeat is stale and the prowjob completed",
+			objects: []ctrlruntimeclient.Object{namespace(map[string]string{
+				nsttl.AnnotationNamespaceLastActive: activeAgo(2 * time.Hour),
+				nsttl.AnnotationProwJobID:           "uuid",
+			})},
+			prowJobs:          []ctrlruntimeclient.Object{prowJob(prowv1.SuccessState)},
+			expectedDeletion:  true,
+			expectedHookCalls: []string{"build01/ci-op-1234"},
+		},
+		{
+			name: "abandoned: heartbeat is stale and the prowjob is gone",
+			objects: []ctrlruntimeclient.Object{namespace(map[string]string{
+				nsttl.AnnotationNamespaceLastActive: activeAgo(2 * time.Hour),
+				nsttl.AnnotationProwJobID:           "uuid",
+			})},
+			expectedDeletion:  true,
+			expectedHookCalls: []string{"build01/ci-op-1234"},
+		},
+		{
+			name: "not abandoned while the prowjob is running",
+			objects: []ctrlruntimeclient.Object{namespace(map[string]string{
+				nsttl.AnnotationNamespaceLastActive: activeAgo(2 * time.Hour),
+				nsttl.AnnotationProwJobID:           "uuid",
+			})},
+			prowJobs:       []ctrlruntimeclient.Object{prowJob(prowv1.PendingState)},
+			expectedResult: reconcile.Result{RequeueAfter: maxRequeue},
+		},
+		{
+			name: "not abandoned while the heartbeat is fresh",
+			objects: []ctrlruntimeclient.Object{namespace(map[string]string{
+				nsttl.AnnotationNamespaceLastActive: activeAgo(55 * time.Minute),
+				nsttl.AnnotationProwJobID:           "uuid",
+			})},
+			prowJobs:       []ctrlruntimeclient.Object{prowJob(prowv1.SuccessState)},
+			expectedResult: reconcile.Result{RequeueAfter: 5 * time.Minute},
+		},
+		{
+			name: "not abandoned while pods are running",
+			objects: []ctrlruntimeclient.Object{
+				namespace(map[string]string{
+					nsttl.AnnotationNamespaceLastActive: activeAgo(2 * time.Hour),
+					nsttl.AnnotationProwJobID:           "uuid",
+				}),
+				pod(corev1.PodPending, 0),
+			},
+			prowJobs:       []ctrlruntimeclient.Object{prowJob(prowv1.SuccessState)},
+			expectedResult: reconcile.Result{RequeueAfter: maxRequeue},
+		},
+		{
+			name: "failing hook keeps the namespace",
+			objects: []ctrlruntimeclient.Object{namespace(map[string]string{
+				nsttl.AnnotationNamespaceLastActive: activeAgo(2 * time.Hour),
+				nsttl.AnnotationCleanupDurationTTL:  "1h",
+			})},
+			hookErr:           errors.New("injected"),
+			expectedErr:       errors.New("cleanup hook fake failed for namespace ci-op-1234: injected"),
+			expectedHookCalls: []string{"build01/ci-op-1234"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to register core scheme: %v", err)
+			}
+			if err := prowv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to register prowv1 scheme: %v", err)
+			}
+			hook := &fakeHook{err: tc.hookErr}
+			r := &reconciler{
+				log:           logrus.NewEntry(logrus.StandardLogger()),
+				cluster:       "build01",
+				client:        fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build(),
+				prowJobClient: fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(tc.prowJobs...).Build(),
+				configGetter: func() *prowconfig.Config {
+					return &prowconfig.Config{ProwConfig: prowconfig.ProwConfig{ProwJobNamespace: "ci"}}
+				},
+				abandonedAfter: time.Hour,
+				hooks:          []CleanupHook{hook},
+				now:            func() time.Time { return now },
+			}
+
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "ci-op-1234"}})
+			if diff := cmp.Diff(tc.expectedErr, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("error differs from expected:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedResult, result); diff != "" {
+				t.Errorf("result differs from expected:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedHookCalls, hook.called); diff != "" {
+				t.Errorf("hook calls differ from expected:\n%s", diff)
+			}
+			if len(tc.objects) == 0 {
+				return
+			}
+			namespaces := &corev1.NamespaceList{}
+			if err := r.client.List(context.Background(), namespaces); err != nil {
+				t.Fatalf("failed to list namespaces: %v", err)
+			}
+			if deleted := len(namespaces.Items) == 0; deleted != tc.expectedDeletion {
+				t.Errorf("expected namespace to be deleted: %t, was deleted: %t", tc.expectedDeletion, deleted)
+			}
+		})
+	}
+}
+
+func TestReclaimable(t *testing.T) {
+	requests := func(cpu, memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+	pods := []corev1.Pod{
+		{
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Resources: requests("500m", "1Gi")}, {Resources: requests("1", "1Gi")}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Resources: requests("4", "8Gi")}}},
+			Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+	}
+	client := fakectrlruntimeclient.NewClientBuilder().WithObjects(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci-op-1234", Name: "data"},
+		Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse("10Gi"),
+		}}},
+	}).Build()
+
+	r := &reconciler{client: client}
+	actual, err := r.reclaimable(context.Background(), "ci-op-1234", pods)
+	if err != nil {
+		t.Fatalf("failed to compute reclaimable quota: %v", err)
+	}
+	expected := quota{cpu: 1.5, memory: 2 * 1024 * 1024 * 1024, storage: 10 * 1024 * 1024 * 1024}
+	if diff := cmp.Diff(expected, actual, cmp.AllowUnexported(quota{})); diff != "" {
+		t.Errorf("quota differs from expected:\n%s", diff)
+	}
+}
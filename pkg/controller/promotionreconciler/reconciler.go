@@ -6,11 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	controllerruntime "sigs.k8s.io/controller-runtime"
@@ -27,6 +29,7 @@ import (
 	controllerutil "github.com/openshift/ci-tools/pkg/controller/util"
 	"github.com/openshift/ci-tools/pkg/load/agents"
 	"github.com/openshift/ci-tools/pkg/promotion"
+	"github.com/openshift/ci-tools/pkg/promotion/history"
 	"github.com/openshift/ci-tools/pkg/steps/release"
 	"github.com/openshift/ci-tools/pkg/util/imagestreamtagmapper"
 	"github.com/openshift/ci-tools/pkg/util/imagestreamtagwrapper"
@@ -43,6 +46,10 @@ type Options struct {
 	RegistryManager controllerruntime.Manager
 
 	IgnoredImageStreams []*regexp.Regexp
+
+	// ReportInterval is how often the report of all promoted tags is generated,
+	// it is not generated if zero
+	ReportInterval time.Duration
 }
 
 const ControllerName = "promotionreconciler"
@@ -95,6 +102,12 @@ func AddToManager(mgr controllerruntime.Manager, opts Options) error {
 	); err != nil {
 		return fmt.Errorf("failed to create watch for ImageStreams: %w", err)
 	}
+
+	if opts.ReportInterval > 0 {
+		if err := addReporter(mgr, opts, r.client); err != nil {
+			return err
+		}
+	}
 	r.log.Info("Successfully added reconciler to manager")
 
 	return nil
@@ -169,13 +182,22 @@ func (r *reconciler) reconcile(ctx context.Context, req controllerruntime.Reques
 	}
 	log = log.WithField("org", ciOPConfig.Metadata.Org).WithField("repo", ciOPConfig.Metadata.Repo).WithField("branch", ciOPConfig.Metadata.Branch)
 
+	pinned, err := r.isPinned(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to determine if the tag is pinned: %w", err)
+	}
+	if pinned {
+		log.Debug("ImageStreamTag is pinned")
+		return nil
+	}
+
 	istCommit, err := commitForIST(ist)
 	if err != nil {
 		return controllerutil.TerminalError(fmt.Errorf("failed to get commit for imageStreamTag: %w", err))
 	}
 	log = log.WithField("istCommit", istCommit)
 
-	currentHEAD, found, err := r.currentHEADForBranch(ciOPConfig.Metadata, log)
+	currentHEAD, found, err := currentHEADForBranch(r.gitHubClient, ciOPConfig.Metadata, log)
 	if err != nil {
 		return fmt.Errorf("failed to get current git head for imageStreamTag: %w", err)
 	}
@@ -198,6 +220,19 @@ func (r *reconciler) reconcile(ctx context.Context, req controllerruntime.Reques
 	return nil
 }
 
+// isPinned determines if the ImageStreamTag is pinned on its ImageStream, so it must not be promoted
+func (r *reconciler) isPinned(ctx context.Context, req controllerruntime.Request) (bool, error) {
+	name, tag, _ := strings.Cut(req.Name, ":")
+	is := &imagev1.ImageStream{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: name}, is); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get imagestream: %w", err)
+	}
+	return history.PinnedTags(is).Has(tag), nil
+}
+
 func (r *reconciler) promotionConfig(ist *imagev1.ImageStreamTag) (*cioperatorapi.ReleaseBuildConfiguration, error) {
 	results, err := r.releaseBuildConfigs(configIndexKeyForIST(ist))
 	if err != nil {
@@ -228,10 +263,10 @@ func commitForIST(ist *imagev1.ImageStreamTag) (string, error) {
 	return commit, nil
 }
 
-func (r *reconciler) currentHEADForBranch(metadata cioperatorapi.Metadata, log *logrus.Entry) (string, bool, error) {
+func currentHEADForBranch(gitHubClient githubClient, metadata cioperatorapi.Metadata, log *logrus.Entry) (string, bool, error) {
 	// We attempted for some time to use the gitClient for this, but we do so many reconciliations that
 	// it results in a massive performance issues that can easely kill the developers laptop.
-	ref, err := r.gitHubClient.GetRef(metadata.Org, metadata.Repo, "heads/"+metadata.Branch)
+	ref, err := gitHubClient.GetRef(metadata.Org, metadata.Repo, "heads/"+metadata.Branch)
 	if err != nil {
 		if github.IsNotFound(err) {
 			return "", false, nil
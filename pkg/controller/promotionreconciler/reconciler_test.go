@@ -16,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/test-infra/prow/github"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/yaml"
@@ -25,6 +26,7 @@ import (
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/controller/promotionreconciler/prowjobreconciler"
 	controllerutil "github.com/openshift/ci-tools/pkg/controller/util"
+	"github.com/openshift/ci-tools/pkg/promotion/history"
 )
 
 func init() {
@@ -95,6 +97,7 @@ func TestReconcile(t *testing.T) {
 		name              string
 		githubClient      func(owner, repo, ref string) (string, error)
 		promotionDisabled bool
+		pinnedTags        string
 		verify            func(error, *prowjobreconciler.OrgRepoBranchCommit) error
 	}{
 		{
@@ -146,6 +149,34 @@ func TestReconcile(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:         "Ist outdated, tag pinned, no prowjob created",
+			githubClient: func(_, _, _ string) (string, error) { return "newer", nil },
+			pinnedTags:   "other,tag",
+			verify: func(e error, req *prowjobreconciler.OrgRepoBranchCommit) error {
+				if e != nil {
+					return fmt.Errorf("expected error to be nil, was %w", e)
+				}
+				if req != nil {
+					return fmt.Errorf("expected no request, got %v", req)
+				}
+				return nil
+			},
+		},
+		{
+			name:         "Ist outdated, other tag pinned, prowjob created",
+			githubClient: func(_, _, _ string) (string, error) { return "newer", nil },
+			pinnedTags:   "other",
+			verify: func(e error, req *prowjobreconciler.OrgRepoBranchCommit) error {
+				if e != nil {
+					return fmt.Errorf("expected error to be nil, was %w", e)
+				}
+				if req == nil {
+					return errors.New("expected to get request, was nil")
+				}
+				return nil
+			},
+		},
 		{
 			name:         "Ist outdated, prowjob created",
 			githubClient: func(_, _, _ string) (string, error) { return "newer", nil },
@@ -288,11 +319,20 @@ func TestReconcile(t *testing.T) {
 				},
 			}
 
+			objects := []ctrlruntimeclient.Object{imageStreamTag}
+			if tc.pinnedTags != "" {
+				objects = append(objects, &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "namespace",
+					Name:        "name",
+					Annotations: map[string]string{history.PinnedTagsAnnotation: tc.pinnedTags},
+				}})
+			}
+
 			var req *prowjobreconciler.OrgRepoBranchCommit
 
 			r := &reconciler{
 				log:    logrus.NewEntry(logrus.New()),
-				client: fakectrlruntimeclient.NewFakeClient(imageStreamTag),
+				client: fakectrlruntimeclient.NewClientBuilder().WithObjects(objects...).Build(),
 				releaseBuildConfigs: func(_ string) ([]*cioperatorapi.ReleaseBuildConfiguration, error) {
 					return []*cioperatorapi.ReleaseBuildConfiguration{{
 						Metadata: cioperatorapi.Metadata{
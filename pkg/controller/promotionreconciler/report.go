@@ -0,0 +1,417 @@
+package promotionreconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/promotion/history"
+	"github.com/openshift/ci-tools/pkg/steps/release"
+)
+
+// ReportPath is where dptp-controller-manager serves the latest report
+const ReportPath = "/promotion-report"
+
+// TagKind describes why a tag is expected to be promoted
+type TagKind string
+
+const (
+	TagKindImage           TagKind = "image"
+	TagKindAdditionalImage TagKind = "additional_image"
+	TagKindTagByCommit     TagKind = "tag_by_commit"
+	TagKindBuildCache      TagKind = "build_cache"
+)
+
+// TagState describes how a promoted tag relates to the HEAD of its branch
+type TagState string
+
+const (
+	// TagStateCurrent tags were built from the HEAD of their branch
+	TagStateCurrent TagState = "current"
+	// TagStateStale tags were built from an older commit
+	TagStateStale TagState = "stale"
+	// TagStateMissing tags do not exist
+	TagStateMissing TagState = "missing"
+	// TagStatePinned tags are not promoted until they are unpinned
+	TagStatePinned TagState = "pinned"
+	// TagStateUnknown tags could not be checked, see the error of the entry
+	TagStateUnknown TagState = "unknown"
+)
+
+// ReportEntry describes a tag that a promotion configuration promotes
+type ReportEntry struct {
+	ImageStreamTag string   `json:"imageStreamTag"`
+	Kind           TagKind  `json:"kind"`
+	Org            string   `json:"org"`
+	Repo           string   `json:"repo"`
+	Branch         string   `json:"branch"`
+	Variant        string   `json:"variant,omitempty"`
+	State          TagState `json:"state"`
+	// Commit is the commit the tag was built from
+	Commit     string `json:"commit,omitempty"`
+	BranchHEAD string `json:"branchHead,omitempty"`
+	// LagSeconds is how much older the commit of a stale tag is than the HEAD of the branch
+	LagSeconds float64 `json:"lagSeconds,omitempty"`
+	// PromotionInFlight is set when a promotion job for the branch is running
+	PromotionInFlight bool   `json:"promotionInFlight"`
+	Error             string `json:"error,omitempty"`
+}
+
+// Report lists all tags promoted by the promotion configurations
+type Report struct {
+	Generated time.Time     `json:"generated"`
+	Entries   []ReportEntry `json:"entries"`
+}
+
+type reportGitHubClient interface {
+	githubClient
+	GetSingleCommit(org, repo, SHA string) (github.RepositoryCommit, error)
+}
+
+// reporter periodically reports the state of all tags promoted by the promotion configurations
+type reporter struct {
+	log              *logrus.Entry
+	configs          func() config.ByOrgRepo
+	registryClient   ctrlruntimeclient.Client
+	prowJobClient    ctrlruntimeclient.Client
+	prowJobNamespace func() string
+	gitHubClient     reportGitHubClient
+	interval         time.Duration
+
+	lock   sync.RWMutex
+	latest *Report
+
+	tags *prometheus.GaugeVec
+	lag  *prometheus.GaugeVec
+}
+
+func newReporterMetrics() (*prometheus.GaugeVec, *prometheus.GaugeVec) {
+	tags := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: ControllerName,
+		Name:      "promoted_tags",
+		Help:      "The number of tags promotion configurations promote, by kind and state",
+	}, []string{"kind", "state"})
+	lag := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: ControllerName,
+		Name:      "branch_lag_seconds",
+		Help:      "How much older the oldest commit a stale tag of the branch was built from is than the HEAD of the branch",
+	}, []string{"org", "repo", "branch"})
+	return tags, lag
+}
+
+func addReporter(mgr controllerruntime.Manager, opts Options, registryClient ctrlruntimeclient.Client) error {
+	tags, lag := newReporterMetrics()
+	for _, collector := range []prometheus.Collector{tags, lag} {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return fmt.Errorf("failed to register report metric: %w", err)
+		}
+	}
+	r := &reporter{
+		log:              logrus.WithField("controller", ControllerName).WithField("component", "report"),
+		configs:          opts.CIOperatorConfigAgent.GetAll,
+		registryClient:   registryClient,
+		prowJobClient:    mgr.GetClient(),
+		prowJobNamespace: func() string { return opts.ConfigGetter().ProwJobNamespace },
+		gitHubClient:     opts.GitHubClient,
+		interval:         opts.ReportInterval,
+		tags:             tags,
+		lag:              lag,
+	}
+	if err := mgr.Add(r); err != nil {
+		return fmt.Errorf("failed to add the reporter to the manager: %w", err)
+	}
+	if err := mgr.AddMetricsExtraHandler(ReportPath, r); err != nil {
+		return fmt.Errorf("failed to serve the report: %w", err)
+	}
+	return nil
+}
+
+// Start generates a report every interval until the context is cancelled
+func (r *reporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.update(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *reporter) update(ctx context.Context) {
+	start := time.Now()
+	report, err := r.generate(ctx, start)
+	if err != nil {
+		r.log.WithError(err).Error("Failed to generate the promotion report")
+		return
+	}
+	r.lock.Lock()
+	r.latest = report
+	r.lock.Unlock()
+	r.recordMetrics(report)
+	r.log.WithField("entries", len(report.Entries)).WithField("duration", time.Since(start)).Info("Generated the promotion report")
+}
+
+func (r *reporter) recordMetrics(report *Report) {
+	r.tags.Reset()
+	r.lag.Reset()
+	lags := map[[3]string]float64{}
+	for _, entry := range report.Entries {
+		r.tags.WithLabelValues(string(entry.Kind), string(entry.State)).Inc()
+		if branch := [3]string{entry.Org, entry.Repo, entry.Branch}; entry.State == TagStateStale && entry.LagSeconds >= lags[branch] {
+			lags[branch] = entry.LagSeconds
+		}
+	}
+	for branch, lag := range lags {
+		r.lag.WithLabelValues(branch[0], branch[1], branch[2]).Set(lag)
+	}
+}
+
+// ServeHTTP serves the latest report as JSON, optionally filtered by the state and kind query parameters
+func (r *reporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.RLock()
+	latest := r.latest
+	r.lock.RUnlock()
+	if latest == nil {
+		http.Error(w, "no promotion report was generated yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	state, kind := req.URL.Query().Get("state"), req.URL.Query().Get("kind")
+	filtered := Report{Generated: latest.Generated, Entries: []ReportEntry{}}
+	for _, entry := range latest.Entries {
+		if (state == "" || string(entry.State) == state) && (kind == "" || string(entry.Kind) == kind) {
+			filtered.Entries = append(filtered.Entries, entry)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(filtered); err != nil {
+		r.log.WithError(err).Warn("Failed to serve the promotion report")
+	}
+}
+
+func (r *reporter) generate(ctx context.Context, now time.Time) (*Report, error) {
+	inFlight, err := r.promotionsInFlight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*cioperatorapi.ReleaseBuildConfiguration
+	for _, repos := range r.configs() {
+		for _, repoConfigs := range repos {
+			for i := range repoConfigs {
+				if repoConfigs[i].PromotionConfiguration != nil && !cioperatorapi.IsPromotionDisabled(&repoConfigs[i]) {
+					configs = append(configs, &repoConfigs[i])
+				}
+			}
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Metadata.Basename() < configs[j].Metadata.Basename()
+	})
+
+	report := &Report{Generated: now}
+	check := &tagChecker{reporter: r, heads: map[string]headResult{}, commitDates: map[string]time.Time{}, pinned: map[string]sets.String{}}
+	for _, configuration := range configs {
+		metadata := configuration.Metadata
+		head, headErr := check.head(metadata)
+		for _, tag := range expectedTags(configuration, head) {
+			entry := ReportEntry{
+				ImageStreamTag:    tag.reference.ISTagName(),
+				Kind:              tag.kind,
+				Org:               metadata.Org,
+				Repo:              metadata.Repo,
+				Branch:            metadata.Branch,
+				Variant:           metadata.Variant,
+				BranchHEAD:        head,
+				PromotionInFlight: inFlight.Has(fmt.Sprintf("%s/%s@%s", metadata.Org, metadata.Repo, metadata.Branch)),
+			}
+			if headErr != nil {
+				entry.State, entry.Error = TagStateUnknown, headErr.Error()
+			} else {
+				check.tag(ctx, tag, &entry)
+			}
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+	return report, nil
+}
+
+// promotionsInFlight returns the org/repo@branch of the running promotion jobs
+func (r *reporter) promotionsInFlight(ctx context.Context) (sets.String, error) {
+	prowJobs := &prowv1.ProwJobList{}
+	if err := r.prowJobClient.List(ctx, prowJobs, ctrlruntimeclient.InNamespace(r.prowJobNamespace()), ctrlruntimeclient.MatchingLabels{kube.ProwJobTypeLabel: string(prowv1.PostsubmitJob)}); err != nil {
+		return nil, fmt.Errorf("failed to list prowjobs: %w", err)
+	}
+	inFlight := sets.NewString()
+	for _, job := range prowJobs.Items {
+		if job.Complete() || !cioperatorapi.IsPromotionJob(job.Labels) || job.Spec.Refs == nil {
+			continue
+		}
+		inFlight.Insert(fmt.Sprintf("%s/%s@%s", job.Spec.Refs.Org, job.Spec.Refs.Repo, job.Spec.Refs.BaseRef))
+	}
+	return inFlight, nil
+}
+
+type expectedTag struct {
+	reference cioperatorapi.ImageStreamTagReference
+	kind      TagKind
+}
+
+// expectedTags returns the tags the promotion of the configuration at the commit creates
+func expectedTags(configuration *cioperatorapi.ReleaseBuildConfiguration, commit string) []expectedTag {
+	promotedTags, _ := release.PromotedTagsWithRequiredImages(configuration, release.WithCommitSha(commit))
+	promotionConfiguration := configuration.PromotionConfiguration
+	buildCache := cioperatorapi.BuildCacheFor(configuration.Metadata)
+	var tags []expectedTag
+	for _, dsts := range promotedTags {
+		for _, dst := range dsts {
+			kind := TagKindImage
+			component := dst.Name
+			if promotionConfiguration.Name != "" {
+				component = dst.Tag
+			}
+			switch {
+			case dst == buildCache:
+				kind = TagKindBuildCache
+			case promotionConfiguration.TagByCommit && commit != "" && dst.Tag == commit:
+				kind = TagKindTagByCommit
+			default:
+				if _, ok := promotionConfiguration.AdditionalImages[component]; ok {
+					kind = TagKindAdditionalImage
+				}
+			}
+			tags = append(tags, expectedTag{reference: dst, kind: kind})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].reference.ISTagName() < tags[j].reference.ISTagName()
+	})
+	return tags
+}
+
+type headResult struct {
+	head string
+	err  error
+}
+
+// tagChecker checks the tags of one report, caching what many tags share
+type tagChecker struct {
+	*reporter
+	heads       map[string]headResult
+	commitDates map[string]time.Time
+	// pinned holds the pinned tags by namespace/name of their ImageStream
+	pinned map[string]sets.String
+}
+
+func (c *tagChecker) head(metadata cioperatorapi.Metadata) (string, error) {
+	key := fmt.Sprintf("%s/%s@%s", metadata.Org, metadata.Repo, metadata.Branch)
+	if result, ok := c.heads[key]; ok {
+		return result.head, result.err
+	}
+	head, found, err := currentHEADForBranch(c.gitHubClient, metadata, c.log)
+	if err == nil && !found {
+		err = fmt.Errorf("branch %s not found", key)
+	}
+	c.heads[key] = headResult{head: head, err: err}
+	return head, err
+}
+
+func (c *tagChecker) tag(ctx context.Context, tag expectedTag, entry *ReportEntry) {
+	pinned, err := c.isPinned(ctx, tag.reference)
+	if err != nil {
+		entry.State, entry.Error = TagStateUnknown, err.Error()
+		return
+	}
+	if pinned {
+		entry.State = TagStatePinned
+	}
+
+	ist := &imagev1.ImageStreamTag{}
+	if err := c.registryClient.Get(ctx, types.NamespacedName{Namespace: tag.reference.Namespace, Name: fmt.Sprintf("%s:%s", tag.reference.Name, tag.reference.Tag)}, ist); err != nil {
+		if apierrors.IsNotFound(err) {
+			if !pinned {
+				entry.State = TagStateMissing
+			}
+			return
+		}
+		entry.State, entry.Error = TagStateUnknown, fmt.Sprintf("failed to get imagestreamtag: %v", err)
+		return
+	}
+	// The tag is named after the commit, which is all we need to know
+	if tag.kind == TagKindTagByCommit {
+		entry.Commit = tag.reference.Tag
+	} else if entry.Commit, err = commitForIST(ist); err != nil {
+		entry.State, entry.Error = TagStateUnknown, err.Error()
+		return
+	}
+	if pinned {
+		return
+	}
+	if entry.Commit == entry.BranchHEAD {
+		entry.State = TagStateCurrent
+		return
+	}
+	entry.State = TagStateStale
+	entry.LagSeconds = c.lag(entry.Org, entry.Repo, entry.Commit, entry.BranchHEAD).Seconds()
+}
+
+func (c *tagChecker) isPinned(ctx context.Context, reference cioperatorapi.ImageStreamTagReference) (bool, error) {
+	key := fmt.Sprintf("%s/%s", reference.Namespace, reference.Name)
+	pinned, ok := c.pinned[key]
+	if !ok {
+		is := &imagev1.ImageStream{}
+		if err := c.registryClient.Get(ctx, types.NamespacedName{Namespace: reference.Namespace, Name: reference.Name}, is); err != nil && !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to get imagestream: %w", err)
+		}
+		pinned = history.PinnedTags(is)
+		c.pinned[key] = pinned
+	}
+	return pinned.Has(reference.Tag), nil
+}
+
+// lag is how much older the commit is than the HEAD, zero if it cannot be determined
+func (c *tagChecker) lag(org, repo, commit, head string) time.Duration {
+	commitDate, headDate := c.commitDate(org, repo, commit), c.commitDate(org, repo, head)
+	if commitDate.IsZero() || headDate.IsZero() || headDate.Before(commitDate) {
+		return 0
+	}
+	return headDate.Sub(commitDate)
+}
+
+func (c *tagChecker) commitDate(org, repo, sha string) time.Time {
+	key := strings.Join([]string{org, repo, sha}, "/")
+	if date, ok := c.commitDates[key]; ok {
+		return date
+	}
+	commit, err := c.gitHubClient.GetSingleCommit(org, repo, sha)
+	if err != nil {
+		c.log.WithError(err).WithField("commit", key).Debug("Failed to get commit")
+	}
+	c.commitDates[key] = commit.Commit.Committer.Date
+	return commit.Commit.Committer.Date
+}
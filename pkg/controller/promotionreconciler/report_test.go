@@ -0,0 +1,221 @@
+package promotionreconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/promotion/history"
+)
+
+type fakeReportGitHubClient struct {
+	heads       map[string]string
+	commitDates map[string]time.Time
+}
+
+func (c fakeReportGitHubClient) GetRef(org, repo, ref string) (string, error) {
+	head, ok := c.heads[fmt.Sprintf("%s/%s/%s", org, repo, ref)]
+	if !ok {
+		return "", github.NewNotFound()
+	}
+	return head, nil
+}
+
+func (c fakeReportGitHubClient) GetSingleCommit(org, repo, sha string) (github.RepositoryCommit, error) {
+	date, ok := c.commitDates[sha]
+	if !ok {
+		return github.RepositoryCommit{}, github.NewNotFound()
+	}
+	return github.RepositoryCommit{SHA: sha, Commit: github.GitCommit{Committer: github.CommitAuthor{Date: date}}}, nil
+}
+
+func TestExpectedTags(t *testing.T) {
+	configuration := &cioperatorapi.ReleaseBuildConfiguration{
+		Metadata: cioperatorapi.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+		Images:   []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{{To: "cli"}},
+		PromotionConfiguration: &cioperatorapi.PromotionConfiguration{
+			Namespace:        "ci",
+			Tag:              "latest",
+			TagByCommit:      true,
+			AdditionalImages: map[string]string{"extra": "src"},
+		},
+		BinaryBuildCommands: "make",
+	}
+	expected := []expectedTag{
+		{reference: cioperatorapi.ImageStreamTagReference{Namespace: "build-cache", Name: "org-repo", Tag: "master"}, kind: TagKindBuildCache},
+		{reference: cioperatorapi.ImageStreamTagReference{Namespace: "ci", Name: "cli", Tag: "head"}, kind: TagKindTagByCommit},
+		{reference: cioperatorapi.ImageStreamTagReference{Namespace: "ci", Name: "cli", Tag: "latest"}, kind: TagKindImage},
+		{reference: cioperatorapi.ImageStreamTagReference{Namespace: "ci", Name: "extra", Tag: "head"}, kind: TagKindTagByCommit},
+		{reference: cioperatorapi.ImageStreamTagReference{Namespace: "ci", Name: "extra", Tag: "latest"}, kind: TagKindAdditionalImage},
+	}
+	if diff := cmp.Diff(expected, expectedTags(configuration, "head"), cmp.AllowUnexported(expectedTag{})); diff != "" {
+		t.Errorf("expected tags differ from expected:\n%s", diff)
+	}
+}
+
+func TestGenerateReport(t *testing.T) {
+	now := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	ist := func(namespace, name, commit string) *imagev1.ImageStreamTag {
+		return &imagev1.ImageStreamTag{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Image: imagev1.Image{DockerImageMetadata: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"Config":{"Labels":{"io.openshift.build.commit.id":%q}}}`, commit)),
+			}},
+		}
+	}
+	scheme := runtime.NewScheme()
+	if err := imagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register imagev1 scheme: %v", err)
+	}
+	if err := prowv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register prowv1 scheme: %v", err)
+	}
+	registryClient := fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+		ist("ocp", "4.12:cli", "head"),
+		ist("ocp", "4.12:tests", "old"),
+		ist("ocp", "4.12:pinned", "old"),
+		ist("ci", "tool:latest", "old"),
+		&imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Namespace: "ocp", Name: "4.12", Annotations: map[string]string{history.PinnedTagsAnnotation: "pinned"}}},
+	).Build()
+	prowJobClient := fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&prowv1.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "running", Labels: map[string]string{kube.ProwJobTypeLabel: "postsubmit", cioperatorapi.PromotionJobLabelKey: "true"}},
+			Spec:       prowv1.ProwJobSpec{Refs: &prowv1.Refs{Org: "org", Repo: "tool", BaseRef: "master"}},
+		},
+		&prowv1.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "finished", Labels: map[string]string{kube.ProwJobTypeLabel: "postsubmit", cioperatorapi.PromotionJobLabelKey: "true"}},
+			Spec:       prowv1.ProwJobSpec{Refs: &prowv1.Refs{Org: "org", Repo: "repo", BaseRef: "master"}},
+			Status:     prowv1.ProwJobStatus{CompletionTime: &metav1.Time{Time: now}},
+		},
+	).Build()
+
+	r := &reporter{
+		log: logrus.NewEntry(logrus.StandardLogger()),
+		configs: func() config.ByOrgRepo {
+			return config.ByOrgRepo{"org": {
+				"repo": {{
+					Metadata: cioperatorapi.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+					Images:   []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{{To: "cli"}, {To: "tests"}, {To: "pinned"}, {To: "new"}},
+					PromotionConfiguration: &cioperatorapi.PromotionConfiguration{
+						Namespace: "ocp",
+						Name:      "4.12",
+					},
+				}},
+				"tool": {{
+					Metadata:               cioperatorapi.Metadata{Org: "org", Repo: "tool", Branch: "master"},
+					Images:                 []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{{To: "tool"}},
+					PromotionConfiguration: &cioperatorapi.PromotionConfiguration{Namespace: "ci", Tag: "latest", TagByCommit: true},
+				}},
+				"gone": {{
+					Metadata:               cioperatorapi.Metadata{Org: "org", Repo: "gone", Branch: "master"},
+					Images:                 []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{{To: "gone"}},
+					PromotionConfiguration: &cioperatorapi.PromotionConfiguration{Namespace: "ci", Tag: "latest"},
+				}},
+				"disabled": {{
+					Metadata:               cioperatorapi.Metadata{Org: "org", Repo: "disabled", Branch: "master"},
+					Images:                 []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{{To: "disabled"}},
+					PromotionConfiguration: &cioperatorapi.PromotionConfiguration{Namespace: "ci", Tag: "latest", Disabled: true},
+				}},
+			}}
+		},
+		registryClient:   registryClient,
+		prowJobClient:    prowJobClient,
+		prowJobNamespace: func() string { return "ci" },
+		gitHubClient: fakeReportGitHubClient{
+			heads: map[string]string{"org/repo/heads/master": "head", "org/tool/heads/master": "tool-head"},
+			commitDates: map[string]time.Time{
+				"head":      now,
+				"old":       now.Add(-time.Hour),
+				"tool-head": now,
+			},
+		},
+	}
+	r.tags, r.lag = newReporterMetrics()
+
+	report, err := r.generate(context.TODO(), now)
+	if err != nil {
+		t.Fatalf("failed to generate report: %v", err)
+	}
+	expected := &Report{Generated: now, Entries: []ReportEntry{
+		{ImageStreamTag: "ci/gone:latest", Kind: TagKindImage, Org: "org", Repo: "gone", Branch: "master", State: TagStateUnknown, Error: "branch org/gone@master not found"},
+		{ImageStreamTag: "ocp/4.12:cli", Kind: TagKindImage, Org: "org", Repo: "repo", Branch: "master", State: TagStateCurrent, Commit: "head", BranchHEAD: "head"},
+		{ImageStreamTag: "ocp/4.12:new", Kind: TagKindImage, Org: "org", Repo: "repo", Branch: "master", State: TagStateMissing, BranchHEAD: "head"},
+		{ImageStreamTag: "ocp/4.12:pinned", Kind: TagKindImage, Org: "org", Repo: "repo", Branch: "master", State: TagStatePinned, Commit: "old", BranchHEAD: "head"},
+		{ImageStreamTag: "ocp/4.12:tests", Kind: TagKindImage, Org: "org", Repo: "repo", Branch: "master", State: TagStateStale, Commit: "old", BranchHEAD: "head", LagSeconds: 3600},
+		{ImageStreamTag: "ci/tool:latest", Kind: TagKindImage, Org: "org", Repo: "tool", Branch: "master", State: TagStateStale, Commit: "old", BranchHEAD: "tool-head", LagSeconds: 3600, PromotionInFlight: true},
+		{ImageStreamTag: "ci/tool:tool-head", Kind: TagKindTagByCommit, Org: "org", Repo: "tool", Branch: "master", State: TagStateMissing, BranchHEAD: "tool-head", PromotionInFlight: true},
+	}}
+	if diff := cmp.Diff(expected, report); diff != "" {
+		t.Errorf("report differs from expected:\n%s", diff)
+	}
+
+	r.recordMetrics(report)
+	if actual := gaugeValue(t, r.tags.WithLabelValues(string(TagKindImage), string(TagStateStale))); actual != 2 {
+		t.Errorf("expected 2 stale images, got %v", actual)
+	}
+	if actual := gaugeValue(t, r.lag.WithLabelValues("org", "repo", "master")); actual != 3600 {
+		t.Errorf("expected a lag of 3600s, got %v", actual)
+	}
+}
+
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	metric := &dto.Metric{}
+	if err := gauge.Write(metric); err != nil {
+		t.Fatalf("failed to read gauge: %v", err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestServeReport(t *testing.T) {
+	r := &reporter{log: logrus.NewEntry(logrus.StandardLogger())}
+	get := func(query string) (int, Report) {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ReportPath+query, nil))
+		var report Report
+		if recorder.Code == http.StatusOK {
+			if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+				t.Fatalf("failed to unmarshal report: %v", err)
+			}
+		}
+		return recorder.Code, report
+	}
+
+	if code, _ := get(""); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d before the first report, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	generated := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	r.latest = &Report{Generated: generated, Entries: []ReportEntry{
+		{ImageStreamTag: "ocp/4.12:cli", Kind: TagKindImage, State: TagStateCurrent},
+		{ImageStreamTag: "ocp/4.12:tests", Kind: TagKindImage, State: TagStateStale},
+		{ImageStreamTag: "ocp/4.12:extra", Kind: TagKindAdditionalImage, State: TagStateStale},
+	}}
+	code, report := get("?state=stale&kind=image")
+	if code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, code)
+	}
+	expected := Report{Generated: generated, Entries: []ReportEntry{{ImageStreamTag: "ocp/4.12:tests", Kind: TagKindImage, State: TagStateStale}}}
+	if diff := cmp.Diff(expected, report); diff != "" {
+		t.Errorf("report differs from expected:\n%s", diff)
+	}
+}
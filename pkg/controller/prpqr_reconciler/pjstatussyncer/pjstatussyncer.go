@@ -11,8 +11,10 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/util/retry"
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -30,6 +32,10 @@ const (
 	controllerName = "prowjob_status_syncer"
 
 	conditionAllJobsFinished = "AllJobsFinished"
+
+	aggregationIDLabel = "release.openshift.io/aggregation-id"
+	// payloadTagAnnotation holds the payload the release controller runs a job on
+	payloadTagAnnotation = "release.openshift.io/tag"
 )
 
 func AddToManager(mgr controllerruntime.Manager, ns string) error {
@@ -116,27 +122,32 @@ func (r *reconciler) reconcile(ctx context.Context, log *logrus.Entry, req contr
 	if err := r.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: req.Namespace, Name: prpqrName}, prpqr); err != nil {
 		return fmt.Errorf("failed to get the PullRequestPayloadQualificationRun: %s in namespace %s: %w", prpqrName, req.Namespace, err)
 	}
+	basePayload, baseStatus, err := r.baseStatus(ctx, pj, prpqr)
+	if err != nil {
+		return err
+	}
 	prpqrMutations = append(prpqrMutations, func(prpqr *v1.PullRequestPayloadQualificationRun) {
 		for i, job := range prpqr.Status.Jobs {
-			if job.ProwJob == pj.Name && !reflect.DeepEqual(pj.Status, job.Status) {
+			if job.ProwJob != pj.Name {
+				continue
+			}
+			if !reflect.DeepEqual(pj.Status, job.Status) {
 				prpqr.Status.Jobs[i].Status = pj.Status
-
+			}
+			if basePayload != "" {
+				prpqr.Status.Jobs[i].BasePayload = basePayload
+			}
+			if baseStatus != nil {
+				prpqr.Status.Jobs[i].BaseStatus = baseStatus
+				prpqr.Status.Jobs[i].Comparison = compareWithBase(pj.Status, *baseStatus)
 			}
 		}
 	})
 
 	prpqrMutations = append(prpqrMutations, func(prpqr *v1.PullRequestPayloadQualificationRun) {
-		conditionFound := false
-		condition := constructCondition(prpqr.Status.Jobs)
-		for i, conditions := range prpqr.Status.Conditions {
-			if conditions.Type == conditionAllJobsFinished {
-				prpqr.Status.Conditions[i] = condition
-				conditionFound = true
-				break
-			}
-		}
-		if !conditionFound {
-			prpqr.Status.Conditions = append(prpqr.Status.Conditions, condition)
+		setCondition(prpqr, constructCondition(prpqr.Status.Jobs))
+		if hasAllJobsFinished(prpqr.Status.Jobs) {
+			setCondition(prpqr, constructComparisonCondition(prpqr.Status.Jobs))
 		}
 	})
 
@@ -162,6 +173,145 @@ func (r *reconciler) reconcile(ctx context.Context, log *logrus.Entry, req contr
 	return nil
 }
 
+// baseStatus returns the base payload and the status of the job on it, or nil when the job has not
+// finished yet, is aggregated, or its result on the base payload is not known. The job under test
+// does not record the payload it was resolved to, so the base payload is inferred: it is the latest
+// payload the job started to run on before the job under test started, which is the one the job under
+// test was most likely resolved to.
+func (r *reconciler) baseStatus(ctx context.Context, pj *prowv1.ProwJob, prpqr *v1.PullRequestPayloadQualificationRun) (string, *prowv1.ProwJobStatus, error) {
+	if !pj.Complete() {
+		return "", nil, nil
+	}
+	// The aggregator jobs do not have a counterpart running on the base payload
+	if _, aggregated := pj.Labels[aggregationIDLabel]; aggregated {
+		return "", nil, nil
+	}
+	var releaseJobName string
+	for _, job := range prpqr.Status.Jobs {
+		if job.ProwJob == pj.Name {
+			releaseJobName = job.ReleaseJobName
+		}
+	}
+	if releaseJobName == "" {
+		return "", nil, nil
+	}
+
+	// The label holds a truncated job name when it is too long, see decorate.LabelsAndAnnotationsForSpec
+	label := releaseJobName
+	if len(label) > validation.LabelValueMaxLength {
+		label = strings.TrimRight(label[:validation.LabelValueMaxLength], "._-")
+	}
+	runs := &prowv1.ProwJobList{}
+	if err := r.client.List(ctx, runs, ctrlruntimeclient.MatchingLabels{kube.ProwJobAnnotation: label}, ctrlruntimeclient.InNamespace(pj.Namespace)); err != nil {
+		return "", nil, fmt.Errorf("failed to list the runs of job %s: %w", releaseJobName, err)
+	}
+	var payloadRuns []*prowv1.ProwJob
+	for i := range runs.Items {
+		run := &runs.Items[i]
+		if run.Spec.Job != releaseJobName || run.Annotations[payloadTagAnnotation] == "" {
+			continue
+		}
+		if _, ok := run.Labels[v1.PullRequestPayloadQualificationRunLabel]; ok {
+			continue
+		}
+		payloadRuns = append(payloadRuns, run)
+	}
+
+	var basePayload string
+	var baseStarted *metav1.Time
+	for _, run := range payloadRuns {
+		if run.Status.StartTime.After(pj.Status.StartTime.Time) {
+			continue
+		}
+		if baseStarted == nil || baseStarted.Before(&run.Status.StartTime) {
+			basePayload, baseStarted = run.Annotations[payloadTagAnnotation], &run.Status.StartTime
+		}
+	}
+	if basePayload == "" {
+		return "", nil, nil
+	}
+
+	// The job may have been retried on the base payload, the latest result counts
+	var latest *prowv1.ProwJob
+	for _, run := range payloadRuns {
+		if run.Annotations[payloadTagAnnotation] != basePayload || run.Status.CompletionTime == nil {
+			continue
+		}
+		if run.Status.State != prowv1.SuccessState && run.Status.State != prowv1.FailureState {
+			continue
+		}
+		if latest == nil || latest.Status.CompletionTime.Before(run.Status.CompletionTime) {
+			latest = run
+		}
+	}
+	if latest == nil {
+		return basePayload, nil, nil
+	}
+	return basePayload, &latest.Status, nil
+}
+
+// compareWithBase compares the result of a job with its result on the base payload. Only
+// passing and failing jobs are compared, as other results say nothing about the code under test.
+func compareWithBase(status, base prowv1.ProwJobStatus) v1.PayloadJobComparison {
+	if status.State != prowv1.SuccessState && status.State != prowv1.FailureState {
+		return ""
+	}
+	switch passed, basePassed := status.State == prowv1.SuccessState, base.State == prowv1.SuccessState; {
+	case !passed && basePassed:
+		return v1.PayloadJobRegressed
+	case passed && !basePassed:
+		return v1.PayloadJobFixed
+	default:
+		return v1.PayloadJobSameAsBase
+	}
+}
+
+func setCondition(prpqr *v1.PullRequestPayloadQualificationRun, condition metav1.Condition) {
+	for i, existing := range prpqr.Status.Conditions {
+		if existing.Type == condition.Type {
+			prpqr.Status.Conditions[i] = condition
+			return
+		}
+	}
+	prpqr.Status.Conditions = append(prpqr.Status.Conditions, condition)
+}
+
+func constructComparisonCondition(jobs []v1.PullRequestPayloadJobStatus) metav1.Condition {
+	var compared int
+	var regressed, fixed []string
+	for _, job := range jobs {
+		switch job.Comparison {
+		case v1.PayloadJobRegressed:
+			regressed = append(regressed, job.ReleaseJobName)
+		case v1.PayloadJobFixed:
+			fixed = append(fixed, job.ReleaseJobName)
+		}
+		if job.Comparison != "" {
+			compared++
+		}
+	}
+
+	status := metav1.ConditionTrue
+	reason := "NoRegressions"
+	message := fmt.Sprintf("%d of %d jobs were compared with the base payload", compared, len(jobs))
+	if len(regressed) > 0 {
+		status = metav1.ConditionFalse
+		reason = string(v1.PayloadJobRegressed)
+		message = fmt.Sprintf("%s, jobs [%s] regressed", message, strings.Join(regressed, ","))
+	}
+	if len(fixed) > 0 {
+		message = fmt.Sprintf("%s, jobs [%s] were fixed", message, strings.Join(fixed, ","))
+	}
+
+	return metav1.Condition{
+		Type:               v1.ConditionComparedToBase,
+		Status:             status,
+		LastTransitionTime: metav1.Time{Time: time.Now()},
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
 func constructCondition(jobs []v1.PullRequestPayloadJobStatus) metav1.Condition {
 	status := metav1.ConditionTrue
 	message := "All jobs have finished."
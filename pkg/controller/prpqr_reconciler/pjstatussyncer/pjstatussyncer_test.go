@@ -22,6 +22,7 @@ func TestReconcile(t *testing.T) {
 	testCases := []struct {
 		name        string
 		prowjobs    []ctrlruntimeclient.Object
+		baseRuns    []ctrlruntimeclient.Object
 		pjMutations func(pj ctrlruntimeclient.Object, client ctrlruntimeclient.Client, t *testing.T)
 		prpqr       []ctrlruntimeclient.Object
 		expected    []v1.PullRequestPayloadQualificationRun
@@ -138,13 +139,92 @@ func TestReconcile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "jobs are compared with the base payload",
+			prowjobs: []ctrlruntimeclient.Object{
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{UID: "1", Name: "test-pj-regressed", Namespace: "test-namespace", Labels: map[string]string{"pullrequestpayloadqualificationruns.ci.openshift.io": "prpqr-test"}},
+					Status:     prowv1.ProwJobStatus{State: prowv1.TriggeredState, StartTime: metav1.Time{Time: time.Now()}},
+				},
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{UID: "2", Name: "test-pj-fixed", Namespace: "test-namespace", Labels: map[string]string{"pullrequestpayloadqualificationruns.ci.openshift.io": "prpqr-test"}},
+					Status:     prowv1.ProwJobStatus{State: prowv1.TriggeredState, StartTime: metav1.Time{Time: time.Now()}},
+				},
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{UID: "3", Name: "test-pj-no-base", Namespace: "test-namespace", Labels: map[string]string{"pullrequestpayloadqualificationruns.ci.openshift.io": "prpqr-test"}},
+					Status:     prowv1.ProwJobStatus{State: prowv1.TriggeredState, StartTime: metav1.Time{Time: time.Now()}},
+				},
+			},
+			baseRuns: []ctrlruntimeclient.Object{
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "base-previous-payload", Namespace: "test-namespace", Labels: map[string]string{"prow.k8s.io/job": "release-job-regressed"}, Annotations: map[string]string{"release.openshift.io/tag": "payload-1"}},
+					Spec:       prowv1.ProwJobSpec{Job: "release-job-regressed"},
+					Status:     prowv1.ProwJobStatus{State: prowv1.FailureState, StartTime: baseTime, CompletionTime: &baseTime},
+				},
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "base-payload", Namespace: "test-namespace", Labels: map[string]string{"prow.k8s.io/job": "release-job-regressed"}, Annotations: map[string]string{"release.openshift.io/tag": "payload-2"}},
+					Spec:       prowv1.ProwJobSpec{Job: "release-job-regressed"},
+					Status:     prowv1.ProwJobStatus{State: prowv1.SuccessState, StartTime: laterBaseTime, CompletionTime: &laterBaseTime, URL: "https://prow/base-payload"},
+				},
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "base-newer-payload", Namespace: "test-namespace", Labels: map[string]string{"prow.k8s.io/job": "release-job-regressed"}, Annotations: map[string]string{"release.openshift.io/tag": "payload-3"}},
+					Spec:       prowv1.ProwJobSpec{Job: "release-job-regressed"},
+					Status:     prowv1.ProwJobStatus{State: prowv1.FailureState, StartTime: futureTime, CompletionTime: &futureTime},
+				},
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "base-aborted", Namespace: "test-namespace", Labels: map[string]string{"prow.k8s.io/job": "release-job-fixed"}, Annotations: map[string]string{"release.openshift.io/tag": "payload-2"}},
+					Spec:       prowv1.ProwJobSpec{Job: "release-job-fixed"},
+					Status:     prowv1.ProwJobStatus{State: prowv1.AbortedState, StartTime: laterBaseTime, CompletionTime: &laterBaseTime},
+				},
+				&prowv1.ProwJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "base-failed", Namespace: "test-namespace", Labels: map[string]string{"prow.k8s.io/job": "release-job-fixed"}, Annotations: map[string]string{"release.openshift.io/tag": "payload-2"}},
+					Spec:       prowv1.ProwJobSpec{Job: "release-job-fixed"},
+					Status:     prowv1.ProwJobStatus{State: prowv1.FailureState, StartTime: baseTime, CompletionTime: &baseTime},
+				},
+			},
+			pjMutations: func(obj ctrlruntimeclient.Object, client ctrlruntimeclient.Client, t *testing.T) {
+				pj, _ := obj.(*prowv1.ProwJob)
+				pj.Status.State = prowv1.SuccessState
+				if pj.Name == "test-pj-regressed" {
+					pj.Status.State = prowv1.FailureState
+				}
+				pj.Status.CompletionTime = &laterBaseTime
+				if err := client.Update(context.Background(), pj); err != nil {
+					t.Fatal(err)
+				}
+			},
+			prpqr: []ctrlruntimeclient.Object{
+				&v1.PullRequestPayloadQualificationRun{
+					ObjectMeta: metav1.ObjectMeta{Name: "prpqr-test", Namespace: "test-namespace"},
+					Status: v1.PullRequestPayloadTestStatus{
+						Jobs: []v1.PullRequestPayloadJobStatus{
+							{
+								ReleaseJobName: "release-job-regressed",
+								ProwJob:        "test-pj-regressed",
+								Status:         prowv1.ProwJobStatus{State: prowv1.TriggeredState},
+							},
+							{
+								ReleaseJobName: "release-job-fixed",
+								ProwJob:        "test-pj-fixed",
+								Status:         prowv1.ProwJobStatus{State: prowv1.TriggeredState},
+							},
+							{
+								ReleaseJobName: "release-job-no-base",
+								ProwJob:        "test-pj-no-base",
+								Status:         prowv1.ProwJobStatus{State: prowv1.TriggeredState},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			r := &reconciler{
 				logger: logrus.WithField("test-name", tc.name),
-				client: fakectrlruntimeclient.NewClientBuilder().WithObjects(append(tc.prowjobs, tc.prpqr...)...).Build(),
+				client: fakectrlruntimeclient.NewClientBuilder().WithObjects(append(append(tc.prowjobs, tc.baseRuns...), tc.prpqr...)...).Build(),
 			}
 
 			for _, pj := range tc.prowjobs {
@@ -170,7 +250,11 @@ func TestReconcile(t *testing.T) {
 }
 
 var (
-	zeroTime = metav1.NewTime(time.Unix(0, 0))
+	zeroTime      = metav1.NewTime(time.Unix(0, 0))
+	baseTime      = metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	laterBaseTime = metav1.NewTime(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC))
+	// futureTime is after the jobs under test started
+	futureTime = metav1.NewTime(time.Now().Add(time.Hour).Truncate(time.Second))
 )
 
 func prunePRPQRForTests(items []v1.PullRequestPayloadQualificationRun) {
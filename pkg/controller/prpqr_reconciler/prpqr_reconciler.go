@@ -164,7 +164,8 @@ func (r *reconciler) reconcile(ctx context.Context, req reconcile.Request, logge
 		statusByJobName[jobName] = &prpqr.Status.Jobs[i]
 	}
 
-	baseMetadata := metadataFromPullRequestUnderTest(prpqr.Spec.PullRequest)
+	prs := prpqr.Spec.PullRequestsUnderTest()
+	baseMetadata := metadataFromPullRequestUnderTest(prs[0])
 	for _, jobSpec := range prpqr.Spec.Jobs.Jobs {
 		var prowjobsToCreate []*prowv1.ProwJob
 		mimickedJob := jobSpec.JobName(jobconfig.PeriodicPrefix)
@@ -216,7 +217,7 @@ func (r *reconciler) reconcile(ctx context.Context, req reconcile.Request, logge
 
 		if jobSpec.AggregatedCount > 0 {
 			uid := jobNameHash(req.Name + mimickedJob)
-			aggregatedProwjobs, err := generateAggregatedProwjobs(uid, ciopConfig, r.prowConfigGetter.Config(), baseMetadata, req.Name, req.Namespace, &jobSpec, &prpqr.Spec.PullRequest, inject)
+			aggregatedProwjobs, err := generateAggregatedProwjobs(uid, ciopConfig, r.prowConfigGetter.Config(), baseMetadata, req.Name, req.Namespace, &jobSpec, prs, inject)
 			if err != nil {
 				logger.WithError(err).Error("Failed to generate the aggregated prowjobs")
 				statuses[mimickedJob] = &v1.PullRequestPayloadJobStatus{
@@ -230,7 +231,7 @@ func (r *reconciler) reconcile(ctx context.Context, req reconcile.Request, logge
 			}
 			prowjobsToCreate = append(prowjobsToCreate, aggregatedProwjobs...)
 
-			submitted := generateJobNameToSubmit(baseMetadata, inject, &prpqr.Spec.PullRequest)
+			submitted := generateJobNameToSubmit(baseMetadata, inject, prs)
 			aggregatorJob, err := generateAggregatorJob(baseMetadata, uid, mimickedJob, jobSpec.JobName(jobconfig.PeriodicPrefix), req.Name, req.Namespace, r.prowConfigGetter.Config(), time.Now(), submitted)
 			if err != nil {
 				logger.WithError(err).Error("Failed to generate an aggregator prowjob")
@@ -251,7 +252,7 @@ func (r *reconciler) reconcile(ctx context.Context, req reconcile.Request, logge
 			prowjobsToCreate = append(prowjobsToCreate, aggregatorJob)
 
 		} else {
-			prowjob, err := generateProwjob(ciopConfig, r.prowConfigGetter.Config(), baseMetadata, req.Name, req.Namespace, &prpqr.Spec.PullRequest, mimickedJob, inject, nil)
+			prowjob, err := generateProwjob(ciopConfig, r.prowConfigGetter.Config(), baseMetadata, req.Name, req.Namespace, prs, mimickedJob, inject, nil)
 			if err != nil {
 				logger.WithError(err).Error("Failed to generate prowjob")
 				statuses[mimickedJob] = &v1.PullRequestPayloadJobStatus{
@@ -441,7 +442,7 @@ type aggregatedOptions struct {
 	releaseJobName  string
 }
 
-func generateProwjob(ciopConfig *api.ReleaseBuildConfiguration, defaulter periodicDefaulter, baseCiop *api.Metadata, prpqrName, prpqrNamespace string, pr *v1.PullRequestUnderTest, mimickedJob string, inject *api.MetadataWithTest, aggregatedOptions *aggregatedOptions) (*prowv1.ProwJob, error) {
+func generateProwjob(ciopConfig *api.ReleaseBuildConfiguration, defaulter periodicDefaulter, baseCiop *api.Metadata, prpqrName, prpqrNamespace string, prs []v1.PullRequestUnderTest, mimickedJob string, inject *api.MetadataWithTest, aggregatedOptions *aggregatedOptions) (*prowv1.ProwJob, error) {
 	fakeProwgenInfo := &prowgen.ProwgenInfo{Metadata: *baseCiop}
 
 	annotations := map[string]string{
@@ -498,7 +499,7 @@ func generateProwjob(ciopConfig *api.ReleaseBuildConfiguration, defaulter period
 		periodic = prowgen.GeneratePeriodicForTest(jobBaseGen, fakeProwgenInfo, prowgen.FromConfigSpec(ciopConfig), func(options *prowgen.GeneratePeriodicOptions) {
 			options.Cron = "@yearly"
 		})
-		periodic.Name = generateJobNameToSubmit(baseCiop, inject, pr)
+		periodic.Name = generateJobNameToSubmit(baseCiop, inject, prs)
 		break
 	}
 	// We did not find the injected test: this is a bug
@@ -506,29 +507,7 @@ func generateProwjob(ciopConfig *api.ReleaseBuildConfiguration, defaulter period
 		return nil, fmt.Errorf("BUG: test '%s' not found in injected config", inject.Test)
 	}
 
-	extraRefs := prowv1.Refs{
-		Org:  baseCiop.Org,
-		Repo: baseCiop.Repo,
-		// TODO(muller): All these commented-out fields need to be propagated via the PRPQR spec
-		// We do not need them now but we should eventually wire them through
-		// RepoLink:  pr.Base.Repo.HTMLURL,
-		BaseRef: pr.BaseRef,
-		BaseSHA: pr.BaseSHA,
-		// BaseLink:  fmt.Sprintf("%s/commit/%s", pr.Base.Repo.HTMLURL, pr.BaseSHA),
-		PathAlias: periodic.ExtraRefs[0].PathAlias,
-		Pulls: []prowv1.Pull{
-			{
-				Number: pr.PullRequest.Number,
-				Author: pr.PullRequest.Author,
-				SHA:    pr.PullRequest.SHA,
-				Title:  pr.PullRequest.Title,
-				// Link:       pr.HTMLURL,
-				// AuthorLink: pr.User.HTMLURL,
-				// CommitLink: fmt.Sprintf("%s/pull/%d/commits/%s", pr.Base.Repo.HTMLURL, pr.Number, pr.Head.SHA),
-			},
-		},
-	}
-	periodic.ExtraRefs = []prowv1.Refs{extraRefs}
+	periodic.ExtraRefs = extraRefsForPullRequests(prs, periodic.ExtraRefs[0].PathAlias)
 
 	if err := defaulter.DefaultPeriodic(periodic); err != nil {
 		return nil, fmt.Errorf("failed to default the ProwJob: %w", err)
@@ -540,11 +519,52 @@ func generateProwjob(ciopConfig *api.ReleaseBuildConfiguration, defaulter period
 	return &pj, nil
 }
 
+// extraRefsForPullRequests returns the refs to clone for the PRs under test: the PRs are grouped
+// by the repository and branch they target, in the order each group first appears, so the first
+// ref is always the repository under test. Only that one gets the pathAlias from its configuration;
+// ci-operator sets the path alias of the others when it loads their configuration.
+func extraRefsForPullRequests(prs []v1.PullRequestUnderTest, pathAlias string) []prowv1.Refs {
+	var refs []prowv1.Refs
+	indexByTarget := map[string]int{}
+	for _, pr := range prs {
+		target := fmt.Sprintf("%s/%s@%s", pr.Org, pr.Repo, pr.BaseRef)
+		index, exists := indexByTarget[target]
+		if !exists {
+			index = len(refs)
+			indexByTarget[target] = index
+			ref := prowv1.Refs{
+				Org:  pr.Org,
+				Repo: pr.Repo,
+				// TODO(muller): All these commented-out fields need to be propagated via the PRPQR spec
+				// We do not need them now but we should eventually wire them through
+				// RepoLink:  pr.Base.Repo.HTMLURL,
+				BaseRef: pr.BaseRef,
+				BaseSHA: pr.BaseSHA,
+				// BaseLink:  fmt.Sprintf("%s/commit/%s", pr.Base.Repo.HTMLURL, pr.BaseSHA),
+			}
+			if index == 0 {
+				ref.PathAlias = pathAlias
+			}
+			refs = append(refs, ref)
+		}
+		refs[index].Pulls = append(refs[index].Pulls, prowv1.Pull{
+			Number: pr.PullRequest.Number,
+			Author: pr.PullRequest.Author,
+			SHA:    pr.PullRequest.SHA,
+			Title:  pr.PullRequest.Title,
+			// Link:       pr.HTMLURL,
+			// AuthorLink: pr.User.HTMLURL,
+			// CommitLink: fmt.Sprintf("%s/pull/%d/commits/%s", pr.Base.Repo.HTMLURL, pr.Number, pr.Head.SHA),
+		})
+	}
+	return refs
+}
+
 func metadataFromPullRequestUnderTest(pr v1.PullRequestUnderTest) *api.Metadata {
 	return &api.Metadata{Org: pr.Org, Repo: pr.Repo, Branch: pr.BaseRef}
 }
 
-func generateAggregatedProwjobs(uid string, ciopConfig *api.ReleaseBuildConfiguration, defaulter periodicDefaulter, baseCiop *api.Metadata, prpqrName, prpqrNamespace string, spec *v1.ReleaseJobSpec, pr *v1.PullRequestUnderTest, inject *api.MetadataWithTest) ([]*prowv1.ProwJob, error) {
+func generateAggregatedProwjobs(uid string, ciopConfig *api.ReleaseBuildConfiguration, defaulter periodicDefaulter, baseCiop *api.Metadata, prpqrName, prpqrNamespace string, spec *v1.ReleaseJobSpec, prs []v1.PullRequestUnderTest, inject *api.MetadataWithTest) ([]*prowv1.ProwJob, error) {
 	var ret []*prowv1.ProwJob
 
 	for i := 0; i < spec.AggregatedCount; i++ {
@@ -555,7 +575,7 @@ func generateAggregatedProwjobs(uid string, ciopConfig *api.ReleaseBuildConfigur
 		}
 		jobName := fmt.Sprintf("%s-%d", spec.JobName(jobconfig.PeriodicPrefix), i)
 
-		pj, err := generateProwjob(ciopConfig, defaulter, baseCiop, prpqrName, prpqrNamespace, pr, jobName, inject, opts)
+		pj, err := generateProwjob(ciopConfig, defaulter, baseCiop, prpqrName, prpqrNamespace, prs, jobName, inject, opts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create prowjob: %w", err)
 		}
@@ -630,10 +650,19 @@ func generateAggregatorJob(baseCiop *api.Metadata, uid, aggregatorJobName, jobNa
 	return &pj, nil
 }
 
-func generateJobNameToSubmit(baseCiop *api.Metadata, inject *api.MetadataWithTest, pr *v1.PullRequestUnderTest) string {
+func generateJobNameToSubmit(baseCiop *api.Metadata, inject *api.MetadataWithTest, prs []v1.PullRequestUnderTest) string {
 	var variant string
 	if inject.Variant != "" {
 		variant = fmt.Sprintf("-%s", inject.Variant)
 	}
-	return fmt.Sprintf("%s-%s-%d%s-%s", baseCiop.Org, baseCiop.Repo, pr.PullRequest.Number, variant, inject.Test)
+	// The name is used as a label value, so it must not grow with the number of PRs
+	prID := strconv.Itoa(prs[0].PullRequest.Number)
+	if len(prs) > 1 {
+		var numbers []string
+		for _, pr := range prs {
+			numbers = append(numbers, strconv.Itoa(pr.PullRequest.Number))
+		}
+		prID = fmt.Sprintf("%s-%s", prID, jobNameHash(strings.Join(numbers, "-"))[:8])
+	}
+	return fmt.Sprintf("%s-%s-%s%s-%s", baseCiop.Org, baseCiop.Repo, prID, variant, inject.Test)
 }
@@ -0,0 +1,386 @@
+package testimagesdistributor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	crcontrollerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	apihelper "github.com/openshift/ci-tools/pkg/api/helper"
+	"github.com/openshift/ci-tools/pkg/jobconfig"
+	"github.com/openshift/ci-tools/pkg/util/imagestreamtagwrapper"
+)
+
+// PrePullerName is the name of the DaemonSet that keeps the test images pulled on the nodes of a
+// build farm cluster, and of its ServiceAccount and RoleBindings
+const PrePullerName = "test-images-prepuller"
+
+// internalRegistry is how nodes reach the integrated registry of their cluster
+const internalRegistry = "image-registry.openshift-image-registry.svc:5000"
+
+// decompressionFactor estimates the disk space an image takes on a node from the compressed size
+// of its layers, which is all the registry knows about. Layers typically unpack to two to three
+// times their size, so the upper end keeps the pre-pulled images within the budget.
+const decompressionFactor = 3
+
+// PrewarmOptions configures keeping the most used test images pulled on the nodes of the build farm clusters
+type PrewarmOptions struct {
+	// Budgets is the disk space on every node the pre-pulled images may use once unpacked, by cluster.
+	// Clusters without a budget are not pre-warmed.
+	Budgets map[string]resource.Quantity
+	// Namespace is where the pre-puller DaemonSet lives
+	Namespace string
+	Interval  time.Duration
+	// Window is how far back to look at ProwJobs to determine how often images are used
+	Window           time.Duration
+	ProwJobNamespace func() string
+	// InstallerImage is an amd64 image with a statically linked busybox at /bin/busybox, pinned by digest
+	InstallerImage string
+}
+
+type configGetter interface {
+	GetMatchingConfig(metadata api.Metadata) (api.ReleaseBuildConfiguration, error)
+}
+
+// AddPrewarmerToManager adds a runnable that periodically determines the images the jobs on each build farm
+// cluster used most and keeps as many of them pre-pulled on the nodes of the cluster as fit into its budget.
+func AddPrewarmerToManager(mgr manager.Manager, buildClusterManagers map[string]manager.Manager, configAgent configGetter, resolver registryResolver, opts PrewarmOptions) error {
+	p := &prewarmer{
+		log:           logrus.WithField("controller", ControllerName).WithField("component", "prewarmer"),
+		prowJobClient: mgr.GetClient(),
+		clients:       map[string]ctrlruntimeclient.Client{},
+		configAgent:   configAgent,
+		resolver:      resolver,
+		opts:          opts,
+		now:           time.Now,
+		images: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "test_images_distributor_prewarmed_images",
+			Help: "The number of images kept pulled on the nodes of a cluster",
+		}, []string{"cluster"}),
+		bytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "test_images_distributor_prewarmed_bytes",
+			Help: "The estimated unpacked size of the images kept pulled on the nodes of a cluster",
+		}, []string{"cluster"}),
+	}
+	for _, collector := range []prometheus.Collector{p.images, p.bytes} {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+	for cluster := range opts.Budgets {
+		clusterManager, ok := buildClusterManagers[cluster]
+		if !ok {
+			return fmt.Errorf("there is a pre-warming budget for cluster %s, but no such cluster", cluster)
+		}
+		p.clients[cluster] = imagestreamtagwrapper.MustNew(clusterManager.GetClient(), clusterManager.GetCache())
+	}
+	if err := mgr.Add(p); err != nil {
+		return fmt.Errorf("failed to add the prewarmer to the manager: %w", err)
+	}
+	return nil
+}
+
+type prewarmer struct {
+	log           *logrus.Entry
+	prowJobClient ctrlruntimeclient.Client
+	clients       map[string]ctrlruntimeclient.Client
+	configAgent   configGetter
+	resolver      registryResolver
+	opts          PrewarmOptions
+	now           func() time.Time
+
+	images *prometheus.GaugeVec
+	bytes  *prometheus.GaugeVec
+}
+
+// Start pre-warms every interval until the context is cancelled
+func (p *prewarmer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.prewarm(ctx); err != nil {
+			p.log.WithError(err).Error("Failed to pre-warm build farm clusters")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *prewarmer) prewarm(ctx context.Context) error {
+	usage, err := p.usageByCluster(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, cluster := range sets.StringKeySet(p.clients).List() {
+		log := p.log.WithField("cluster", cluster)
+		budget := p.opts.Budgets[cluster]
+		images, size, err := selectImages(ctx, p.clients[cluster], usage[cluster], budget.Value(), log)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to select images for cluster %s: %w", cluster, err))
+			continue
+		}
+		if err := p.ensurePrePuller(ctx, p.clients[cluster], images, log); err != nil {
+			errs = append(errs, fmt.Errorf("failed to ensure the pre-puller on cluster %s: %w", cluster, err))
+			continue
+		}
+		p.images.WithLabelValues(cluster).Set(float64(len(images)))
+		p.bytes.WithLabelValues(cluster).Set(float64(size))
+		log.WithField("images", len(images)).WithField("bytes", size).Info("Pre-warmed cluster")
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// imageUsage is how many jobs used an ImageStreamTag
+type imageUsage struct {
+	tag  types.NamespacedName
+	jobs int
+}
+
+// usageByCluster counts how many of the ProwJobs that started within the window on each cluster used
+// each test input image, most used images first
+func (p *prewarmer) usageByCluster(ctx context.Context) (map[string][]imageUsage, error) {
+	jobs := &prowv1.ProwJobList{}
+	if err := p.prowJobClient.List(ctx, jobs, ctrlruntimeclient.InNamespace(p.opts.ProwJobNamespace())); err != nil {
+		return nil, fmt.Errorf("failed to list prowjobs: %w", err)
+	}
+	since := p.now().Add(-p.opts.Window)
+	inputsByConfig := map[string][]types.NamespacedName{}
+	counts := map[string]map[types.NamespacedName]int{}
+	for _, job := range jobs.Items {
+		if _, budgeted := p.clients[job.Spec.Cluster]; !budgeted || job.Status.StartTime.Time.Before(since) {
+			continue
+		}
+		metadata := api.Metadata{
+			Org:     job.Labels[kube.OrgLabel],
+			Repo:    job.Labels[kube.RepoLabel],
+			Branch:  job.Labels[kube.BaseRefLabel],
+			Variant: job.Labels[jobconfig.ProwJobLabelVariant],
+		}
+		if metadata.Org == "" || metadata.Repo == "" || metadata.Branch == "" {
+			continue
+		}
+		inputs, seen := inputsByConfig[metadata.AsString()]
+		if !seen {
+			inputs = p.testInputs(metadata)
+			inputsByConfig[metadata.AsString()] = inputs
+		}
+		if counts[job.Spec.Cluster] == nil {
+			counts[job.Spec.Cluster] = map[types.NamespacedName]int{}
+		}
+		for _, tag := range inputs {
+			counts[job.Spec.Cluster][tag]++
+		}
+	}
+
+	usage := map[string][]imageUsage{}
+	for cluster, tags := range counts {
+		for tag, jobs := range tags {
+			usage[cluster] = append(usage[cluster], imageUsage{tag: tag, jobs: jobs})
+		}
+		sort.Slice(usage[cluster], func(i, j int) bool {
+			if usage[cluster][i].jobs != usage[cluster][j].jobs {
+				return usage[cluster][i].jobs > usage[cluster][j].jobs
+			}
+			return usage[cluster][i].tag.String() < usage[cluster][j].tag.String()
+		})
+	}
+	return usage, nil
+}
+
+func (p *prewarmer) testInputs(metadata api.Metadata) []types.NamespacedName {
+	log := p.log.WithField("config", metadata.AsString())
+	cfg, err := p.configAgent.GetMatchingConfig(metadata)
+	if err != nil {
+		// not every job is a ci-operator job
+		log.WithError(err).Debug("No configuration for job")
+		return nil
+	}
+	resolved, err := p.resolver.ResolveConfig(cfg)
+	if err != nil {
+		log.WithError(err).Warn("Failed to resolve configuration")
+		return nil
+	}
+	tags, err := apihelper.TestInputImageStreamTagsFromResolvedConfig(resolved)
+	if err != nil {
+		log.WithError(err).Warn("Failed to get the test input images of configuration")
+	}
+	var inputs []types.NamespacedName
+	for _, tag := range tags {
+		inputs = append(inputs, tag)
+	}
+	return inputs
+}
+
+type prePulledImage struct {
+	namespace string
+	pullSpec  string
+	size      int64
+}
+
+// selectImages picks the most used images that were distributed to the cluster until the budget is exhausted.
+// The size of an image is the estimated size of its unpacked layers.
+func selectImages(ctx context.Context, client ctrlruntimeclient.Client, usage []imageUsage, budget int64, log *logrus.Entry) ([]prePulledImage, int64, error) {
+	var images []prePulledImage
+	var total int64
+	for _, used := range usage {
+		istag := &imagev1.ImageStreamTag{}
+		if err := client.Get(ctx, used.tag, istag); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, 0, fmt.Errorf("failed to get imagestreamtag %s: %w", used.tag, err)
+		}
+		var size int64
+		for _, layer := range istag.Image.DockerImageLayers {
+			size += layer.LayerSize * decompressionFactor
+		}
+		if size == 0 {
+			log.WithField("imagestreamtag", used.tag.String()).Debug("Image has no layer sizes, not pre-warming it")
+			continue
+		}
+		if total+size > budget {
+			continue
+		}
+		total += size
+		stream, err := imageStreamNameFromImageStreamTagName(used.tag)
+		if err != nil {
+			return nil, 0, err
+		}
+		images = append(images, prePulledImage{
+			namespace: used.tag.Namespace,
+			pullSpec:  fmt.Sprintf("%s/%s/%s@%s", internalRegistry, stream.Namespace, stream.Name, istag.Image.Name),
+			size:      size,
+		})
+	}
+	return images, total, nil
+}
+
+func (p *prewarmer) ensurePrePuller(ctx context.Context, client ctrlruntimeclient.Client, images []prePulledImage, log *logrus.Entry) error {
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: p.opts.Namespace, Name: PrePullerName}}
+	if err := upsertObject(ctx, client, serviceAccount, func() error { return nil }, log); err != nil {
+		return fmt.Errorf("failed to ensure serviceaccount: %w", err)
+	}
+	namespaces := sets.NewString()
+	for _, image := range images {
+		namespaces.Insert(image.namespace)
+	}
+	for _, namespace := range namespaces.List() {
+		roleBinding, mutateFn := prePullerRoleBinding(namespace, p.opts.Namespace)
+		if err := upsertObject(ctx, client, roleBinding, mutateFn, log); err != nil {
+			return fmt.Errorf("failed to ensure rolebinding in namespace %s: %w", namespace, err)
+		}
+	}
+	daemonSet, mutateFn := prePullerDaemonSet(p.opts.Namespace, p.opts.InstallerImage, images)
+	return upsertObject(ctx, client, daemonSet, mutateFn, log)
+}
+
+// prePullerRoleBinding allows the pre-puller to pull the images in a namespace from the integrated registry
+func prePullerRoleBinding(namespace, prePullerNamespace string) (*rbacv1.RoleBinding, crcontrollerutil.MutateFn) {
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      PrePullerName,
+		},
+	}
+	return rb, func() error {
+		rb.Subjects = []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      PrePullerName,
+			Namespace: prePullerNamespace,
+		}}
+		rb.RoleRef = rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "system:image-puller",
+		}
+		return nil
+	}
+}
+
+const (
+	// prePullerBinaries is where the pre-puller installs a static binary that sleeps forever,
+	// so it does not depend on the pre-pulled images having any
+	prePullerBinaries = "/pre-puller"
+	// busybox decides what to run by the name it is invoked by
+	prePullerSleep = prePullerBinaries + "/sleep"
+)
+
+// prePullerDaemonSet runs every image in its own container on every amd64 node, as the test images
+// are built for amd64. The containers run a static `sleep` binary that the init container installs from
+// the installer image into a shared volume, so it works for images without a shell or any binary at all.
+// The containers start independently, so an image that fails to pull does not keep the others from
+// being pulled, and the running containers keep their images from being garbage collected by the kubelet.
+func prePullerDaemonSet(namespace, installerImage string, images []prePulledImage) (*appsv1.DaemonSet, crcontrollerutil.MutateFn) {
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      PrePullerName,
+		},
+	}
+	labels := map[string]string{"app": PrePullerName}
+	return ds, func() error {
+		ds.Labels = labels
+		ds.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		ds.Spec.Template.Labels = labels
+		ds.Spec.Template.Spec.ServiceAccountName = PrePullerName
+		ds.Spec.Template.Spec.NodeSelector = map[string]string{corev1.LabelArchStable: string(api.AMD64Arch)}
+		ds.Spec.Template.Spec.Tolerations = []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+		ds.Spec.Template.Spec.TerminationGracePeriodSeconds = new(int64)
+		ds.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "bin", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+		mounts := []corev1.VolumeMount{{Name: "bin", MountPath: prePullerBinaries}}
+		resources := corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1m"),
+			corev1.ResourceMemory: resource.MustParse("4Mi"),
+		}}
+		ds.Spec.Template.Spec.InitContainers = []corev1.Container{{
+			Name:         "install",
+			Image:        installerImage,
+			Command:      []string{"cp", "/bin/busybox", prePullerSleep},
+			VolumeMounts: mounts,
+			Resources:    resources,
+		}}
+		var containers []corev1.Container
+		for i, image := range images {
+			containers = append(containers, corev1.Container{
+				Name:            fmt.Sprintf("image-%d", i),
+				Image:           image.pullSpec,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         []string{prePullerSleep, "infinity"},
+				VolumeMounts:    mounts,
+				Resources:       resources,
+			})
+		}
+		if len(containers) == 0 {
+			// a pod needs at least one container
+			containers = []corev1.Container{{Name: "idle", Image: installerImage, Command: []string{prePullerSleep, "infinity"}, VolumeMounts: mounts, Resources: resources}}
+		}
+		ds.Spec.Template.Spec.Containers = containers
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package testimagesdistributor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+type fakeConfigGetter map[string]api.ReleaseBuildConfiguration
+
+func (f fakeConfigGetter) GetMatchingConfig(metadata api.Metadata) (api.ReleaseBuildConfiguration, error) {
+	cfg, ok := f[metadata.AsString()]
+	if !ok {
+		return api.ReleaseBuildConfiguration{}, fmt.Errorf("no config for %s", metadata.AsString())
+	}
+	return cfg, nil
+}
+
+func configWithBaseImages(tags ...string) api.ReleaseBuildConfiguration {
+	images := map[string]api.ImageStreamTagReference{}
+	for _, tag := range tags {
+		images[tag] = api.ImageStreamTagReference{Namespace: "ci", Name: tag, Tag: "1"}
+	}
+	return api.ReleaseBuildConfiguration{InputConfiguration: api.InputConfiguration{BaseImages: images}}
+}
+
+func TestPrewarm(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	job := func(name, cluster, repo string, started time.Time) *prowv1.ProwJob {
+		return &prowv1.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ci",
+				Name:      name,
+				Labels:    map[string]string{kube.OrgLabel: "org", kube.RepoLabel: repo, kube.BaseRefLabel: "master"},
+			},
+			Spec:   prowv1.ProwJobSpec{Cluster: cluster},
+			Status: prowv1.ProwJobStatus{StartTime: metav1.NewTime(started)},
+		}
+	}
+	istag := func(name string, sizes ...int64) *imagev1.ImageStreamTag {
+		tag := &imagev1.ImageStreamTag{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: name},
+			Image:      imagev1.Image{ObjectMeta: metav1.ObjectMeta{Name: "sha256:" + name[:1]}},
+		}
+		for _, size := range sizes {
+			tag.Image.DockerImageLayers = append(tag.Image.DockerImageLayers, imagev1.ImageLayer{LayerSize: size})
+		}
+		return tag
+	}
+
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{prowv1.AddToScheme, imagev1.AddToScheme, appsv1.AddToScheme, corev1.AddToScheme, rbacv1.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatalf("failed to build scheme: %v", err)
+		}
+	}
+	prowJobClient := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(
+		job("a-1", "build01", "repo", now.Add(-time.Hour)),
+		job("a-2", "build01", "repo", now.Add(-2*time.Hour)),
+		job("a-3", "build01", "repo", now.Add(-3*time.Hour)),
+		job("other-1", "build01", "other", now.Add(-time.Hour)),
+		job("old", "build01", "other", now.Add(-48*time.Hour)),
+		job("unbudgeted", "build02", "other", now.Add(-time.Hour)),
+		job("no-config", "build01", "unknown", now.Add(-time.Hour)),
+	).Build()
+	buildClient := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(
+		istag("a:1", 40, 20),
+		istag("b:1", 50),
+		istag("c:1", 30),
+	).Build()
+
+	p := &prewarmer{
+		log:           logrus.NewEntry(logrus.StandardLogger()),
+		prowJobClient: prowJobClient,
+		clients:       map[string]ctrlruntimeclient.Client{"build01": buildClient},
+		configAgent: fakeConfigGetter{
+			"org/repo@master":  configWithBaseImages("a", "b"),
+			"org/other@master": configWithBaseImages("b", "c"),
+		},
+		resolver: noOpRegistryResolver{},
+		opts: PrewarmOptions{
+			Budgets:          map[string]resource.Quantity{"build01": *resource.NewQuantity(300, resource.DecimalSI)},
+			Namespace:        "ci",
+			Window:           24 * time.Hour,
+			ProwJobNamespace: func() string { return "ci" },
+			InstallerImage:   "docker.io/library/busybox@sha256:installer",
+		},
+		now:    func() time.Time { return now },
+		images: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "images"}, []string{"cluster"}),
+		bytes:  prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bytes"}, []string{"cluster"}),
+	}
+
+	usage, err := p.usageByCluster(context.Background())
+	if err != nil {
+		t.Fatalf("failed to determine usage: %v", err)
+	}
+	expectedUsage := map[string][]imageUsage{"build01": {
+		{tag: types.NamespacedName{Namespace: "ci", Name: "b:1"}, jobs: 4},
+		{tag: types.NamespacedName{Namespace: "ci", Name: "a:1"}, jobs: 3},
+		{tag: types.NamespacedName{Namespace: "ci", Name: "c:1"}, jobs: 1},
+	}}
+	if diff := cmp.Diff(expectedUsage, usage, cmp.AllowUnexported(imageUsage{})); diff != "" {
+		t.Errorf("usage differs from expected: %s", diff)
+	}
+
+	if err := p.prewarm(context.Background()); err != nil {
+		t.Fatalf("failed to pre-warm: %v", err)
+	}
+	ds := &appsv1.DaemonSet{}
+	if err := buildClient.Get(context.Background(), types.NamespacedName{Namespace: "ci", Name: PrePullerName}, ds); err != nil {
+		t.Fatalf("failed to get daemonset: %v", err)
+	}
+	if initContainers := ds.Spec.Template.Spec.InitContainers; len(initContainers) != 1 || initContainers[0].Image != "docker.io/library/busybox@sha256:installer" {
+		t.Errorf("expected a single init container installing from the installer image, got %v", initContainers)
+	}
+	if diff := cmp.Diff(map[string]string{"kubernetes.io/arch": "amd64"}, ds.Spec.Template.Spec.NodeSelector); diff != "" {
+		t.Errorf("node selector differs from expected: %s", diff)
+	}
+	var images []string
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		images = append(images, container.Image)
+		if !reflect.DeepEqual(container.Command, []string{"/pre-puller/sleep", "infinity"}) {
+			t.Errorf("container %s does not run the installed binary: %v", container.Name, container.Command)
+		}
+	}
+	// a:1 is used more often than c:1, but does not fit next to b:1 once unpacked
+	expectedImages := []string{
+		"image-registry.openshift-image-registry.svc:5000/ci/b@sha256:b",
+		"image-registry.openshift-image-registry.svc:5000/ci/c@sha256:c",
+	}
+	if diff := cmp.Diff(expectedImages, images); diff != "" {
+		t.Errorf("pre-pulled images differ from expected: %s", diff)
+	}
+	if err := buildClient.Get(context.Background(), types.NamespacedName{Namespace: "ci", Name: PrePullerName}, &rbacv1.RoleBinding{}); err != nil {
+		t.Errorf("failed to get rolebinding: %v", err)
+	}
+	if err := buildClient.Get(context.Background(), types.NamespacedName{Namespace: "ci", Name: PrePullerName}, &corev1.ServiceAccount{}); err != nil {
+		t.Errorf("failed to get serviceaccount: %v", err)
+	}
+}
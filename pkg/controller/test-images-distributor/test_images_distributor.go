@@ -483,6 +483,10 @@ func (r *reconciler) ensureCIOperatorRoleBinding(ctx context.Context, namespace
 // to copy the annotation if it exists
 const releaseConfigAnnotation = "release.openshift.io/config"
 
+// DistributedLabel is set on the ImageStreams the controller imports tags into, the
+// imagestreamtag_gc deletes the unreferenced tags of ImageStreams with this label
+const DistributedLabel = "ci.openshift.io/test-images-distributor"
+
 func imagestream(imageStream *imagev1.ImageStream) (*imagev1.ImageStream, crcontrollerutil.MutateFn) {
 	stream := &imagev1.ImageStream{
 		ObjectMeta: metav1.ObjectMeta{
@@ -491,6 +495,10 @@ func imagestream(imageStream *imagev1.ImageStream) (*imagev1.ImageStream, crcont
 		},
 	}
 	return stream, func() error {
+		if stream.Labels == nil {
+			stream.Labels = map[string]string{}
+		}
+		stream.Labels[DistributedLabel] = "true"
 		if config, set := imageStream.Annotations[releaseConfigAnnotation]; set {
 			if stream.Annotations == nil {
 				stream.Annotations = map[string]string{}
@@ -268,6 +268,7 @@ func TestReconcile(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: referenceImageStreamTag.Namespace,
 			Name:      strings.Split(referenceImageStreamTag.Name, ":")[0],
+			Labels:    map[string]string{"ci.openshift.io/test-images-distributor": "true"},
 			Annotations: map[string]string{
 				"release.openshift.io/config": "bar",
 			},
@@ -281,6 +282,7 @@ func TestReconcile(t *testing.T) {
 	outdatedImageStream := func() *imagev1.ImageStream {
 		copy := expectedImageStream.DeepCopy()
 		copy.Spec.LookupPolicy.Local = false
+		copy.Labels = nil
 		copy.ObjectMeta.Annotations["release.openshift.io/config"] = "baz"
 		return copy
 	}
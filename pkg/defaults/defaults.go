@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 	"time"
 
@@ -35,6 +36,7 @@ import (
 	testimagestreamtagimportv1 "github.com/openshift/ci-tools/pkg/api/testimagestreamtagimport/v1"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
 	"github.com/openshift/ci-tools/pkg/lease"
+	"github.com/openshift/ci-tools/pkg/provenance"
 	"github.com/openshift/ci-tools/pkg/release"
 	"github.com/openshift/ci-tools/pkg/release/candidate"
 	"github.com/openshift/ci-tools/pkg/release/official"
@@ -71,6 +73,8 @@ func FromConfig(
 	requiredTargets []string,
 	cloneAuthConfig *steps.CloneAuthConfig,
 	pullSecret, pushSecret *coreapi.Secret,
+	provenanceSigner *provenance.Signer,
+	provenanceVerifier *provenance.Verifier,
 	censor *secrets.DynamicCensor,
 	hiveKubeconfig *rest.Config,
 	consoleHost string,
@@ -113,7 +117,7 @@ func FromConfig(
 	httpClient := retryablehttp.NewClient()
 	httpClient.Logger = nil
 
-	return fromConfig(ctx, config, graphConf, jobSpec, templates, paramFile, promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient.StandardClient(), requiredTargets, cloneAuthConfig, pullSecret, pushSecret, api.NewDeferredParameters(nil), censor, consoleHost, nodeName, targetAdditionalSuffix)
+	return fromConfig(ctx, config, graphConf, jobSpec, templates, paramFile, promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient.StandardClient(), requiredTargets, cloneAuthConfig, pullSecret, pushSecret, provenanceSigner, provenanceVerifier, api.NewDeferredParameters(nil), censor, consoleHost, nodeName, targetAdditionalSuffix)
 }
 
 func fromConfig(
@@ -134,6 +138,8 @@ func fromConfig(
 	requiredTargets []string,
 	cloneAuthConfig *steps.CloneAuthConfig,
 	pullSecret, pushSecret *coreapi.Secret,
+	provenanceSigner *provenance.Signer,
+	provenanceVerifier *provenance.Verifier,
 	params *api.DeferredParameters,
 	censor *secrets.DynamicCensor,
 	consoleHost string,
@@ -213,7 +219,7 @@ func fromConfig(
 						Namespace:          resolveConfig.Integration.Namespace,
 						Name:               resolveConfig.Integration.Name,
 						IncludeBuiltImages: resolveConfig.Integration.IncludeBuiltImages,
-					}, config.Resources, podClient, jobSpec)
+					}, config.Resources, podClient, jobSpec, pullSecret, provenanceVerifier)
 					for _, s := range []api.Step{snapshot, assemble} {
 						buildSteps = append(buildSteps, s)
 						addProvidesForStep(s, params)
@@ -297,7 +303,7 @@ func fromConfig(
 					// for backwards compatibility, users get inclusion for free with tag_spec
 					cfg := *rawStep.ReleaseImagesTagStepConfiguration
 					cfg.IncludeBuiltImages = name == api.LatestReleaseName
-					releaseStep = releasesteps.AssembleReleaseStep(name, nodeName, &cfg, config.Resources, podClient, jobSpec)
+					releaseStep = releasesteps.AssembleReleaseStep(name, nodeName, &cfg, config.Resources, podClient, jobSpec, pullSecret, provenanceVerifier)
 				}
 				overridableSteps = append(overridableSteps, releaseStep)
 				addProvidesForStep(releaseStep, params)
@@ -363,12 +369,12 @@ func fromConfig(
 		if config.PromotionConfiguration == nil {
 			return nil, nil, fmt.Errorf("cannot promote images, no promotion configuration defined")
 		}
-		postSteps = append(postSteps, releasesteps.PromotionStep(api.PromotionStepName, config, requiredNames, jobSpec, podClient, pushSecret, registryDomain(config.PromotionConfiguration), api.DefaultMirrorFunc))
+		postSteps = append(postSteps, releasesteps.PromotionStep(api.PromotionStepName, config, requiredNames, jobSpec, podClient, pushSecret, registryDomain(config.PromotionConfiguration), api.DefaultMirrorFunc, provenanceSigner))
 		// Used primarily (only?) by the ci-chat-bot
 		if config.PromotionConfiguration.RegistryOverride != "" {
 			logrus.Info("No images to promote to quay.io if the registry is overridden")
 		} else {
-			postSteps = append(postSteps, releasesteps.PromotionStep(api.PromotionQuayStepName, config, requiredNames, jobSpec, podClient, pushSecret, api.QuayOpenShiftCIRepo, api.QuayMirrorFunc))
+			postSteps = append(postSteps, releasesteps.PromotionStep(api.PromotionQuayStepName, config, requiredNames, jobSpec, podClient, pushSecret, api.QuayOpenShiftCIRepo, api.QuayMirrorFunc, provenanceSigner))
 		}
 	}
 
@@ -862,6 +868,126 @@ func runtimeStepConfigsForBuild(
 	return buildSteps, nil
 }
 
+// AdditionalRefSteps returns the steps that build the images of another repository
+// with pull requests under test, from that repository's own configuration. readFile
+// reads from the repository's checkout, for build roots it defines in the repository.
+// The repository's source, binaries and base images are tagged with the ref as a
+// suffix so they cannot clash with the ones of the repository under test, while its
+// images are promoted into stable so they are part of the payload we assemble.
+func AdditionalRefSteps(ref string, config *api.ReleaseBuildConfiguration, readFile readFile) ([]api.StepConfiguration, error) {
+	var root api.ImageStreamTagReference
+	switch buildRoot := config.BuildRootImage; {
+	case buildRoot == nil:
+		return nil, fmt.Errorf("%s does not define a build root", ref)
+	case buildRoot.ImageStreamTagReference != nil:
+		root = *buildRoot.ImageStreamTagReference
+	case buildRoot.FromRepository:
+		fromRepository, err := buildRootImageStreamFromRepository(readFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the build root of %s from the repository: %w", ref, err)
+		}
+		root = *fromRepository
+	default:
+		return nil, fmt.Errorf("the build root of %s must be an image stream tag or come from the repository", ref)
+	}
+	pipeline := map[api.PipelineImageStreamTagReference]api.PipelineImageStreamTagReference{
+		api.PipelineImageStreamTagReferenceRoot:   api.PipelineImageStreamTagReferenceForRef(api.PipelineImageStreamTagReferenceRoot, ref),
+		api.PipelineImageStreamTagReferenceSource: api.PipelineImageStreamTagReferenceForRef(api.PipelineImageStreamTagReferenceSource, ref),
+	}
+	buildSteps := []api.StepConfiguration{
+		{InputImageTagStepConfiguration: &api.InputImageTagStepConfiguration{
+			InputImage: api.InputImage{BaseImage: root, To: pipeline[api.PipelineImageStreamTagReferenceRoot]},
+			Sources:    []api.ImageStreamSource{{SourceType: api.ImageStreamSourceRoot}},
+		}},
+		{SourceStepConfiguration: &api.SourceStepConfiguration{
+			From: pipeline[api.PipelineImageStreamTagReferenceRoot],
+			To:   pipeline[api.PipelineImageStreamTagReferenceSource],
+			ClonerefsImage: api.ImageStreamTagReference{
+				Namespace: "ci",
+				Name:      "managed-clonerefs",
+				Tag:       "latest",
+			},
+			ClonerefsPath: "/clonerefs",
+			Ref:           ref,
+		}},
+	}
+	if len(config.BinaryBuildCommands) > 0 {
+		pipeline[api.PipelineImageStreamTagReferenceBinaries] = api.PipelineImageStreamTagReferenceForRef(api.PipelineImageStreamTagReferenceBinaries, ref)
+		buildSteps = append(buildSteps, api.StepConfiguration{PipelineImageCacheStepConfiguration: &api.PipelineImageCacheStepConfiguration{
+			From:     pipeline[api.PipelineImageStreamTagReferenceSource],
+			To:       pipeline[api.PipelineImageStreamTagReferenceBinaries],
+			Commands: config.BinaryBuildCommands,
+		}})
+	}
+	var aliases []string
+	for alias := range config.BaseImages {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		to := api.PipelineImageStreamTagReferenceForRef(api.PipelineImageStreamTagReference(alias), ref)
+		pipeline[api.PipelineImageStreamTagReference(alias)] = to
+		buildSteps = append(buildSteps, api.StepConfiguration{InputImageTagStepConfiguration: &api.InputImageTagStepConfiguration{
+			InputImage: api.InputImage{
+				BaseImage: defaultImageFromReleaseTag(alias, config.BaseImages[alias], config.ReleaseTagConfiguration),
+				To:        to,
+			},
+			Sources: []api.ImageStreamSource{{SourceType: api.ImageStreamSourceBase, Name: alias}},
+		}})
+	}
+
+	built := sets.NewString()
+	for _, image := range config.Images {
+		built.Insert(string(image.To))
+	}
+	// resolve maps a pipeline image the repository's configuration refers to onto the one
+	// we tag for it; images the repository builds itself keep their name
+	resolve := func(image, name string) (string, error) {
+		if to, ok := pipeline[api.PipelineImageStreamTagReference(name)]; ok {
+			return string(to), nil
+		}
+		if built.Has(name) {
+			return name, nil
+		}
+		return "", fmt.Errorf("image %s of %s depends on %s, which cannot be built for an additional repository", image, ref, name)
+	}
+	for _, image := range config.Images {
+		if image.Optional {
+			continue
+		}
+		image.Ref = ref
+		if image.From != "" {
+			from, err := resolve(string(image.To), string(image.From))
+			if err != nil {
+				return nil, err
+			}
+			image.From = api.PipelineImageStreamTagReference(from)
+		}
+		if image.Inputs != nil {
+			inputs := make(map[string]api.ImageBuildInputs, len(image.Inputs))
+			for name, input := range image.Inputs {
+				resolved, err := resolve(string(image.To), name)
+				if err != nil {
+					return nil, err
+				}
+				inputs[resolved] = input
+			}
+			image.Inputs = inputs
+		}
+		image := image
+		buildSteps = append(buildSteps,
+			api.StepConfiguration{ProjectDirectoryImageBuildStepConfiguration: &image},
+			api.StepConfiguration{OutputImageTagStepConfiguration: &api.OutputImageTagStepConfiguration{
+				From: image.To,
+				To: api.ImageStreamTagReference{
+					Name: api.StableImageStream,
+					Tag:  string(image.To),
+				},
+			}})
+	}
+	return buildSteps, nil
+}
+
 func paramsHasAllParametersAsInput(p api.Parameters, params map[string]func() (string, error)) (map[string]string, bool) {
 	if len(params) == 0 {
 		return nil, false
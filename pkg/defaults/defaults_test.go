@@ -1447,7 +1447,7 @@ func TestFromConfig(t *testing.T) {
 				params.Add(k, func() (string, error) { return v, nil })
 			}
 			graphConf := FromConfigStatic(&tc.config)
-			configSteps, post, err := fromConfig(context.Background(), &tc.config, &graphConf, &jobSpec, tc.templates, tc.paramFiles, tc.promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient, requiredTargets, cloneAuthConfig, pullSecret, pushSecret, params, &secrets.DynamicCensor{}, "", "", "")
+			configSteps, post, err := fromConfig(context.Background(), &tc.config, &graphConf, &jobSpec, tc.templates, tc.paramFiles, tc.promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient, requiredTargets, cloneAuthConfig, pullSecret, pushSecret, nil, nil, params, &secrets.DynamicCensor{}, "", "", "")
 			if diff := cmp.Diff(tc.expectedErr, err); diff != "" {
 				t.Errorf("unexpected error: %v", diff)
 			}
@@ -1514,3 +1514,108 @@ func TestRegistryDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestAdditionalRefSteps(t *testing.T) {
+	root := api.ImageStreamTagReference{Namespace: "ocp", Name: "builder", Tag: "golang"}
+	base := api.ImageStreamTagReference{Namespace: "ocp", Name: "4.14", Tag: "base"}
+	noFile := func(string) ([]byte, error) { return nil, fmt.Errorf("no file") }
+	rootStep := api.StepConfiguration{InputImageTagStepConfiguration: &api.InputImageTagStepConfiguration{
+		InputImage: api.InputImage{BaseImage: root, To: "root-org.repo"},
+		Sources:    []api.ImageStreamSource{{SourceType: api.ImageStreamSourceRoot}},
+	}}
+	sourceStep := api.StepConfiguration{SourceStepConfiguration: addCloneRefs(&api.SourceStepConfiguration{
+		From: "root-org.repo",
+		To:   "src-org.repo",
+		Ref:  "org.repo",
+	})}
+	var testCases = []struct {
+		name          string
+		config        *api.ReleaseBuildConfiguration
+		readFile      readFile
+		expected      []api.StepConfiguration
+		expectedError error
+	}{
+		{
+			name: "images are built from the suffixed pipeline images and promoted into stable",
+			config: &api.ReleaseBuildConfiguration{
+				InputConfiguration: api.InputConfiguration{
+					BuildRootImage: &api.BuildRootImageConfiguration{ImageStreamTagReference: &root},
+					BaseImages:     map[string]api.ImageStreamTagReference{"base": base},
+				},
+				BinaryBuildCommands: "make",
+				Images: []api.ProjectDirectoryImageBuildStepConfiguration{
+					{From: "base", To: "component", ProjectDirectoryImageBuildInputs: api.ProjectDirectoryImageBuildInputs{
+						Inputs: map[string]api.ImageBuildInputs{"bin": {As: []string{"builder"}}},
+					}},
+					{From: "component", To: "other-component"},
+					{To: "optional", Optional: true},
+				},
+			},
+			readFile: noFile,
+			expected: []api.StepConfiguration{
+				rootStep,
+				sourceStep,
+				{PipelineImageCacheStepConfiguration: &api.PipelineImageCacheStepConfiguration{From: "src-org.repo", To: "bin-org.repo", Commands: "make"}},
+				{InputImageTagStepConfiguration: &api.InputImageTagStepConfiguration{
+					InputImage: api.InputImage{BaseImage: api.ImageStreamTagReference{Namespace: "ocp", Name: "4.14", Tag: "base", As: "base"}, To: "base-org.repo"},
+					Sources:    []api.ImageStreamSource{{SourceType: api.ImageStreamSourceBase, Name: "base"}},
+				}},
+				{ProjectDirectoryImageBuildStepConfiguration: &api.ProjectDirectoryImageBuildStepConfiguration{
+					From: "base-org.repo", To: "component", Ref: "org.repo",
+					ProjectDirectoryImageBuildInputs: api.ProjectDirectoryImageBuildInputs{
+						Inputs: map[string]api.ImageBuildInputs{"bin-org.repo": {As: []string{"builder"}}},
+					},
+				}},
+				{OutputImageTagStepConfiguration: &api.OutputImageTagStepConfiguration{From: "component", To: api.ImageStreamTagReference{Name: api.StableImageStream, Tag: "component"}}},
+				{ProjectDirectoryImageBuildStepConfiguration: &api.ProjectDirectoryImageBuildStepConfiguration{From: "component", To: "other-component", Ref: "org.repo"}},
+				{OutputImageTagStepConfiguration: &api.OutputImageTagStepConfiguration{From: "other-component", To: api.ImageStreamTagReference{Name: api.StableImageStream, Tag: "other-component"}}},
+			},
+		},
+		{
+			name: "build root from the repository",
+			config: &api.ReleaseBuildConfiguration{
+				InputConfiguration: api.InputConfiguration{
+					BuildRootImage: &api.BuildRootImageConfiguration{FromRepository: true},
+				},
+			},
+			readFile: func(filename string) ([]byte, error) {
+				if filename != ".ci-operator.yaml" {
+					return nil, fmt.Errorf("expected '.ci-operator.yaml', got %s", filename)
+				}
+				return []byte(`build_root_image:
+  namespace: ocp
+  name: builder
+  tag: golang`), nil
+			},
+			expected: []api.StepConfiguration{rootStep, sourceStep},
+		},
+		{
+			name: "image depending on an image that cannot be built",
+			config: &api.ReleaseBuildConfiguration{
+				InputConfiguration: api.InputConfiguration{
+					BuildRootImage: &api.BuildRootImageConfiguration{ImageStreamTagReference: &root},
+				},
+				Images: []api.ProjectDirectoryImageBuildStepConfiguration{{From: "rpms", To: "component"}},
+			},
+			readFile:      noFile,
+			expectedError: fmt.Errorf("image component of org.repo depends on rpms, which cannot be built for an additional repository"),
+		},
+		{
+			name:          "no build root",
+			config:        &api.ReleaseBuildConfiguration{},
+			readFile:      noFile,
+			expectedError: fmt.Errorf("org.repo does not define a build root"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := AdditionalRefSteps("org.repo", testCase.config, testCase.readFile)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("unexpected steps: %s", diff)
+			}
+		})
+	}
+}
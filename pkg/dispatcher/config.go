@@ -33,6 +33,9 @@ type Config struct {
 	KVM []api.Cluster `json:"kvm"`
 	// the cluster names for no-builds jobs
 	NoBuilds []api.Cluster `json:"noBuilds,omitempty"`
+	// Capabilities maps a capability, e.g., arm64 or vpn, to the clusters having it.
+	// Jobs requiring capabilities run only on the clusters having all of them.
+	Capabilities map[string][]api.Cluster `json:"capabilities,omitempty"`
 	// Groups maps a group of jobs to a cluster
 	Groups JobGroups `json:"groups"`
 	// BuildFarm maps groups of jobs to a cloud provider, like GCP
@@ -108,42 +111,51 @@ func DetermineCloud(jobBase prowconfig.JobBase) string {
 	return ""
 }
 
-// DetermineClusterForJob return the cluster for a prow job and if it can be relocated to a cluster in build farm
+// DetermineClusterForJob return the cluster for a prow job and if it can be relocated to a cluster in build farm.
+// A job requiring capabilities only runs on a cluster having all of them.
 func (config *Config) DetermineClusterForJob(jobBase prowconfig.JobBase, path string) (clusterName api.Cluster, mayBeRelocated bool, _ error) {
 	if jobBase.Agent != "kubernetes" && jobBase.Agent != "" {
 		return "", false, nil
 	}
+	required := RequiredCapabilities(jobBase)
+	if required.Len() > 0 && len(config.ClustersWithCapabilities(required)) == 0 {
+		return "", false, fmt.Errorf("no cluster has the capabilities %s required by the job %s", strings.Join(required.List(), ", "), jobBase.Name)
+	}
 	if strings.Contains(jobBase.Name, "vsphere") && !isApplyConfigJob(jobBase) {
-		return api.ClusterVSphere, false, nil
+		return config.capableCluster(api.ClusterVSphere, required, jobBase.Name)
 	}
 	if isSSHBastionJob(jobBase) && config.SSHBastion != "" {
-		return config.SSHBastion, false, nil
+		return config.capableCluster(config.SSHBastion, required, jobBase.Name)
 	}
 	if jobBase.Labels != nil {
 		if _, ok := jobBase.Labels[api.KVMDeviceLabel]; ok && len(config.KVM) > 0 {
+			clusters := config.capableClusters(config.KVM, required)
+			if len(clusters) == 0 {
+				return "", false, fmt.Errorf("no cluster for kvm jobs has the capabilities %s required by the job %s", strings.Join(required.List(), ", "), jobBase.Name)
+			}
 			// Any deterministic distribution is fine for now.
 			// We could implement more effective distribution when we understand more about the jobs.
-			return config.KVM[len(filepath.Base(path))%len(config.KVM)], false, nil
+			return pick(clusters, path), false, nil
 		}
 		if cluster, ok := jobBase.Labels[api.ClusterLabel]; ok {
-			return api.Cluster(cluster), false, nil
+			return config.capableCluster(api.Cluster(cluster), required, jobBase.Name)
 		}
 	}
 
 	if config.DetermineE2EByJob {
 		if cloud := config.DetermineCloudMapping(jobBase); cloud != "" {
-			if clusters, ok := config.BuildFarmCloud[api.Cloud(cloud)]; ok {
-				if len(clusters) > 0 {
-					return api.Cluster(clusters[len(filepath.Base(path))%len(clusters)]), false, nil
-				}
+			if clusters := config.capableClusters(config.cloudClusters(api.Cloud(cloud)), required); len(clusters) > 0 {
+				return pick(clusters, path), false, nil
 			}
 		}
 	}
 
 	if jobBase.Labels != nil {
-		if _, ok := jobBase.Labels[api.NoBuildsLabel]; ok && len(config.NoBuilds) > 0 {
-			// Any deterministic distribution is fine for now.
-			return config.NoBuilds[len(filepath.Base(path))%len(config.NoBuilds)], false, nil
+		if _, ok := jobBase.Labels[api.NoBuildsLabel]; ok {
+			if clusters := config.capableClusters(config.NoBuilds, required); len(clusters) > 0 {
+				// Any deterministic distribution is fine for now.
+				return pick(clusters, path), false, nil
+			}
 		}
 	}
 
@@ -187,9 +199,98 @@ func (config *Config) DetermineClusterForJob(jobBase prowconfig.JobBase, path st
 		clusterName = config.Default
 		mayBeRelocated = true
 	}
+	if !config.HasCapabilities(clusterName, required) {
+		// The job stays on the cloud it would run on, if any of its clusters is capable
+		clusters := config.capableClusters(config.cloudClusters(config.IsInBuildFarm(clusterName)), required)
+		if len(clusters) == 0 {
+			clusters = config.ClustersWithCapabilities(required)
+		}
+		return pick(clusters, path), false, nil
+	}
 	return clusterName, mayBeRelocated, nil
 }
 
+// capableCluster returns the cluster a job is bound to if it has the capabilities the job requires
+func (config *Config) capableCluster(cluster api.Cluster, required sets.String, jobName string) (api.Cluster, bool, error) {
+	if !config.HasCapabilities(cluster, required) {
+		return "", false, fmt.Errorf("cluster %s does not have the capabilities %s required by the job %s", cluster, strings.Join(required.List(), ", "), jobName)
+	}
+	return cluster, false, nil
+}
+
+// capableClusters returns the clusters having all the capabilities
+func (config *Config) capableClusters(clusters []api.Cluster, capabilities sets.String) []api.Cluster {
+	var capable []api.Cluster
+	for _, cluster := range clusters {
+		if config.HasCapabilities(cluster, capabilities) {
+			capable = append(capable, cluster)
+		}
+	}
+	return capable
+}
+
+// cloudClusters returns the clusters of the build farm on the cloud
+func (config *Config) cloudClusters(cloud api.Cloud) []api.Cluster {
+	var clusters []api.Cluster
+	for _, cluster := range config.BuildFarmCloud[cloud] {
+		clusters = append(clusters, api.Cluster(cluster))
+	}
+	return clusters
+}
+
+// pick returns one of the clusters for the jobs of the file at the path.
+// Any deterministic distribution is fine for now.
+func pick(clusters []api.Cluster, path string) api.Cluster {
+	return clusters[len(filepath.Base(path))%len(clusters)]
+}
+
+// RequiredCapabilities returns the capabilities that the cluster where the job runs must have
+func RequiredCapabilities(jobBase prowconfig.JobBase) sets.String {
+	required := sets.NewString()
+	for label, value := range jobBase.Labels {
+		if strings.HasPrefix(label, api.CapabilityLabelPrefix) {
+			required.Insert(value)
+		}
+	}
+	return required
+}
+
+// HasCapabilities returns true if the cluster has all the capabilities
+func (config *Config) HasCapabilities(cluster api.Cluster, capabilities sets.String) bool {
+	for _, capability := range capabilities.List() {
+		if !hasCluster(config.Capabilities[capability], cluster) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClustersWithCapabilities returns the sorted names of the clusters having all the capabilities
+func (config *Config) ClustersWithCapabilities(capabilities sets.String) []api.Cluster {
+	candidates := sets.NewString()
+	for _, clusters := range config.Capabilities {
+		for _, cluster := range clusters {
+			candidates.Insert(string(cluster))
+		}
+	}
+	var clusters []api.Cluster
+	for _, cluster := range candidates.List() {
+		if config.HasCapabilities(api.Cluster(cluster), capabilities) {
+			clusters = append(clusters, api.Cluster(cluster))
+		}
+	}
+	return clusters
+}
+
+func hasCluster(clusters []api.Cluster, cluster api.Cluster) bool {
+	for _, c := range clusters {
+		if c == cluster {
+			return true
+		}
+	}
+	return false
+}
+
 func isSSHBastionJob(base prowconfig.JobBase) bool {
 	for k := range base.Labels {
 		if k == jobconfig.SSHBastionLabel {
@@ -372,6 +372,14 @@ func TestGetClusterForJob(t *testing.T) {
 }
 
 func TestDetermineClusterForJob(t *testing.T) {
+	configWithCapabilities := configWithBuildFarmWithJobs
+	configWithCapabilities.Capabilities = map[string][]api.Cluster{
+		"arm64": {"build01"},
+		"vpn":   {"build01", "build02", "build03"},
+		"gpu":   {"build02"},
+	}
+	configWithCapabilitiesAndDetermineE2EByJob := configWithCapabilities
+	configWithCapabilitiesAndDetermineE2EByJob.DetermineE2EByJob = true
 	testCases := []struct {
 		name                   string
 		config                 *Config
@@ -521,6 +529,118 @@ func TestDetermineClusterForJob(t *testing.T) {
 			expected:               "build01",
 			expectedCanBeRelocated: false,
 		},
+		{
+			name:   "a job requiring a capability",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-arm64-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/arm64": "arm64"},
+			},
+			path:     "org/repo/some-presubmits.yaml",
+			expected: "build01",
+		},
+		{
+			name:   "a job requiring a capability of several clusters",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-vpn-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/vpn": "vpn"},
+			},
+			path:     "org/repo/some-presubmits.yaml",
+			expected: "build03",
+		},
+		{
+			name:   "a kvm job requiring a capability runs on a cluster for kvm jobs having it",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-vpn-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/vpn": "vpn", "devices.kubevirt.io/kvm": "1"},
+			},
+			expected: "build02",
+		},
+		{
+			name:   "no cluster for kvm jobs has the capability",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-arm64-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/arm64": "arm64", "devices.kubevirt.io/kvm": "1"},
+			},
+			expectedErr: fmt.Errorf("no cluster for kvm jobs has the capabilities arm64 required by the job some-arm64-job"),
+		},
+		{
+			name:   "no cluster has the capabilities",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-gpu-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/arm64": "arm64", "capabilities.ci.openshift.io/gpu": "gpu"},
+			},
+			expectedErr: fmt.Errorf("no cluster has the capabilities arm64, gpu required by the job some-gpu-job"),
+		},
+		{
+			name:   "a job with cluster label and a capability the cluster does not have",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-gpu-job",
+				Labels: map[string]string{"ci-operator.openshift.io/cluster": "b01", "capabilities.ci.openshift.io/gpu": "gpu"},
+			},
+			expectedErr: fmt.Errorf("cluster b01 does not have the capabilities gpu required by the job some-gpu-job"),
+		},
+		{
+			name:   "a job with cluster label and a capability the cluster has",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-gpu-job",
+				Labels: map[string]string{"ci-operator.openshift.io/cluster": "build02", "capabilities.ci.openshift.io/gpu": "gpu"},
+			},
+			expected: "build02",
+		},
+		{
+			name:   "a vsphere job requiring a capability",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "pull-ci-openshift-release-master-vsphere-vpn",
+				Labels: map[string]string{"capabilities.ci.openshift.io/vpn": "vpn"},
+			},
+			expectedErr: fmt.Errorf("cluster vsphere does not have the capabilities vpn required by the job pull-ci-openshift-release-master-vsphere-vpn"),
+		},
+		{
+			name:   "a job requiring a capability stays on the cluster of its group when it has the capability",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "periodic-build01-upgrade",
+				Labels: map[string]string{"capabilities.ci.openshift.io/vpn": "vpn"},
+			},
+			path:     "org/repo/some-periodics.yaml",
+			expected: "build01",
+		},
+		{
+			name:   "a job requiring a capability stays on the cluster of its file when it has the capability",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-vpn-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/vpn": "vpn"},
+			},
+			path:                   "org/repo/some-build-farm-presubmits.yaml",
+			expected:               "build01",
+			expectedCanBeRelocated: true,
+		},
+		{
+			name:   "a job requiring a capability runs on the cloud of its e2e test",
+			config: &configWithCapabilitiesAndDetermineE2EByJob,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-vpn-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/vpn": "vpn", "ci-operator.openshift.io/cloud": "gcp"},
+			},
+			path:     "org/repo/some-build-farm-presubmits.yaml",
+			expected: "build02",
+		},
+		{
+			name:   "a job requiring a capability its cloud does not have runs on a capable cluster",
+			config: &configWithCapabilitiesAndDetermineE2EByJob,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-arm64-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/arm64": "arm64", "ci-operator.openshift.io/cloud": "gcp"},
+			},
+			path:     "org/repo/some-presubmits.yaml",
+			expected: "build01",
+		},
+		{
+			name:   "a no-builds job requiring a capability no cluster for no-builds jobs has",
+			config: &configWithCapabilities,
+			jobBase: config.JobBase{Agent: "kubernetes", Name: "some-gpu-job",
+				Labels: map[string]string{"capabilities.ci.openshift.io/gpu": "gpu", "ci.openshift.io/no-builds": "true"},
+			},
+			path:     "org/repo/some-build-farm-presubmits.yaml",
+			expected: "build02",
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -0,0 +1,285 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// freeCapacityQuery is the fraction of the allocatable CPU of the cluster not requested by pods
+	freeCapacityQuery = `1 - sum(kube_pod_container_resource_requests{resource="cpu"}) / sum(kube_node_status_allocatable{resource="cpu"})`
+	// nodePressureQuery is the fraction of the nodes under memory, disk or PID pressure
+	nodePressureQuery = `(count(kube_node_status_condition{condition=~"MemoryPressure|DiskPressure|PIDPressure",status="true"} == 1) or vector(0)) / count(kube_node_info)`
+	// pendingRateQuery is the fraction of the test pods that were pending in the last 30 minutes
+	pendingRateQuery = `(sum(avg_over_time(kube_pod_status_phase{namespace=~"ci-op-.*",phase="Pending"}[30m])) or vector(0)) / sum(avg_over_time(kube_pod_status_phase{namespace=~"ci-op-.*"}[30m]))`
+)
+
+// ClusterLoad describes the current load of a cluster in the build farm
+type ClusterLoad struct {
+	// FreeCapacity is the fraction of the allocatable CPU of the cluster not requested by pods
+	FreeCapacity float64 `json:"freeCapacity"`
+	// NodePressure is the fraction of the nodes under memory, disk or PID pressure
+	NodePressure float64 `json:"nodePressure"`
+	// PendingRate is the fraction of the test pods that were pending recently
+	PendingRate float64 `json:"pendingRate"`
+}
+
+// Score returns a value between 0 and 1 describing how much more work the cluster can take
+func (l ClusterLoad) Score() float64 {
+	return bounded(l.FreeCapacity) * (1 - bounded(l.NodePressure)) * (1 - bounded(l.PendingRate))
+}
+
+func bounded(f float64) float64 {
+	return math.Max(0, math.Min(1, f))
+}
+
+// GetClusterLoadFromPrometheus gets the load of a cluster from its Prometheus server for the given time
+func GetClusterLoadFromPrometheus(ctx context.Context, prometheusAPI PrometheusAPI, ts time.Time) (ClusterLoad, error) {
+	var load ClusterLoad
+	for query, into := range map[string]*float64{
+		freeCapacityQuery: &load.FreeCapacity,
+		nodePressureQuery: &load.NodePressure,
+		pendingRateQuery:  &load.PendingRate,
+	} {
+		value, err := queryScalar(ctx, prometheusAPI, query, ts)
+		if err != nil {
+			return ClusterLoad{}, err
+		}
+		*into = value
+	}
+	return load, nil
+}
+
+// queryScalar returns the value of a query resulting in a single sample, or zero when there are none
+func queryScalar(ctx context.Context, prometheusAPI PrometheusAPI, query string, ts time.Time) (float64, error) {
+	result, warnings, err := prometheusAPI.Query(ctx, query, ts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %q: %w", query, err)
+	}
+	if len(warnings) > 0 {
+		logrus.WithField("Warnings", warnings).Warn("Got warnings from Prometheus")
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return 0, fmt.Errorf("returned result of type %T from Prometheus cannot be cast to vector", result)
+	}
+	switch len(vector) {
+	case 0:
+		return 0, nil
+	case 1:
+		if value := float64(vector[0].Value); !math.IsNaN(value) && !math.IsInf(value, 0) {
+			return value, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("query %q returned %d samples, expected one", query, len(vector))
+	}
+}
+
+// Requirements are what a cluster must provide to run the jobs of a Prow job config file
+type Requirements struct {
+	Capabilities []string `json:"capabilities,omitempty"`
+	// KVM is true when any job needs the kvm device, which only the clusters for kvm jobs provide
+	KVM bool `json:"kvm,omitempty"`
+	// Builds is true when any job builds images, i.e. it is not labeled as no-builds
+	Builds bool `json:"builds,omitempty"`
+}
+
+// JobRequirements returns what a cluster must provide to run a job
+func JobRequirements(jobBase prowconfig.JobBase) Requirements {
+	_, kvm := jobBase.Labels[api.KVMDeviceLabel]
+	_, noBuilds := jobBase.Labels[api.NoBuildsLabel]
+	return Requirements{Capabilities: RequiredCapabilities(jobBase).List(), KVM: kvm, Builds: !noBuilds}
+}
+
+// FileLoad is the load that the relocatable jobs of a Prow job config file put on their cluster
+type FileLoad struct {
+	// Volume is the number of runs of the jobs
+	Volume       float64
+	Requirements Requirements
+}
+
+// AddJob adds the load of a job to the file
+func (f *FileLoad) AddJob(jobBase prowconfig.JobBase, jobVolumes map[string]float64) {
+	requirements := JobRequirements(jobBase)
+	f.Volume += jobVolumes[jobBase.Name]
+	if capabilities := sets.NewString(f.Requirements.Capabilities...).Insert(requirements.Capabilities...); capabilities.Len() > 0 {
+		f.Requirements.Capabilities = capabilities.List()
+	}
+	f.Requirements.KVM = f.Requirements.KVM || requirements.KVM
+	f.Requirements.Builds = f.Requirements.Builds || requirements.Builds
+}
+
+// eligible returns true if the cluster provides what the requirements ask for
+func (config *Config) eligible(cluster api.Cluster, requirements Requirements) bool {
+	if !config.HasCapabilities(cluster, sets.NewString(requirements.Capabilities...)) {
+		return false
+	}
+	if requirements.KVM && !hasCluster(config.KVM, cluster) {
+		return false
+	}
+	// Clusters that do not run builds only take jobs that do not build
+	if requirements.Builds {
+		for _, c := range config.NoBuilds {
+			if c == cluster {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Move moves the jobs of a Prow job config file from a cluster to another one
+type Move struct {
+	Filename string      `json:"filename"`
+	From     api.Cluster `json:"from"`
+	To       api.Cluster `json:"to"`
+	Volume   float64     `json:"volume"`
+}
+
+// RedispatchPlan is the list of moves that balance the build farm according to the current load of its clusters
+type RedispatchPlan struct {
+	Loads map[api.Cluster]ClusterLoad `json:"loads"`
+	Moves []Move                      `json:"moves,omitempty"`
+	// Degraded are the clusters scoring below the threshold, which should not run any jobs
+	Degraded []api.Cluster `json:"degraded,omitempty"`
+	// Stuck are the files left on degraded clusters, because no eligible cluster could take them
+	// or the move budget was exhausted
+	Stuck []string `json:"stuck,omitempty"`
+}
+
+// PlanRedispatch plans at most maxMoves moves of the Prow job config files in the build farm, so that
+// each cluster gets the share of the volume of its cloud proportional to the score of its current load.
+// Files only move between clusters of the same cloud provider, to clusters having the capabilities their
+// jobs require. Clusters scoring below degradedScore get no share, and clusters without a known load keep
+// their files.
+func (config *Config) PlanRedispatch(loads map[api.Cluster]ClusterLoad, files map[string]FileLoad, maxMoves int, degradedScore float64) RedispatchPlan {
+	plan := RedispatchPlan{Loads: loads}
+	var clouds []string
+	for cloud := range config.BuildFarm {
+		clouds = append(clouds, string(cloud))
+	}
+	sort.Strings(clouds)
+
+	type clusterState struct {
+		name     api.Cluster
+		files    sets.String
+		volume   float64
+		expected float64
+	}
+	for _, cloud := range clouds {
+		var states []*clusterState
+		var total, scores float64
+		for cluster, buildFarmConfig := range config.BuildFarm[api.Cloud(cloud)] {
+			load, known := loads[cluster]
+			if !known {
+				continue
+			}
+			state := &clusterState{name: cluster, files: sets.NewString(buildFarmConfig.FilenamesRaw...)}
+			for _, filename := range buildFarmConfig.FilenamesRaw {
+				state.volume += files[filename].Volume
+			}
+			total += state.volume
+			if score := load.Score(); score >= degradedScore {
+				state.expected = score
+				scores += score
+			} else {
+				plan.Degraded = append(plan.Degraded, cluster)
+			}
+			states = append(states, state)
+		}
+		sort.Slice(states, func(i, j int) bool { return states[i].name < states[j].name })
+		for _, state := range states {
+			if scores > 0 {
+				state.expected = total * state.expected / scores
+			}
+		}
+
+		for len(plan.Moves) < maxMoves {
+			// Move the file giving the largest improvement of the balance
+			var best *Move
+			var bestFrom, bestTo *clusterState
+			bestGain := 0.0
+			for _, from := range states {
+				excess := from.volume - from.expected
+				if excess <= 0 {
+					continue
+				}
+				for _, filename := range from.files.List() {
+					file := files[filename]
+					for _, to := range states {
+						deficit := to.expected - to.volume
+						if to == from || deficit <= 0 || !config.eligible(to.name, file.Requirements) {
+							continue
+						}
+						gain := excess + deficit - math.Abs(excess-file.Volume) - math.Abs(file.Volume-deficit)
+						if gain > bestGain {
+							bestGain = gain
+							best = &Move{Filename: filename, From: from.name, To: to.name, Volume: file.Volume}
+							bestFrom, bestTo = from, to
+						}
+					}
+				}
+			}
+			if best == nil {
+				break
+			}
+			plan.Moves = append(plan.Moves, *best)
+			bestFrom.files.Delete(best.Filename)
+			bestFrom.volume -= best.Volume
+			bestTo.files.Insert(best.Filename)
+			bestTo.volume += best.Volume
+		}
+	}
+
+	degraded := sets.NewString()
+	for _, cluster := range plan.Degraded {
+		degraded.Insert(string(cluster))
+	}
+	moved := sets.NewString()
+	for _, move := range plan.Moves {
+		moved.Insert(move.Filename)
+	}
+	for _, cloud := range clouds {
+		for cluster, buildFarmConfig := range config.BuildFarm[api.Cloud(cloud)] {
+			if !degraded.Has(string(cluster)) {
+				continue
+			}
+			for _, filename := range buildFarmConfig.FilenamesRaw {
+				if !moved.Has(filename) && files[filename].Volume > 0 {
+					plan.Stuck = append(plan.Stuck, filename)
+				}
+			}
+		}
+	}
+	sort.Slice(plan.Degraded, func(i, j int) bool { return plan.Degraded[i] < plan.Degraded[j] })
+	sort.Strings(plan.Stuck)
+	return plan
+}
+
+// Apply moves the files in the build farm of the config according to the plan
+func (p RedispatchPlan) Apply(config *Config) {
+	for _, move := range p.Moves {
+		for _, clusters := range config.BuildFarm {
+			if from, ok := clusters[move.From]; ok {
+				from.FilenamesRaw = sets.NewString(from.FilenamesRaw...).Delete(move.Filename).List()
+				from.Filenames = sets.NewString(from.FilenamesRaw...)
+			}
+			if to, ok := clusters[move.To]; ok {
+				to.FilenamesRaw = sets.NewString(to.FilenamesRaw...).Insert(move.Filename).List()
+				to.Filenames = sets.NewString(to.FilenamesRaw...)
+			}
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	prometheusapi "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestGetClusterLoadFromPrometheus(t *testing.T) {
+	sample := func(value float64) model.Vector {
+		return model.Vector{{Value: model.SampleValue(value)}}
+	}
+	testCases := []struct {
+		name          string
+		queryFunc     func(ctx context.Context, query string, ts time.Time) (model.Value, prometheusapi.Warnings, error)
+		expected      ClusterLoad
+		expectedError error
+	}{
+		{
+			name: "basic case",
+			queryFunc: func(ctx context.Context, query string, ts time.Time) (model.Value, prometheusapi.Warnings, error) {
+				return map[string]model.Vector{
+					freeCapacityQuery: sample(0.4),
+					nodePressureQuery: sample(0.1),
+					pendingRateQuery:  sample(0.2),
+				}[query], nil, nil
+			},
+			expected: ClusterLoad{FreeCapacity: 0.4, NodePressure: 0.1, PendingRate: 0.2},
+		},
+		{
+			name: "no samples",
+			queryFunc: func(ctx context.Context, query string, ts time.Time) (model.Value, prometheusapi.Warnings, error) {
+				if query == freeCapacityQuery {
+					return sample(0.4), nil, nil
+				}
+				return model.Vector{}, nil, nil
+			},
+			expected: ClusterLoad{FreeCapacity: 0.4},
+		},
+		{
+			name: "query fails",
+			queryFunc: func(ctx context.Context, query string, ts time.Time) (model.Value, prometheusapi.Warnings, error) {
+				return nil, nil, fmt.Errorf("injected error")
+			},
+			expectedError: fmt.Errorf("failed to query %q: injected error", freeCapacityQuery),
+		},
+		{
+			name: "too many samples",
+			queryFunc: func(ctx context.Context, query string, ts time.Time) (model.Value, prometheusapi.Warnings, error) {
+				return model.Vector{{Value: 1}, {Value: 2}}, nil, nil
+			},
+			expectedError: fmt.Errorf("query %q returned 2 samples, expected one", freeCapacityQuery),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Make the order of the queries deterministic for the errors
+			queryFunc := tc.queryFunc
+			if tc.expectedError != nil {
+				queryFunc = func(ctx context.Context, query string, ts time.Time) (model.Value, prometheusapi.Warnings, error) {
+					if query != freeCapacityQuery {
+						return sample(0), nil, nil
+					}
+					return tc.queryFunc(ctx, query, ts)
+				}
+			}
+			actual, actualError := GetClusterLoadFromPrometheus(context.TODO(), &prometheusAPIForTest{queryFunc}, time.Now())
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("%s: actual does not match expected, diff: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expectedError, actualError, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("%s: actual does not match expected, diff: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestFileLoadAddJob(t *testing.T) {
+	var file FileLoad
+	jobVolumes := map[string]float64{"kvm": 3, "arm64": 2}
+	file.AddJob(prowconfig.JobBase{Name: "kvm", Labels: map[string]string{api.KVMDeviceLabel: "true", api.NoBuildsLabel: "true"}}, jobVolumes)
+	file.AddJob(prowconfig.JobBase{Name: "arm64", Labels: map[string]string{api.CapabilityLabelPrefix + "arm64": "arm64"}}, jobVolumes)
+	file.AddJob(prowconfig.JobBase{Name: "unknown", Labels: map[string]string{api.NoBuildsLabel: "true"}}, jobVolumes)
+	expected := FileLoad{Volume: 5, Requirements: Requirements{Capabilities: []string{"arm64"}, KVM: true, Builds: true}}
+	if diff := cmp.Diff(expected, file); diff != "" {
+		t.Errorf("actual does not match expected, diff: %s", diff)
+	}
+}
+
+func TestPlanRedispatch(t *testing.T) {
+	newConfig := func() *Config {
+		return &Config{
+			KVM: []api.Cluster{api.ClusterBuild02},
+			BuildFarm: map[api.Cloud]map[api.Cluster]*BuildFarmConfig{
+				api.CloudAWS: {
+					api.ClusterBuild01: {FilenamesRaw: []string{"a.yaml", "b.yaml", "c.yaml"}},
+					api.ClusterBuild03: {FilenamesRaw: []string{"d.yaml"}},
+				},
+				api.CloudGCP: {
+					api.ClusterBuild02: {FilenamesRaw: []string{"e.yaml"}},
+				},
+			},
+		}
+	}
+	files := map[string]FileLoad{
+		"a.yaml": {Volume: 60, Requirements: Requirements{Builds: true}},
+		"b.yaml": {Volume: 30, Requirements: Requirements{Builds: true}},
+		"c.yaml": {Volume: 10, Requirements: Requirements{Builds: true}},
+		"e.yaml": {Volume: 20, Requirements: Requirements{Builds: true}},
+	}
+	loads := func(build01, build03 float64) map[api.Cluster]ClusterLoad {
+		return map[api.Cluster]ClusterLoad{
+			api.ClusterBuild01: {FreeCapacity: build01},
+			api.ClusterBuild02: {FreeCapacity: 0.01},
+			api.ClusterBuild03: {FreeCapacity: build03},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		config   *Config
+		loads    map[api.Cluster]ClusterLoad
+		files    map[string]FileLoad
+		maxMoves int
+		expected RedispatchPlan
+	}{
+		{
+			name:     "the cloud is balanced by the scores of the clusters",
+			config:   newConfig(),
+			loads:    loads(0.5, 0.5),
+			files:    files,
+			maxMoves: 10,
+			expected: RedispatchPlan{
+				Loads:    loads(0.5, 0.5),
+				Moves:    []Move{{Filename: "a.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 60}},
+				Degraded: []api.Cluster{api.ClusterBuild02},
+				Stuck:    []string{"e.yaml"},
+			},
+		},
+		{
+			name:     "balanced clusters keep their files",
+			config:   newConfig(),
+			loads:    loads(0.9, 0.0),
+			files:    files,
+			maxMoves: 10,
+			expected: RedispatchPlan{
+				Loads:    loads(0.9, 0.0),
+				Degraded: []api.Cluster{api.ClusterBuild02, api.ClusterBuild03},
+				Stuck:    []string{"e.yaml"},
+			},
+		},
+		{
+			name:     "files are moved away from a degraded cluster",
+			config:   newConfig(),
+			loads:    loads(0.01, 0.5),
+			files:    files,
+			maxMoves: 10,
+			expected: RedispatchPlan{
+				Loads: loads(0.01, 0.5),
+				Moves: []Move{
+					{Filename: "a.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 60},
+					{Filename: "b.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 30},
+					{Filename: "c.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 10},
+				},
+				Degraded: []api.Cluster{api.ClusterBuild01, api.ClusterBuild02},
+				Stuck:    []string{"e.yaml"},
+			},
+		},
+		{
+			name:     "the number of moves is limited",
+			config:   newConfig(),
+			loads:    loads(0.01, 0.5),
+			files:    files,
+			maxMoves: 2,
+			expected: RedispatchPlan{
+				Loads: loads(0.01, 0.5),
+				Moves: []Move{
+					{Filename: "a.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 60},
+					{Filename: "b.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 30},
+				},
+				Degraded: []api.Cluster{api.ClusterBuild01, api.ClusterBuild02},
+				Stuck:    []string{"c.yaml", "e.yaml"},
+			},
+		},
+		{
+			name:   "files are moved only to clusters having the required capabilities",
+			config: newConfig(),
+			loads:  loads(0.01, 0.5),
+			files: map[string]FileLoad{
+				"a.yaml": {Volume: 60, Requirements: Requirements{Capabilities: []string{"vpn"}}},
+				"b.yaml": {Volume: 30, Requirements: Requirements{Builds: true}},
+			},
+			maxMoves: 10,
+			expected: RedispatchPlan{
+				Loads:    loads(0.01, 0.5),
+				Moves:    []Move{{Filename: "b.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 30}},
+				Degraded: []api.Cluster{api.ClusterBuild01, api.ClusterBuild02},
+				Stuck:    []string{"a.yaml"},
+			},
+		},
+		{
+			name: "files are moved only to clusters listed for the capabilities and kvm jobs",
+			config: func() *Config {
+				config := newConfig()
+				config.Capabilities = map[string][]api.Cluster{"arm64": {api.ClusterBuild03}}
+				return config
+			}(),
+			loads: loads(0.01, 0.5),
+			files: map[string]FileLoad{
+				"a.yaml": {Volume: 60, Requirements: Requirements{Capabilities: []string{"arm64"}}},
+				"c.yaml": {Volume: 10, Requirements: Requirements{Capabilities: []string{"arm64"}, KVM: true}},
+			},
+			maxMoves: 10,
+			expected: RedispatchPlan{
+				Loads:    loads(0.01, 0.5),
+				Moves:    []Move{{Filename: "a.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03, Volume: 60}},
+				Degraded: []api.Cluster{api.ClusterBuild01, api.ClusterBuild02},
+				Stuck:    []string{"c.yaml"},
+			},
+		},
+		{
+			name:   "clusters without a known load keep their files",
+			config: newConfig(),
+			loads: map[api.Cluster]ClusterLoad{
+				api.ClusterBuild01: {FreeCapacity: 0.01},
+			},
+			files:    files,
+			maxMoves: 10,
+			expected: RedispatchPlan{
+				Loads:    map[api.Cluster]ClusterLoad{api.ClusterBuild01: {FreeCapacity: 0.01}},
+				Degraded: []api.Cluster{api.ClusterBuild01},
+				Stuck:    []string{"a.yaml", "b.yaml", "c.yaml"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := tc.config.PlanRedispatch(tc.loads, tc.files, tc.maxMoves, 0.05)
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("%s: actual does not match expected, diff: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRedispatchPlanApply(t *testing.T) {
+	config := &Config{
+		BuildFarm: map[api.Cloud]map[api.Cluster]*BuildFarmConfig{
+			api.CloudAWS: {
+				api.ClusterBuild01: {FilenamesRaw: []string{"a.yaml", "b.yaml"}},
+				api.ClusterBuild03: {FilenamesRaw: []string{"d.yaml"}},
+			},
+		},
+	}
+	plan := RedispatchPlan{Moves: []Move{{Filename: "a.yaml", From: api.ClusterBuild01, To: api.ClusterBuild03}}}
+	plan.Apply(config)
+	expected := map[api.Cloud]map[api.Cluster]*BuildFarmConfig{
+		api.CloudAWS: {
+			api.ClusterBuild01: {FilenamesRaw: []string{"b.yaml"}, Filenames: sets.NewString("b.yaml")},
+			api.ClusterBuild03: {FilenamesRaw: []string{"a.yaml", "d.yaml"}, Filenames: sets.NewString("a.yaml", "d.yaml")},
+		},
+	}
+	if diff := cmp.Diff(expected, config.BuildFarm); diff != "" {
+		t.Errorf("actual does not match expected, diff: %s", diff)
+	}
+}
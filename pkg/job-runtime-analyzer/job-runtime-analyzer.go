@@ -80,10 +80,58 @@ func Run(baseJobURL string) error {
 
 	printRuntimes("All runtimes", runtimes)
 	printRuntimeByContainer(runtimesByContainer)
+	printPhases(phasesFromProfiles(stepGraph))
 
 	return nil
 }
 
+type stepPhase struct {
+	step     string
+	process  string
+	duration time.Duration
+}
+
+// phasesFromProfiles breaks the multi-stage steps that were profiled by the
+// entrypoint-wrapper into the processes they ran, indented by their depth in
+// the process tree
+func phasesFromProfiles(graph api.CIOperatorStepGraph) []stepPhase {
+	var phases []stepPhase
+	for _, step := range graph {
+		for _, substep := range step.Substeps {
+			if substep.Profile == nil {
+				continue
+			}
+			depths := map[int]int{}
+			for _, process := range substep.Profile.Processes {
+				depth := 0
+				if parentDepth, ok := depths[process.Parent]; ok {
+					depth = parentDepth + 1
+				}
+				depths[process.PID] = depth
+				phases = append(phases, stepPhase{
+					step:     substep.StepName,
+					process:  strings.Repeat("  ", depth) + process.Command,
+					duration: process.Duration,
+				})
+			}
+		}
+	}
+	return phases
+}
+
+func printPhases(data []stepPhase) {
+	if len(data) == 0 {
+		return
+	}
+	_, _ = fmt.Printf("Phases of profiled steps\n")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"step", "process", "runtime"})
+	for _, item := range data {
+		table.Append([]string{item.step, item.process, item.duration.String()})
+	}
+	table.Render()
+}
+
 func printRuntimes(title string, data []*podContainerRuntime, footers ...[]string) {
 	_, _ = fmt.Printf("%s\n", title)
 	table := tablewriter.NewWriter(os.Stdout)
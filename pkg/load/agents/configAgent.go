@@ -33,6 +33,10 @@ type ConfigAgent interface {
 	AddIndex(indexName string, indexFunc IndexFn) error
 	GetFromIndex(indexName string, indexKey string) ([]*api.ReleaseBuildConfiguration, error)
 	SubscribeToIndexChanges(indexName string) (<-chan IndexDelta, error)
+	// RebuildIndexes recomputes all indexes from the loaded configurations, for index
+	// functions that depend on more than the configurations, like the registry.
+	// Readers are not blocked while the indexes are computed.
+	RebuildIndexes()
 }
 
 // IndexFn can be used to add indexes to the ConfigAgent
@@ -48,6 +52,7 @@ type configAgent struct {
 	errorMetrics     *prometheus.CounterVec
 	indexFuncs       map[string]IndexFn
 	indexes          map[string]configIndex
+	indexGeneration  int
 	indexSubscribers map[string][]chan IndexDelta
 	reloadConfig     func() error
 }
@@ -144,7 +149,13 @@ func NewConfigAgent(configPath string, opts ...ConfigAgentOption) (ConfigAgent,
 func (a *configAgent) GetMatchingConfig(metadata api.Metadata) (api.ReleaseBuildConfiguration, error) {
 	a.lock.RLock()
 	defer a.lock.RUnlock()
-	orgConfigs, exist := a.configs[metadata.Org]
+	return MatchingConfig(a.configs, metadata)
+}
+
+// MatchingConfig finds the configuration that matches the metadata,
+// allowing for regex matching on branch names.
+func MatchingConfig(configs config.ByOrgRepo, metadata api.Metadata) (api.ReleaseBuildConfiguration, error) {
+	orgConfigs, exist := configs[metadata.Org]
 	if !exist {
 		return api.ReleaseBuildConfiguration{}, fmt.Errorf("could not find any config for org %s", metadata.Org)
 	}
@@ -221,6 +232,28 @@ func (a *configAgent) AddIndex(indexName string, indexFunc IndexFn) error {
 	return nil
 }
 
+// RebuildIndexes computes the indexes without holding the lock, which would block
+// all readers of the configurations for as long as the index functions run, and only
+// takes it to swap the new indexes in, unless they were built again in the meantime.
+func (a *configAgent) RebuildIndexes() {
+	a.lock.RLock()
+	configs, generation := a.configs, a.indexGeneration
+	indexFuncs := make(map[string]IndexFn, len(a.indexFuncs))
+	for indexName, indexFunc := range a.indexFuncs {
+		indexFuncs[indexName] = indexFunc
+	}
+	a.lock.RUnlock()
+
+	indexes := computeIndexes(configs, indexFuncs)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.indexGeneration != generation {
+		return
+	}
+	a.setIndexes(indexes)
+}
+
 func (a *configAgent) SubscribeToIndexChanges(indexName string) (<-chan IndexDelta, error) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
@@ -262,16 +295,19 @@ func (a *configAgent) loadFilenameToConfig() error {
 }
 
 func (a *configAgent) buildIndexes() {
-	oldIndexes := a.indexes
+	a.setIndexes(computeIndexes(a.configs, a.indexFuncs))
+}
 
-	a.indexes = map[string]configIndex{}
-	for indexName, indexFunc := range a.indexFuncs {
+// computeIndexes runs the index functions over all configurations
+func computeIndexes(configs config.ByOrgRepo, indexFuncs map[string]IndexFn) map[string]configIndex {
+	indexes := map[string]configIndex{}
+	for indexName, indexFunc := range indexFuncs {
 		// Make sure the index always exists even if empty, otherwise we return a confusing
 		// "index does not exist error" in case its empty
-		if _, exists := a.indexes[indexName]; !exists {
-			a.indexes[indexName] = configIndex{}
+		if _, exists := indexes[indexName]; !exists {
+			indexes[indexName] = configIndex{}
 		}
-		for _, orgConfigs := range a.configs {
+		for _, orgConfigs := range configs {
 			for _, repoConfigs := range orgConfigs {
 				for _, config := range repoConfigs {
 					var resusableConfigPtr *api.ReleaseBuildConfiguration
@@ -281,16 +317,26 @@ func (a *configAgent) buildIndexes() {
 							config := config
 							resusableConfigPtr = &config
 						}
-						if _, exists := a.indexes[indexName][indexKey]; !exists {
-							a.indexes[indexName][indexKey] = []*api.ReleaseBuildConfiguration{}
+						if _, exists := indexes[indexName][indexKey]; !exists {
+							indexes[indexName][indexKey] = []*api.ReleaseBuildConfiguration{}
 						}
 
-						a.indexes[indexName][indexKey] = append(a.indexes[indexName][indexKey], resusableConfigPtr)
+						indexes[indexName][indexKey] = append(indexes[indexName][indexKey], resusableConfigPtr)
 					}
 				}
 			}
 		}
+	}
+	return indexes
+}
 
+// setIndexes replaces the indexes and sends the changes to the subscribers. The caller
+// must hold the lock.
+func (a *configAgent) setIndexes(indexes map[string]configIndex) {
+	oldIndexes := a.indexes
+	a.indexes = indexes
+	a.indexGeneration++
+	for indexName := range a.indexes {
 		// Building the diff is expensive, so cache it in case we have multiple
 		// subscribers.
 		var changes []IndexDelta
@@ -179,6 +179,32 @@ func TestBuildIndexes(t *testing.T) {
 	}
 }
 
+func TestRebuildIndexes(t *testing.T) {
+	old := api.ReleaseBuildConfiguration{TestBinaryBuildCommands: "make old"}
+	reloaded := api.ReleaseBuildConfiguration{TestBinaryBuildCommands: "make reloaded"}
+	agent := &configAgent{lock: &sync.RWMutex{}, configs: config.ByOrgRepo{"org": {"repo": []api.ReleaseBuildConfiguration{old}}}}
+	var reloading bool
+	agent.indexFuncs = map[string]IndexFn{
+		"index-a": func(cfg api.ReleaseBuildConfiguration) []string {
+			if !reloading {
+				// the configurations are reloaded while the indexes are rebuilt, which
+				// can only happen when the rebuild does not hold the lock
+				reloading = true
+				agent.lock.Lock()
+				agent.configs = config.ByOrgRepo{"org": {"repo": []api.ReleaseBuildConfiguration{reloaded}}}
+				agent.buildIndexes()
+				agent.lock.Unlock()
+			}
+			return []string{cfg.TestBinaryBuildCommands}
+		},
+	}
+	agent.RebuildIndexes()
+	expected := map[string]configIndex{"index-a": {"make reloaded": []*api.ReleaseBuildConfiguration{&reloaded}}}
+	if diff := cmp.Diff(expected, agent.indexes); diff != "" {
+		t.Errorf("the rebuild replaced the indexes of the reloaded configurations, diff: %v", diff)
+	}
+}
+
 func TestConfigAgent_GetMatchingConfig(t *testing.T) {
 	var testCases = []struct {
 		name        string
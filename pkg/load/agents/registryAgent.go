@@ -20,6 +20,8 @@ import (
 type RegistryAgent interface {
 	ResolveConfig(config api.ReleaseBuildConfiguration) (api.ReleaseBuildConfiguration, error)
 	GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata)
+	GetObservers() registry.ObserverByName
+	GetVersions() registry.VersionByName
 	GetGeneration() int
 	registry.Resolver
 }
@@ -28,12 +30,15 @@ type registryAgent struct {
 	lock          *sync.RWMutex
 	resolver      registry.Resolver
 	registryPath  string
+	versionsPath  string
 	generation    int
 	errorMetrics  *prometheus.CounterVec
 	flags         load.RegistryFlag
 	references    registry.ReferenceByName
 	chains        registry.ChainByName
 	workflows     registry.WorkflowByName
+	observers     registry.ObserverByName
+	versions      registry.VersionByName
 	documentation map[string]string
 	metadata      api.RegistryMetadata
 }
@@ -56,7 +61,10 @@ type RegistryAgentOptions struct {
 	ErrorMetric *prometheus.CounterVec
 	// FlatRegistry describes if the registry is flat, which means org/repo/branch info can not be inferred
 	// from the filepath. Defaults to true.
-	FlatRegistry            *bool
+	FlatRegistry *bool
+	// RegistryVersions is the directory that holds the versions of the registry
+	// tests can pin components to, one subdirectory for each version.
+	RegistryVersions        string
 	UniversalSymlinkWatcher *UniversalSymlinkWatcher
 }
 
@@ -74,6 +82,13 @@ func WithRegistryFlat(v bool) RegistryAgentOption {
 	}
 }
 
+// WithRegistryVersions loads the versions of the registry from a directory
+func WithRegistryVersions(dir string) RegistryAgentOption {
+	return func(o *RegistryAgentOptions) {
+		o.RegistryVersions = dir
+	}
+}
+
 // NewRegistryAgent returns a RegistryAgent interface that automatically reloads when
 // the registry is changed on disk.
 func NewRegistryAgent(registryPath string, opts ...RegistryAgentOption) (RegistryAgent, error) {
@@ -93,6 +108,7 @@ func NewRegistryAgent(registryPath string, opts ...RegistryAgentOption) (Registr
 	}
 	a := &registryAgent{
 		registryPath: registryPath,
+		versionsPath: opt.RegistryVersions,
 		lock:         &sync.RWMutex{},
 		errorMetrics: opt.ErrorMetric,
 		flags:        flags,
@@ -106,7 +122,13 @@ func NewRegistryAgent(registryPath string, opts ...RegistryAgentOption) (Registr
 		opt.UniversalSymlinkWatcher.RegistryEventFn = a.loadRegistry
 	}
 
-	return a, startWatchers(registryPath, a.loadRegistry, a.errorMetrics, opt.UniversalSymlinkWatcher)
+	if err := startWatchers(registryPath, a.loadRegistry, a.errorMetrics, opt.UniversalSymlinkWatcher); err != nil {
+		return a, err
+	}
+	if opt.RegistryVersions != "" {
+		return a, startWatchers(opt.RegistryVersions, a.loadRegistry, a.errorMetrics, nil)
+	}
+	return a, nil
 }
 
 // ResolveConfig uses the registryAgent's resolver to resolve a provided ReleaseBuildConfiguration
@@ -138,6 +160,19 @@ func (a *registryAgent) GetRegistryComponents() (registry.ReferenceByName, regis
 	return a.references, a.chains, a.workflows, a.documentation, a.metadata
 }
 
+func (a *registryAgent) GetObservers() registry.ObserverByName {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.observers
+}
+
+// GetVersions returns the versions of the registry tests can pin components to
+func (a *registryAgent) GetVersions() registry.VersionByName {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.versions
+}
+
 func (a *registryAgent) loadRegistry() error {
 	logrus.Debug("Reloading registry")
 	duration, err := func() (time.Duration, error) {
@@ -149,12 +184,22 @@ func (a *registryAgent) loadRegistry() error {
 			recordErrorForMetric(a.errorMetrics, "failed to load ci-operator registry")
 			return time.Duration(0), fmt.Errorf("failed to load ci-operator registry (%w)", err)
 		}
+		versions := a.versions
+		if a.versionsPath != "" {
+			// versions never change once published, so only new ones are loaded
+			if versions, err = load.RegistryVersions(a.versionsPath, a.flags, a.versions); err != nil {
+				recordErrorForMetric(a.errorMetrics, "failed to load ci-operator registry versions")
+				return time.Duration(0), fmt.Errorf("failed to load ci-operator registry versions (%w)", err)
+			}
+		}
 		a.references = references
 		a.chains = chains
 		a.workflows = workflows
+		a.observers = observers
 		a.documentation = documentation
 		a.metadata = metadata
-		a.resolver = registry.NewResolver(references, chains, workflows, observers)
+		a.versions = versions
+		a.resolver = registry.NewVersionedResolver(references, chains, workflows, observers, versions)
 		a.generation++
 		return time.Since(startTime), nil
 	}()
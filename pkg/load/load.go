@@ -209,6 +209,38 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 	return references, chains, workflows, documentation, metadata, observers, nil
 }
 
+// RegistryVersions loads the versions of the registry in the subdirectories
+// of a directory, named after the revision or version tag they hold. Versions
+// already present in the cache are immutable and are not loaded again.
+func RegistryVersions(root string, flags RegistryFlag, cache registry.VersionByName) (registry.VersionByName, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry versions: %w", err)
+	}
+	versions := registry.VersionByName{}
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, "..") {
+			continue
+		}
+		if version, cached := cache[name]; cached {
+			versions[name] = version
+			continue
+		}
+		references, chains, workflows, _, _, _, err := Registry(filepath.Join(root, name), flags&^(RegistryMetadata|RegistryDocumentation))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load registry version %s: %w", name, err))
+			continue
+		}
+		versions[name] = registry.Version{References: references, Chains: chains, Workflows: workflows}
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return versions, nil
+}
+
 func loadReference(bytes []byte, baseDir, prefix string, flat bool) (string, string, api.LiteralTestStep, error) {
 	step := api.RegistryReferenceConfig{}
 	err := yaml.UnmarshalStrict(bytes, &step)
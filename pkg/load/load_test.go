@@ -257,3 +257,57 @@ func TestRegistry(t *testing.T) {
 		t.Error("got no error when expecting error on incorrect reference name")
 	}
 }
+
+func TestRegistryVersions(t *testing.T) {
+	temp := t.TempDir()
+	for _, version := range []string{"v1", "v2", "..2022_01_01"} {
+		dir := filepath.Join(temp, version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		ref := "ref:\n  as: step\n  from: cli\n  commands: step-commands.sh\n  resources:\n    requests:\n      cpu: 100m\n  documentation: " + version + "\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, "step-ref.yaml"), []byte(ref), 0644); err != nil {
+			t.Fatalf("failed to write reference: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "step-commands.sh"), []byte(version), 0644); err != nil {
+			t.Fatalf("failed to write commands: %v", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(temp, "README.md"), []byte("versions"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	step := func(commands string) registry.Version {
+		return registry.Version{
+			References: registry.ReferenceByName{"step": {
+				As:        "step",
+				From:      "cli",
+				Commands:  commands,
+				Resources: api.ResourceRequirements{Requests: api.ResourceList{"cpu": "100m"}},
+			}},
+			Chains:    registry.ChainByName{},
+			Workflows: registry.WorkflowByName{},
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		cache    registry.VersionByName
+		expected registry.VersionByName
+	}{{
+		name:     "all versions are loaded",
+		expected: registry.VersionByName{"v1": step("v1"), "v2": step("v2")},
+	}, {
+		name:     "cached versions are not loaded again",
+		cache:    registry.VersionByName{"v1": step("cached"), "v0": step("removed")},
+		expected: registry.VersionByName{"v1": step("cached"), "v2": step("v2")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			versions, err := RegistryVersions(temp, RegistryFlat|RegistryDocumentation, tc.cache)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(versions, tc.expected) {
+				t.Errorf("output versions different from expected: %s", diff.ObjectReflectDiff(tc.expected, versions))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package pod_scaler
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/utils"
+)
+
+const (
+	// buildContainerName is the container that runs a Docker strategy build in the build Pod
+	buildContainerName = "docker"
+	// testContainerName is the container that runs the commands of a test or step
+	testContainerName = "test"
+)
+
+// ConfiguredContainer is a container ci-operator creates for a configuration, identified the
+// same way as the usage data recorded for it, with the resources the configuration requests
+type ConfiguredContainer struct {
+	Metadata  FullMetadata                `json:"metadata"`
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// ContainersFor enumerates the containers ci-operator creates for the builds and tests of
+// a resolved configuration. Containers that Prow and ci-operator add to every Pod, like the
+// sidecar, are not included as configurations do not control their resources. Multi-stage
+// tests that are not resolved are an error, as their steps are only known once resolved.
+func ContainersFor(config api.ReleaseBuildConfiguration) ([]ConfiguredContainer, error) {
+	var builds []string
+	if config.BuildRootImage != nil {
+		builds = append(builds, string(api.PipelineImageStreamTagReferenceSource))
+	}
+	for _, build := range []struct {
+		name     api.PipelineImageStreamTagReference
+		commands string
+	}{
+		{name: api.PipelineImageStreamTagReferenceBinaries, commands: config.BinaryBuildCommands},
+		{name: api.PipelineImageStreamTagReferenceTestBinaries, commands: config.TestBinaryBuildCommands},
+		{name: api.PipelineImageStreamTagReferenceRPMs, commands: config.RpmBuildCommands},
+	} {
+		if build.commands != "" {
+			builds = append(builds, string(build.name))
+		}
+	}
+	for _, image := range config.Images {
+		builds = append(builds, string(image.To))
+	}
+
+	var containers []ConfiguredContainer
+	for _, build := range builds {
+		resources, err := steps.ResourcesFor(config.Resources.RequirementsForStep(build))
+		if err != nil {
+			return nil, fmt.Errorf("build %s: %w", build, err)
+		}
+		labels := labelsFor(config.Metadata, "", map[string]string{buildv1.BuildLabel: build})
+		containers = append(containers, ConfiguredContainer{
+			Metadata:  MetadataFor(labels, build+"-build", buildContainerName),
+			Resources: resources,
+		})
+	}
+
+	for _, test := range config.Tests {
+		switch {
+		case test.ContainerTestConfiguration != nil:
+			resources, err := steps.ResourcesFor(config.Resources.RequirementsForStep(test.As))
+			if err != nil {
+				return nil, fmt.Errorf("test %s: %w", test.As, err)
+			}
+			containers = append(containers, ConfiguredContainer{
+				Metadata:  MetadataFor(labelsFor(config.Metadata, test.As, nil), test.As, testContainerName),
+				Resources: resources,
+			})
+		case test.MultiStageTestConfiguration != nil:
+			return nil, fmt.Errorf("test %s: the test is not resolved, resolve the configuration against the step registry first", test.As)
+		case test.MultiStageTestConfigurationLiteral != nil:
+			literal := test.MultiStageTestConfigurationLiteral
+			for _, phase := range [][]api.LiteralTestStep{literal.Pre, literal.Test, literal.Post} {
+				for _, step := range phase {
+					resources, err := steps.ResourcesFor(step.Resources)
+					if err != nil {
+						return nil, fmt.Errorf("test %s: step %s: %w", test.As, step.As, err)
+					}
+					labels := labelsFor(config.Metadata, test.As, map[string]string{steps.LabelMetadataStep: step.As})
+					containers = append(containers, ConfiguredContainer{
+						Metadata:  MetadataFor(labels, fmt.Sprintf("%s-%s", test.As, step.As), testContainerName),
+						Resources: resources,
+					})
+				}
+			}
+		}
+	}
+	return containers, nil
+}
+
+// labelsFor mirrors the labels ci-operator puts on the Pods it creates
+func labelsFor(metadata api.Metadata, target string, extra map[string]string) map[string]string {
+	labels := map[string]string{
+		steps.LabelMetadataOrg:     metadata.Org,
+		steps.LabelMetadataRepo:    metadata.Repo,
+		steps.LabelMetadataBranch:  metadata.Branch,
+		steps.LabelMetadataVariant: metadata.Variant,
+		steps.LabelMetadataTarget:  target,
+		steps.CreatedByCILabel:     "true",
+	}
+	for key, value := range extra {
+		labels[key] = value
+	}
+	return utils.SanitizeLabels(labels)
+}
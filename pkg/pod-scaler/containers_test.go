@@ -0,0 +1,110 @@
+package pod_scaler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestContainersFor(t *testing.T) {
+	workflow := "ipi-aws"
+	metadata := api.Metadata{Org: "org", Repo: "repo", Branch: "branch", Variant: "variant"}
+	var testCases = []struct {
+		name          string
+		config        api.ReleaseBuildConfiguration
+		expected      []ConfiguredContainer
+		expectedError error
+	}{
+		{
+			name: "builds and tests",
+			config: api.ReleaseBuildConfiguration{
+				Metadata: metadata,
+				InputConfiguration: api.InputConfiguration{
+					BuildRootImage: &api.BuildRootImageConfiguration{},
+				},
+				BinaryBuildCommands: "make",
+				Images:              []api.ProjectDirectoryImageBuildStepConfiguration{{To: "component"}},
+				Resources: api.ResourceConfiguration{
+					"*":         {Requests: api.ResourceList{"cpu": "100m"}},
+					"component": {Requests: api.ResourceList{"memory": "1Gi"}},
+					"unit":      {Requests: api.ResourceList{"cpu": "2"}},
+				},
+				Tests: []api.TestStepConfiguration{
+					{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+					{As: "e2e", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						Test: []api.LiteralTestStep{{As: "run", Resources: api.ResourceRequirements{Requests: api.ResourceList{"memory": "2Gi"}}}},
+						Post: []api.LiteralTestStep{{As: "gather"}},
+					}},
+				},
+			},
+			expected: []ConfiguredContainer{
+				{
+					Metadata:  FullMetadata{Metadata: metadata, Pod: "src-build", Container: "docker"},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+				},
+				{
+					Metadata:  FullMetadata{Metadata: metadata, Pod: "bin-build", Container: "docker"},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+				},
+				{
+					Metadata: FullMetadata{Metadata: metadata, Pod: "component-build", Container: "docker"},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("100m"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					}},
+				},
+				{
+					Metadata:  FullMetadata{Metadata: metadata, Target: "unit", Pod: "unit", Container: "test"},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}},
+				},
+				{
+					Metadata:  FullMetadata{Metadata: metadata, Target: "e2e", Step: "run", Pod: "e2e-run", Container: "test"},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")}},
+				},
+				{
+					Metadata: FullMetadata{Metadata: metadata, Target: "e2e", Step: "gather", Pod: "e2e-gather", Container: "test"},
+				},
+			},
+		},
+		{
+			name: "invalid resources",
+			config: api.ReleaseBuildConfiguration{
+				Metadata: metadata,
+				Tests: []api.TestStepConfiguration{
+					{As: "e2e", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						Test: []api.LiteralTestStep{{As: "run", Resources: api.ResourceRequirements{Requests: api.ResourceList{"memory": "lots"}}}},
+					}},
+				},
+			},
+			expectedError: errors.New("test e2e: step run: invalid resource request: quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'"),
+		},
+		{
+			name: "unresolved test",
+			config: api.ReleaseBuildConfiguration{
+				Metadata: metadata,
+				Tests: []api.TestStepConfiguration{
+					{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: &workflow}},
+				},
+			},
+			expectedError: errors.New("test e2e: the test is not resolved, resolve the configuration against the step registry first"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := ContainersFor(testCase.config)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("%s: got incorrect error: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect containers: %v", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package history records the images promotions replace on ImageStreams and
+// the tags that promotions must leave untouched.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	imagev1 "github.com/openshift/api/image/v1"
+)
+
+const (
+	// Annotation on an ImageStream records, for each of its tags, the images
+	// which promotions replaced, newest first
+	Annotation = "ci.openshift.io/promotion-history"
+	// PinnedTagsAnnotation on an ImageStream holds a comma-separated list of its tags
+	// which promotions and the promotionreconciler must leave untouched
+	PinnedTagsAnnotation = "ci.openshift.io/promotion-pinned-tags"
+
+	// maxRecords is the number of records kept for each tag
+	maxRecords = 10
+)
+
+// Record describes an image that a tag pointed to before it was replaced
+type Record struct {
+	// Image is the digest of the replaced image, e.g. sha256:...
+	Image string `json:"image"`
+	// Replaced is the time the image was replaced
+	Replaced metav1.Time `json:"replaced"`
+	// By describes what replaced the image, e.g. the URL of the promotion job
+	By string `json:"by,omitempty"`
+}
+
+// ByTag holds the records of the replaced images for each tag, newest first
+type ByTag map[string][]Record
+
+// For returns the promotion history of an ImageStream
+func For(is *imagev1.ImageStream) (ByTag, error) {
+	history := ByTag{}
+	raw, ok := is.Annotations[Annotation]
+	if !ok || raw == "" {
+		return history, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &history); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the %s annotation of imagestream %s/%s: %w", Annotation, is.Namespace, is.Name, err)
+	}
+	return history, nil
+}
+
+// Add records the image a tag pointed to before it was replaced, keeping the
+// most recent records only
+func (h ByTag) Add(tag string, record Record) {
+	if record.Image == "" {
+		return
+	}
+	records := append([]Record{record}, h[tag]...)
+	if len(records) > maxRecords {
+		records = records[:maxRecords]
+	}
+	h[tag] = records
+}
+
+// Set stores the promotion history on an ImageStream
+func Set(is *imagev1.ImageStream, history ByTag) error {
+	raw, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the promotion history: %w", err)
+	}
+	if is.Annotations == nil {
+		is.Annotations = map[string]string{}
+	}
+	is.Annotations[Annotation] = string(raw)
+	return nil
+}
+
+// PinnedTags returns the tags of an ImageStream which must not be promoted
+func PinnedTags(is *imagev1.ImageStream) sets.String {
+	pinned := sets.NewString()
+	for _, tag := range strings.Split(is.Annotations[PinnedTagsAnnotation], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			pinned.Insert(tag)
+		}
+	}
+	return pinned
+}
+
+// SetPinnedTags stores the tags which must not be promoted on an ImageStream
+func SetPinnedTags(is *imagev1.ImageStream, pinned sets.String) {
+	if pinned.Len() == 0 {
+		delete(is.Annotations, PinnedTagsAnnotation)
+		return
+	}
+	if is.Annotations == nil {
+		is.Annotations = map[string]string{}
+	}
+	is.Annotations[PinnedTagsAnnotation] = strings.Join(pinned.List(), ",")
+}
+
+// CurrentImage returns the digest of the image a tag of an ImageStream points to
+func CurrentImage(is *imagev1.ImageStream, tag string) string {
+	for _, t := range is.Status.Tags {
+		if t.Tag == tag && len(t.Items) > 0 {
+			return t.Items[0].Image
+		}
+	}
+	return ""
+}
@@ -0,0 +1,20 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	history := ByTag{}
+	for i := 0; i < maxRecords+2; i++ {
+		history.Add("tag", Record{Image: fmt.Sprintf("sha256:%d", i)})
+	}
+	history.Add("tag", Record{})
+	if len(history["tag"]) != maxRecords {
+		t.Fatalf("expected %d records, got %d", maxRecords, len(history["tag"]))
+	}
+	if history["tag"][0].Image != fmt.Sprintf("sha256:%d", maxRecords+1) {
+		t.Errorf("expected the newest record first, got %s", history["tag"][0].Image)
+	}
+}
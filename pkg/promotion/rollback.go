@@ -0,0 +1,148 @@
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/promotion/history"
+)
+
+// Rollback describes the restoration of tags of an ImageStream to images they pointed to before
+type Rollback struct {
+	Namespace string
+	Name      string
+	Tags      []string
+	// Image is the digest of the image to restore. If empty, the image is taken from
+	// the promotion history of each tag, where Generation 1 is the most recently replaced one.
+	Image      string
+	Generation int
+	// Pin pins the restored tags, so that later promotions do not replace them
+	Pin bool
+	// By describes who rolls back, recorded in the promotion history
+	By string
+}
+
+// RolledBackTag is a tag restored to a previous image
+type RolledBackTag struct {
+	Tag  string
+	From string
+	To   string
+}
+
+// RollbackTags restores the tags of an ImageStream to previous images, recording the replaced
+// images in the promotion history. Tags already pointing to the requested image are left as they are.
+func RollbackTags(ctx context.Context, client ctrlruntimeclient.Client, rollback Rollback, dryRun bool, now time.Time, logger *logrus.Entry) ([]RolledBackTag, error) {
+	is := &imagev1.ImageStream{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: rollback.Namespace, Name: rollback.Name}, is); err != nil {
+		return nil, fmt.Errorf("failed to get imagestream %s/%s: %w", rollback.Namespace, rollback.Name, err)
+	}
+	tagHistory, err := history.For(is)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []RolledBackTag
+	for _, tag := range rollback.Tags {
+		target := rollback.Image
+		if target == "" {
+			records := tagHistory[tag]
+			if rollback.Generation < 1 || rollback.Generation > len(records) {
+				return nil, fmt.Errorf("tag %s has %d images in its promotion history, cannot roll back %d generations", tag, len(records), rollback.Generation)
+			}
+			target = records[rollback.Generation-1].Image
+		}
+		current := history.CurrentImage(is, tag)
+		if current == target {
+			logger.WithField("tag", tag).Info("Tag already points to the image")
+			continue
+		}
+		rolledBack = append(rolledBack, RolledBackTag{Tag: tag, From: current, To: target})
+	}
+	if dryRun {
+		return rolledBack, nil
+	}
+
+	for _, tag := range rolledBack {
+		if err := setTag(ctx, client, rollback.Namespace, rollback.Name, tag.Tag, tag.To); err != nil {
+			return nil, err
+		}
+		logger.WithField("tag", tag.Tag).WithField("image", tag.To).Info("Rolled back tag")
+	}
+
+	// Tagging updated the ImageStream
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: rollback.Namespace, Name: rollback.Name}, is); err != nil {
+		return nil, fmt.Errorf("failed to get imagestream %s/%s: %w", rollback.Namespace, rollback.Name, err)
+	}
+	for _, tag := range rolledBack {
+		tagHistory.Add(tag.Tag, history.Record{Image: tag.From, Replaced: metav1.NewTime(now), By: rollback.By})
+	}
+	if err := history.Set(is, tagHistory); err != nil {
+		return nil, err
+	}
+	if rollback.Pin {
+		history.SetPinnedTags(is, history.PinnedTags(is).Insert(rollback.Tags...))
+	}
+	if err := client.Update(ctx, is); err != nil {
+		return nil, fmt.Errorf("failed to update imagestream %s/%s: %w", rollback.Namespace, rollback.Name, err)
+	}
+	return rolledBack, nil
+}
+
+// setTag points the tag to an image of the ImageStream
+func setTag(ctx context.Context, client ctrlruntimeclient.Client, namespace, name, tag, image string) error {
+	reference := &imagev1.TagReference{
+		Name: tag,
+		From: &corev1.ObjectReference{Kind: "ImageStreamImage", Namespace: namespace, Name: fmt.Sprintf("%s@%s", name, image)},
+	}
+	ist := &imagev1.ImageStreamTag{}
+	key := ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: fmt.Sprintf("%s:%s", name, tag)}
+	if err := client.Get(ctx, key, ist); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get imagestreamtag %s: %w", key, err)
+		}
+		ist = &imagev1.ImageStreamTag{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: key.Name}, Tag: reference}
+		if err := client.Create(ctx, ist); err != nil {
+			return fmt.Errorf("failed to create imagestreamtag %s: %w", key, err)
+		}
+		return nil
+	}
+	ist.Tag = reference
+	if err := client.Update(ctx, ist); err != nil {
+		return fmt.Errorf("failed to update imagestreamtag %s: %w", key, err)
+	}
+	return nil
+}
+
+// SetPinned pins or unpins the tags of an ImageStream
+func SetPinned(ctx context.Context, client ctrlruntimeclient.Client, namespace, name string, tags []string, pinned bool) error {
+	is := &imagev1.ImageStream{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: name}, is); err != nil {
+		return fmt.Errorf("failed to get imagestream %s/%s: %w", namespace, name, err)
+	}
+	current := history.PinnedTags(is)
+	updated := sets.NewString(current.List()...)
+	if pinned {
+		updated.Insert(tags...)
+	} else {
+		updated.Delete(tags...)
+	}
+	if updated.Equal(current) {
+		return nil
+	}
+	history.SetPinnedTags(is, updated)
+	if err := client.Update(ctx, is); err != nil {
+		return fmt.Errorf("failed to update imagestream %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/promotion/history"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRollbackTags(t *testing.T) {
+	now := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+	imageStream := func() *imagev1.ImageStream {
+		is := &imagev1.ImageStream{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ocp", Name: "4.12", Annotations: map[string]string{history.PinnedTagsAnnotation: "other"}},
+			Status: imagev1.ImageStreamStatus{Tags: []imagev1.NamedTagEventList{
+				{Tag: "cli", Items: []imagev1.TagEvent{{Image: "sha256:bad"}, {Image: "sha256:good"}}},
+				{Tag: "tests", Items: []imagev1.TagEvent{{Image: "sha256:tests"}}},
+			}},
+		}
+		if err := history.Set(is, history.ByTag{
+			"cli":   {{Image: "sha256:good", Replaced: earlier}, {Image: "sha256:older", Replaced: earlier}},
+			"tests": {{Image: "sha256:tests", Replaced: earlier}},
+		}); err != nil {
+			t.Fatalf("failed to set history: %v", err)
+		}
+		return is
+	}
+
+	testCases := []struct {
+		name               string
+		rollback           Rollback
+		dryRun             bool
+		expected           []RolledBackTag
+		expectedError      error
+		expectedHistory    history.ByTag
+		expectedPinnedTags sets.String
+	}{
+		{
+			name:     "roll back to the previous image",
+			rollback: Rollback{Tags: []string{"cli", "tests"}, Generation: 1, By: "someone"},
+			expected: []RolledBackTag{{Tag: "cli", From: "sha256:bad", To: "sha256:good"}},
+			expectedHistory: history.ByTag{
+				"cli":   {{Image: "sha256:bad", Replaced: metav1.NewTime(now), By: "someone"}, {Image: "sha256:good", Replaced: earlier}, {Image: "sha256:older", Replaced: earlier}},
+				"tests": {{Image: "sha256:tests", Replaced: earlier}},
+			},
+			expectedPinnedTags: sets.NewString("other"),
+		},
+		{
+			name:     "roll back to an image and pin the tag",
+			rollback: Rollback{Tags: []string{"cli"}, Image: "sha256:older", Pin: true},
+			expected: []RolledBackTag{{Tag: "cli", From: "sha256:bad", To: "sha256:older"}},
+			expectedHistory: history.ByTag{
+				"cli":   {{Image: "sha256:bad", Replaced: metav1.NewTime(now)}, {Image: "sha256:good", Replaced: earlier}, {Image: "sha256:older", Replaced: earlier}},
+				"tests": {{Image: "sha256:tests", Replaced: earlier}},
+			},
+			expectedPinnedTags: sets.NewString("cli", "other"),
+		},
+		{
+			name:     "dry run changes nothing",
+			rollback: Rollback{Tags: []string{"cli"}, Generation: 2, Pin: true},
+			dryRun:   true,
+			expected: []RolledBackTag{{Tag: "cli", From: "sha256:bad", To: "sha256:older"}},
+			expectedHistory: history.ByTag{
+				"cli":   {{Image: "sha256:good", Replaced: earlier}, {Image: "sha256:older", Replaced: earlier}},
+				"tests": {{Image: "sha256:tests", Replaced: earlier}},
+			},
+			expectedPinnedTags: sets.NewString("other"),
+		},
+		{
+			name:          "not enough history",
+			rollback:      Rollback{Tags: []string{"cli"}, Generation: 3},
+			expectedError: fmt.Errorf("tag cli has 2 images in its promotion history, cannot roll back 3 generations"),
+			expectedHistory: history.ByTag{
+				"cli":   {{Image: "sha256:good", Replaced: earlier}, {Image: "sha256:older", Replaced: earlier}},
+				"tests": {{Image: "sha256:tests", Replaced: earlier}},
+			},
+			expectedPinnedTags: sets.NewString("other"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithScheme(imageScheme(t)).WithObjects(imageStream()).Build()
+			tc.rollback.Namespace, tc.rollback.Name = "ocp", "4.12"
+			actual, err := RollbackTags(context.TODO(), client, tc.rollback, tc.dryRun, now, logrus.NewEntry(logrus.StandardLogger()))
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("rolled back tags differ from expected:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("error differs from expected:\n%s", diff)
+			}
+
+			is := &imagev1.ImageStream{}
+			if err := client.Get(context.TODO(), ctrlruntimeclient.ObjectKey{Namespace: "ocp", Name: "4.12"}, is); err != nil {
+				t.Fatalf("failed to get imagestream: %v", err)
+			}
+			tagHistory, err := history.For(is)
+			if err != nil {
+				t.Fatalf("failed to get history: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedHistory, tagHistory); diff != "" {
+				t.Errorf("history differs from expected:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedPinnedTags, history.PinnedTags(is)); diff != "" {
+				t.Errorf("pinned tags differ from expected:\n%s", diff)
+			}
+			for _, tag := range actual {
+				ist := &imagev1.ImageStreamTag{}
+				err := client.Get(context.TODO(), ctrlruntimeclient.ObjectKey{Namespace: "ocp", Name: "4.12:" + tag.Tag}, ist)
+				if tc.dryRun {
+					if err == nil {
+						t.Errorf("expected no imagestreamtag %s in dry run", tag.Tag)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("failed to get imagestreamtag: %v", err)
+				}
+				if expected := "4.12@" + tag.To; ist.Tag == nil || ist.Tag.From == nil || ist.Tag.From.Name != expected {
+					t.Errorf("expected imagestreamtag %s to refer to %s, got %v", tag.Tag, expected, ist.Tag)
+				}
+			}
+		})
+	}
+}
+
+func TestSetPinned(t *testing.T) {
+	client := fakectrlruntimeclient.NewClientBuilder().WithScheme(imageScheme(t)).WithObjects(&imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ocp", Name: "4.12", Annotations: map[string]string{history.PinnedTagsAnnotation: "cli"}},
+	}).Build()
+	pinned := func() sets.String {
+		is := &imagev1.ImageStream{}
+		if err := client.Get(context.TODO(), ctrlruntimeclient.ObjectKey{Namespace: "ocp", Name: "4.12"}, is); err != nil {
+			t.Fatalf("failed to get imagestream: %v", err)
+		}
+		return history.PinnedTags(is)
+	}
+
+	if err := SetPinned(context.TODO(), client, "ocp", "4.12", []string{"tests", "cli"}, true); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+	if diff := cmp.Diff(sets.NewString("cli", "tests"), pinned()); diff != "" {
+		t.Errorf("pinned tags differ from expected:\n%s", diff)
+	}
+	if err := SetPinned(context.TODO(), client, "ocp", "4.12", []string{"cli", "tests"}, false); err != nil {
+		t.Fatalf("failed to unpin: %v", err)
+	}
+	if diff := cmp.Diff(sets.NewString(), pinned()); diff != "" {
+		t.Errorf("pinned tags differ from expected:\n%s", diff)
+	}
+}
+
+func imageScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := imagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register imagev1 scheme: %v", err)
+	}
+	return scheme
+}
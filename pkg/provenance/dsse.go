@@ -0,0 +1,208 @@
+package provenance
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// PayloadType is the DSSE payload type of in-toto statements
+const PayloadType = "application/vnd.in-toto+json"
+
+// Envelope is a DSSE envelope, the format sigstore uses for signed attestations
+type Envelope struct {
+	PayloadType string `json:"payloadType"`
+	// Payload is the base64 encoded statement
+	Payload    string      `json:"payload"`
+	Signatures []Signature `json:"signatures"`
+}
+
+// Signature is a signature of an envelope
+type Signature struct {
+	// KeyID identifies the key that made the signature, see KeyID
+	KeyID string `json:"keyid"`
+	// Sig is the base64 encoded signature
+	Sig string `json:"sig"`
+}
+
+// pae is the DSSE pre-authentication encoding of a payload, which is what gets signed
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// KeyID is the hex encoded SHA-256 digest of the DER encoded public key, so
+// verifiers can tell which key an envelope is signed with when keys rotate
+func KeyID(key *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	digest := sha256.Sum256(der)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// Signer signs statements with an ECDSA private key
+type Signer struct {
+	key   *ecdsa.PrivateKey
+	keyID string
+}
+
+func newSigner(key *ecdsa.PrivateKey) (*Signer, error) {
+	keyID, err := KeyID(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: key, keyID: keyID}, nil
+}
+
+// LoadSigner loads the PEM encoded ECDSA private key at the path
+func LoadSigner(path string) (*Signer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	return NewSigner(raw)
+}
+
+// NewSigner creates a signer from a PEM encoded, unencrypted ECDSA private key
+func NewSigner(raw []byte) (*Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key: %w", err)
+		}
+		return newSigner(key)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key: %w", err)
+		}
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("signing key is a %T, not an ECDSA key", key)
+		}
+		return newSigner(ecKey)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q for the signing key", block.Type)
+	}
+}
+
+// Sign serializes the statement and signs it
+func (s *Signer) Sign(statement Statement) (Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal statement: %w", err)
+	}
+	return s.SignPayload(PayloadType, payload)
+}
+
+// SignPayload signs an arbitrary payload of the given type
+func (s *Signer) SignPayload(payloadType string, payload []byte) (Envelope, error) {
+	digest := sha256.Sum256(pae(payloadType, payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to sign payload: %w", err)
+	}
+	return Envelope{
+		PayloadType: payloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []Signature{{KeyID: s.keyID, Sig: base64.StdEncoding.EncodeToString(sig)}},
+	}, nil
+}
+
+// Verifier returns a verifier for the public half of the signing key
+func (s *Signer) Verifier() *Verifier {
+	return &Verifier{key: &s.key.PublicKey, keyID: s.keyID}
+}
+
+// Verifier verifies envelopes with an ECDSA public key
+type Verifier struct {
+	key   *ecdsa.PublicKey
+	keyID string
+}
+
+// LoadVerifier loads the PEM encoded ECDSA public key at the path
+func LoadVerifier(path string) (*Verifier, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	return NewVerifier(raw)
+}
+
+// NewVerifier creates a verifier from a PEM encoded ECDSA public key
+func NewVerifier(raw []byte) (*Verifier, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("public key is not a PEM encoded PUBLIC KEY block")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is a %T, not an ECDSA key", key)
+	}
+	keyID, err := KeyID(ecKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{key: ecKey, keyID: keyID}, nil
+}
+
+// Verify checks that the envelope is signed by the key and returns the statement in it
+func (v *Verifier) Verify(envelope Envelope) (*Statement, error) {
+	payload, err := v.VerifyPayload(envelope, PayloadType)
+	if err != nil {
+		return nil, err
+	}
+	var statement Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal statement: %w", err)
+	}
+	if statement.Type != StatementType || statement.PredicateType != PredicateType {
+		return nil, fmt.Errorf("unexpected statement type %q with predicate type %q", statement.Type, statement.PredicateType)
+	}
+	return &statement, nil
+}
+
+// VerifyPayload checks that the envelope holds a payload of the given type that is
+// signed by the key and returns the payload. Signatures made with another key ID are
+// skipped, signatures without one are tried.
+func (v *Verifier) VerifyPayload(envelope Envelope, payloadType string) ([]byte, error) {
+	if envelope.PayloadType != payloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	digest := sha256.Sum256(pae(envelope.PayloadType, payload))
+	for _, signature := range envelope.Signatures {
+		if signature.KeyID != "" && signature.KeyID != v.keyID {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err != nil {
+			continue
+		}
+		if ecdsa.VerifyASN1(v.key, digest[:], sig) {
+			return payload, nil
+		}
+	}
+	return nil, errors.New("no valid signature")
+}
@@ -0,0 +1,145 @@
+// Package provenance describes how promoted images were built with in-toto statements
+// carrying SLSA provenance, signed in DSSE envelopes the way sigstore tooling expects them.
+package provenance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// StatementType is the type of in-toto statements
+	StatementType = "https://in-toto.io/Statement/v0.1"
+	// PredicateType is the type of the SLSA provenance predicate
+	PredicateType = "https://slsa.dev/provenance/v0.2"
+	// BuilderID identifies ci-operator as the builder of promoted images
+	BuilderID = "https://github.com/openshift/ci-tools/tree/master/cmd/ci-operator"
+	// BuildType identifies builds of images promoted by ci-operator
+	BuildType = "https://github.com/openshift/ci-tools/promotion@v1"
+)
+
+// Statement is an in-toto statement about promoted images
+type Statement struct {
+	Type          string     `json:"_type"`
+	PredicateType string     `json:"predicateType"`
+	Subject       []Subject  `json:"subject"`
+	Predicate     Provenance `json:"predicate"`
+}
+
+// Subject is an image the statement is about
+type Subject struct {
+	// Name is the pull spec of the promoted image
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is the SLSA provenance predicate
+type Provenance struct {
+	Builder    Builder    `json:"builder"`
+	BuildType  string     `json:"buildType"`
+	Invocation Invocation `json:"invocation"`
+	// BuildConfig is the configuration of the image build, if the image was built from the repository
+	BuildConfig *api.ProjectDirectoryImageBuildStepConfiguration `json:"buildConfig,omitempty"`
+	Metadata    Metadata                                         `json:"metadata"`
+	// Materials are the source commits and the base images of the build
+	Materials []Material `json:"materials,omitempty"`
+}
+
+// Builder identifies what built the image
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// Invocation describes the job that built the image
+type Invocation struct {
+	ConfigSource ConfigSource `json:"configSource"`
+	// Environment holds the identifiers of the job, including the ProwJob ID
+	Environment map[string]string `json:"environment,omitempty"`
+}
+
+// ConfigSource is the repository whose configuration started the build
+type ConfigSource struct {
+	URI        string            `json:"uri,omitempty"`
+	Digest     map[string]string `json:"digest,omitempty"`
+	EntryPoint string            `json:"entryPoint,omitempty"`
+}
+
+// Metadata holds information about the build
+type Metadata struct {
+	BuildInvocationID string     `json:"buildInvocationId,omitempty"`
+	BuildFinishedOn   *time.Time `json:"buildFinishedOn,omitempty"`
+}
+
+// Material is an input of the build
+type Material struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// Digest splits an image digest like sha256:... into the form used in statements
+func Digest(image string) map[string]string {
+	algorithm, value, ok := strings.Cut(image, ":")
+	if !ok || value == "" {
+		return nil
+	}
+	return map[string]string{algorithm: value}
+}
+
+// NewStatement creates the statement about a promoted image. The image is the configuration
+// of its build, nil if the promoted image was not built from the repository.
+func NewStatement(subject Subject, jobSpec *api.JobSpec, image *api.ProjectDirectoryImageBuildStepConfiguration, baseImages []Material, finished time.Time) Statement {
+	statement := Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Subject:       []Subject{subject},
+		Predicate: Provenance{
+			Builder:     Builder{ID: BuilderID},
+			BuildType:   BuildType,
+			BuildConfig: image,
+			Invocation: Invocation{
+				ConfigSource: ConfigSource{EntryPoint: jobSpec.Job},
+				Environment: map[string]string{
+					"job":        jobSpec.Job,
+					"build_id":   jobSpec.BuildID,
+					"prowjob_id": jobSpec.ProwJobID,
+				},
+			},
+			Metadata: Metadata{BuildInvocationID: jobSpec.ProwJobID, BuildFinishedOn: &finished},
+		},
+	}
+
+	var refs []prowapi.Refs
+	if jobSpec.Refs != nil {
+		refs = append(refs, *jobSpec.Refs)
+	}
+	refs = append(refs, jobSpec.ExtraRefs...)
+	for i, ref := range refs {
+		uri := "git+" + repoLink(ref)
+		if i == 0 {
+			statement.Predicate.Invocation.ConfigSource.URI = uri
+			statement.Predicate.Invocation.ConfigSource.Digest = map[string]string{"sha1": ref.BaseSHA}
+		}
+		statement.Predicate.Materials = append(statement.Predicate.Materials, Material{URI: uri, Digest: map[string]string{"sha1": ref.BaseSHA}})
+		for _, pull := range ref.Pulls {
+			statement.Predicate.Materials = append(statement.Predicate.Materials, Material{URI: uri, Digest: map[string]string{"sha1": pull.SHA}})
+		}
+	}
+
+	sorted := append([]Material{}, baseImages...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].URI < sorted[j].URI })
+	statement.Predicate.Materials = append(statement.Predicate.Materials, sorted...)
+	return statement
+}
+
+func repoLink(ref prowapi.Refs) string {
+	if ref.RepoLink != "" {
+		return ref.RepoLink
+	}
+	return fmt.Sprintf("https://github.com/%s/%s", ref.Org, ref.Repo)
+}
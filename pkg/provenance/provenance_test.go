@@ -0,0 +1,240 @@
+package provenance
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestNewStatement(t *testing.T) {
+	finished := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	jobSpec := &api.JobSpec{JobSpec: downwardapi.JobSpec{
+		Job:       "branch-ci-org-repo-master-images",
+		BuildID:   "1",
+		ProwJobID: "uuid",
+		Refs:      &prowapi.Refs{Org: "org", Repo: "repo", BaseSHA: "base", Pulls: []prowapi.Pull{{SHA: "pull"}}},
+		ExtraRefs: []prowapi.Refs{{Org: "org", Repo: "other", RepoLink: "https://example.com/org/other", BaseSHA: "other"}},
+	}}
+	image := &api.ProjectDirectoryImageBuildStepConfiguration{From: "base", To: "cli"}
+	baseImages := []Material{{URI: "registry/ci/b@sha256:b", Digest: Digest("sha256:b")}, {URI: "registry/ci/a@sha256:a", Digest: Digest("sha256:a")}}
+	subject := Subject{Name: "registry/ocp/4.12:cli", Digest: Digest("sha256:cli")}
+
+	expected := Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Subject:       []Subject{{Name: "registry/ocp/4.12:cli", Digest: map[string]string{"sha256": "cli"}}},
+		Predicate: Provenance{
+			Builder:     Builder{ID: BuilderID},
+			BuildType:   BuildType,
+			BuildConfig: image,
+			Invocation: Invocation{
+				ConfigSource: ConfigSource{URI: "git+https://github.com/org/repo", Digest: map[string]string{"sha1": "base"}, EntryPoint: "branch-ci-org-repo-master-images"},
+				Environment:  map[string]string{"job": "branch-ci-org-repo-master-images", "build_id": "1", "prowjob_id": "uuid"},
+			},
+			Metadata: Metadata{BuildInvocationID: "uuid", BuildFinishedOn: &finished},
+			Materials: []Material{
+				{URI: "git+https://github.com/org/repo", Digest: map[string]string{"sha1": "base"}},
+				{URI: "git+https://github.com/org/repo", Digest: map[string]string{"sha1": "pull"}},
+				{URI: "git+https://example.com/org/other", Digest: map[string]string{"sha1": "other"}},
+				{URI: "registry/ci/a@sha256:a", Digest: map[string]string{"sha256": "a"}},
+				{URI: "registry/ci/b@sha256:b", Digest: map[string]string{"sha256": "b"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, NewStatement(subject, jobSpec, image, baseImages, finished)); diff != "" {
+		t.Errorf("statement differs from expected:\n%s", diff)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer, verifier := newKeys(t)
+	_, otherVerifier := newKeys(t)
+	statement := Statement{Type: StatementType, PredicateType: PredicateType, Subject: []Subject{{Name: "cli", Digest: Digest("sha256:cli")}}}
+	envelope, err := signer.Sign(statement)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if len(envelope.Signatures) != 1 || envelope.Signatures[0].KeyID == "" || envelope.Signatures[0].KeyID != verifier.keyID {
+		t.Fatalf("expected one signature with the key ID %q, got %v", verifier.keyID, envelope.Signatures)
+	}
+	withKeyID := func(keyID string) func() Envelope {
+		return func() Envelope {
+			other := envelope
+			other.Signatures = []Signature{{KeyID: keyID, Sig: envelope.Signatures[0].Sig}}
+			return other
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		envelope      func() Envelope
+		verifier      *Verifier
+		expectedError error
+	}{
+		{
+			name:     "valid signature",
+			envelope: func() Envelope { return envelope },
+			verifier: verifier,
+		},
+		{
+			name: "tampered payload",
+			envelope: func() Envelope {
+				tampered := envelope
+				tampered.Payload = base64.StdEncoding.EncodeToString([]byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`))
+				return tampered
+			},
+			verifier:      verifier,
+			expectedError: errors.New("no valid signature"),
+		},
+		{
+			name:     "signature without a key ID",
+			envelope: withKeyID(""),
+			verifier: verifier,
+		},
+		{
+			name:          "signature with another key ID",
+			envelope:      withKeyID("other"),
+			verifier:      verifier,
+			expectedError: errors.New("no valid signature"),
+		},
+		{
+			name:          "signed with another key",
+			envelope:      func() Envelope { return envelope },
+			verifier:      otherVerifier,
+			expectedError: errors.New("no valid signature"),
+		},
+		{
+			name: "unexpected payload type",
+			envelope: func() Envelope {
+				other := envelope
+				other.PayloadType = "text/plain"
+				return other
+			},
+			verifier:      verifier,
+			expectedError: errors.New(`unexpected payload type "text/plain"`),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := tc.verifier.Verify(tc.envelope())
+			if diff := cmp.Diff(tc.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("error differs from expected:\n%s", diff)
+			}
+			if err == nil {
+				if diff := cmp.Diff(&statement, actual); diff != "" {
+					t.Errorf("statement differs from expected:\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreAndVerifyImageStream(t *testing.T) {
+	signer, verifier := newKeys(t)
+	scheme := runtime.NewScheme()
+	if err := imagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register imagev1 scheme: %v", err)
+	}
+	if err := coreapi.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register core scheme: %v", err)
+	}
+	client := fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(&imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ocp", Name: "4.12"},
+		Status: imagev1.ImageStreamStatus{Tags: []imagev1.NamedTagEventList{
+			{Tag: "cli", Items: []imagev1.TagEvent{{Image: "sha256:cli"}}},
+			{Tag: "replaced", Items: []imagev1.TagEvent{{Image: "sha256:new"}}},
+			{Tag: "tests", Items: []imagev1.TagEvent{{Image: "sha256:tests"}}},
+			{Tag: "unsigned", Items: []imagev1.TagEvent{{Image: "sha256:unsigned"}}},
+		}},
+	}).Build()
+
+	sign := func(image string) Envelope {
+		envelope, err := signer.Sign(Statement{Type: StatementType, PredicateType: PredicateType, Subject: []Subject{{Digest: Digest(image)}}})
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return envelope
+	}
+	for _, stored := range []struct {
+		tag, registry, image string
+	}{
+		{tag: "cli", registry: "registry.ci.openshift.org", image: "sha256:old"},
+		{tag: "replaced", registry: "registry.ci.openshift.org", image: "sha256:old"},
+		{tag: "cli", registry: "registry.ci.openshift.org", image: "sha256:cli"},
+		{tag: "replaced", registry: "quay.io/openshift/ci", image: "sha256:old"},
+		{tag: "tests", registry: "quay.io/openshift/ci", image: "sha256:old"},
+		{tag: "tests", registry: "registry.ci.openshift.org", image: "sha256:tests"},
+	} {
+		if err := Store(context.TODO(), client, "ocp", "4.12", stored.tag, stored.registry, sign(stored.image)); err != nil {
+			t.Fatalf("failed to store: %v", err)
+		}
+	}
+
+	actual, err := VerifyImageStream(context.TODO(), client, verifier, "ocp", "4.12", nil)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	expected := []TagVerification{
+		{Tag: "cli", Image: "sha256:cli"},
+		{Tag: "replaced", Image: "sha256:new", Error: fmt.Errorf("provenance is about a different image than sha256:new")},
+		{Tag: "tests", Image: "sha256:tests"},
+		{Tag: "unsigned", Image: "sha256:unsigned", Error: fmt.Errorf("no provenance recorded")},
+	}
+	if diff := cmp.Diff(expected, actual, testhelper.EquateErrorMessage); diff != "" {
+		t.Errorf("verifications differ from expected:\n%s", diff)
+	}
+
+	cms := &coreapi.ConfigMapList{}
+	if err := client.List(context.TODO(), cms); err != nil {
+		t.Fatalf("failed to list configmaps: %v", err)
+	}
+	if len(cms.Items) != 3 {
+		t.Errorf("expected one configmap for each of the 3 tags, got %d", len(cms.Items))
+	}
+}
+
+func newKeys(t *testing.T) (*Signer, *Verifier) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	private, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	signer, err := NewSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private}))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	verifier, err := NewVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	return signer, verifier
+}
@@ -0,0 +1,165 @@
+package provenance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	coreapi "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+)
+
+const (
+	// ImageStreamLabel on a provenance ConfigMap names the ImageStream it holds the envelopes of
+	ImageStreamLabel = "ci.openshift.io/provenance-for"
+	// TagAnnotation on a provenance ConfigMap names the tag it holds the envelopes of
+	TagAnnotation = "ci.openshift.io/provenance-for-tag"
+)
+
+// ConfigMapName is the name of the ConfigMap that holds the envelopes for a tag of an ImageStream.
+// Tags are not valid object names and the two together may be too long for one, so the name is hashed.
+func ConfigMapName(imageStream, tag string) string {
+	hash := sha256.Sum256([]byte(imageStream + ":" + tag))
+	return fmt.Sprintf("provenance-%x", hash[:16])
+}
+
+var invalidKeyCharacters = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// keyFor is the key of the envelope about the image promoted into the registry
+func keyFor(registry string) string {
+	return invalidKeyCharacters.ReplaceAllString(registry, "_")
+}
+
+// Store saves the envelope about the image promoted into the registry next to the
+// ImageStream tag it is about, replacing the envelope previously stored for them
+func Store(ctx context.Context, client ctrlruntimeclient.Client, namespace, imageStream, tag, registry string, envelope Envelope) error {
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal envelope for %s:%s: %w", imageStream, tag, err)
+	}
+
+	key := ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: ConfigMapName(imageStream, tag)}
+	cm := &coreapi.ConfigMap{}
+	if err := client.Get(ctx, key, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get configmap %s: %w", key, err)
+		}
+		cm = &coreapi.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        key.Name,
+				Labels:      map[string]string{ImageStreamLabel: imageStream},
+				Annotations: map[string]string{TagAnnotation: tag},
+			},
+			Data: map[string]string{keyFor(registry): string(raw)},
+		}
+		if err := client.Create(ctx, cm); err != nil {
+			return fmt.Errorf("failed to create configmap %s: %w", key, err)
+		}
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[keyFor(registry)] = string(raw)
+	if err := client.Update(ctx, cm); err != nil {
+		return fmt.Errorf("failed to update configmap %s: %w", key, err)
+	}
+	return nil
+}
+
+// TagVerification is the result of verifying the provenance of a tag
+type TagVerification struct {
+	Tag   string
+	Image string
+	// Error is why the provenance of the tag is not trusted, nil if it is
+	Error error
+}
+
+// VerifyImageStream verifies that the given tags of the ImageStream, all of its tags if none
+// are given, point to the images described by correctly signed statements
+func VerifyImageStream(ctx context.Context, client ctrlruntimeclient.Client, verifier *Verifier, namespace, name string, tags []string) ([]TagVerification, error) {
+	is := &imagev1.ImageStream{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: name}, is); err != nil {
+		return nil, fmt.Errorf("failed to get imagestream %s/%s: %w", namespace, name, err)
+	}
+
+	images := map[string]string{}
+	for _, tag := range is.Status.Tags {
+		if len(tag.Items) > 0 {
+			images[tag.Tag] = tag.Items[0].Image
+		}
+	}
+	if len(tags) == 0 {
+		for tag := range images {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+	}
+
+	var verifications []TagVerification
+	for _, tag := range tags {
+		verification := TagVerification{Tag: tag, Image: images[tag]}
+		verification.Error = Verify(ctx, client, verifier, namespace, name, tag, verification.Image)
+		verifications = append(verifications, verification)
+	}
+	return verifications, nil
+}
+
+// Verify verifies that the image the ImageStream tag points to is described by a correctly signed
+// statement. Any of the envelopes recorded for the tag, one for each registry it was promoted into, will do.
+func Verify(ctx context.Context, client ctrlruntimeclient.Client, verifier *Verifier, namespace, imageStream, tag, image string) error {
+	if image == "" {
+		return fmt.Errorf("tag does not point to an image")
+	}
+	cm := &coreapi.ConfigMap{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: ConfigMapName(imageStream, tag)}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("no provenance recorded")
+		}
+		return fmt.Errorf("failed to get provenance: %w", err)
+	}
+	if len(cm.Data) == 0 {
+		return fmt.Errorf("no provenance recorded")
+	}
+
+	var keys []string
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var err error
+	for _, key := range keys {
+		if err = verifyEnvelope(verifier, image, cm.Data[key]); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func verifyEnvelope(verifier *Verifier, image, raw string) error {
+	var envelope Envelope
+	if err := json.Unmarshal([]byte(raw), &envelope); err != nil {
+		return fmt.Errorf("failed to unmarshal envelope: %w", err)
+	}
+	statement, err := verifier.Verify(envelope)
+	if err != nil {
+		return err
+	}
+	expected := Digest(image)
+	for _, subject := range statement.Subject {
+		for algorithm, value := range expected {
+			if subject.Digest[algorithm] == value {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("provenance is about a different image than %s", image)
+}
@@ -124,6 +124,9 @@ func NewProwJobBaseBuilderForTest(configSpec *cioperatorapi.ReleaseBuildConfigur
 	if test.Cluster != "" {
 		p.WithLabel(cioperatorapi.ClusterLabel, string(test.Cluster))
 	}
+	for _, capability := range test.Capabilities {
+		p.WithLabel(cioperatorapi.CapabilityLabelPrefix+capability, capability)
+	}
 
 	if test.ClusterClaim != nil {
 		p.PodSpec.Add(Claims())
@@ -445,6 +445,15 @@ func TestNewProwJobBaseBuilderForTest(t *testing.T) {
 				ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"},
 			},
 		},
+		{
+			name: "simple container-based test with capabilities",
+			test: ciop.TestStepConfiguration{
+				As:                         "simple",
+				Commands:                   "make",
+				Capabilities:               []string{"arm64", "vpn"},
+				ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
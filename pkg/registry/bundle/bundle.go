@@ -0,0 +1,161 @@
+// Package bundle holds snapshots of the ci-operator configurations and the step
+// registry, so configurations can be resolved without the configresolver.
+package bundle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/provenance"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	// PayloadType is the type of the signed payload in a bundle
+	PayloadType = "application/vnd.openshift.ci.resolver-bundle+json"
+	// Version is the version of the bundle format this package reads and writes
+	Version = 1
+)
+
+// Bundle is a snapshot of the configurations and the registry served by the configresolver
+type Bundle struct {
+	Version int `json:"version"`
+	// Generation increases with every snapshot written to the same path, so consumers
+	// can refuse to use bundles older than one they know of
+	Generation int64     `json:"generation"`
+	Created    time.Time `json:"created"`
+
+	Configs    config.ByOrgRepo         `json:"configs"`
+	References registry.ReferenceByName `json:"references"`
+	Chains     registry.ChainByName     `json:"chains"`
+	Workflows  registry.WorkflowByName  `json:"workflows"`
+	Observers  registry.ObserverByName  `json:"observers"`
+	// Versions are the versions of the registry tests can pin components to
+	Versions registry.VersionByName `json:"versions,omitempty"`
+}
+
+// New snapshots the configurations and the registry the agents currently serve
+func New(configs agents.ConfigAgent, registryAgent agents.RegistryAgent, generation int64, created time.Time) *Bundle {
+	references, chains, workflows, _, _ := registryAgent.GetRegistryComponents()
+	return &Bundle{
+		Version:    Version,
+		Generation: generation,
+		Created:    created,
+		Configs:    configs.GetAll(),
+		References: references,
+		Chains:     chains,
+		Workflows:  workflows,
+		Observers:  registryAgent.GetObservers(),
+		Versions:   registryAgent.GetVersions(),
+	}
+}
+
+// Config resolves the configuration that matches the metadata against the registry in the bundle
+func (b *Bundle) Config(metadata api.Metadata) (*api.ReleaseBuildConfiguration, error) {
+	unresolved, err := agents.MatchingConfig(b.Configs, metadata)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := registry.ResolveConfig(registry.NewVersionedResolver(b.References, b.Chains, b.Workflows, b.Observers, b.Versions), unresolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve configuration: %w", err)
+	}
+	return &resolved, nil
+}
+
+// Write signs the bundle and writes it to the path, compressed
+func Write(path string, bundle *Bundle, signer *provenance.Signer) error {
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	envelope, err := signer.SignPayload(PayloadType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign bundle: %w", err)
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(envelope); err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to compress bundle: %w", err)
+	}
+	// write to a temporary file first so readers never see a partial bundle
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// Read loads the bundle at the path, checking that it is signed by the verifier's key,
+// that this package understands its format and that it is not older than minGeneration
+func Read(path string, verifier *provenance.Verifier, minGeneration int64) (*Bundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress bundle: %w", err)
+	}
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress bundle: %w", err)
+	}
+	var envelope provenance.Envelope
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
+	payload, err := verifier.VerifyPayload(envelope, PayloadType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify bundle: %w", err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(payload, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
+	if bundle.Version != Version {
+		return nil, fmt.Errorf("bundle has version %d, only version %d is supported", bundle.Version, Version)
+	}
+	if bundle.Generation < minGeneration {
+		return nil, fmt.Errorf("bundle has generation %d, older than the minimum generation %d", bundle.Generation, minGeneration)
+	}
+	return &bundle, nil
+}
+
+// NextGeneration determines the generation of a new bundle written to the path. Existing
+// bundles need to be signed by the same key, so their generation can be trusted.
+func NextGeneration(path string, verifier *provenance.Verifier) (int64, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return 1, nil
+	}
+	previous, err := Read(path, verifier, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read previous bundle: %w", err)
+	}
+	return previous.Generation + 1, nil
+}
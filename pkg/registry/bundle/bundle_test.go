@@ -0,0 +1,236 @@
+package bundle
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/provenance"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func newSigner(t *testing.T) *provenance.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	private, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	signer, err := provenance.NewSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private}))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
+func testBundle(generation int64) *Bundle {
+	return &Bundle{
+		Version:    Version,
+		Generation: generation,
+		Created:    time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC),
+		Configs: config.ByOrgRepo{
+			"org": {
+				"repo": {
+					{
+						Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+						Tests: []api.TestStepConfiguration{{
+							As:                          "e2e",
+							MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: utilpointer.String("workflow")},
+						}},
+					},
+				},
+				"pinned": {
+					{
+						Metadata: api.Metadata{Org: "org", Repo: "pinned", Branch: "release-4.11"},
+						Tests: []api.TestStepConfiguration{{
+							As:                          "e2e",
+							MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: utilpointer.String("workflow@v1")},
+						}},
+					},
+				},
+			},
+		},
+		References: registry.ReferenceByName{
+			"step": {As: "step", From: "src", Commands: "make test"},
+		},
+		Chains: registry.ChainByName{},
+		Workflows: registry.WorkflowByName{
+			"workflow": {Test: []api.TestStep{{Reference: utilpointer.String("step")}}},
+		},
+		Observers: registry.ObserverByName{},
+		Versions: registry.VersionByName{
+			"v1": {
+				References: registry.ReferenceByName{
+					"step": {As: "step", From: "src", Commands: "make test-v1"},
+				},
+				Workflows: registry.WorkflowByName{
+					"workflow": {Test: []api.TestStep{{Reference: utilpointer.String("step")}}},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	signer := newSigner(t)
+	var testCases = []struct {
+		name          string
+		bundle        *Bundle
+		verifier      *provenance.Verifier
+		minGeneration int64
+		expectedError error
+	}{
+		{
+			name:          "valid bundle",
+			bundle:        testBundle(3),
+			verifier:      signer.Verifier(),
+			minGeneration: 3,
+		},
+		{
+			name:          "bundle signed by another key",
+			bundle:        testBundle(3),
+			verifier:      newSigner(t).Verifier(),
+			expectedError: errors.New("failed to verify bundle: no valid signature"),
+		},
+		{
+			name:          "bundle older than the minimum generation",
+			bundle:        testBundle(3),
+			verifier:      signer.Verifier(),
+			minGeneration: 4,
+			expectedError: errors.New("bundle has generation 3, older than the minimum generation 4"),
+		},
+		{
+			name: "bundle of an unknown version",
+			bundle: func() *Bundle {
+				b := testBundle(3)
+				b.Version = 2
+				return b
+			}(),
+			verifier:      signer.Verifier(),
+			expectedError: errors.New("bundle has version 2, only version 1 is supported"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bundle.json.gz")
+			if err := Write(path, testCase.bundle, signer); err != nil {
+				t.Fatalf("%s: failed to write bundle: %v", testCase.name, err)
+			}
+			actual, err := Read(path, testCase.verifier, testCase.minGeneration)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("%s: got incorrect error: %v", testCase.name, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(testCase.bundle, actual); diff != "" {
+				t.Errorf("%s: got incorrect bundle: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestNextGeneration(t *testing.T) {
+	signer := newSigner(t)
+	path := filepath.Join(t.TempDir(), "bundle.json.gz")
+	generation, err := NextGeneration(path, signer.Verifier())
+	if err != nil {
+		t.Fatalf("failed to determine the first generation: %v", err)
+	}
+	if generation != 1 {
+		t.Errorf("expected the first generation to be 1, got %d", generation)
+	}
+	if err := Write(path, testBundle(generation), signer); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+	generation, err = NextGeneration(path, signer.Verifier())
+	if err != nil {
+		t.Fatalf("failed to determine the next generation: %v", err)
+	}
+	if generation != 2 {
+		t.Errorf("expected the next generation to be 2, got %d", generation)
+	}
+	if _, err := NextGeneration(path, newSigner(t).Verifier()); err == nil {
+		t.Error("expected to fail to trust a bundle signed by another key")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		metadata      api.Metadata
+		expected      *api.ReleaseBuildConfiguration
+		expectedError error
+	}{
+		{
+			name:     "configuration is resolved",
+			metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+			expected: &api.ReleaseBuildConfiguration{
+				Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+				Tests: []api.TestStepConfiguration{{
+					As: "e2e",
+					MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						Test: []api.LiteralTestStep{{As: "step", From: "src", Commands: "make test"}},
+					},
+				}},
+			},
+		},
+		{
+			name:     "configuration of a feature branch",
+			metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master-feature"},
+			expected: &api.ReleaseBuildConfiguration{
+				Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+				Tests: []api.TestStepConfiguration{{
+					As: "e2e",
+					MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						Test: []api.LiteralTestStep{{As: "step", From: "src", Commands: "make test"}},
+					},
+				}},
+			},
+		},
+		{
+			name:     "configuration pinned to a registry version",
+			metadata: api.Metadata{Org: "org", Repo: "pinned", Branch: "release-4.11"},
+			expected: &api.ReleaseBuildConfiguration{
+				Metadata: api.Metadata{Org: "org", Repo: "pinned", Branch: "release-4.11"},
+				Tests: []api.TestStepConfiguration{{
+					As: "e2e",
+					MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						Test: []api.LiteralTestStep{{As: "step", From: "src", Commands: "make test-v1"}},
+					},
+				}},
+			},
+		},
+		{
+			name:          "no configuration for the branch",
+			metadata:      api.Metadata{Org: "org", Repo: "repo", Branch: "release-4.12"},
+			expectedError: errors.New("could not find any config for branch release-4.12 on repo org/repo"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := testBundle(1).Config(testCase.metadata)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("%s: got incorrect error: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect configuration: %v", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package registry
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -21,11 +23,22 @@ type ChainByName map[string]api.RegistryChain
 type WorkflowByName map[string]api.MultiStageTestConfiguration
 type ObserverByName map[string]api.Observer
 
+// Version is the registry at a revision or version tag. Tests can pin the
+// components they use to a version, like `ref: ipi-install@v3`.
+type Version struct {
+	References ReferenceByName `json:"references"`
+	Chains     ChainByName     `json:"chains"`
+	Workflows  WorkflowByName  `json:"workflows"`
+}
+
+// VersionByName holds the versions of the registry, by revision or version tag
+type VersionByName map[string]Version
+
 // Validate verifies the internal consistency of steps, chains, and workflows.
 // A superset of this validation is performed later when actual test
 // configurations are resolved.
 func Validate(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName) error {
-	reg := registry{stepsByName, chainsByName, workflowsByName, observersByName}
+	reg := registry{stepsByName: stepsByName, chainsByName: chainsByName, workflowsByName: workflowsByName, observersByName: observersByName}
 	var ret []error
 	for k := range chainsByName {
 		if _, err := reg.process([]api.TestStep{{Chain: &k}}, sets.NewString(), stackForChain()); err != nil {
@@ -44,9 +57,49 @@ func Validate(stepsByName ReferenceByName, chainsByName ChainByName, workflowsBy
 	for _, v := range observersByName {
 		ret = append(ret, validation.Observer(v)...)
 	}
+	for k, v := range stepsByName {
+		ret = append(ret, validateDeprecation(Reference, k, v.Deprecation, func(name string) bool {
+			_, ok := stepsByName[name]
+			return ok
+		})...)
+	}
+	for k, v := range chainsByName {
+		ret = append(ret, validateDeprecation(Chain, k, v.Deprecation, func(name string) bool {
+			_, ok := chainsByName[name]
+			return ok
+		})...)
+	}
+	for k, v := range workflowsByName {
+		ret = append(ret, validateDeprecation(Workflow, k, v.Deprecation, func(name string) bool {
+			_, ok := workflowsByName[name]
+			return ok
+		})...)
+	}
 	return utilerrors.NewAggregate(ret)
 }
 
+// validateDeprecation verifies that a deprecated component points to an
+// existing replacement of the same type and has a well-formed removal date.
+func validateDeprecation(t Type, name string, deprecation *api.Deprecation, exists func(string) bool) (ret []error) {
+	if deprecation == nil {
+		return nil
+	}
+	prefix := t.String() + "/" + name
+	if replacement := deprecation.ReplacedBy; replacement != "" {
+		if replacement == name {
+			ret = append(ret, fmt.Errorf("%s: deprecated component cannot be replaced by itself", prefix))
+		} else if !exists(replacement) {
+			ret = append(ret, fmt.Errorf("%s: deprecated component is replaced by unknown %s %s", prefix, t.String(), replacement))
+		}
+	}
+	if date := deprecation.RemovalDate; date != "" {
+		if _, err := time.Parse(api.DeprecationDateFormat, date); err != nil {
+			ret = append(ret, fmt.Errorf("%s: invalid removal date %q, expected the format YYYY-MM-DD", prefix, date))
+		}
+	}
+	return ret
+}
+
 // registry will hold all the registry information needed to convert between the
 // user provided configs referencing the registry and the internal, complete
 // representation
@@ -55,15 +108,104 @@ type registry struct {
 	chainsByName    ChainByName
 	workflowsByName WorkflowByName
 	observersByName ObserverByName
+	versions        VersionByName
 }
 
 func NewResolver(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName) Resolver {
+	return NewVersionedResolver(stepsByName, chainsByName, workflowsByName, observersByName, nil)
+}
+
+// NewVersionedResolver returns a resolver that resolves components pinned to
+// a version, like `ipi-install@v3`, from that version of the registry and all
+// other components from the current registry.
+func NewVersionedResolver(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName, versions VersionByName) Resolver {
 	return &registry{
 		stepsByName:     stepsByName,
 		chainsByName:    chainsByName,
 		workflowsByName: workflowsByName,
 		observersByName: observersByName,
+		versions:        versions,
+	}
+}
+
+// version determines the registry a component name refers to, returning the
+// name without the version it is pinned to
+func (r *registry) version(name string) (*registry, string, string, error) {
+	base, version := api.SplitRegistryVersion(name)
+	if version == "" {
+		return r, base, "", nil
+	}
+	v, ok := r.versions[version]
+	if !ok {
+		return nil, "", "", fmt.Errorf("unknown registry version %s for %s", version, name)
+	}
+	return &registry{stepsByName: v.References, chainsByName: v.Chains, workflowsByName: v.Workflows}, base, version, nil
+}
+
+func (r *registry) reference(name string) (api.LiteralTestStep, error) {
+	reg, base, _, err := r.version(name)
+	if err != nil {
+		return api.LiteralTestStep{}, err
+	}
+	ref, ok := reg.stepsByName[base]
+	if !ok {
+		return api.LiteralTestStep{}, fmt.Errorf("invalid step reference: %s", name)
+	}
+	return ref, nil
+}
+
+func (r *registry) chain(name string) (api.RegistryChain, error) {
+	reg, base, version, err := r.version(name)
+	if err != nil {
+		return api.RegistryChain{}, err
+	}
+	chain, ok := reg.chainsByName[base]
+	if !ok {
+		return api.RegistryChain{}, fmt.Errorf("unknown step chain: %s", name)
 	}
+	chain.Steps = pinSteps(chain.Steps, version)
+	return chain, nil
+}
+
+func (r *registry) workflow(name string) (api.MultiStageTestConfiguration, error) {
+	reg, base, version, err := r.version(name)
+	if err != nil {
+		return api.MultiStageTestConfiguration{}, err
+	}
+	workflow, ok := reg.workflowsByName[base]
+	if !ok {
+		return api.MultiStageTestConfiguration{}, fmt.Errorf("no workflow named %s", name)
+	}
+	workflow.Pre = pinSteps(workflow.Pre, version)
+	workflow.Test = pinSteps(workflow.Test, version)
+	workflow.Post = pinSteps(workflow.Post, version)
+	return workflow, nil
+}
+
+// pinSteps pins the components steps refer to to a version of the registry,
+// as the components of a version only refer to components of the same version
+func pinSteps(steps []api.TestStep, version string) []api.TestStep {
+	if version == "" || steps == nil {
+		return steps
+	}
+	pin := func(name *string) *string {
+		if _, pinned := api.SplitRegistryVersion(*name); pinned != "" {
+			return name
+		}
+		ret := *name + api.RegistryVersionSeparator + version
+		return &ret
+	}
+	ret := make([]api.TestStep, 0, len(steps))
+	for _, step := range steps {
+		switch {
+		case step.Reference != nil:
+			step.Reference = pin(step.Reference)
+		case step.Chain != nil:
+			step.Chain = pin(step.Chain)
+		}
+		ret = append(ret, step)
+	}
+	return ret
 }
 
 func (r *registry) Resolve(name string, config api.MultiStageTestConfiguration) (api.MultiStageTestConfigurationLiteral, error) {
@@ -80,9 +222,9 @@ func (r *registry) Resolve(name string, config api.MultiStageTestConfiguration)
 
 func (r *registry) mergeWorkflow(config *api.MultiStageTestConfiguration) ([][]api.TestStep, []error) {
 	var overridden [][]api.TestStep
-	workflow, ok := r.workflowsByName[*config.Workflow]
-	if !ok {
-		return nil, []error{fmt.Errorf("no workflow named %s", *config.Workflow)}
+	workflow, err := r.workflow(*config.Workflow)
+	if err != nil {
+		return nil, []error{err}
 	}
 	var errs []error
 	if config.ClusterProfile == "" {
@@ -117,6 +259,9 @@ func (r *registry) mergeWorkflow(config *api.MultiStageTestConfiguration) ([][]a
 	if config.AllowBestEffortPostSteps == nil {
 		config.AllowBestEffortPostSteps = workflow.AllowBestEffortPostSteps
 	}
+	if config.SharedDirStorage == nil {
+		config.SharedDirStorage = workflow.SharedDirStorage
+	}
 	return overridden, errs
 }
 
@@ -131,6 +276,7 @@ func (r *registry) resolveTest(
 		AllowSkipOnSuccess:       config.AllowSkipOnSuccess,
 		AllowBestEffortPostSteps: config.AllowBestEffortPostSteps,
 		Leases:                   config.Leases,
+		SharedDirStorage:         config.SharedDirStorage,
 		DependencyOverrides:      config.DependencyOverrides,
 	}
 	if config.Workflow != nil {
@@ -165,6 +311,7 @@ func (r *registry) resolveTest(
 		observers = append(observers, observer)
 	}
 	expandedFlow.Observers = observers
+	expandedFlow.Deprecated = r.deprecated(config)
 	if resolveErrors != nil {
 		return api.MultiStageTestConfigurationLiteral{}, utilerrors.NewAggregate(resolveErrors)
 	}
@@ -172,9 +319,9 @@ func (r *registry) resolveTest(
 }
 
 func (r *registry) ResolveWorkflow(name string) (api.MultiStageTestConfigurationLiteral, error) {
-	workflow, ok := r.workflowsByName[name]
-	if !ok {
-		return api.MultiStageTestConfigurationLiteral{}, fmt.Errorf("no workflow named %s", name)
+	workflow, err := r.workflow(name)
+	if err != nil {
+		return api.MultiStageTestConfigurationLiteral{}, err
 	}
 	stack := stackForWorkflow(name, workflow.Environment, workflow.Dependencies)
 	ret, err := r.resolveTest(workflow, stack, nil)
@@ -272,9 +419,9 @@ func (r *registry) process(steps []api.TestStep, seen sets.String, stack stack)
 }
 
 func (r *registry) processChain(name string, seen sets.String, stack stack) ([]api.LiteralTestStep, []error) {
-	chain, ok := r.chainsByName[name]
-	if !ok {
-		return nil, []error{stack.errorf("unknown step chain: %s", name)}
+	chain, lookupErr := r.chain(name)
+	if lookupErr != nil {
+		return nil, []error{stack.errorf("%v", lookupErr)}
 	}
 	rec := stackRecordForStep("chain/"+name, chain.Environment, nil)
 	stack.push(rec)
@@ -286,10 +433,9 @@ func (r *registry) processChain(name string, seen sets.String, stack stack) ([]a
 
 func (r *registry) processStep(step *api.TestStep, seen sets.String, stack stack) (ret api.LiteralTestStep, err []error) {
 	if ref := step.Reference; ref != nil {
-		var ok bool
-		ret, ok = r.stepsByName[*ref]
-		if !ok {
-			return api.LiteralTestStep{}, []error{stack.errorf("invalid step reference: %s", *ref)}
+		var err error
+		if ret, err = r.reference(*ref); err != nil {
+			return api.LiteralTestStep{}, []error{stack.errorf("%v", err)}
 		}
 	} else if step.LiteralTestStep != nil {
 		ret = *step.LiteralTestStep
@@ -300,6 +446,8 @@ func (r *registry) processStep(step *api.TestStep, seen sets.String, stack stack
 		return api.LiteralTestStep{}, []error{stack.errorf("duplicate name: %s", ret.As)}
 	}
 	seen.Insert(ret.As)
+	// deprecation is a property of the registry entry, resolved tests list it in `Deprecated`
+	ret.Deprecation = nil
 	var errs []error
 	if ret.Leases != nil {
 		ret.Leases = append([]api.StepLease(nil), ret.Leases...)
@@ -329,13 +477,61 @@ func (r *registry) processStep(step *api.TestStep, seen sets.String, stack stack
 	return ret, errs
 }
 
+// deprecated lists the deprecated workflow, chains and references a test uses,
+// sorted by type and name.
+func (r *registry) deprecated(config api.MultiStageTestConfiguration) []api.DeprecatedComponent {
+	var ret []api.DeprecatedComponent
+	seen := sets.NewString()
+	add := func(t Type, name string, deprecation *api.Deprecation) {
+		key := t.String() + "/" + name
+		if deprecation == nil || seen.Has(key) {
+			return
+		}
+		seen.Insert(key)
+		ret = append(ret, api.DeprecatedComponent{Type: t.String(), Name: name, Deprecation: *deprecation})
+	}
+	if config.Workflow != nil {
+		workflow, _ := r.workflow(*config.Workflow)
+		add(Workflow, *config.Workflow, workflow.Deprecation)
+	}
+	walked := sets.NewString()
+	var walk func(steps []api.TestStep)
+	walk = func(steps []api.TestStep) {
+		for _, step := range steps {
+			switch {
+			case step.Chain != nil:
+				if walked.Has(*step.Chain) {
+					continue
+				}
+				walked.Insert(*step.Chain)
+				chain, _ := r.chain(*step.Chain)
+				add(Chain, *step.Chain, chain.Deprecation)
+				walk(chain.Steps)
+			case step.Reference != nil:
+				ref, _ := r.reference(*step.Reference)
+				add(Reference, *step.Reference, ref.Deprecation)
+			}
+		}
+	}
+	for _, steps := range [][]api.TestStep{config.Pre, config.Test, config.Post} {
+		walk(steps)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Type != ret[j].Type {
+			return ret[i].Type < ret[j].Type
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
 // iterateSteps calls a function for each leaf child of a step.
 func (r *registry) iterateSteps(s api.TestStep, f func(*api.LiteralTestStep)) error {
 	switch {
 	case s.Chain != nil:
-		c, ok := r.chainsByName[*s.Chain]
-		if !ok {
-			return fmt.Errorf("invalid reference: %s", *s.Reference)
+		c, err := r.chain(*s.Chain)
+		if err != nil {
+			return err
 		}
 		for _, s := range c.Steps {
 			if err := r.iterateSteps(s, f); err != nil {
@@ -343,9 +539,9 @@ func (r *registry) iterateSteps(s api.TestStep, f func(*api.LiteralTestStep)) er
 			}
 		}
 	case s.Reference != nil:
-		r, ok := r.stepsByName[*s.Reference]
-		if !ok {
-			return fmt.Errorf("invalid reference: %s", *s.Reference)
+		r, err := r.reference(*s.Reference)
+		if err != nil {
+			return err
 		}
 		f(&r)
 	case s.LiteralTestStep != nil:
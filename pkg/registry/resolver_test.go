@@ -10,6 +10,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/diff"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/pointer"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/testhelper"
@@ -1150,3 +1151,217 @@ func TestResolveLeasesCopy(t *testing.T) {
 	expected := []api.StepLease{{Count: 42}, {Count: 0}}
 	testhelper.Diff(t, "leases", leases, expected)
 }
+
+func TestResolveDeprecated(t *testing.T) {
+	deprecation := func(replacedBy string) *api.Deprecation {
+		return &api.Deprecation{Message: "going away", ReplacedBy: replacedBy, RemovalDate: "2022-12-01"}
+	}
+	refs := ReferenceByName{
+		"old-ref": {As: "old-ref", Commands: "old", Deprecation: deprecation("new-ref")},
+		"new-ref": {As: "new-ref", Commands: "new"},
+		"ipi":     {As: "ipi", Commands: "install"},
+	}
+	chains := ChainByName{
+		"old-chain": {As: "old-chain", Steps: []api.TestStep{{Reference: pointer.String("ipi")}}, Deprecation: deprecation("")},
+		"outer":     {As: "outer", Steps: []api.TestStep{{Chain: pointer.String("old-chain")}}},
+	}
+	workflows := WorkflowByName{
+		"old-workflow": {Test: []api.TestStep{{Reference: pointer.String("old-ref")}}, Deprecation: deprecation("workflow")},
+		"workflow":     {Pre: []api.TestStep{{Chain: pointer.String("outer")}}, Test: []api.TestStep{{Reference: pointer.String("new-ref")}}},
+	}
+	var testCases = []struct {
+		name     string
+		config   api.MultiStageTestConfiguration
+		expected []api.DeprecatedComponent
+	}{
+		{
+			name:   "no deprecated components",
+			config: api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: pointer.String("new-ref")}}},
+		},
+		{
+			name:   "deprecated workflow and reference",
+			config: api.MultiStageTestConfiguration{Workflow: pointer.String("old-workflow")},
+			expected: []api.DeprecatedComponent{
+				{Type: "reference", Name: "old-ref", Deprecation: *deprecation("new-ref")},
+				{Type: "workflow", Name: "old-workflow", Deprecation: *deprecation("workflow")},
+			},
+		},
+		{
+			name:   "overriding the deprecated step of a workflow",
+			config: api.MultiStageTestConfiguration{Workflow: pointer.String("old-workflow"), Test: []api.TestStep{{Reference: pointer.String("new-ref")}}},
+			expected: []api.DeprecatedComponent{
+				{Type: "workflow", Name: "old-workflow", Deprecation: *deprecation("workflow")},
+			},
+		},
+		{
+			name:   "chain nested in the workflow",
+			config: api.MultiStageTestConfiguration{Workflow: pointer.String("workflow")},
+			expected: []api.DeprecatedComponent{
+				{Type: "chain", Name: "old-chain", Deprecation: *deprecation("")},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ret, err := NewResolver(refs, chains, workflows, nil).Resolve("test", testCase.config)
+			if err != nil {
+				t.Fatalf("%s: failed to resolve: %v", testCase.name, err)
+			}
+			if diff := cmp.Diff(testCase.expected, ret.Deprecated); diff != "" {
+				t.Errorf("%s: got incorrect deprecated components: %v", testCase.name, diff)
+			}
+			for _, step := range append(ret.Pre, append(ret.Test, ret.Post...)...) {
+				if step.Deprecation != nil {
+					t.Errorf("%s: resolved step %s is marked as deprecated", testCase.name, step.As)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDeprecation(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		refs        ReferenceByName
+		chains      ChainByName
+		workflows   WorkflowByName
+		expectedErr error
+	}{
+		{
+			name: "valid deprecations",
+			refs: ReferenceByName{
+				"old": {As: "old", Commands: "old", Deprecation: &api.Deprecation{ReplacedBy: "new", RemovalDate: "2022-12-01"}},
+				"new": {As: "new", Commands: "new"},
+			},
+			workflows: WorkflowByName{
+				"workflow": {Test: []api.TestStep{{Reference: pointer.String("old")}}, Deprecation: &api.Deprecation{Message: "no replacement"}},
+			},
+		},
+		{
+			name: "reference replaced by itself",
+			refs: ReferenceByName{
+				"old": {As: "old", Commands: "old", Deprecation: &api.Deprecation{ReplacedBy: "old"}},
+			},
+			expectedErr: errors.New("reference/old: deprecated component cannot be replaced by itself"),
+		},
+		{
+			name: "chain replaced by a reference",
+			refs: ReferenceByName{
+				"new": {As: "new", Commands: "new"},
+			},
+			chains: ChainByName{
+				"old": {As: "old", Steps: []api.TestStep{{Reference: pointer.String("new")}}, Deprecation: &api.Deprecation{ReplacedBy: "new"}},
+			},
+			expectedErr: errors.New("chain/old: deprecated component is replaced by unknown chain new"),
+		},
+		{
+			name: "invalid removal date",
+			workflows: WorkflowByName{
+				"workflow": {Deprecation: &api.Deprecation{RemovalDate: "12/01/2022"}},
+			},
+			expectedErr: errors.New(`workflow/workflow: invalid removal date "12/01/2022", expected the format YYYY-MM-DD`),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := Validate(testCase.refs, testCase.chains, testCase.workflows, nil)
+			var expected error
+			if testCase.expectedErr != nil {
+				expected = utilerrors.NewAggregate([]error{testCase.expectedErr})
+			}
+			if diff := cmp.Diff(expected, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("%s: got incorrect error: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestResolveVersioned(t *testing.T) {
+	refs := ReferenceByName{
+		"install": {As: "install", Commands: "install HEAD"},
+		"test":    {As: "test", Commands: "test HEAD"},
+	}
+	chains := ChainByName{
+		"pre": {As: "pre", Steps: []api.TestStep{{Reference: pointer.String("install")}}},
+	}
+	workflows := WorkflowByName{
+		"workflow": {Pre: []api.TestStep{{Chain: pointer.String("pre")}}, Test: []api.TestStep{{Reference: pointer.String("test")}}},
+	}
+	versions := VersionByName{
+		"v1": {
+			References: ReferenceByName{
+				"install": {As: "install", Commands: "install v1"},
+				"test":    {As: "test", Commands: "test v1"},
+			},
+			Chains: ChainByName{
+				"pre": {As: "pre", Steps: []api.TestStep{{Reference: pointer.String("install")}}},
+			},
+			Workflows: WorkflowByName{
+				"workflow": {Pre: []api.TestStep{{Chain: pointer.String("pre")}}, Test: []api.TestStep{{Reference: pointer.String("test")}}},
+			},
+		},
+	}
+	var testCases = []struct {
+		name          string
+		config        api.MultiStageTestConfiguration
+		expected      api.MultiStageTestConfigurationLiteral
+		expectedError error
+	}{
+		{
+			name:   "unpinned workflow resolves from HEAD",
+			config: api.MultiStageTestConfiguration{Workflow: pointer.String("workflow")},
+			expected: api.MultiStageTestConfigurationLiteral{
+				Pre:  []api.LiteralTestStep{{As: "install", Commands: "install HEAD"}},
+				Test: []api.LiteralTestStep{{As: "test", Commands: "test HEAD"}},
+			},
+		},
+		{
+			name:   "pinned workflow resolves all its steps from the version",
+			config: api.MultiStageTestConfiguration{Workflow: pointer.String("workflow@v1")},
+			expected: api.MultiStageTestConfigurationLiteral{
+				Pre:  []api.LiteralTestStep{{As: "install", Commands: "install v1"}},
+				Test: []api.LiteralTestStep{{As: "test", Commands: "test v1"}},
+			},
+		},
+		{
+			name:   "steps overriding a pinned workflow resolve from HEAD",
+			config: api.MultiStageTestConfiguration{Workflow: pointer.String("workflow@v1"), Test: []api.TestStep{{Reference: pointer.String("test")}}},
+			expected: api.MultiStageTestConfigurationLiteral{
+				Pre:  []api.LiteralTestStep{{As: "install", Commands: "install v1"}},
+				Test: []api.LiteralTestStep{{As: "test", Commands: "test HEAD"}},
+			},
+		},
+		{
+			name:   "pinned chain and reference",
+			config: api.MultiStageTestConfiguration{Pre: []api.TestStep{{Chain: pointer.String("pre@v1")}}, Test: []api.TestStep{{Reference: pointer.String("test@v1")}}},
+			expected: api.MultiStageTestConfigurationLiteral{
+				Pre:  []api.LiteralTestStep{{As: "install", Commands: "install v1"}},
+				Test: []api.LiteralTestStep{{As: "test", Commands: "test v1"}},
+			},
+		},
+		{
+			name:          "unknown version",
+			config:        api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: pointer.String("test@v2")}}},
+			expectedError: errors.New("test/test: unknown registry version v2 for test@v2"),
+		},
+		{
+			name:          "component missing from the version",
+			config:        api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: pointer.String("missing@v1")}}},
+			expectedError: errors.New("test/test: invalid step reference: missing@v1"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ret, err := NewVersionedResolver(refs, chains, workflows, nil, versions).Resolve("test", testCase.config)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("%s: got incorrect error: %v", testCase.name, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(testCase.expected, ret); diff != "" {
+				t.Errorf("%s: got incorrect resolved configuration: %v", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/metrics"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	WorkflowQuery       = "workflow"
+	ClusterProfileQuery = "cluster_profile"
+	StepQuery           = "step"
+	LeaseQuery          = "lease"
+	EnvQuery            = "env"
+
+	// CatalogIndexName is the name of the configuration index the catalog searches
+	CatalogIndexName = "configresolver-catalog"
+
+	// watchBuffer is how many events a watcher may fall behind before it is disconnected
+	watchBuffer = 100
+	// watchKeepAlive is how often watchers are sent a comment so idle connections stay open
+	watchKeepAlive = 30 * time.Second
+	// reindexInterval is how often the registry is checked for changes that need the index rebuilt
+	reindexInterval = time.Minute
+)
+
+// searchQueries are the fields tests can be searched for by
+var searchQueries = []string{WorkflowQuery, ClusterProfileQuery, StepQuery, LeaseQuery, EnvQuery}
+
+// SearchResult identifies a test that matched a search
+type SearchResult struct {
+	Metadata api.Metadata `json:"metadata"`
+	Test     string       `json:"test"`
+}
+
+// WatchEvent describes configurations that changed under a key of the catalog index.
+// Configurations that changed but are still under the key are both added and removed.
+type WatchEvent struct {
+	Key     string         `json:"key"`
+	Added   []api.Metadata `json:"added,omitempty"`
+	Removed []api.Metadata `json:"removed,omitempty"`
+}
+
+// RegistryResolver resolves tests against a registry that is reloaded, its generation
+// changes whenever it is
+type RegistryResolver interface {
+	registry.Resolver
+	GetGeneration() int
+}
+
+// Catalog serves read-only APIs over the configurations and the registry, so clients
+// do not need to load and resolve all configurations themselves to answer questions
+// like which tests use a workflow. Tests are indexed by the keys of their resolved form
+// when configurations are loaded and again when the registry changes, and search results
+// are always resolved against the current registry. Until the index catches up with a
+// change of the registry, searches can miss the tests the change put under their keys.
+type Catalog struct {
+	configs  agents.ConfigAgent
+	resolver RegistryResolver
+	metrics  *metrics.Metrics
+
+	// indexLock guards the generation of the registry the index was built with
+	indexLock         sync.Mutex
+	indexedGeneration int
+
+	lock     sync.Mutex
+	watchers map[chan WatchEvent]sets.String
+}
+
+// NewCatalog indexes the configurations, starts relaying changes to the index to watchers
+// and re-indexing the configurations when the registry changes, until the context is done
+func NewCatalog(ctx context.Context, configs agents.ConfigAgent, resolver RegistryResolver, catalogMetrics *metrics.Metrics) (*Catalog, error) {
+	c := &Catalog{
+		configs:           configs,
+		resolver:          resolver,
+		metrics:           catalogMetrics,
+		indexedGeneration: resolver.GetGeneration(),
+		watchers:          map[chan WatchEvent]sets.String{},
+	}
+	if err := configs.AddIndex(CatalogIndexName, c.index); err != nil {
+		return nil, fmt.Errorf("failed to add index: %w", err)
+	}
+	deltas, err := configs.SubscribeToIndexChanges(CatalogIndexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to index changes: %w", err)
+	}
+	go c.relay(deltas)
+	ticker := time.NewTicker(reindexInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.reindex()
+			}
+		}
+	}()
+	return c, nil
+}
+
+// reindex rebuilds the index when the registry changed since it was built, as the
+// keys of a test depend on the registry it is resolved against
+func (c *Catalog) reindex() {
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+	generation := c.resolver.GetGeneration()
+	if generation == c.indexedGeneration {
+		return
+	}
+	c.configs.RebuildIndexes()
+	c.indexedGeneration = generation
+}
+
+func catalogKey(field, value string) string {
+	return field + "=" + value
+}
+
+// index files every configuration under its org, so that watchers see all changes,
+// and under the search keys of all of its tests
+func (c *Catalog) index(config api.ReleaseBuildConfiguration) []string {
+	keys := sets.NewString(catalogKey(OrgQuery, config.Metadata.Org))
+	for _, test := range config.Tests {
+		testKeys, err := searchKeysFor(test, c.resolver)
+		if err != nil {
+			logrus.WithFields(api.LogFieldsFor(config.Metadata)).WithField("test", test.As).WithError(err).Debug("Could not resolve test to index it.")
+		}
+		keys = keys.Union(testKeys)
+	}
+	return keys.List()
+}
+
+// searchKeysFor determines the keys a test can be found by. Keys that need the
+// test to be resolved are omitted when resolving it fails.
+func searchKeysFor(test api.TestStepConfiguration, resolver registry.Resolver) (sets.String, error) {
+	keys := sets.NewString()
+	literal := test.MultiStageTestConfigurationLiteral
+	if literal != nil {
+		for name := range literal.Environment {
+			keys.Insert(catalogKey(EnvQuery, name))
+		}
+	}
+	if unresolved := test.MultiStageTestConfiguration; unresolved != nil {
+		if unresolved.Workflow != nil {
+			keys.Insert(catalogKey(WorkflowQuery, *unresolved.Workflow))
+		}
+		// resolving applies the environment to the parameters of the steps
+		for name := range unresolved.Environment {
+			keys.Insert(catalogKey(EnvQuery, name))
+		}
+		resolved, err := resolver.Resolve(test.As, *unresolved)
+		if err != nil {
+			return keys, err
+		}
+		literal = &resolved
+	}
+	if literal == nil {
+		return keys, nil
+	}
+	if literal.ClusterProfile != "" {
+		keys.Insert(catalogKey(ClusterProfileQuery, string(literal.ClusterProfile)))
+	}
+	for _, phase := range [][]api.LiteralTestStep{literal.Pre, literal.Test, literal.Post} {
+		for _, step := range phase {
+			keys.Insert(catalogKey(StepQuery, step.As))
+		}
+	}
+	for _, lease := range api.LeasesForTest(literal) {
+		keys.Insert(catalogKey(LeaseQuery, lease.ResourceType))
+	}
+	return keys, nil
+}
+
+// keysFromQuery determines the catalog keys requested in the query
+func keysFromQuery(r *http.Request) sets.String {
+	keys := sets.NewString()
+	for _, field := range searchQueries {
+		for _, value := range r.URL.Query()[field] {
+			keys.Insert(catalogKey(field, value))
+		}
+	}
+	return keys
+}
+
+// ListConfigs lists the metadata of all configurations, optionally of one org or repo
+func (c *Catalog) ListConfigs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		org, repo := r.URL.Query().Get(OrgQuery), r.URL.Query().Get(RepoQuery)
+		listed := []api.Metadata{}
+		for configOrg, orgConfigs := range c.configs.GetAll() {
+			if org != "" && configOrg != org {
+				continue
+			}
+			for configRepo, repoConfigs := range orgConfigs {
+				if repo != "" && configRepo != repo {
+					continue
+				}
+				for _, config := range repoConfigs {
+					listed = append(listed, config.Metadata)
+				}
+			}
+		}
+		sort.Slice(listed, func(i, j int) bool {
+			return listed[i].AsString() < listed[j].AsString()
+		})
+		c.respond(w, listed)
+	}
+}
+
+// Search finds the tests that match all fields in the query, optionally of one org or repo
+func (c *Catalog) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		keys := keysFromQuery(r)
+		if keys.Len() == 0 {
+			metrics.RecordError("invalid query", c.metrics.ErrorRate)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "at least one of the %v queries is required", searchQueries)
+			return
+		}
+		candidates, err := c.configs.GetFromIndex(CatalogIndexName, keys.List()[0])
+		if err != nil {
+			metrics.RecordError("failed to search index", c.metrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to search: %v", err)
+			return
+		}
+		org, repo := r.URL.Query().Get(OrgQuery), r.URL.Query().Get(RepoQuery)
+		results := []SearchResult{}
+		for _, config := range candidates {
+			if (org != "" && config.Metadata.Org != org) || (repo != "" && config.Metadata.Repo != repo) {
+				continue
+			}
+			for _, test := range config.Tests {
+				testKeys, err := searchKeysFor(test, c.resolver)
+				if err != nil {
+					logrus.WithFields(api.LogFieldsFor(config.Metadata)).WithField("test", test.As).WithError(err).Debug("Could not resolve test to search it.")
+				}
+				if testKeys.HasAll(keys.UnsortedList()...) {
+					results = append(results, SearchResult{Metadata: config.Metadata, Test: test.As})
+				}
+			}
+		}
+		sort.Slice(results, func(i, j int) bool {
+			if results[i].Metadata != results[j].Metadata {
+				return results[i].Metadata.AsString() < results[j].Metadata.AsString()
+			}
+			return results[i].Test < results[j].Test
+		})
+		c.respond(w, results)
+	}
+}
+
+func (c *Catalog) respond(w http.ResponseWriter, data interface{}) {
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		metrics.RecordError("failed to marshal response", c.metrics.ErrorRate)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "failed to marshal response to JSON: %v", err)
+		logrus.WithError(err).Error("failed to marshal response to JSON")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(raw); err != nil {
+		logrus.WithError(err).Error("Failed to write response")
+	}
+}
+
+// Watch streams changes to the catalog index as server-sent events, optionally only
+// for the keys in the query, which can be search fields or an org. Watchers that fall behind are disconnected and need to
+// reconnect. The handler needs to flush after every event, so it cannot be wrapped by
+// handlers that hide the http.Flusher of the response.
+func (c *Catalog) Watch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			metrics.RecordError("streaming unsupported", c.metrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("streaming is not supported"))
+			return
+		}
+		keys := keysFromQuery(r)
+		if org := r.URL.Query().Get(OrgQuery); org != "" {
+			keys.Insert(catalogKey(OrgQuery, org))
+		}
+		events := c.subscribe(keys)
+		defer c.unsubscribe(events)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		keepAlive := time.NewTicker(watchKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				raw, err := json.Marshal(event)
+				if err != nil {
+					logrus.WithError(err).Error("Failed to marshal watch event.")
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: delta\ndata: %s\n\n", raw); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (c *Catalog) subscribe(keys sets.String) chan WatchEvent {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	events := make(chan WatchEvent, watchBuffer)
+	c.watchers[events] = keys
+	return events
+}
+
+func (c *Catalog) unsubscribe(events chan WatchEvent) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, subscribed := c.watchers[events]; subscribed {
+		delete(c.watchers, events)
+		close(events)
+	}
+}
+
+// relay fans the changes to the index out to all watchers interested in them
+func (c *Catalog) relay(deltas <-chan agents.IndexDelta) {
+	for delta := range deltas {
+		event := WatchEvent{Key: delta.IndexKey}
+		for _, config := range delta.Added {
+			event.Added = append(event.Added, config.Metadata)
+		}
+		for _, config := range delta.Removed {
+			event.Removed = append(event.Removed, config.Metadata)
+		}
+		c.lock.Lock()
+		for events, keys := range c.watchers {
+			if keys.Len() != 0 && !keys.Has(event.Key) {
+				continue
+			}
+			select {
+			case events <- event:
+			default:
+				logrus.Warn("Disconnecting watcher that fell behind.")
+				delete(c.watchers, events)
+				close(events)
+			}
+		}
+		c.lock.Unlock()
+	}
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/metrics"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// fakeRegistryResolver resolves against a registry that tests can replace
+type fakeRegistryResolver struct {
+	registry.Resolver
+	generation int
+}
+
+func (r *fakeRegistryResolver) GetGeneration() int {
+	return r.generation
+}
+
+// testResolver resolves the ipi-aws workflow with the steps before the tests
+func testResolver(pre ...string) registry.Resolver {
+	var preSteps []api.TestStep
+	for _, name := range pre {
+		preSteps = append(preSteps, api.TestStep{Reference: utilpointer.String(name)})
+	}
+	return registry.NewResolver(
+		registry.ReferenceByName{
+			"ipi-install": {As: "ipi-install", From: "installer", Commands: "install"},
+			"ipi-conf":    {As: "ipi-conf", From: "installer", Commands: "configure"},
+			"e2e-test":    {As: "e2e-test", From: "tests", Commands: "test", Environment: []api.StepParameter{{Name: "FOO", Default: utilpointer.String("")}}},
+			"gather":      {As: "gather", From: "cli", Commands: "gather"},
+		},
+		registry.ChainByName{},
+		registry.WorkflowByName{
+			"ipi-aws": {
+				ClusterProfile: api.ClusterProfileAWS,
+				Pre:            preSteps,
+				Test:           []api.TestStep{{Reference: utilpointer.String("e2e-test")}},
+				Post:           []api.TestStep{{Reference: utilpointer.String("gather")}},
+			},
+		},
+		registry.ObserverByName{},
+	)
+}
+
+func testCatalog(t *testing.T) *Catalog {
+	resolver := &fakeRegistryResolver{Resolver: testResolver("ipi-install")}
+	e2e := func(env api.TestEnvironment) api.TestStepConfiguration {
+		return api.TestStepConfiguration{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+			Workflow:    utilpointer.String("ipi-aws"),
+			Environment: env,
+		}}
+	}
+	configs := config.ByOrgRepo{
+		"org": {
+			"repo": {
+				{
+					Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+					Tests: []api.TestStepConfiguration{
+						e2e(api.TestEnvironment{"FOO": "bar"}),
+						{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+						{As: "gpu", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+							Test: []api.LiteralTestStep{{As: "custom", From: "src", Commands: "run", Leases: []api.StepLease{{ResourceType: "gpu-quota", Env: "GPU"}}}},
+						}},
+					},
+				},
+				{
+					Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "release-4.12"},
+					Tests:    []api.TestStepConfiguration{e2e(nil)},
+				},
+			},
+			"other": {
+				{Metadata: api.Metadata{Org: "org", Repo: "other", Branch: "main"}},
+			},
+		},
+		"another": {
+			"repo": {
+				{
+					Metadata: api.Metadata{Org: "another", Repo: "repo", Branch: "main"},
+					Tests:    []api.TestStepConfiguration{e2e(nil)},
+				},
+			},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	catalog, err := NewCatalog(ctx, agents.NewFakeConfigAgent(configs), resolver, &metrics.Metrics{
+		ErrorRate: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors"}, []string{"error"}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create catalog: %v", err)
+	}
+	return catalog
+}
+
+func TestListConfigs(t *testing.T) {
+	catalog := testCatalog(t)
+	var testCases = []struct {
+		name     string
+		query    string
+		expected []api.Metadata
+	}{
+		{
+			name:  "all configurations",
+			query: "",
+			expected: []api.Metadata{
+				{Org: "another", Repo: "repo", Branch: "main"},
+				{Org: "org", Repo: "other", Branch: "main"},
+				{Org: "org", Repo: "repo", Branch: "master"},
+				{Org: "org", Repo: "repo", Branch: "release-4.12"},
+			},
+		},
+		{
+			name:  "configurations of an org",
+			query: "org=org",
+			expected: []api.Metadata{
+				{Org: "org", Repo: "other", Branch: "main"},
+				{Org: "org", Repo: "repo", Branch: "master"},
+				{Org: "org", Repo: "repo", Branch: "release-4.12"},
+			},
+		},
+		{
+			name:  "configurations of a repo",
+			query: "org=org&repo=repo",
+			expected: []api.Metadata{
+				{Org: "org", Repo: "repo", Branch: "master"},
+				{Org: "org", Repo: "repo", Branch: "release-4.12"},
+			},
+		},
+		{
+			name:     "no configurations",
+			query:    "org=missing",
+			expected: []api.Metadata{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			catalog.ListConfigs()(recorder, httptest.NewRequest(http.MethodGet, "/configs?"+testCase.query, nil))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("%s: expected status %d, got %d: %s", testCase.name, http.StatusOK, recorder.Code, recorder.Body.String())
+			}
+			var actual []api.Metadata
+			if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil {
+				t.Fatalf("%s: failed to unmarshal response: %v", testCase.name, err)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect configurations: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	catalog := testCatalog(t)
+	e2e := func(org, branch string) SearchResult {
+		return SearchResult{Metadata: api.Metadata{Org: org, Repo: "repo", Branch: branch}, Test: "e2e"}
+	}
+	var testCases = []struct {
+		name         string
+		query        string
+		expectedCode int
+		expected     []SearchResult
+	}{
+		{
+			name:         "by workflow",
+			query:        "workflow=ipi-aws",
+			expectedCode: http.StatusOK,
+			expected:     []SearchResult{e2e("another", "main"), e2e("org", "master"), e2e("org", "release-4.12")},
+		},
+		{
+			name:         "by workflow in an org",
+			query:        "workflow=ipi-aws&org=org",
+			expectedCode: http.StatusOK,
+			expected:     []SearchResult{e2e("org", "master"), e2e("org", "release-4.12")},
+		},
+		{
+			name:         "by cluster profile and env var",
+			query:        "cluster_profile=aws&env=FOO",
+			expectedCode: http.StatusOK,
+			expected:     []SearchResult{e2e("org", "master")},
+		},
+		{
+			name:         "by step from the workflow",
+			query:        "step=gather",
+			expectedCode: http.StatusOK,
+			expected:     []SearchResult{e2e("another", "main"), e2e("org", "master"), e2e("org", "release-4.12")},
+		},
+		{
+			name:         "by lease of a literal step",
+			query:        "lease=gpu-quota",
+			expectedCode: http.StatusOK,
+			expected:     []SearchResult{{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Test: "gpu"}},
+		},
+		{
+			name:         "by lease of the cluster profile",
+			query:        "lease=aws-quota-slice&repo=repo&org=another",
+			expectedCode: http.StatusOK,
+			expected:     []SearchResult{e2e("another", "main")},
+		},
+		{
+			name:         "no matches",
+			query:        "workflow=ipi-aws&lease=gpu-quota",
+			expectedCode: http.StatusOK,
+			expected:     []SearchResult{},
+		},
+		{
+			name:         "no search fields",
+			query:        "org=org",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			catalog.Search()(recorder, httptest.NewRequest(http.MethodGet, "/search?"+testCase.query, nil))
+			if recorder.Code != testCase.expectedCode {
+				t.Fatalf("%s: expected status %d, got %d: %s", testCase.name, testCase.expectedCode, recorder.Code, recorder.Body.String())
+			}
+			if testCase.expectedCode != http.StatusOK {
+				return
+			}
+			var actual []SearchResult
+			if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil {
+				t.Fatalf("%s: failed to unmarshal response: %v", testCase.name, err)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect results: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestSearchAfterRegistryChange(t *testing.T) {
+	catalog := testCatalog(t)
+	search := func() []SearchResult {
+		recorder := httptest.NewRecorder()
+		catalog.Search()(recorder, httptest.NewRequest(http.MethodGet, "/search?step=ipi-conf&org=another", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var actual []SearchResult
+		if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return actual
+	}
+	if diff := cmp.Diff([]SearchResult{}, search()); diff != "" {
+		t.Errorf("got incorrect results before the registry changed: %v", diff)
+	}
+
+	resolver := catalog.resolver.(*fakeRegistryResolver)
+	resolver.Resolver = testResolver("ipi-conf", "ipi-install")
+	resolver.generation++
+	if diff := cmp.Diff([]SearchResult{}, search()); diff != "" {
+		t.Errorf("got incorrect results before the index caught up with the registry: %v", diff)
+	}
+	catalog.reindex()
+	expected := []SearchResult{{Metadata: api.Metadata{Org: "another", Repo: "repo", Branch: "main"}, Test: "e2e"}}
+	if diff := cmp.Diff(expected, search()); diff != "" {
+		t.Errorf("got incorrect results after the registry changed: %v", diff)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	catalog := &Catalog{watchers: map[chan WatchEvent]sets.String{}}
+	deltas := make(chan agents.IndexDelta)
+	go catalog.relay(deltas)
+	server := httptest.NewServer(catalog.Watch())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/watch?workflow=ipi-aws")
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected an event stream, got %s", contentType)
+	}
+
+	changed := &api.ReleaseBuildConfiguration{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}}
+	added := &api.ReleaseBuildConfiguration{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "release-4.12"}}
+	deltas <- agents.IndexDelta{IndexKey: "org=org", Added: []*api.ReleaseBuildConfiguration{changed}, Removed: []*api.ReleaseBuildConfiguration{changed}}
+	deltas <- agents.IndexDelta{IndexKey: "workflow=ipi-aws", Added: []*api.ReleaseBuildConfiguration{added}}
+
+	reader := bufio.NewReader(response.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	expected := []string{
+		"event: delta",
+		`data: {"key":"workflow=ipi-aws","added":[{"org":"org","repo":"repo","branch":"release-4.12"}]}`,
+		"",
+	}
+	if diff := cmp.Diff(expected, lines); diff != "" {
+		t.Errorf("got incorrect events: %v", diff)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/metrics"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// Component identifies a registry component
+type Component struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// DeprecationReport lists the remaining users of a deprecated registry component
+type DeprecationReport struct {
+	api.DeprecatedComponent `json:",inline"`
+	// Components are the chains and workflows that use the deprecated component,
+	// directly or through other chains
+	Components []Component `json:"components,omitempty"`
+	// Tests are the tests that use the deprecated component, directly or through
+	// a chain or workflow
+	Tests []SearchResult `json:"tests,omitempty"`
+}
+
+// Deprecations reports the remaining users of every deprecated registry component
+func Deprecations(configs agents.ConfigAgent, registryAgent agents.RegistryAgent, resolverMetrics *metrics.Metrics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		refs, chains, workflows, _, _ := registryAgent.GetRegistryComponents()
+		reports, err := DeprecationReports(configs.GetAll(), refs, chains, workflows, registryAgent.GetObservers())
+		if err != nil {
+			metrics.RecordError("failed to build registry graph", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to build registry graph: %v", err)
+			return
+		}
+		raw, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			metrics.RecordError("failed to marshal response", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to marshal response to JSON: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(raw); err != nil {
+			logrus.WithError(err).Error("Failed to write response")
+		}
+	}
+}
+
+func componentKey(t registry.Type, name string) string {
+	return fmt.Sprintf("%s/%s", t, name)
+}
+
+// DeprecationReports finds the components and tests that use each deprecated
+// component of the registry. A test uses every component its workflow uses,
+// even when it overrides the phase of the workflow that holds the component.
+func DeprecationReports(configs config.ByOrgRepo, refs registry.ReferenceByName, chains registry.ChainByName, workflows registry.WorkflowByName, observers registry.ObserverByName) ([]DeprecationReport, error) {
+	graph, err := registry.NewGraph(refs, chains, workflows, observers)
+	if err != nil {
+		return nil, err
+	}
+	var reports []DeprecationReport
+	// users holds the keys of the deprecated component and of all its ancestors
+	var users []sets.String
+	add := func(node registry.Node, deprecation *api.Deprecation) {
+		if deprecation == nil {
+			return
+		}
+		report := DeprecationReport{DeprecatedComponent: api.DeprecatedComponent{
+			Type:        node.Type().String(),
+			Name:        node.Name(),
+			Deprecation: *deprecation,
+		}}
+		keys := sets.NewString(componentKey(node.Type(), node.Name()))
+		// a component is an ancestor along every path that leads to it
+		for _, ancestor := range node.Ancestors() {
+			key := componentKey(ancestor.Type(), ancestor.Name())
+			if keys.Has(key) {
+				continue
+			}
+			keys.Insert(key)
+			report.Components = append(report.Components, Component{Type: ancestor.Type().String(), Name: ancestor.Name()})
+		}
+		sort.Slice(report.Components, func(i, j int) bool {
+			if report.Components[i].Type != report.Components[j].Type {
+				return report.Components[i].Type < report.Components[j].Type
+			}
+			return report.Components[i].Name < report.Components[j].Name
+		})
+		reports = append(reports, report)
+		users = append(users, keys)
+	}
+	for name, ref := range refs {
+		add(graph.References[name], ref.Deprecation)
+	}
+	for name, chain := range chains {
+		add(graph.Chains[name], chain.Deprecation)
+	}
+	for name, workflow := range workflows {
+		add(graph.Workflows[name], workflow.Deprecation)
+	}
+
+	for _, orgConfigs := range configs {
+		for _, repoConfigs := range orgConfigs {
+			for _, configuration := range repoConfigs {
+				for _, test := range configuration.Tests {
+					used := usedComponents(test.MultiStageTestConfiguration)
+					if used.Len() == 0 {
+						continue
+					}
+					for i := range reports {
+						if users[i].HasAny(used.UnsortedList()...) {
+							reports[i].Tests = append(reports[i].Tests, SearchResult{Metadata: configuration.Metadata, Test: test.As})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for i := range reports {
+		tests := reports[i].Tests
+		sort.Slice(tests, func(i, j int) bool {
+			if tests[i].Metadata.AsString() != tests[j].Metadata.AsString() {
+				return tests[i].Metadata.AsString() < tests[j].Metadata.AsString()
+			}
+			return tests[i].Test < tests[j].Test
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Type != reports[j].Type {
+			return reports[i].Type < reports[j].Type
+		}
+		return reports[i].Name < reports[j].Name
+	})
+	return reports, nil
+}
+
+// usedComponents lists the keys of the registry components a test references directly
+func usedComponents(test *api.MultiStageTestConfiguration) sets.String {
+	used := sets.NewString()
+	if test == nil {
+		return used
+	}
+	if test.Workflow != nil {
+		used.Insert(componentKey(registry.Workflow, *test.Workflow))
+	}
+	for _, steps := range [][]api.TestStep{test.Pre, test.Test, test.Post} {
+		for _, step := range steps {
+			switch {
+			case step.Reference != nil:
+				used.Insert(componentKey(registry.Reference, *step.Reference))
+			case step.Chain != nil:
+				used.Insert(componentKey(registry.Chain, *step.Chain))
+			}
+		}
+	}
+	return used
+}
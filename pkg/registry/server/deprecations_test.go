@@ -0,0 +1,92 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+func TestDeprecationReports(t *testing.T) {
+	ref := func(name string) api.TestStep {
+		return api.TestStep{Reference: utilpointer.String(name)}
+	}
+	chain := func(name string) api.TestStep {
+		return api.TestStep{Chain: utilpointer.String(name)}
+	}
+	oldInstall := api.Deprecation{ReplacedBy: "ipi-install", RemovalDate: "2022-12-01"}
+	oldWorkflow := api.Deprecation{Message: "use ipi-aws"}
+	refs := registry.ReferenceByName{
+		"old-install": {As: "old-install", From: "installer", Commands: "install", Deprecation: &oldInstall},
+		"ipi-install": {As: "ipi-install", From: "installer", Commands: "install"},
+		"test":        {As: "test", From: "tests", Commands: "test"},
+	}
+	chains := registry.ChainByName{
+		"old-chain":   {As: "old-chain", Steps: []api.TestStep{ref("old-install")}},
+		"outer-chain": {As: "outer-chain", Steps: []api.TestStep{chain("old-chain")}},
+		"ipi-chain":   {As: "ipi-chain", Steps: []api.TestStep{ref("ipi-install")}},
+	}
+	workflows := registry.WorkflowByName{
+		"old-aws": {Pre: []api.TestStep{chain("outer-chain"), chain("old-chain")}, Test: []api.TestStep{ref("test")}, Deprecation: &oldWorkflow},
+		"ipi-aws": {Pre: []api.TestStep{chain("ipi-chain")}, Test: []api.TestStep{ref("test")}},
+	}
+	test := func(as string, configuration api.MultiStageTestConfiguration) api.TestStepConfiguration {
+		return api.TestStepConfiguration{As: as, MultiStageTestConfiguration: &configuration}
+	}
+	configs := config.ByOrgRepo{
+		"org": {
+			"repo": {
+				{
+					Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+					Tests: []api.TestStepConfiguration{
+						test("e2e", api.MultiStageTestConfiguration{Workflow: utilpointer.String("old-aws")}),
+						test("e2e-new", api.MultiStageTestConfiguration{Workflow: utilpointer.String("ipi-aws")}),
+						test("e2e-custom", api.MultiStageTestConfiguration{Workflow: utilpointer.String("ipi-aws"), Pre: []api.TestStep{ref("old-install")}}),
+						{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+					},
+				},
+			},
+		},
+		"another": {
+			"repo": {
+				{
+					Metadata: api.Metadata{Org: "another", Repo: "repo", Branch: "main"},
+					Tests:    []api.TestStepConfiguration{test("chain", api.MultiStageTestConfiguration{Test: []api.TestStep{chain("outer-chain")}})},
+				},
+			},
+		},
+	}
+	expected := []DeprecationReport{
+		{
+			DeprecatedComponent: api.DeprecatedComponent{Type: "reference", Name: "old-install", Deprecation: oldInstall},
+			Components: []Component{
+				{Type: "chain", Name: "old-chain"},
+				{Type: "chain", Name: "outer-chain"},
+				{Type: "workflow", Name: "old-aws"},
+			},
+			Tests: []SearchResult{
+				{Metadata: api.Metadata{Org: "another", Repo: "repo", Branch: "main"}, Test: "chain"},
+				{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Test: "e2e"},
+				{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Test: "e2e-custom"},
+			},
+		},
+		{
+			DeprecatedComponent: api.DeprecatedComponent{Type: "workflow", Name: "old-aws", Deprecation: oldWorkflow},
+			Tests: []SearchResult{
+				{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Test: "e2e"},
+			},
+		},
+	}
+	actual, err := DeprecationReports(configs, refs, chains, workflows, registry.ObserverByName{})
+	if err != nil {
+		t.Fatalf("failed to build reports: %v", err)
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("got incorrect reports: %v", diff)
+	}
+}
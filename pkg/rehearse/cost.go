@@ -0,0 +1,341 @@
+package rehearse
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	// minPassRate bounds the cost of jobs that rarely pass, which would otherwise never be picked
+	minPassRate = 0.1
+	// minCost avoids dividing by zero for jobs that are (reportedly) free
+	minCost = 0.01
+)
+
+// CostModel holds the historical statistics used to estimate how much a rehearsal of a job costs.
+// The cost of a job is its expected duration in hours multiplied by the hourly cost of its cloud,
+// divided by its pass rate, as a job that often fails needs more runs to give a meaningful signal.
+type CostModel struct {
+	// Jobs holds the historical statistics of jobs by their production name
+	Jobs map[string]JobStatistics `json:"jobs,omitempty"`
+	// CloudCostPerHour is the relative cost of running a job for an hour in a cloud
+	CloudCostPerHour map[string]float64 `json:"cloud_cost_per_hour,omitempty"`
+	// DefaultCloudCostPerHour is used for jobs with no or an unknown cloud
+	DefaultCloudCostPerHour float64 `json:"default_cloud_cost_per_hour,omitempty"`
+	// DefaultDuration is used for jobs with no historical statistics
+	DefaultDuration metav1.Duration `json:"default_duration,omitempty"`
+}
+
+// JobStatistics holds historical statistics of a job
+type JobStatistics struct {
+	// Duration is the typical duration of the job
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// Cloud is the cloud the job runs in, when not set, it is determined from the job labels
+	Cloud string `json:"cloud,omitempty"`
+	// PassRate is the fraction of runs of the job that passed, between 0 and 1
+	PassRate *float64 `json:"pass_rate,omitempty"`
+}
+
+// LoadCostModel loads a CostModel from a file
+func LoadCostModel(path string) (*CostModel, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cost model %s: %w", path, err)
+	}
+	var model CostModel
+	if err := yaml.Unmarshal(raw, &model); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cost model %s: %w", path, err)
+	}
+	for name, stats := range model.Jobs {
+		if stats.PassRate != nil && (*stats.PassRate < 0 || *stats.PassRate > 1) {
+			return nil, fmt.Errorf("cost model %s: pass rate of job %s must be between 0 and 1, got %f", path, name, *stats.PassRate)
+		}
+	}
+	return &model, nil
+}
+
+// Cost estimates the cost of a rehearsal of the job
+func (m *CostModel) Cost(job prowconfig.JobBase) float64 {
+	stats := m.Jobs[job.Name]
+	duration := stats.Duration.Duration
+	if duration == 0 {
+		duration = m.DefaultDuration.Duration
+	}
+	cloud := stats.Cloud
+	if cloud == "" {
+		cloud = job.Labels[api.CloudLabel]
+	}
+	costPerHour, ok := m.CloudCostPerHour[cloud]
+	if !ok {
+		costPerHour = m.DefaultCloudCostPerHour
+	}
+	passRate := 1.0
+	if stats.PassRate != nil {
+		passRate = math.Max(*stats.PassRate, minPassRate)
+	}
+	return math.Max(duration.Hours()*costPerHour/passRate, minCost)
+}
+
+// JobCoverage maps names of jobs to the changed items their rehearsals cover
+type JobCoverage map[string]sets.String
+
+func (c JobCoverage) add(job string, items ...string) {
+	if _, ok := c[job]; !ok {
+		c[job] = sets.NewString()
+	}
+	c[job].Insert(items...)
+}
+
+// Describe returns the changed items covered by the job, other than its own change
+func (c JobCoverage) Describe(job string) []string {
+	return c[job].Difference(sets.NewString(jobCoverageItem(job))).List()
+}
+
+func registryCoverageItem(node registry.Node) string {
+	var nodeType string
+	switch node.Type() {
+	case registry.Workflow:
+		nodeType = "workflow"
+	case registry.Chain:
+		nodeType = "chain"
+	case registry.Reference:
+		nodeType = "reference"
+	case registry.Observer:
+		nodeType = "observer"
+	}
+	return fmt.Sprintf("%s %s", nodeType, node.Name())
+}
+
+func clusterProfileCoverageItem(profile string) string {
+	return fmt.Sprintf("cluster profile %s", profile)
+}
+
+func jobCoverageItem(job string) string {
+	return fmt.Sprintf("job %s", job)
+}
+
+// DetermineJobCoverage determines which of the changed registry nodes and cluster profiles are covered
+// by the rehearsal of each of the jobs. Jobs that cover neither only cover their own change.
+func DetermineJobCoverage(prConfig *config.ReleaseRepoConfig, changedRegistrySteps []registry.Node, presubmits config.Presubmits, periodics config.Periodics, changedClusterProfiles *ConfigMaps, logger *logrus.Entry) JobCoverage {
+	coverage := JobCoverage{}
+	if len(changedRegistrySteps) > 0 {
+		for job, items := range registryCoverage(changedRegistrySteps, prConfig.Prow.JobConfig.PresubmitsStatic, prConfig.Prow.JobConfig.Periodics, prConfig.CiOperator, logger) {
+			coverage.add(job, items.UnsortedList()...)
+		}
+	}
+
+	var profiles sets.String
+	if changedClusterProfiles != nil {
+		profiles = changedClusterProfiles.ProductionNames
+	}
+	addJob := func(job prowconfig.JobBase) {
+		for _, profile := range clusterProfilesOf(job) {
+			if profiles.Has(profile) {
+				coverage.add(job.Name, clusterProfileCoverageItem(profile))
+			}
+		}
+		if coverage[job.Name].Len() == 0 {
+			coverage.add(job.Name, jobCoverageItem(job.Name))
+		}
+	}
+	for _, jobs := range presubmits {
+		for _, job := range jobs {
+			addJob(job.JobBase)
+		}
+	}
+	for _, job := range periodics {
+		addJob(job.JobBase)
+	}
+	return coverage
+}
+
+// registryCoverage maps the jobs using any of the changed registry nodes, directly or through
+// their ancestors, to the changed nodes they use
+func registryCoverage(changed []registry.Node, allPresubmits presubmitsByRepo, allPeriodics []prowconfig.Periodic, ciopConfigs config.DataByFilename, logger *logrus.Entry) JobCoverage {
+	var configs []*config.DataWithInfo
+	for idx := range ciopConfigs {
+		cfg := ciopConfigs[idx]
+		configs = append(configs, &cfg)
+	}
+	presubmitIndex := presubmitsByName{}
+	for _, jobs := range allPresubmits {
+		for _, job := range jobs {
+			presubmitIndex[job.Name] = job
+		}
+	}
+	periodicsIndex := periodicsByName{}
+	for _, job := range allPeriodics {
+		periodicsIndex[job.Name] = job
+	}
+
+	coverage := JobCoverage{}
+	for _, node := range changed {
+		for _, user := range getAffectedNodes([]registry.Node{node}) {
+			presubmits, periodics := selectJobsForRegistryStep(user, configs, presubmitIndex, periodicsIndex, sets.NewString(), logger)
+			for _, jobs := range presubmits {
+				for _, job := range jobs {
+					coverage.add(job.Name, registryCoverageItem(node))
+				}
+			}
+			for _, jobs := range periodics {
+				for _, job := range jobs {
+					coverage.add(job.Name, registryCoverageItem(node))
+				}
+			}
+		}
+	}
+	return coverage
+}
+
+func clusterProfilesOf(job prowconfig.JobBase) []string {
+	var profiles []string
+	if job.Spec == nil {
+		return nil
+	}
+	for _, volume := range job.Spec.Volumes {
+		if volume.Name != "cluster-profile" || volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if source.ConfigMap != nil {
+				profiles = append(profiles, source.ConfigMap.Name)
+			}
+		}
+	}
+	return profiles
+}
+
+// BudgetSelection describes which jobs were selected to be rehearsed within a budget and why
+type BudgetSelection struct {
+	Budget float64
+	Total  int
+	Jobs   []SelectedJob
+	// Uncovered are the changed items no selected job covers
+	Uncovered []string
+}
+
+// SelectedJob is a job selected to be rehearsed within a budget
+type SelectedJob struct {
+	Name string
+	Cost float64
+	// Covers are the changed items covered by the job that no job selected before it covers
+	Covers []string
+}
+
+// SelectJobsByCost selects the jobs to rehearse within the budget for the limit when a CostModel is configured.
+// Otherwise, the jobs are returned unchanged with a nil selection.
+func (r RehearsalConfig) SelectJobsByCost(presubmits config.Presubmits, periodics config.Periodics, coverage JobCoverage, limit int, logger *logrus.Entry) (config.Presubmits, config.Periodics, *BudgetSelection) {
+	if r.CostModel == nil {
+		return presubmits, periodics, nil
+	}
+	budget := r.Budget
+	if r.NormalLimit > 0 && limit != math.MaxInt {
+		budget = r.Budget * float64(limit) / float64(r.NormalLimit)
+	}
+	selectedPresubmits, selectedPeriodics, selection := SelectJobsWithinBudget(presubmits, periodics, coverage, r.CostModel, budget, limit, logger)
+	return selectedPresubmits, selectedPeriodics, &selection
+}
+
+// SelectJobsWithinBudget selects at most limit jobs whose total cost fits the budget, while covering
+// as many changed items as possible. The selection is greedy: the job covering the most items not yet
+// covered relative to its cost is picked until no other job fits the budget or covers anything new.
+func SelectJobsWithinBudget(presubmits config.Presubmits, periodics config.Periodics, coverage JobCoverage, model *CostModel, budget float64, limit int, logger *logrus.Entry) (config.Presubmits, config.Periodics, BudgetSelection) {
+	type candidate struct {
+		job  prowconfig.JobBase
+		cost float64
+	}
+	var candidates []candidate
+	allItems := sets.NewString()
+	for _, jobs := range presubmits {
+		for _, job := range jobs {
+			candidates = append(candidates, candidate{job: job.JobBase, cost: model.Cost(job.JobBase)})
+			allItems.Insert(coverage[job.Name].UnsortedList()...)
+		}
+	}
+	for _, job := range periodics {
+		candidates = append(candidates, candidate{job: job.JobBase, cost: model.Cost(job.JobBase)})
+		allItems.Insert(coverage[job.Name].UnsortedList()...)
+	}
+	// A stable order makes the selection deterministic when jobs are equally good
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].job.Name < candidates[j].job.Name })
+
+	selection := BudgetSelection{Budget: budget, Total: len(candidates)}
+	selectedPresubmits, selectedPeriodics := config.Presubmits{}, config.Periodics{}
+	selected := sets.NewString()
+	covered := sets.NewString()
+	remaining := budget
+	for len(selection.Jobs) < limit {
+		best, bestValue := -1, 0.0
+		for i, c := range candidates {
+			if selected.Has(c.job.Name) || c.cost > remaining {
+				continue
+			}
+			gain := coverage[c.job.Name].Difference(covered).Len()
+			if value := float64(gain) / c.cost; gain > 0 && value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		if best == -1 {
+			break
+		}
+		c := candidates[best]
+		newlyCovered := coverage[c.job.Name].Difference(covered)
+		selection.Jobs = append(selection.Jobs, SelectedJob{Name: c.job.Name, Cost: c.cost, Covers: newlyCovered.List()})
+		selected.Insert(c.job.Name)
+		covered.Insert(newlyCovered.UnsortedList()...)
+		remaining -= c.cost
+		logger.WithFields(logrus.Fields{"job": c.job.Name, "cost": c.cost, "covers": newlyCovered.List()}).Debug("Selected job within the rehearsal budget")
+	}
+	if uncovered := allItems.Difference(covered); uncovered.Len() > 0 {
+		selection.Uncovered = uncovered.List()
+	}
+
+	for repo, jobs := range presubmits {
+		for _, job := range jobs {
+			if selected.Has(job.Name) {
+				selectedPresubmits[repo] = append(selectedPresubmits[repo], job)
+			}
+		}
+	}
+	for name, job := range periodics {
+		if selected.Has(name) {
+			selectedPeriodics[name] = job
+		}
+	}
+	return selectedPresubmits, selectedPeriodics, selection
+}
+
+// Describe returns a Markdown formatted description of the selection
+func (s BudgetSelection) Describe() string {
+	var total float64
+	for _, job := range s.Jobs {
+		total += job.Cost
+	}
+	lines := []string{
+		fmt.Sprintf("Selected %d of %d affected jobs to rehearse, with a total cost of %.2f within the budget of %.2f:", len(s.Jobs), s.Total, total, s.Budget),
+		"",
+		"Test name | Cost | Covers",
+		"--- | --- | ---",
+	}
+	for _, job := range s.Jobs {
+		lines = append(lines, fmt.Sprintf("%s | %.2f | %s", job.Name, job.Cost, strings.Join(job.Covers, ", ")))
+	}
+	if len(s.Uncovered) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Changes not covered by any selected job: %s", strings.Join(s.Uncovered, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,218 @@
+package rehearse
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestCostModelCost(t *testing.T) {
+	model := &CostModel{
+		Jobs: map[string]JobStatistics{
+			"known":         {Duration: metav1.Duration{Duration: 2 * time.Hour}, Cloud: "gcp"},
+			"flaky":         {Duration: metav1.Duration{Duration: time.Hour}, Cloud: "gcp", PassRate: floatPtr(0.5)},
+			"always-failed": {Duration: metav1.Duration{Duration: time.Hour}, Cloud: "gcp", PassRate: floatPtr(0)},
+			"labeled":       {Duration: metav1.Duration{Duration: time.Hour}},
+			"free":          {Duration: metav1.Duration{Duration: time.Hour}, Cloud: "free"},
+		},
+		CloudCostPerHour:        map[string]float64{"gcp": 1, "aws": 3, "free": 0},
+		DefaultCloudCostPerHour: 2,
+		DefaultDuration:         metav1.Duration{Duration: 3 * time.Hour},
+	}
+	testCases := []struct {
+		name     string
+		job      prowconfig.JobBase
+		expected float64
+	}{
+		{
+			name:     "known job",
+			job:      prowconfig.JobBase{Name: "known"},
+			expected: 2,
+		},
+		{
+			name:     "unknown job uses the defaults",
+			job:      prowconfig.JobBase{Name: "unknown"},
+			expected: 6,
+		},
+		{
+			name:     "cloud is taken from the label",
+			job:      prowconfig.JobBase{Name: "labeled", Labels: map[string]string{api.CloudLabel: "aws"}},
+			expected: 3,
+		},
+		{
+			name:     "flaky job is more expensive",
+			job:      prowconfig.JobBase{Name: "flaky"},
+			expected: 2,
+		},
+		{
+			name:     "pass rate is bounded",
+			job:      prowconfig.JobBase{Name: "always-failed"},
+			expected: 10,
+		},
+		{
+			name:     "cost is bounded",
+			job:      prowconfig.JobBase{Name: "free"},
+			expected: minCost,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := model.Cost(tc.job); math.Abs(actual-tc.expected) > 1e-9 {
+				t.Errorf("expected cost %f, got %f", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSelectJobsWithinBudget(t *testing.T) {
+	presubmit := func(name string) prowconfig.Presubmit {
+		return prowconfig.Presubmit{JobBase: prowconfig.JobBase{Name: name}}
+	}
+	model := &CostModel{
+		Jobs: map[string]JobStatistics{
+			"cheap-a":    {Duration: metav1.Duration{Duration: time.Hour}},
+			"cheap-b":    {Duration: metav1.Duration{Duration: time.Hour}},
+			"expensive":  {Duration: metav1.Duration{Duration: 4 * time.Hour}},
+			"periodic-a": {Duration: metav1.Duration{Duration: 2 * time.Hour}},
+		},
+		DefaultCloudCostPerHour: 1,
+		DefaultDuration:         metav1.Duration{Duration: time.Hour},
+	}
+	presubmits := config.Presubmits{
+		"org/repo": {presubmit("cheap-a"), presubmit("cheap-b"), presubmit("expensive")},
+	}
+	periodics := config.Periodics{
+		"periodic-a": prowconfig.Periodic{JobBase: prowconfig.JobBase{Name: "periodic-a"}},
+	}
+	coverage := JobCoverage{
+		"cheap-a":    sets.NewString("chain a"),
+		"cheap-b":    sets.NewString("chain a"),
+		"expensive":  sets.NewString("chain a", "chain b", "cluster profile c"),
+		"periodic-a": sets.NewString("chain b"),
+	}
+
+	testCases := []struct {
+		name               string
+		budget             float64
+		limit              int
+		expectedPresubmits map[string][]string
+		expectedPeriodics  []string
+		expectedSelection  BudgetSelection
+	}{
+		{
+			name:               "large budget covers everything, picking the jobs covering the most per cost first",
+			budget:             10,
+			limit:              10,
+			expectedPresubmits: map[string][]string{"org/repo": {"cheap-a", "expensive"}},
+			expectedSelection: BudgetSelection{
+				Budget: 10,
+				Total:  4,
+				Jobs: []SelectedJob{
+					{Name: "cheap-a", Cost: 1, Covers: []string{"chain a"}},
+					{Name: "expensive", Cost: 4, Covers: []string{"chain b", "cluster profile c"}},
+				},
+			},
+		},
+		{
+			name:               "small budget picks cheap jobs covering distinct changes",
+			budget:             3,
+			limit:              10,
+			expectedPresubmits: map[string][]string{"org/repo": {"cheap-a"}},
+			expectedPeriodics:  []string{"periodic-a"},
+			expectedSelection: BudgetSelection{
+				Budget: 3,
+				Total:  4,
+				Jobs: []SelectedJob{
+					{Name: "cheap-a", Cost: 1, Covers: []string{"chain a"}},
+					{Name: "periodic-a", Cost: 2, Covers: []string{"chain b"}},
+				},
+				Uncovered: []string{"cluster profile c"},
+			},
+		},
+		{
+			name:               "limit is honored",
+			budget:             3,
+			limit:              1,
+			expectedPresubmits: map[string][]string{"org/repo": {"cheap-a"}},
+			expectedSelection: BudgetSelection{
+				Budget:    3,
+				Total:     4,
+				Jobs:      []SelectedJob{{Name: "cheap-a", Cost: 1, Covers: []string{"chain a"}}},
+				Uncovered: []string{"chain b", "cluster profile c"},
+			},
+		},
+		{
+			name:               "nothing fits the budget",
+			budget:             0.5,
+			limit:              10,
+			expectedPresubmits: map[string][]string{},
+			expectedSelection: BudgetSelection{
+				Budget:    0.5,
+				Total:     4,
+				Uncovered: []string{"chain a", "chain b", "cluster profile c"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualPresubmits, actualPeriodics, actualSelection := SelectJobsWithinBudget(presubmits, periodics, coverage, model, tc.budget, tc.limit, logrus.NewEntry(logrus.StandardLogger()))
+			presubmitNames := map[string][]string{}
+			for repo, jobs := range actualPresubmits {
+				for _, job := range jobs {
+					presubmitNames[repo] = append(presubmitNames[repo], job.Name)
+				}
+			}
+			if diff := cmp.Diff(tc.expectedPresubmits, presubmitNames); diff != "" {
+				t.Errorf("presubmits differ from expected:\n%s", diff)
+			}
+			var periodicNames []string
+			for name := range actualPeriodics {
+				periodicNames = append(periodicNames, name)
+			}
+			if diff := cmp.Diff(tc.expectedPeriodics, periodicNames); diff != "" {
+				t.Errorf("periodics differ from expected:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedSelection, actualSelection); diff != "" {
+				t.Errorf("selection differs from expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClusterProfilesOf(t *testing.T) {
+	job := prowconfig.JobBase{
+		Spec: &v1.PodSpec{
+			Volumes: []v1.Volume{
+				{
+					Name: "cluster-profile",
+					VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{
+						{Secret: &v1.SecretProjection{LocalObjectReference: v1.LocalObjectReference{Name: "secret"}}},
+						{ConfigMap: &v1.ConfigMapProjection{LocalObjectReference: v1.LocalObjectReference{Name: "cluster-profile-aws"}}},
+					}}},
+				},
+				{
+					Name:         "other",
+					VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "other"}}},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff([]string{"cluster-profile-aws"}, clusterProfilesOf(job)); diff != "" {
+		t.Errorf("cluster profiles differ from expected:\n%s", diff)
+	}
+}
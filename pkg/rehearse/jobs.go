@@ -866,6 +866,8 @@ type Executor struct {
 	namespace  string
 	// Allow faking this in tests
 	pollFunc func(interval, timeout time.Duration, condition wait.ConditionFunc) error
+	// finished holds the rehearsals that finished while waiting for them
+	finished []pjapi.ProwJob
 }
 
 // NewExecutor creates an executor. It also configures the rehearsal jobs as a list of presubmits.
@@ -966,6 +968,7 @@ func (e *Executor) waitForJobs(jobs sets.String, selector ctrlruntimeclient.List
 			default:
 				continue
 			}
+			e.finished = append(e.finished, pj)
 			jobs.Delete(pj.Name)
 			if jobs.Len() == 0 {
 				return true, nil
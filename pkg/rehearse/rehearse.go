@@ -56,6 +56,9 @@ type RehearsalConfig struct {
 	NoTemplates       bool
 	NoRegistry        bool
 	NoClusterProfiles bool
+	// RegistryVersionsPath is the directory with the versions of the registry
+	// tests can pin components to
+	RegistryVersionsPath string
 
 	NormalLimit int
 	MoreLimit   int
@@ -63,6 +66,13 @@ type RehearsalConfig struct {
 
 	StickyLabelAuthors sets.String
 
+	// CostModel, when set, is used to select the jobs to rehearse within Budget
+	// instead of an arbitrary subset of at most the limit of jobs
+	CostModel *CostModel
+	// Budget is the cost of rehearsals allowed for the NormalLimit, it is scaled
+	// proportionally for the other limits
+	Budget float64
+
 	GCSBucket          string
 	GCSCredentialsFile string
 	GCSBrowserPrefix   string
@@ -124,7 +134,9 @@ type ref struct {
 	ref string
 }
 
-func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, candidatePath string, logger *logrus.Entry) (config.Presubmits, config.Periodics, *ConfigMaps, *ConfigMaps, error) {
+// DetermineAffectedJobs determines the jobs affected by the candidate, the changed templates and cluster profiles, and
+// which of the changes the rehearsal of each of the affected jobs covers
+func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, candidatePath string, logger *logrus.Entry) (config.Presubmits, config.Periodics, *ConfigMaps, *ConfigMaps, JobCoverage, error) {
 	start := time.Now()
 	defer func() {
 		logger.Infof("determineAffectedJobs ran in %s", time.Since(start).Truncate(time.Second))
@@ -132,17 +144,17 @@ func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, can
 
 	prConfig, err := config.GetAllConfigs(candidatePath)
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("could not load configuration from candidate revision of release repo: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("could not load configuration from candidate revision of release repo: %w", err)
 	}
 	baseSHA := candidate.base.sha
 	masterConfig, err := config.GetAllConfigsFromSHA(candidatePath, baseSHA)
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("could not load configuration from base revision of release repo: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("could not load configuration from base revision of release repo: %w", err)
 	}
 
 	configUpdaterCfg, err := loadConfigUpdaterCfg(candidatePath)
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("could not load plugin configuration from tested revision of release repo: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("could not load plugin configuration from tested revision of release repo: %w", err)
 	}
 
 	presubmits := config.Presubmits{}
@@ -162,7 +174,7 @@ func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, can
 	if !r.NoRegistry {
 		changedRegistrySteps, err = determineChangedRegistrySteps(candidatePath, baseSHA, logger)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("could not determine changed registry steps: %w", err)
+			return nil, nil, nil, nil, nil, fmt.Errorf("could not determine changed registry steps: %w", err)
 		}
 		presubmitsForRegistry, periodicsForRegistry := SelectJobsForChangedRegistry(changedRegistrySteps, prConfig.Prow.JobConfig.PresubmitsStatic, prConfig.Prow.JobConfig.Periodics, prConfig.CiOperator, logger)
 		presubmits.AddAll(presubmitsForRegistry, config.ChangedRegistryContent)
@@ -173,7 +185,7 @@ func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, can
 	if !r.NoTemplates {
 		changedTemplates, err = determineChangedTemplates(candidatePath, baseSHA, candidate.head.sha, candidate.prNumber, configUpdaterCfg, logger)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("could not determine changed templates: %w", err)
+			return nil, nil, nil, nil, nil, fmt.Errorf("could not determine changed templates: %w", err)
 		}
 		randomJobsForChangedTemplates := AddRandomJobsForChangedTemplates(changedTemplates.ProductionNames, presubmits, prConfig.Prow.JobConfig.PresubmitsStatic, logger)
 		presubmits.AddAll(randomJobsForChangedTemplates, config.ChangedTemplate)
@@ -183,13 +195,15 @@ func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, can
 	if !r.NoClusterProfiles {
 		changedClusterProfiles, err = determineChangedClusterProfiles(candidatePath, baseSHA, candidate.head.sha, candidate.prNumber, configUpdaterCfg, logger)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("could not determine changed cluster profiles: %w", err)
+			return nil, nil, nil, nil, nil, fmt.Errorf("could not determine changed cluster profiles: %w", err)
 		}
 		presubmitsForClusterProfiles := diffs.GetPresubmitsForClusterProfiles(prConfig.Prow, changedClusterProfiles.ProductionNames, logger)
 		presubmits.AddAll(presubmitsForClusterProfiles, config.ChangedClusterProfile)
 	}
 
-	return filterPresubmits(presubmits, logger), filterPeriodics(periodics, logger), changedTemplates, changedClusterProfiles, nil
+	presubmits, periodics = filterPresubmits(presubmits, logger), filterPeriodics(periodics, logger)
+	coverage := DetermineJobCoverage(prConfig, changedRegistrySteps, presubmits, periodics, changedClusterProfiles, logger)
+	return presubmits, periodics, changedTemplates, changedClusterProfiles, coverage, nil
 }
 
 func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath string, presubmits config.Presubmits, periodics config.Periodics, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, limit int, logger *logrus.Entry) (*config.ReleaseRepoConfig, *pjapi.Refs, apihelper.ImageStreamTagMap, []*prowconfig.Presubmit, error) {
@@ -251,14 +265,20 @@ func (r RehearsalConfig) createResolver(candidatePath string) (registry.Resolver
 	var chains registry.ChainByName
 	var workflows registry.WorkflowByName
 	var observers registry.ObserverByName
+	var versions registry.VersionByName
 	if !r.NoRegistry {
 		var err error
 		registryRefs, chains, workflows, _, _, observers, err = load.Registry(filepath.Join(candidatePath, config.RegistryPath), load.RegistryFlag(0))
 		if err != nil {
 			return nil, fmt.Errorf("could not load step registry: %w", err)
 		}
+		if r.RegistryVersionsPath != "" {
+			if versions, err = load.RegistryVersions(r.RegistryVersionsPath, load.RegistryFlag(0), nil); err != nil {
+				return nil, fmt.Errorf("could not load step registry versions: %w", err)
+			}
+		}
 	}
-	resolver := registry.NewResolver(registryRefs, chains, workflows, observers)
+	resolver := registry.NewVersionedResolver(registryRefs, chains, workflows, observers, versions)
 	return resolver, nil
 }
 
@@ -307,8 +327,9 @@ func labelSelectorForRehearsalJobs(org, repo string, prNumber int) ctrlruntimecl
 	}
 }
 
-// RehearseJobs returns true if the jobs were triggered and succeed
-func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePath string, prRefs *pjapi.Refs, imageStreamTags apihelper.ImageStreamTagMap, presubmitsToRehearse []*prowconfig.Presubmit, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, logger *logrus.Entry) (bool, error) {
+// RehearseJobs returns true if the jobs were triggered and succeed, and the summary of the
+// results of the rehearsals that finished compared to their production jobs
+func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePath string, prRefs *pjapi.Refs, imageStreamTags apihelper.ImageStreamTagMap, presubmitsToRehearse []*prowconfig.Presubmit, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, logger *logrus.Entry) (bool, RehearsalSummary, error) {
 	buildClusterConfigs, prowJobConfig := r.getBuildClusterAndProwJobConfigs(logger)
 	pjclient, err := NewProwJobClient(prowJobConfig, r.DryRun)
 	if err != nil {
@@ -345,16 +366,20 @@ func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePat
 
 	executor := NewExecutor(presubmitsToRehearse, candidate.prNumber, candidatePath, prRefs, r.DryRun, logger, pjclient, r.ProwjobNamespace)
 	success, err := executor.ExecuteJobs()
+	summary, summaryErr := executor.Summary()
+	if summaryErr != nil {
+		logger.WithError(summaryErr).Warn("Failed to compare rehearsals with their production jobs")
+	}
 	if err != nil {
 		logger.WithError(err).Error("Failed to rehearse jobs")
-		return false, utilerrors.NewAggregate(errs)
+		return false, summary, utilerrors.NewAggregate(errs)
 	} else if !success {
 		logger.Info("Some jobs failed their rehearsal runs")
 	} else {
 		logger.Info("All jobs were rehearsed successfully")
 	}
 
-	return success, utilerrors.NewAggregate(errs)
+	return success, summary, utilerrors.NewAggregate(errs)
 }
 
 func (r RehearsalConfig) getBuildClusterAndProwJobConfigs(logger *logrus.Entry) (map[string]rest.Config, *rest.Config) {
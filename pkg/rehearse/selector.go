@@ -0,0 +1,190 @@
+package rehearse
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	profileSelectorPrefix      = "profile="
+	registryNodeSelectorPrefix = "ref="
+)
+
+// RehearsalSelector selects affected jobs to rehearse by their name, the cluster profile
+// they use, or the step registry content they use. A job is selected when it matches
+// any of the selectors.
+type RehearsalSelector struct {
+	Jobs          []string
+	Profiles      []string
+	RegistryNodes []string
+}
+
+// ParseRehearsalSelector parses the arguments of a rehearsal command, where `profile=<name>`
+// selects jobs by cluster profile, `ref=<name>` selects jobs using a registry step, chain,
+// workflow, or observer, and any other argument is a job name.
+func ParseRehearsalSelector(args []string) RehearsalSelector {
+	var selector RehearsalSelector
+	for _, arg := range args {
+		switch {
+		case arg == "":
+			continue
+		case strings.HasPrefix(arg, profileSelectorPrefix):
+			selector.Profiles = append(selector.Profiles, strings.TrimPrefix(arg, profileSelectorPrefix))
+		case strings.HasPrefix(arg, registryNodeSelectorPrefix):
+			selector.RegistryNodes = append(selector.RegistryNodes, strings.TrimPrefix(arg, registryNodeSelectorPrefix))
+		default:
+			selector.Jobs = append(selector.Jobs, arg)
+		}
+	}
+	return selector
+}
+
+// IsEmpty returns true when the selector does not select any job
+func (s RehearsalSelector) IsEmpty() bool {
+	return len(s.Jobs) == 0 && len(s.Profiles) == 0 && len(s.RegistryNodes) == 0
+}
+
+// OnlyJobs returns true when the selector only selects jobs by their name
+func (s RehearsalSelector) OnlyJobs() bool {
+	return len(s.Profiles) == 0 && len(s.RegistryNodes) == 0
+}
+
+// FilterJobsBySelector returns the affected jobs matching the selector, and the selectors that
+// did not match any affected job
+func (r RehearsalConfig) FilterJobsBySelector(selector RehearsalSelector, candidatePath string, presubmits config.Presubmits, periodics config.Periodics, logger *logrus.Entry) (config.Presubmits, config.Periodics, []string, error) {
+	filteredPresubmits, filteredPeriodics := config.Presubmits{}, config.Periodics{}
+	var unmatched []string
+	if len(selector.Jobs) > 0 {
+		var unaffected []string
+		filteredPresubmits, filteredPeriodics, unaffected = FilterJobsByRequested(selector.Jobs, presubmits, periodics, logger)
+		unmatched = append(unmatched, unaffected...)
+	}
+
+	byProfile, byProfilePeriodics, unmatchedProfiles := filterJobsByProfile(selector.Profiles, presubmits, periodics)
+	filteredPresubmits.AddAll(byProfile, config.ChangedClusterProfile)
+	filteredPeriodics.AddAll(byProfilePeriodics, config.ChangedClusterProfile)
+	unmatched = append(unmatched, unmatchedProfiles...)
+
+	if len(selector.RegistryNodes) > 0 {
+		if r.NoRegistry {
+			return nil, nil, nil, fmt.Errorf("cannot select jobs by %s: step registry content is not rehearsed", strings.Join(prefixAll(registryNodeSelectorPrefix, selector.RegistryNodes), ", "))
+		}
+		refs, chains, workflows, _, _, observers, err := load.Registry(filepath.Join(candidatePath, config.RegistryPath), load.RegistryFlag(0))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not load step registry: %w", err)
+		}
+		graph, err := registry.NewGraph(refs, chains, workflows, observers)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not create step registry graph: %w", err)
+		}
+		prConfig, err := config.GetAllConfigs(candidatePath)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not load configuration from candidate revision of release repo: %w", err)
+		}
+		byNode, byNodePeriodics, unmatchedNodes := filterJobsByRegistryNodes(selector.RegistryNodes, graph, prConfig.CiOperator, presubmits, periodics, logger)
+		filteredPresubmits.AddAll(byNode, config.ChangedRegistryContent)
+		filteredPeriodics.AddAll(byNodePeriodics, config.ChangedRegistryContent)
+		unmatched = append(unmatched, unmatchedNodes...)
+	}
+
+	return filteredPresubmits, filteredPeriodics, unmatched, nil
+}
+
+// filterJobsByProfile returns the jobs using any of the cluster profiles, and the profile
+// selectors no job matched
+func filterJobsByProfile(profiles []string, presubmits config.Presubmits, periodics config.Periodics) (config.Presubmits, config.Periodics, []string) {
+	filteredPresubmits, filteredPeriodics := config.Presubmits{}, config.Periodics{}
+	var unmatched []string
+	for _, profile := range profiles {
+		found := false
+		for repo, jobs := range presubmits {
+			for _, job := range jobs {
+				if job.Labels[api.CloudClusterProfileLabel] == profile {
+					filteredPresubmits.Add(repo, job, config.ChangedClusterProfile)
+					found = true
+				}
+			}
+		}
+		for _, job := range periodics {
+			if job.Labels[api.CloudClusterProfileLabel] == profile {
+				filteredPeriodics.Add(job, config.ChangedClusterProfile)
+				found = true
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, profileSelectorPrefix+profile)
+		}
+	}
+	return filteredPresubmits, filteredPeriodics, unmatched
+}
+
+// filterJobsByRegistryNodes returns the jobs using any of the named registry nodes, directly
+// or through their ancestors, and the registry node selectors no job matched
+func filterJobsByRegistryNodes(names []string, graph registry.NodeByName, ciopConfigs config.DataByFilename, presubmits config.Presubmits, periodics config.Periodics, logger *logrus.Entry) (config.Presubmits, config.Periodics, []string) {
+	var configs []*config.DataWithInfo
+	for idx := range ciopConfigs {
+		cfg := ciopConfigs[idx]
+		configs = append(configs, &cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Info.Filename < configs[j].Info.Filename })
+	presubmitIndex := presubmitsByName{}
+	for _, jobs := range presubmits {
+		for _, job := range jobs {
+			presubmitIndex[job.Name] = job
+		}
+	}
+	periodicsIndex := periodicsByName{}
+	for _, job := range periodics {
+		periodicsIndex[job.Name] = job
+	}
+
+	filteredPresubmits, filteredPeriodics := config.Presubmits{}, config.Periodics{}
+	var unmatched []string
+	for _, name := range names {
+		var nodes []registry.Node
+		for _, byName := range []map[string]registry.Node{graph.References, graph.Chains, graph.Workflows, graph.Observers} {
+			if node, ok := byName[name]; ok {
+				nodes = append(nodes, node)
+			}
+		}
+		found := sets.NewString()
+		for _, node := range getAffectedNodes(nodes) {
+			selectedPresubmits, selectedPeriodics := selectJobsForRegistryStep(node, configs, presubmitIndex, periodicsIndex, found, logger)
+			for repo, jobs := range selectedPresubmits {
+				for _, job := range jobs {
+					filteredPresubmits.Add(repo, job, config.ChangedRegistryContent)
+					found.Insert(job.Name)
+				}
+			}
+			for _, jobs := range selectedPeriodics {
+				for _, job := range jobs {
+					filteredPeriodics.Add(job, config.ChangedRegistryContent)
+					found.Insert(job.Name)
+				}
+			}
+		}
+		if found.Len() == 0 {
+			unmatched = append(unmatched, registryNodeSelectorPrefix+name)
+		}
+	}
+	return filteredPresubmits, filteredPeriodics, unmatched
+}
+
+func prefixAll(prefix string, values []string) []string {
+	var prefixed []string
+	for _, value := range values {
+		prefixed = append(prefixed, prefix+value)
+	}
+	return prefixed
+}
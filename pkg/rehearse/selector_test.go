@@ -0,0 +1,190 @@
+package rehearse
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+func TestParseRehearsalSelector(t *testing.T) {
+	testCases := []struct {
+		name             string
+		args             []string
+		expected         RehearsalSelector
+		expectedOnlyJobs bool
+	}{
+		{
+			name:             "no arguments",
+			expectedOnlyJobs: true,
+		},
+		{
+			name:             "job names",
+			args:             []string{"job-a", "", "job-b"},
+			expected:         RehearsalSelector{Jobs: []string{"job-a", "job-b"}},
+			expectedOnlyJobs: true,
+		},
+		{
+			name: "mixed selectors",
+			args: []string{"profile=aws", "job-a", "ref=ipi-install", "profile=gcp"},
+			expected: RehearsalSelector{
+				Jobs:          []string{"job-a"},
+				Profiles:      []string{"aws", "gcp"},
+				RegistryNodes: []string{"ipi-install"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selector := ParseRehearsalSelector(tc.args)
+			if diff := cmp.Diff(tc.expected, selector); diff != "" {
+				t.Errorf("selector differs from expected:\n%s", diff)
+			}
+			if onlyJobs := selector.OnlyJobs(); onlyJobs != tc.expectedOnlyJobs {
+				t.Errorf("expected OnlyJobs to be %t, got %t", tc.expectedOnlyJobs, onlyJobs)
+			}
+		})
+	}
+}
+
+func jobNames(presubmits config.Presubmits, periodics config.Periodics) []string {
+	var names []string
+	for _, jobs := range presubmits {
+		for _, job := range jobs {
+			names = append(names, job.Name)
+		}
+	}
+	for name := range periodics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFilterJobsByProfile(t *testing.T) {
+	withProfile := func(name, profile string) prowconfig.JobBase {
+		return prowconfig.JobBase{Name: name, Labels: map[string]string{api.CloudClusterProfileLabel: profile}}
+	}
+	presubmits := config.Presubmits{
+		"org/repo": {
+			{JobBase: withProfile("aws-presubmit", "aws")},
+			{JobBase: withProfile("gcp-presubmit", "gcp")},
+			{JobBase: prowconfig.JobBase{Name: "no-profile"}},
+		},
+	}
+	periodics := config.Periodics{
+		"aws-periodic": {JobBase: withProfile("aws-periodic", "aws")},
+	}
+	testCases := []struct {
+		name              string
+		profiles          []string
+		expected          []string
+		expectedUnmatched []string
+	}{
+		{
+			name:     "single profile",
+			profiles: []string{"aws"},
+			expected: []string{"aws-periodic", "aws-presubmit"},
+		},
+		{
+			name:              "unknown profile",
+			profiles:          []string{"gcp", "azure4"},
+			expected:          []string{"gcp-presubmit"},
+			expectedUnmatched: []string{"profile=azure4"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filteredPresubmits, filteredPeriodics, unmatched := filterJobsByProfile(tc.profiles, presubmits, periodics)
+			if diff := cmp.Diff(tc.expected, jobNames(filteredPresubmits, filteredPeriodics)); diff != "" {
+				t.Errorf("jobs differ from expected:\n%s", diff)
+			}
+			for _, jobs := range filteredPresubmits {
+				for _, job := range jobs {
+					if source := config.GetSourceType(job.Labels); source != config.ChangedClusterProfile {
+						t.Errorf("expected job %s to be selected by its cluster profile, got %s", job.Name, source)
+					}
+				}
+			}
+			for _, job := range filteredPeriodics {
+				if source := config.GetSourceType(job.Labels); source != config.ChangedClusterProfile {
+					t.Errorf("expected job %s to be selected by its cluster profile, got %s", job.Name, source)
+				}
+			}
+			if diff := cmp.Diff(tc.expectedUnmatched, unmatched); diff != "" {
+				t.Errorf("unmatched differ from expected:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterJobsByRegistryNodes(t *testing.T) {
+	install, test, chain := "ipi-install", "e2e-test", "ipi"
+	graph, err := registry.NewGraph(
+		registry.ReferenceByName{install: {As: install}, test: {As: test}},
+		registry.ChainByName{chain: {As: chain, Steps: []api.TestStep{{Reference: &install}}}},
+		registry.WorkflowByName{},
+		registry.ObserverByName{},
+	)
+	if err != nil {
+		t.Fatalf("failed to create graph: %v", err)
+	}
+	info := config.Info{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Filename: "org-repo-master.yaml"}
+	ciopConfigs := config.DataByFilename{
+		"org-repo-master.yaml": {
+			Info: info,
+			Configuration: api.ReleaseBuildConfiguration{
+				Tests: []api.TestStepConfiguration{
+					{As: "uses-chain", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Pre: []api.TestStep{{Chain: &chain}}}},
+					{As: "uses-test", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Test: []api.TestStep{{Reference: &test}}}},
+				},
+			},
+		},
+	}
+	presubmits := config.Presubmits{
+		"org/repo": {
+			{JobBase: prowconfig.JobBase{Name: "pull-ci-org-repo-master-uses-chain"}},
+			{JobBase: prowconfig.JobBase{Name: "pull-ci-org-repo-master-uses-test"}},
+		},
+	}
+	testCases := []struct {
+		name              string
+		nodes             []string
+		expected          []string
+		expectedUnmatched []string
+	}{
+		{
+			name:     "reference used through a chain",
+			nodes:    []string{install},
+			expected: []string{"pull-ci-org-repo-master-uses-chain"},
+		},
+		{
+			name:     "chain and reference",
+			nodes:    []string{chain, test},
+			expected: []string{"pull-ci-org-repo-master-uses-chain", "pull-ci-org-repo-master-uses-test"},
+		},
+		{
+			name:              "unknown node",
+			nodes:             []string{"unknown"},
+			expectedUnmatched: []string{"ref=unknown"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filteredPresubmits, filteredPeriodics, unmatched := filterJobsByRegistryNodes(tc.nodes, graph, ciopConfigs, presubmits, config.Periodics{}, logrus.NewEntry(logrus.StandardLogger()))
+			if diff := cmp.Diff(tc.expected, jobNames(filteredPresubmits, filteredPeriodics)); diff != "" {
+				t.Errorf("jobs differ from expected:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedUnmatched, unmatched); diff != "" {
+				t.Errorf("unmatched differ from expected:\n%s", diff)
+			}
+		})
+	}
+}
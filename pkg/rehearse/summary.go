@@ -0,0 +1,136 @@
+package rehearse
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// RehearsalResult is the result of a rehearsal next to the latest result of the production job
+type RehearsalResult struct {
+	// Job is the name of the production job
+	Job       string
+	Rehearsal pjapi.ProwJob
+	// Upstream is the latest completed run of the production job, if any
+	Upstream *pjapi.ProwJob
+}
+
+// LikelyCausedByChange returns true when the rehearsal failed while the production job passed,
+// so the failure is likely caused by the rehearsed change
+func (r RehearsalResult) LikelyCausedByChange() bool {
+	return isFailed(r.Rehearsal) && r.Upstream != nil && r.Upstream.Status.State == pjapi.SuccessState
+}
+
+// RehearsalSummary holds the results of the rehearsals, sorted by job name
+type RehearsalSummary []RehearsalResult
+
+// Summary compares the results of the rehearsals that finished with the latest results of their
+// production jobs
+func (e *Executor) Summary() (RehearsalSummary, error) {
+	var summary RehearsalSummary
+	for _, rehearsal := range e.finished {
+		job := strings.TrimPrefix(rehearsal.Spec.Job, fmt.Sprintf("rehearse-%d-", e.prNumber))
+		upstream, err := e.latestUpstreamRun(job)
+		if err != nil {
+			return nil, err
+		}
+		summary = append(summary, RehearsalResult{Job: job, Rehearsal: rehearsal, Upstream: upstream})
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Job < summary[j].Job })
+	return summary, nil
+}
+
+// latestUpstreamRun returns the latest completed run of the production job, which is not a rehearsal
+func (e *Executor) latestUpstreamRun(job string) (*pjapi.ProwJob, error) {
+	// The label holds a truncated job name when it is too long, see decorate.LabelsAndAnnotationsForSpec
+	label := job
+	if len(label) > validation.LabelValueMaxLength {
+		label = strings.TrimRight(label[:validation.LabelValueMaxLength], "._-")
+	}
+	runs := &pjapi.ProwJobList{}
+	if err := e.pjclient.List(context.Background(), runs, ctrlruntimeclient.MatchingLabels{kube.ProwJobAnnotation: label}, ctrlruntimeclient.InNamespace(e.namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list prowjobs of job %s: %w", job, err)
+	}
+	var latest *pjapi.ProwJob
+	for i := range runs.Items {
+		run := &runs.Items[i]
+		if run.Spec.Job != job || !run.Complete() {
+			continue
+		}
+		if _, rehearsal := run.Labels[Label]; rehearsal {
+			continue
+		}
+		if latest == nil || latest.Status.CompletionTime.Before(run.Status.CompletionTime) {
+			latest = run
+		}
+	}
+	return latest, nil
+}
+
+func isFailed(pj pjapi.ProwJob) bool {
+	switch pj.Status.State {
+	case pjapi.FailureState, pjapi.AbortedState, pjapi.ErrorState:
+		return true
+	}
+	return false
+}
+
+// Describe returns a Markdown formatted table of the results, where the steps graphs
+// of the jobs are linked using the GCS browser prefix
+func (s RehearsalSummary) Describe(gcsBrowserPrefix string) string {
+	lines := []string{
+		"Test name | Rehearsal | Upstream | Notes",
+		"--- | --- | --- | ---",
+	}
+	likelyCaused := 0
+	for _, result := range s {
+		upstream := "N/A"
+		if result.Upstream != nil {
+			upstream = formatResult(*result.Upstream)
+		}
+		var notes string
+		if result.LikelyCausedByChange() {
+			likelyCaused++
+			notes = ":warning: fails in rehearsal but passes upstream, likely caused by this PR"
+			if graph := stepGraphURL(result.Rehearsal, gcsBrowserPrefix); graph != "" {
+				notes += fmt.Sprintf(" ([step graph](%s))", graph)
+			}
+		}
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("%s | %s | %s | %s", result.Job, formatResult(result.Rehearsal), upstream, notes)))
+	}
+	if likelyCaused > 0 {
+		lines = append(lines, "", fmt.Sprintf("%d of %d rehearsals fail while the production job passes.", likelyCaused, len(s)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatResult(pj pjapi.ProwJob) string {
+	if pj.Status.URL == "" {
+		return string(pj.Status.State)
+	}
+	return fmt.Sprintf("[%s](%s)", pj.Status.State, pj.Status.URL)
+}
+
+// stepGraphURL returns the URL of the step graph of a job in the GCS browser, determined from the
+// job's Spyglass URL, e.g. https://prow.ci.openshift.org/view/gs/origin-ci-test/pr-logs/pull/...
+func stepGraphURL(pj pjapi.ProwJob, gcsBrowserPrefix string) string {
+	const view = "/view/gs/"
+	idx := strings.Index(pj.Status.URL, view)
+	if idx == -1 || gcsBrowserPrefix == "" {
+		return ""
+	}
+	// The GCS browser prefix includes the bucket
+	bucketAndPath := strings.SplitN(pj.Status.URL[idx+len(view):], "/", 2)
+	if len(bucketAndPath) != 2 {
+		return ""
+	}
+	return api.StepGraphJSONURL(strings.TrimSuffix(gcsBrowserPrefix, "/") + "/" + bucketAndPath[1])
+}
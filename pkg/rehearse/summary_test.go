@@ -0,0 +1,102 @@
+package rehearse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestExecutorSummary(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	prowJob := func(name, job string, state pjapi.ProwJobState, completed time.Time, labels map[string]string) *pjapi.ProwJob {
+		completion := metav1.NewTime(completed)
+		return &pjapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: labels},
+			Spec:       pjapi.ProwJobSpec{Job: job},
+			Status:     pjapi.ProwJobStatus{State: state, CompletionTime: &completion},
+		}
+	}
+	rehearsalA := prowJob("rehearsal-a", "rehearse-123-job-a", pjapi.FailureState, now, map[string]string{Label: "123"})
+	rehearsalB := prowJob("rehearsal-b", "rehearse-123-job-b", pjapi.SuccessState, now, map[string]string{Label: "123"})
+	oldUpstreamA := prowJob("old-upstream-a", "job-a", pjapi.FailureState, now.Add(-2*time.Hour), map[string]string{kube.ProwJobAnnotation: "job-a"})
+	upstreamA := prowJob("upstream-a", "job-a", pjapi.SuccessState, now.Add(-time.Hour), map[string]string{kube.ProwJobAnnotation: "job-a"})
+	otherRehearsalA := prowJob("other-rehearsal-a", "job-a", pjapi.FailureState, now, map[string]string{kube.ProwJobAnnotation: "job-a", Label: "456"})
+	pendingA := &pjapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending-a", Namespace: "ns", Labels: map[string]string{kube.ProwJobAnnotation: "job-a"}},
+		Spec:       pjapi.ProwJobSpec{Job: "job-a"},
+		Status:     pjapi.ProwJobStatus{State: pjapi.PendingState},
+	}
+
+	client := newTC(rehearsalA, rehearsalB, oldUpstreamA, upstreamA, otherRehearsalA, pendingA)
+	executor := NewExecutor(nil, 123, "", &pjapi.Refs{}, true, logrus.NewEntry(logrus.New()), client, "ns")
+	executor.pollFunc = threetimesTryingPoller
+	if _, err := executor.waitForJobs(sets.NewString("rehearsal-a", "rehearsal-b"), &ctrlruntimeclient.ListOptions{}); err != nil {
+		t.Fatalf("failed to wait for jobs: %v", err)
+	}
+
+	summary, err := executor.Summary()
+	if err != nil {
+		t.Fatalf("failed to summarize: %v", err)
+	}
+	var jobs, upstreams []string
+	for _, result := range summary {
+		jobs = append(jobs, result.Job)
+		upstream := ""
+		if result.Upstream != nil {
+			upstream = result.Upstream.Name
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	if diff := cmp.Diff([]string{"job-a", "job-b"}, jobs); diff != "" {
+		t.Errorf("jobs differ from expected:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"upstream-a", ""}, upstreams); diff != "" {
+		t.Errorf("upstream runs differ from expected:\n%s", diff)
+	}
+	if !summary[0].LikelyCausedByChange() {
+		t.Error("expected failure of job-a to be likely caused by the change")
+	}
+	if summary[1].LikelyCausedByChange() {
+		t.Error("expected job-b not to be likely caused by the change")
+	}
+}
+
+func TestRehearsalSummaryDescribe(t *testing.T) {
+	summary := RehearsalSummary{
+		{
+			Job: "job-a",
+			Rehearsal: pjapi.ProwJob{Status: pjapi.ProwJobStatus{
+				State: pjapi.FailureState,
+				URL:   "https://prow.ci.openshift.org/view/gs/origin-ci-test/pr-logs/pull/openshift_release/123/rehearse-123-job-a/1",
+			}},
+			Upstream: &pjapi.ProwJob{Status: pjapi.ProwJobStatus{State: pjapi.SuccessState, URL: "https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/job-a/2"}},
+		},
+		{
+			Job:       "job-b",
+			Rehearsal: pjapi.ProwJob{Status: pjapi.ProwJobStatus{State: pjapi.SuccessState}},
+		},
+		{
+			Job:       "job-c",
+			Rehearsal: pjapi.ProwJob{Status: pjapi.ProwJobStatus{State: pjapi.FailureState}},
+			Upstream:  &pjapi.ProwJob{Status: pjapi.ProwJobStatus{State: pjapi.FailureState}},
+		},
+	}
+	expected := `Test name | Rehearsal | Upstream | Notes
+--- | --- | --- | ---
+job-a | [failure](https://prow.ci.openshift.org/view/gs/origin-ci-test/pr-logs/pull/openshift_release/123/rehearse-123-job-a/1) | [success](https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/job-a/2) | :warning: fails in rehearsal but passes upstream, likely caused by this PR ([step graph](https://gcsweb/gcs/origin-ci-test/pr-logs/pull/openshift_release/123/rehearse-123-job-a/1/artifacts/ci-operator-step-graph.json))
+job-b | success | N/A |
+job-c | failure | failure |
+
+1 of 3 rehearsals fail while the production job passes.`
+	if diff := cmp.Diff(expected, summary.Describe("https://gcsweb/gcs/origin-ci-test/")); diff != "" {
+		t.Errorf("description differs from expected:\n%s", diff)
+	}
+}
@@ -1,6 +1,12 @@
 package retester
 
-import "k8s.io/test-infra/prow/tide"
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/test-infra/prow/tide"
+	"sigs.k8s.io/yaml"
+)
 
 type retestBackoffAction int
 
@@ -12,6 +18,22 @@ const (
 
 type backoffCache interface {
 	check(pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string)
+	limitRecords() *retestLimits
 	load() error
 	save() error
 }
+
+// limitsFile returns the name of the file storing the retest limits next to the given cache file
+func limitsFile(file string) string {
+	return file + ".limits"
+}
+
+// loadLimits loads content into retest limits and deletes the records too old to affect them
+func loadLimits(content []byte, now time.Time) (retestLimits, error) {
+	var limits retestLimits
+	if err := yaml.Unmarshal(content, &limits); err != nil {
+		return retestLimits{}, fmt.Errorf("failed to unmarshal limits: %w", err)
+	}
+	limits.prune(now)
+	return limits, nil
+}
@@ -0,0 +1,182 @@
+package retester
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/test-infra/prow/tide"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	configMapCacheKey  = "cache"
+	configMapLimitsKey = "limits"
+	// maxConfigMapSize is the size limit of the data of a ConfigMap
+	maxConfigMapSize = 1024 * 1024
+)
+
+// configMapBackoffCache persists the backoff cache in a ConfigMap, so it can be shared by
+// the replicas of the retester running with leader election.
+type configMapBackoffCache struct {
+	cache          map[string]*pullRequest
+	limits         retestLimits
+	configMap      types.NamespacedName
+	cacheRecordAge time.Duration
+	logger         *logrus.Entry
+
+	client ctrlruntimeclient.Client
+	// resourceVersion is the version of the ConfigMap the cache was loaded from. When the
+	// ConfigMap was changed since, e.g., by a replica which was the leader in the meantime,
+	// saving merges the records of both.
+	resourceVersion string
+}
+
+func (b *configMapBackoffCache) load() error {
+	return b.loadFromConfigMapNow(time.Now())
+}
+
+// loadFromConfigMapNow gets the backoff cache ConfigMap and marshals its content into the configMapBackoffCache
+func (b *configMapBackoffCache) loadFromConfigMapNow(now time.Time) error {
+	configMap := &corev1.ConfigMap{}
+	if err := b.client.Get(context.TODO(), b.configMap, configMap); err != nil {
+		if kerrors.IsNotFound(err) {
+			b.logger.WithField("configmap", b.configMap.String()).Info("cache configmap does not exist")
+			b.resourceVersion = ""
+			return nil
+		}
+		return fmt.Errorf("failed to get configmap %s: %w", b.configMap, err)
+	}
+	b.resourceVersion = configMap.ResourceVersion
+
+	cache, err := loadAndDelete([]byte(configMap.Data[configMapCacheKey]), b.logger, now, b.cacheRecordAge)
+	if err != nil {
+		return err
+	}
+	if cache == nil {
+		cache = map[string]*pullRequest{}
+	}
+	b.cache = cache
+
+	limits, err := loadLimits([]byte(configMap.Data[configMapLimitsKey]), now)
+	if err != nil {
+		return err
+	}
+	b.limits = limits
+	return nil
+}
+
+// save writes the contents of configMapBackoffCache into the backoff cache ConfigMap. When the
+// ConfigMap was changed since it was loaded, it is reloaded and its records are merged with ours.
+func (b *configMapBackoffCache) save() error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return kerrors.IsConflict(err) || kerrors.IsAlreadyExists(err)
+	}, func() error {
+		err := b.write()
+		if kerrors.IsConflict(err) || kerrors.IsAlreadyExists(err) {
+			if mergeErr := b.reloadAndMerge(time.Now()); mergeErr != nil {
+				return mergeErr
+			}
+		}
+		return err
+	})
+}
+
+func (b *configMapBackoffCache) write() error {
+	data, err := b.marshal()
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       b.configMap.Namespace,
+			Name:            b.configMap.Name,
+			ResourceVersion: b.resourceVersion,
+		},
+		Data: data,
+	}
+	if b.resourceVersion == "" {
+		if err := b.client.Create(context.TODO(), configMap); err != nil {
+			return fmt.Errorf("failed to create configmap %s: %w", b.configMap, err)
+		}
+	} else if err := b.client.Update(context.TODO(), configMap); err != nil {
+		return fmt.Errorf("failed to update configmap %s: %w", b.configMap, err)
+	}
+	b.resourceVersion = configMap.ResourceVersion
+	return nil
+}
+
+// marshal serializes the cache and the limits for the ConfigMap. When they do not fit, the
+// records of the PRs which were not considered for the longest time are dropped.
+func (b *configMapBackoffCache) marshal() (map[string]string, error) {
+	limitsContent, err := yaml.Marshal(b.limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal limits: %w", err)
+	}
+	keys := make([]string, 0, len(b.cache))
+	for key := range b.cache {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return b.cache[keys[i]].LastConsideredTime.Before(&b.cache[keys[j]].LastConsideredTime)
+	})
+	var dropped int
+	for {
+		content, err := yaml.Marshal(b.cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal: %w", err)
+		}
+		if len(content)+len(limitsContent) <= maxConfigMapSize {
+			if dropped > 0 {
+				b.logger.WithField("configmap", b.configMap.String()).Warnf("Dropped the %d least recently considered records to fit the cache in the configmap", dropped)
+			}
+			return map[string]string{
+				configMapCacheKey:  string(content),
+				configMapLimitsKey: string(limitsContent),
+			}, nil
+		}
+		if len(b.cache) == 0 {
+			return nil, fmt.Errorf("the limits of %d bytes do not fit in configmap %s", len(limitsContent), b.configMap)
+		}
+		// drop a tenth of the records at a time, so large caches do not have to be marshalled over and over
+		n := len(keys)/10 + 1
+		for _, key := range keys[:n] {
+			delete(b.cache, key)
+		}
+		keys = keys[n:]
+		dropped += n
+	}
+}
+
+// reloadAndMerge loads the ConfigMap changed by someone else and merges our records into it,
+// keeping the most recent record of each PR and the retests and results of both.
+func (b *configMapBackoffCache) reloadAndMerge(now time.Time) error {
+	ours, ourLimits := b.cache, b.limits
+	if err := b.loadFromConfigMapNow(now); err != nil {
+		return err
+	}
+	for key, pr := range ours {
+		if theirs, ok := b.cache[key]; !ok || theirs.LastConsideredTime.Before(&pr.LastConsideredTime) {
+			b.cache[key] = pr
+		}
+	}
+	b.limits.merge(ourLimits)
+	return nil
+}
+
+func (b *configMapBackoffCache) check(pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string) {
+	return check(&b.cache, pr, baseSha, policy)
+}
+
+func (b *configMapBackoffCache) limitRecords() *retestLimits {
+	return &b.limits
+}
@@ -17,6 +17,7 @@ import (
 
 type fileBackoffCache struct {
 	cache          map[string]*pullRequest
+	limits         retestLimits
 	file           string
 	cacheRecordAge time.Duration
 	logger         *logrus.Entry
@@ -43,6 +44,19 @@ func (b *fileBackoffCache) loadFromDiskNow(now time.Time) error {
 		return err
 	}
 	b.cache = cache
+
+	limitsBytes, err := ioutil.ReadFile(limitsFile(b.file))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", limitsFile(b.file), err)
+	}
+	limits, err := loadLimits(limitsBytes, now)
+	if err != nil {
+		return err
+	}
+	b.limits = limits
 	return nil
 }
 
@@ -62,7 +76,7 @@ func loadAndDelete(content []byte, logger *logrus.Entry, now time.Time, cacheRec
 	return cache, nil
 }
 
-func (b *fileBackoffCache) save() (ret error) {
+func (b *fileBackoffCache) save() error {
 	if b.file == "" {
 		return nil
 	}
@@ -70,9 +84,20 @@ func (b *fileBackoffCache) save() (ret error) {
 	if err != nil {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
-	// write to a temp file and rename it to the cache file to ensure "atomic write":
-	// either it is complete or nothing
-	tmpFile, err := ioutil.TempFile(filepath.Dir(b.file), "tmp-backoff-cache")
+	if err := writeFileAtomically(b.file, bytes); err != nil {
+		return err
+	}
+	limitsBytes, err := yaml.Marshal(b.limits)
+	if err != nil {
+		return fmt.Errorf("failed to marshal limits: %w", err)
+	}
+	return writeFileAtomically(limitsFile(b.file), limitsBytes)
+}
+
+// writeFileAtomically writes to a temp file and renames it to the given file to ensure "atomic write":
+// either it is complete or nothing
+func writeFileAtomically(file string, bytes []byte) (ret error) {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(file), "tmp-backoff-cache")
 	if err != nil {
 		return fmt.Errorf("failed to create a temp file: %w", err)
 	}
@@ -90,8 +115,8 @@ func (b *fileBackoffCache) save() (ret error) {
 	if err := ioutil.WriteFile(tmp, bytes, 0644); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", tmp, err)
 	}
-	if err := os.Rename(tmp, b.file); err != nil {
-		return fmt.Errorf("failed to rename file from %s to %s: %w", tmp, b.file, err)
+	if err := os.Rename(tmp, file); err != nil {
+		return fmt.Errorf("failed to rename file from %s to %s: %w", tmp, file, err)
 	}
 	return ret
 }
@@ -100,6 +125,10 @@ func (b *fileBackoffCache) check(pr tide.PullRequest, baseSha string, policy Ret
 	return check(&b.cache, pr, baseSha, policy)
 }
 
+func (b *fileBackoffCache) limitRecords() *retestLimits {
+	return &b.limits
+}
+
 // check updates the cache and returns a retestBackoffAction according to baseSha, policy, and number of retests performed for the PR.
 func check(cache *map[string]*pullRequest, pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string) {
 	key := prKey(&pr)
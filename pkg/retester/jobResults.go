@@ -0,0 +1,133 @@
+package retester
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// resultsSearchQuery finds pull requests with the statuses of their head commits. Unlike
+// the combined status, a status context carries the time it was set, which for a finished
+// job is when it completed.
+type resultsSearchQuery struct {
+	Search struct {
+		PageInfo struct {
+			HasNextPage githubql.Boolean
+			EndCursor   githubql.String
+		}
+		Nodes []resultsPRNode
+	} `graphql:"search(type: ISSUE, first: 37, after: $searchCursor, query: $query)"`
+}
+
+type resultsPRNode struct {
+	PullRequest resultsPullRequest `graphql:"... on PullRequest"`
+}
+
+type resultsPullRequest struct {
+	BaseRef struct {
+		Name githubql.String
+	}
+	HeadRefOID githubql.String `graphql:"headRefOid"`
+	Repository struct {
+		NameWithOwner githubql.String
+	}
+	Commits struct {
+		Nodes []struct {
+			Commit resultsCommit
+		}
+	} `graphql:"commits(last: 1)"`
+}
+
+type resultsCommit struct {
+	OID    githubql.String `graphql:"oid"`
+	Status *struct {
+		Contexts []resultsContext
+	}
+}
+
+type resultsContext struct {
+	Context   githubql.String
+	State     githubql.StatusState
+	CreatedAt githubql.DateTime
+}
+
+// observeResults records the results of the required jobs on the head commits of all PRs
+// matching the Tide queries which were updated within the pass rate window. Passing PRs are
+// observed as well as the retest candidates, so the pass rates are not skewed towards failures.
+func (c *RetestController) observeResults(now time.Time) error {
+	var errs []error
+	for i, query := range c.configGetter().Tide.Queries {
+		// Use org-sharded queries only when GitHub apps auth is in use
+		queries := map[string]string{"": query.Query()}
+		if c.usesGitHubApp {
+			queries = query.OrgQueries()
+		}
+		for org, q := range queries {
+			prs, err := searchResults(c.ghClient.QueryWithGitHubAppsSupport, datedQuery(q, now.Add(-jobRetestWindow), now), org)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("query %d, err: %w", i, err))
+			}
+			for _, pr := range prs {
+				c.recordResults(pr)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// recordResults records the finished required jobs on the head commit of the PR
+func (c *RetestController) recordResults(pr resultsPullRequest) {
+	presubmits := c.presubmitsByContext(string(pr.Repository.NameWithOwner), string(pr.BaseRef.Name))
+	if len(presubmits) == 0 {
+		return
+	}
+	for _, node := range pr.Commits.Nodes {
+		if node.Commit.OID != pr.HeadRefOID || node.Commit.Status == nil {
+			continue
+		}
+		for _, ctx := range node.Commit.Status.Contexts {
+			ps, required := presubmits[string(ctx.Context)]
+			if !required {
+				continue
+			}
+			switch ctx.State {
+			case githubql.StatusStateSuccess:
+				c.backoff.limitRecords().recordResult(ps.Name, string(pr.HeadRefOID), true, ctx.CreatedAt.Time)
+			case githubql.StatusStateFailure:
+				c.backoff.limitRecords().recordResult(ps.Name, string(pr.HeadRefOID), false, ctx.CreatedAt.Time)
+			}
+		}
+	}
+}
+
+func searchResults(query querier, q, org string) ([]resultsPullRequest, error) {
+	var cursor *githubql.String
+	vars := map[string]interface{}{
+		"query":        githubql.String(q),
+		"searchCursor": cursor,
+	}
+	var ret []resultsPullRequest
+	var sq resultsSearchQuery
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	for {
+		if err := query(ctx, &sq, vars, org); err != nil {
+			if cursor != nil {
+				err = fmt.Errorf("cursor: %q, err: %w", *cursor, err)
+			}
+			return ret, err
+		}
+		for _, n := range sq.Search.Nodes {
+			ret = append(ret, n.PullRequest)
+		}
+		if !sq.Search.PageInfo.HasNextPage {
+			return ret, nil
+		}
+		cursor = &sq.Search.PageInfo.EndCursor
+		vars["searchCursor"] = cursor
+	}
+}
@@ -0,0 +1,192 @@
+package retester
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// jobRetestWindow is the period in which retests of a job count against its budget
+	// and in which its results count towards its pass rate.
+	jobRetestWindow = 24 * time.Hour
+	// orgRetestWindow is the period in which retests in an org count against its rate limit.
+	orgRetestWindow = time.Hour
+	// minJobResultsForPassRate is the number of results a job needs in the window
+	// before its pass rate is taken into account.
+	minJobResultsForPassRate = 10
+)
+
+// retestLimits holds the records needed to enforce the job and org level limits of retester policies.
+type retestLimits struct {
+	Jobs map[string]*jobRecord `json:"jobs,omitempty"`
+	Orgs map[string]*orgRecord `json:"orgs,omitempty"`
+}
+
+// jobRecord represents retests issued for a job and its results observed on the considered PRs.
+type jobRecord struct {
+	Retests []metav1.Time `json:"retests,omitempty"`
+	// Results are keyed by the PR revision the job ran on, so only the latest result of a revision counts.
+	Results map[string]jobResult `json:"results,omitempty"`
+}
+
+type jobResult struct {
+	Passed bool `json:"passed"`
+	// Time is when the job completed.
+	Time metav1.Time `json:"time"`
+}
+
+// orgRecord represents retests issued for PRs in an org.
+type orgRecord struct {
+	Retests []metav1.Time `json:"retests,omitempty"`
+}
+
+func (l *retestLimits) job(name string) *jobRecord {
+	if l.Jobs == nil {
+		l.Jobs = map[string]*jobRecord{}
+	}
+	if _, ok := l.Jobs[name]; !ok {
+		l.Jobs[name] = &jobRecord{}
+	}
+	return l.Jobs[name]
+}
+
+func (l *retestLimits) org(name string) *orgRecord {
+	if l.Orgs == nil {
+		l.Orgs = map[string]*orgRecord{}
+	}
+	if _, ok := l.Orgs[name]; !ok {
+		l.Orgs[name] = &orgRecord{}
+	}
+	return l.Orgs[name]
+}
+
+// recordResult stores the result of a job on a PR revision, completed at the given time.
+// A result completed before the one already stored for the revision is ignored.
+func (l *retestLimits) recordResult(job, sha string, passed bool, completed time.Time) {
+	record := l.job(job)
+	if record.Results == nil {
+		record.Results = map[string]jobResult{}
+	}
+	if existing, ok := record.Results[sha]; ok && existing.Time.Time.After(completed) {
+		return
+	}
+	record.Results[sha] = jobResult{Passed: passed, Time: metav1.NewTime(completed)}
+}
+
+// recordRetest stores a retest issued in an org for the given failed jobs.
+func (l *retestLimits) recordRetest(org string, jobs []string, now time.Time) {
+	l.org(org).Retests = append(l.org(org).Retests, metav1.NewTime(now))
+	for _, job := range jobs {
+		l.job(job).Retests = append(l.job(job).Retests, metav1.NewTime(now))
+	}
+}
+
+// check returns a message explaining why a retest of the failed jobs in the org is not allowed by the policy,
+// or an empty string if the retest is allowed.
+func (l *retestLimits) check(org string, jobs []string, policy RetesterPolicy, now time.Time) string {
+	if policy.MaxRetestsForOrgPerHour > 0 {
+		if retests := countSince(l.org(org).Retests, now.Add(-orgRetestWindow)); retests >= policy.MaxRetestsForOrgPerHour {
+			return fmt.Sprintf("Org %s was retested %d times in the last hour: pausing", org, retests)
+		}
+	}
+	for _, job := range jobs {
+		record := l.job(job)
+		if policy.MaxRetestsForJobPerDay > 0 {
+			if retests := countSince(record.Retests, now.Add(-jobRetestWindow)); retests >= policy.MaxRetestsForJobPerDay {
+				return fmt.Sprintf("Job %s was retested %d times in the last 24 hours: pausing", job, retests)
+			}
+		}
+		if policy.MinJobPassRate > 0 {
+			var total, passed int
+			for _, result := range record.Results {
+				if result.Time.Time.Before(now.Add(-jobRetestWindow)) {
+					continue
+				}
+				total++
+				if result.Passed {
+					passed++
+				}
+			}
+			if total >= minJobResultsForPassRate && passed*100 < policy.MinJobPassRate*total {
+				return fmt.Sprintf("Job %s passed %d of its last %d runs, below the pass rate of %d%%: pausing", job, passed, total, policy.MinJobPassRate)
+			}
+		}
+	}
+	return ""
+}
+
+// prune deletes the records which are too old to affect any limit.
+func (l *retestLimits) prune(now time.Time) {
+	for name, record := range l.Jobs {
+		record.Retests = keepSince(record.Retests, now.Add(-jobRetestWindow))
+		for sha, result := range record.Results {
+			if result.Time.Time.Before(now.Add(-jobRetestWindow)) {
+				delete(record.Results, sha)
+			}
+		}
+		if len(record.Retests) == 0 && len(record.Results) == 0 {
+			delete(l.Jobs, name)
+		}
+	}
+	for name, record := range l.Orgs {
+		record.Retests = keepSince(record.Retests, now.Add(-orgRetestWindow))
+		if len(record.Retests) == 0 {
+			delete(l.Orgs, name)
+		}
+	}
+}
+
+// merge adds the retests and results of other, which was loaded from the same records but
+// changed separately since, to l.
+func (l *retestLimits) merge(other retestLimits) {
+	for name, record := range other.Jobs {
+		ours := l.job(name)
+		ours.Retests = mergeTimes(ours.Retests, record.Retests)
+		for sha, result := range record.Results {
+			l.recordResult(name, sha, result.Passed, result.Time.Time)
+		}
+	}
+	for name, record := range other.Orgs {
+		l.org(name).Retests = mergeTimes(l.org(name).Retests, record.Retests)
+	}
+}
+
+// mergeTimes merges two lists of times which share a common origin: a time in both lists is
+// kept as many times as it appears in either of them. Times are compared to the second, as
+// they are serialized.
+func mergeTimes(a, b []metav1.Time) []metav1.Time {
+	counts := func(times []metav1.Time) map[int64]int {
+		ret := map[int64]int{}
+		for _, t := range times {
+			ret[t.Unix()]++
+		}
+		return ret
+	}
+	inA, inB := counts(a), counts(b)
+	merged := append([]metav1.Time{}, a...)
+	for _, t := range b {
+		if inB[t.Unix()] > inA[t.Unix()] {
+			merged = append(merged, t)
+			inA[t.Unix()]++
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Before(&merged[j]) })
+	return merged
+}
+
+func countSince(times []metav1.Time, since time.Time) int {
+	return len(keepSince(times, since))
+}
+
+func keepSince(times []metav1.Time, since time.Time) []metav1.Time {
+	var kept []metav1.Time
+	for _, t := range times {
+		if !t.Time.Before(since) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,11 +15,13 @@ import (
 	"github.com/sirupsen/logrus"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/tide"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 )
 
@@ -46,11 +49,19 @@ var (
 		},
 		[]string{"org", "repo"},
 	)
+	retestLimitedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retest_limited_total",
+			Help: "Number of retests in total not issued by the tool because of job or org limits.",
+		},
+		[]string{"org", "repo"},
+	)
 )
 
 func init() {
 	// Metrics have to be registered to be exposed:
 	prometheus.MustRegister(retestTotal)
+	prometheus.MustRegister(retestLimitedTotal)
 }
 
 // Config is retester configuration for all configured repos and orgs.
@@ -84,8 +95,15 @@ type Repo struct {
 // False in level repo means disabled repo. Nothing can change that.
 // True/False in level org means enabled/disabled org. But repo can be disabled/enabled.
 type RetesterPolicy struct {
-	MaxRetestsForShaAndBase int   `json:"max_retests_for_sha_and_base,omitempty"`
-	MaxRetestsForSha        int   `json:"max_retests_for_sha,omitempty"`
+	MaxRetestsForShaAndBase int `json:"max_retests_for_sha_and_base,omitempty"`
+	MaxRetestsForSha        int `json:"max_retests_for_sha,omitempty"`
+	// MaxRetestsForJobPerDay caps the retests of a failed job across all PRs in the last 24 hours.
+	MaxRetestsForJobPerDay int `json:"max_retests_for_job_per_day,omitempty"`
+	// MinJobPassRate stops retesting a failed job when it passed on less than this percentage
+	// of the PRs considered in the last 24 hours, which usually means an outage rather than a flake.
+	MinJobPassRate int `json:"min_job_pass_rate,omitempty"`
+	// MaxRetestsForOrgPerHour caps the retests issued for PRs in an org in the last hour.
+	MaxRetestsForOrgPerHour int   `json:"max_retests_for_org_per_hour,omitempty"`
 	Enabled                 *bool `json:"enabled,omitempty"`
 }
 
@@ -136,6 +154,15 @@ func (c *Config) GetRetesterPolicy(org, repo string) (RetesterPolicy, error) {
 				if repoStruct.MaxRetestsForShaAndBase != 0 {
 					policy.MaxRetestsForShaAndBase = repoStruct.MaxRetestsForShaAndBase
 				}
+				if repoStruct.MaxRetestsForJobPerDay != 0 {
+					policy.MaxRetestsForJobPerDay = repoStruct.MaxRetestsForJobPerDay
+				}
+				if repoStruct.MinJobPassRate != 0 {
+					policy.MinJobPassRate = repoStruct.MinJobPassRate
+				}
+				if repoStruct.MaxRetestsForOrgPerHour != 0 {
+					policy.MaxRetestsForOrgPerHour = repoStruct.MaxRetestsForOrgPerHour
+				}
 			} else {
 				return RetesterPolicy{}, nil
 			}
@@ -148,6 +175,15 @@ func (c *Config) GetRetesterPolicy(org, repo string) (RetesterPolicy, error) {
 			if orgStruct.MaxRetestsForShaAndBase != 0 && policy.MaxRetestsForShaAndBase == 0 {
 				policy.MaxRetestsForShaAndBase = orgStruct.MaxRetestsForShaAndBase
 			}
+			if orgStruct.MaxRetestsForJobPerDay != 0 && policy.MaxRetestsForJobPerDay == 0 {
+				policy.MaxRetestsForJobPerDay = orgStruct.MaxRetestsForJobPerDay
+			}
+			if orgStruct.MinJobPassRate != 0 && policy.MinJobPassRate == 0 {
+				policy.MinJobPassRate = orgStruct.MinJobPassRate
+			}
+			if orgStruct.MaxRetestsForOrgPerHour != 0 && policy.MaxRetestsForOrgPerHour == 0 {
+				policy.MaxRetestsForOrgPerHour = orgStruct.MaxRetestsForOrgPerHour
+			}
 		}
 		if !*policy.Enabled && (c.Retester.Enabled == nil || !*c.Retester.Enabled) {
 			return RetesterPolicy{}, nil
@@ -162,6 +198,15 @@ func (c *Config) GetRetesterPolicy(org, repo string) (RetesterPolicy, error) {
 	if policy.MaxRetestsForShaAndBase == 0 {
 		policy.MaxRetestsForShaAndBase = c.Retester.MaxRetestsForShaAndBase
 	}
+	if policy.MaxRetestsForJobPerDay == 0 {
+		policy.MaxRetestsForJobPerDay = c.Retester.MaxRetestsForJobPerDay
+	}
+	if policy.MinJobPassRate == 0 {
+		policy.MinJobPassRate = c.Retester.MinJobPassRate
+	}
+	if policy.MaxRetestsForOrgPerHour == 0 {
+		policy.MaxRetestsForOrgPerHour = c.Retester.MaxRetestsForOrgPerHour
+	}
 	return policy, nil
 }
 
@@ -178,6 +223,15 @@ func validatePolicies(policy RetesterPolicy) []error {
 			if policy.MaxRetestsForSha < policy.MaxRetestsForShaAndBase {
 				errs = append(errs, fmt.Errorf("max_retest_for_sha value can't be lower than max_retests_for_sha_and_base value: %d < %d", policy.MaxRetestsForSha, policy.MaxRetestsForShaAndBase))
 			}
+			if policy.MaxRetestsForJobPerDay < 0 {
+				errs = append(errs, fmt.Errorf("max_retests_for_job_per_day has invalid value: %d", policy.MaxRetestsForJobPerDay))
+			}
+			if policy.MinJobPassRate < 0 || policy.MinJobPassRate > 100 {
+				errs = append(errs, fmt.Errorf("min_job_pass_rate has invalid value: %d", policy.MinJobPassRate))
+			}
+			if policy.MaxRetestsForOrgPerHour < 0 {
+				errs = append(errs, fmt.Errorf("max_retests_for_org_per_hour has invalid value: %d", policy.MaxRetestsForOrgPerHour))
+			}
 		} else {
 			return nil
 		}
@@ -186,30 +240,59 @@ func validatePolicies(policy RetesterPolicy) []error {
 }
 
 // NewController generates a retest controller.
-func NewController(ghClient githubClient, cfg config.Getter, gitClient git.ClientFactory, usesApp bool, cacheFile string, cacheRecordAge time.Duration, config *Config, awsSession *session.Session) *RetestController {
+// The backoff cache is persisted in the cacheConfigMap ConfigMap when kubeClient is set,
+// in the AWS S3 bucket when awsSession is set, and in cacheFile on disk otherwise.
+func NewController(ghClient githubClient, cfg config.Getter, gitClient git.ClientFactory, usesApp bool, cacheFile string, cacheRecordAge time.Duration, config *Config, awsSession *session.Session, kubeClient ctrlruntimeclient.Client, cacheConfigMap types.NamespacedName) *RetestController {
 	logger := logrus.NewEntry(logrus.StandardLogger())
-	var backoff backoffCache
-	if awsSession != nil {
-		backoff = &s3BackOffCache{cache: map[string]*pullRequest{}, file: cacheFile, cacheRecordAge: cacheRecordAge, logger: logger, awsClient: s3.New(awsSession)}
-	} else {
-		backoff = &fileBackoffCache{cache: map[string]*pullRequest{}, file: cacheFile, cacheRecordAge: cacheRecordAge, logger: logger}
-	}
-
 	ret := &RetestController{
 		ghClient:      ghClient,
 		gitClient:     gitClient,
 		configGetter:  cfg,
 		logger:        logger,
 		usesGitHubApp: usesApp,
-		backoff:       backoff,
+		backoff:       newBackoffCache(cacheFile, cacheRecordAge, logger, awsSession, kubeClient, cacheConfigMap),
 		config:        config,
 	}
 	if err := ret.backoff.load(); err != nil {
-		logger.WithError(err).Warn("Failed to load backoff cache from disk")
+		logger.WithError(err).Warn("Failed to load backoff cache")
 	}
 	return ret
 }
 
+func newBackoffCache(cacheFile string, cacheRecordAge time.Duration, logger *logrus.Entry, awsSession *session.Session, kubeClient ctrlruntimeclient.Client, cacheConfigMap types.NamespacedName) backoffCache {
+	switch {
+	case kubeClient != nil:
+		return &configMapBackoffCache{cache: map[string]*pullRequest{}, configMap: cacheConfigMap, cacheRecordAge: cacheRecordAge, logger: logger, client: kubeClient}
+	case awsSession != nil:
+		return &s3BackOffCache{cache: map[string]*pullRequest{}, file: cacheFile, cacheRecordAge: cacheRecordAge, logger: logger, awsClient: s3.New(awsSession)}
+	default:
+		return &fileBackoffCache{cache: map[string]*pullRequest{}, file: cacheFile, cacheRecordAge: cacheRecordAge, logger: logger}
+	}
+}
+
+// MigrateBackoffCache copies the backoff cache persisted in cacheFile on disk, or in the AWS S3 bucket
+// when awsSession is set, into the ConfigMap backoff cache. Existing content of the ConfigMap is replaced.
+func MigrateBackoffCache(cacheFile string, cacheRecordAge time.Duration, awsSession *session.Session, kubeClient ctrlruntimeclient.Client, configMap types.NamespacedName) error {
+	logger := logrus.WithField("configmap", configMap.String())
+	source := newBackoffCache(cacheFile, cacheRecordAge, logger, awsSession, nil, types.NamespacedName{})
+	if err := source.load(); err != nil {
+		return fmt.Errorf("failed to load backoff cache %s: %w", cacheFile, err)
+	}
+	target := &configMapBackoffCache{cache: map[string]*pullRequest{}, configMap: configMap, cacheRecordAge: cacheRecordAge, logger: logger, client: kubeClient}
+	if err := target.load(); err != nil {
+		return err
+	}
+	switch source := source.(type) {
+	case *fileBackoffCache:
+		target.cache = source.cache
+	case *s3BackOffCache:
+		target.cache = source.cache
+	}
+	target.limits = *source.limitRecords()
+	logger.WithField("records", len(target.cache)).Info("Migrating backoff cache")
+	return target.save()
+}
+
 func prUrl(pr tide.PullRequest) string {
 	return fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Repository.Owner.Login, pr.Repository.Name, pr.Number)
 }
@@ -222,6 +305,9 @@ func (c *RetestController) Run() error {
 	if err != nil {
 		return fmt.Errorf("failed to find retestable candidates: %w", err)
 	}
+	if err := c.observeResults(time.Now()); err != nil {
+		c.logger.WithError(err).Warn("Failed to observe the results of the required jobs")
+	}
 	return c.runWithCandidates(candidates)
 }
 
@@ -234,7 +320,9 @@ func (c *RetestController) runWithCandidates(candidates map[string]tide.PullRequ
 	candidates = c.enabledPRs(candidates)
 	logrus.Infof("Remaining %d candidates for retest (from an enabled org or repo)", len(candidates))
 
-	candidates, err := c.atLeastOneRequiredJob(candidates)
+	c.backoff.limitRecords().prune(time.Now())
+
+	candidates, failedJobs, err := c.atLeastOneRequiredJob(candidates)
 	if err != nil {
 		return fmt.Errorf("failed to filter candidate PRs that have at least one required job: %w", err)
 	}
@@ -245,8 +333,8 @@ func (c *RetestController) runWithCandidates(candidates map[string]tide.PullRequ
 	}
 
 	var errs []error
-	for _, pr := range candidates {
-		errs = append(errs, c.retestOrBackoff(pr))
+	for key, pr := range candidates {
+		errs = append(errs, c.retestOrBackoff(pr, failedJobs[key]))
 	}
 
 	if err := c.backoff.save(); err != nil {
@@ -265,7 +353,7 @@ func (c *RetestController) createComment(pr tide.PullRequest, cmd, message strin
 	}
 }
 
-func (c *RetestController) retestOrBackoff(pr tide.PullRequest) error {
+func (c *RetestController) retestOrBackoff(pr tide.PullRequest, failedJobs []string) error {
 	branchRef := string(pr.BaseRef.Prefix) + string(pr.BaseRef.Name)
 	baseSha, err := c.ghClient.GetRef(string(pr.Repository.Owner.Login), string(pr.Repository.Name), strings.TrimPrefix(branchRef, "refs/"))
 	if err != nil {
@@ -282,6 +370,13 @@ func (c *RetestController) retestOrBackoff(pr tide.PullRequest) error {
 		return fmt.Errorf("failed to validate retester policy: %v", validationErrors)
 	}
 
+	now := time.Now()
+	if message := c.backoff.limitRecords().check(org, failedJobs, policy, now); message != "" {
+		c.logger.Infof("%s: %s (%s)", prUrl(pr), "no comment", message)
+		retestLimitedTotal.With(prometheus.Labels{"org": org, "repo": repo}).Inc()
+		return nil
+	}
+
 	action, message := c.backoff.check(pr, baseSha, policy)
 	switch action {
 	case retestBackoffHold:
@@ -290,6 +385,7 @@ func (c *RetestController) retestOrBackoff(pr tide.PullRequest) error {
 		c.logger.Infof("%s: %s (%s)", prUrl(pr), "no comment", message)
 	case retestBackoffRetest:
 		c.createComment(pr, "/retest-required", message)
+		c.backoff.limitRecords().recordRetest(org, failedJobs, now)
 	}
 	return nil
 }
@@ -303,8 +399,11 @@ func findCandidates(config config.Getter, gc githubClient, usesGitHubAppsAuth bo
 	return prs, nil
 }
 
-func (c *RetestController) atLeastOneRequiredJob(candidates map[string]tide.PullRequest) (map[string]tide.PullRequest, error) {
+// atLeastOneRequiredJob filters the candidates failing at least one required job and returns
+// the names of the failed required jobs of each of them.
+func (c *RetestController) atLeastOneRequiredJob(candidates map[string]tide.PullRequest) (map[string]tide.PullRequest, map[string][]string, error) {
 	output := map[string]tide.PullRequest{}
+	failedJobs := map[string][]string{}
 	for key, pr := range candidates {
 		// Get all non-optional Prowjobs configured for this org/repo/branch that could run on this PR
 		presubmits := c.presubmitsForPRByContext(pr)
@@ -320,26 +419,27 @@ func (c *RetestController) atLeastOneRequiredJob(candidates map[string]tide.Pull
 		contexts, err := headContexts(c.ghClient, pr)
 		if err != nil {
 			c.logger.WithError(err).Errorf("Failed to get contexts for %s", key)
-			return nil, err
+			return nil, nil, err
 		}
 		c.logger.Infof("HEAD commit of PR %s has %d contexts", key, len(contexts))
 
 		for _, ctx := range contexts {
-			if ctx.State != githubql.StatusStateFailure {
+			ps, has := presubmits[string(ctx.Context)]
+			if !has {
 				continue
 			}
-			// It is enough to find a single failed context that corresponds to a required Prowjob
-			if ps, has := presubmits[string(ctx.Context)]; has {
+			if ctx.State == githubql.StatusStateFailure {
 				c.logger.Infof("PR %s fails required job %s (context=%s)", key, ps.Name, ctx.Context)
 				output[key] = pr
-				break
+				failedJobs[key] = append(failedJobs[key], ps.Name)
 			}
 		}
 		if _, ok := output[key]; !ok {
 			c.logger.Infof("PR %s has no failing context of a required Prowjob", key)
 		}
+		sort.Strings(failedJobs[key])
 	}
-	return output, nil
+	return output, failedJobs, nil
 }
 
 // refactor out the query function from the tide's controller
@@ -481,12 +581,17 @@ func search(query querier, log *logrus.Entry, q string, start, end time.Time, or
 }
 
 func (c *RetestController) presubmitsForPRByContext(pr tide.PullRequest) map[string]config.Presubmit {
+	return c.presubmitsByContext(string(pr.Repository.Owner.Login)+"/"+string(pr.Repository.Name), string(pr.BaseRef.Name))
+}
+
+// presubmitsByContext returns the required presubmits of a repository which could run on PRs against the branch
+func (c *RetestController) presubmitsByContext(orgRepo, branch string) map[string]config.Presubmit {
 	presubmits := map[string]config.Presubmit{}
 
-	presubmitsForRepo := c.configGetter().GetPresubmitsStatic(string(pr.Repository.Owner.Login) + "/" + string(pr.Repository.Name))
+	presubmitsForRepo := c.configGetter().GetPresubmitsStatic(orgRepo)
 
 	for _, ps := range presubmitsForRepo {
-		if ps.ContextRequired() && ps.CouldRun(string(pr.BaseRef.Name)) {
+		if ps.ContextRequired() && ps.CouldRun(branch) {
 			presubmits[ps.Context] = ps
 		}
 	}
@@ -18,11 +18,15 @@ import (
 	"github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	configflagutil "k8s.io/test-infra/prow/flagutil/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/tide"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
@@ -160,6 +164,15 @@ func TestGetRetesterPolicy(t *testing.T) {
 						}},
 						"repo": {RetesterPolicy: RetesterPolicy{Enabled: &False}},
 					}},
+				"openshift-limits": {
+					RetesterPolicy: RetesterPolicy{
+						MaxRetestsForSha: 2, MaxRetestsForShaAndBase: 2, MaxRetestsForOrgPerHour: 20, MinJobPassRate: 10, Enabled: &True,
+					},
+					Repos: map[string]Repo{
+						"ci-tools": {RetesterPolicy: RetesterPolicy{
+							MaxRetestsForJobPerDay: 5, MinJobPassRate: 30, Enabled: &True,
+						}},
+					}},
 				"no-openshift": {
 					RetesterPolicy: RetesterPolicy{Enabled: &False},
 					Repos: map[string]Repo{
@@ -184,21 +197,28 @@ func TestGetRetesterPolicy(t *testing.T) {
 			org:      "openshift",
 			repo:     "ci-tools",
 			config:   c,
-			expected: RetesterPolicy{3, 3, &True},
+			expected: RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 3, Enabled: &True},
 		},
 		{
 			name:     "enabled repo with one max retest value and enabled org",
 			org:      "openshift",
 			repo:     "repo-max",
 			config:   c,
-			expected: RetesterPolicy{2, 6, &True},
+			expected: RetesterPolicy{MaxRetestsForShaAndBase: 2, MaxRetestsForSha: 6, Enabled: &True},
 		},
 		{
 			name:     "enabled repo and disabled org",
 			org:      "no-openshift",
 			repo:     "ci-tools",
 			config:   c,
-			expected: RetesterPolicy{4, 4, &True},
+			expected: RetesterPolicy{MaxRetestsForShaAndBase: 4, MaxRetestsForSha: 4, Enabled: &True},
+		},
+		{
+			name:     "job and org limits are inherited",
+			org:      "openshift-limits",
+			repo:     "ci-tools",
+			config:   c,
+			expected: RetesterPolicy{MaxRetestsForShaAndBase: 2, MaxRetestsForSha: 2, MaxRetestsForJobPerDay: 5, MinJobPassRate: 30, MaxRetestsForOrgPerHour: 20, Enabled: &True},
 		},
 		{
 			name:   "disabled repo and enabled org",
@@ -211,7 +231,7 @@ func TestGetRetesterPolicy(t *testing.T) {
 			org:      "openshift",
 			repo:     "ci-docs",
 			config:   c,
-			expected: RetesterPolicy{2, 2, &True},
+			expected: RetesterPolicy{MaxRetestsForShaAndBase: 2, MaxRetestsForSha: 2, Enabled: &True},
 		},
 		{
 			name:   "not configured repo and disabled org",
@@ -224,7 +244,7 @@ func TestGetRetesterPolicy(t *testing.T) {
 			org:      "no-openshift",
 			repo:     "true",
 			config:   c,
-			expected: RetesterPolicy{3, 9, &True},
+			expected: RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 9, Enabled: &True},
 		},
 		{
 			name:   "not configured repo and not configured org",
@@ -263,27 +283,35 @@ func TestValidatePolicies(t *testing.T) {
 	}{
 		{
 			name:   "basic case",
-			policy: RetesterPolicy{3, 9, &True},
+			policy: RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 9, Enabled: &True},
 		},
 		{
 			name: "empty policy is valid",
 		},
 		{
 			name:   "disable",
-			policy: RetesterPolicy{-1, -1, &False},
+			policy: RetesterPolicy{MaxRetestsForShaAndBase: -1, MaxRetestsForSha: -1, Enabled: &False},
 		},
 		{
 			name:   "negative",
-			policy: RetesterPolicy{-1, -1, &True},
+			policy: RetesterPolicy{MaxRetestsForShaAndBase: -1, MaxRetestsForSha: -1, Enabled: &True},
 			expected: []error{
 				errors.New("max_retest_for_sha has invalid value: -1"),
 				errors.New("max_retests_for_sha_and_base has invalid value: -1")},
 		},
 		{
 			name:     "lower",
-			policy:   RetesterPolicy{9, 3, &True},
+			policy:   RetesterPolicy{MaxRetestsForShaAndBase: 9, MaxRetestsForSha: 3, Enabled: &True},
 			expected: []error{errors.New("max_retest_for_sha value can't be lower than max_retests_for_sha_and_base value: 3 < 9")},
 		},
+		{
+			name:   "invalid job and org limits",
+			policy: RetesterPolicy{MaxRetestsForJobPerDay: -1, MinJobPassRate: 101, MaxRetestsForOrgPerHour: -1, Enabled: &True},
+			expected: []error{
+				errors.New("max_retests_for_job_per_day has invalid value: -1"),
+				errors.New("min_job_pass_rate has invalid value: 101"),
+				errors.New("max_retests_for_org_per_hour has invalid value: -1")},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -360,7 +388,7 @@ func TestRetestOrBackoff(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.c.retestOrBackoff(tc.pr)
+			err := tc.c.retestOrBackoff(tc.pr, nil)
 			if diff := cmp.Diff(tc.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
 				t.Errorf("Error differs from expected:\n%s", diff)
 			}
@@ -817,7 +845,7 @@ func TestCheck(t *testing.T) {
 					Owner         struct{ Login githubv4.String }
 				}{Name: "repo", NameWithOwner: "org/repo", Owner: struct{ Login githubv4.String }{Login: "org"}},
 				HeadRefOID: "holdPR"},
-			policy:         RetesterPolicy{3, 9, &True},
+			policy:         RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 9, Enabled: &True},
 			expected:       0,
 			expectedString: "Revision holdPR was retested 9 times: holding",
 		},
@@ -831,7 +859,7 @@ func TestCheck(t *testing.T) {
 					Owner         struct{ Login githubv4.String }
 				}{Name: "repo", NameWithOwner: "org/repo", Owner: struct{ Login githubv4.String }{Login: "org"}},
 				HeadRefOID: "pausePR"},
-			policy:         RetesterPolicy{3, 9, &True},
+			policy:         RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 9, Enabled: &True},
 			expected:       1,
 			expectedString: "Revision pausePR was retested 3 times against base HEAD : pausing",
 		},
@@ -839,7 +867,7 @@ func TestCheck(t *testing.T) {
 			name:           "retest PR",
 			cache:          fileBackoffCache{cache: map[string]*pullRequest{}, logger: logger},
 			pr:             tide.PullRequest{HeadRefOID: "retestPR"},
-			policy:         RetesterPolicy{3, 9, &True},
+			policy:         RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 9, Enabled: &True},
 			expected:       2,
 			expectedString: "Remaining retests: 2 against base HEAD  and 8 for PR HEAD retestPR in total",
 		},
@@ -988,3 +1016,346 @@ func TestFindCandidates(t *testing.T) {
 		})
 	}
 }
+
+func TestRetestLimitsCheck(t *testing.T) {
+	results := func(passed, failed int) map[string]jobResult {
+		ret := map[string]jobResult{}
+		for i := 0; i < passed+failed; i++ {
+			ret[fmt.Sprintf("sha%d", i)] = jobResult{Passed: i < passed, Time: justNow}
+		}
+		return ret
+	}
+	old := metav1.NewTime(now.Add(-25 * time.Hour))
+
+	testCases := []struct {
+		name     string
+		limits   retestLimits
+		jobs     []string
+		policy   RetesterPolicy
+		expected string
+	}{
+		{
+			name:   "no limits configured",
+			limits: retestLimits{Jobs: map[string]*jobRecord{"job": {Retests: []metav1.Time{justNow, justNow}, Results: results(0, 20)}}},
+			jobs:   []string{"job"},
+			policy: RetesterPolicy{Enabled: &True},
+		},
+		{
+			name:     "org rate limit reached",
+			limits:   retestLimits{Orgs: map[string]*orgRecord{"org": {Retests: []metav1.Time{justNow, justNow}}}},
+			jobs:     []string{"job"},
+			policy:   RetesterPolicy{MaxRetestsForOrgPerHour: 2, Enabled: &True},
+			expected: "Org org was retested 2 times in the last hour: pausing",
+		},
+		{
+			name:   "org retests older than an hour do not count",
+			limits: retestLimits{Orgs: map[string]*orgRecord{"org": {Retests: []metav1.Time{metav1.NewTime(now.Add(-2 * time.Hour)), justNow}}}},
+			jobs:   []string{"job"},
+			policy: RetesterPolicy{MaxRetestsForOrgPerHour: 2, Enabled: &True},
+		},
+		{
+			name:     "job budget reached",
+			limits:   retestLimits{Jobs: map[string]*jobRecord{"job": {Retests: []metav1.Time{justNow, justNow}}}},
+			jobs:     []string{"other", "job"},
+			policy:   RetesterPolicy{MaxRetestsForJobPerDay: 2, Enabled: &True},
+			expected: "Job job was retested 2 times in the last 24 hours: pausing",
+		},
+		{
+			name:   "job retests older than a day do not count",
+			limits: retestLimits{Jobs: map[string]*jobRecord{"job": {Retests: []metav1.Time{old, justNow}}}},
+			jobs:   []string{"job"},
+			policy: RetesterPolicy{MaxRetestsForJobPerDay: 2, Enabled: &True},
+		},
+		{
+			name:     "job pass rate below threshold",
+			limits:   retestLimits{Jobs: map[string]*jobRecord{"job": {Results: results(1, 9)}}},
+			jobs:     []string{"job"},
+			policy:   RetesterPolicy{MinJobPassRate: 20, Enabled: &True},
+			expected: "Job job passed 1 of its last 10 runs, below the pass rate of 20%: pausing",
+		},
+		{
+			name:   "job pass rate at threshold",
+			limits: retestLimits{Jobs: map[string]*jobRecord{"job": {Results: results(2, 8)}}},
+			jobs:   []string{"job"},
+			policy: RetesterPolicy{MinJobPassRate: 20, Enabled: &True},
+		},
+		{
+			name:   "too few results for pass rate",
+			limits: retestLimits{Jobs: map[string]*jobRecord{"job": {Results: results(0, 9)}}},
+			jobs:   []string{"job"},
+			policy: RetesterPolicy{MinJobPassRate: 20, Enabled: &True},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := tc.limits.check("org", tc.jobs, tc.policy, now.Time)
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("%s differs from expected:\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRetestLimitsRecordAndPrune(t *testing.T) {
+	limits := retestLimits{}
+	limits.recordResult("job", "sha1", false, now.Add(-25*time.Hour))
+	limits.recordResult("job", "sha2", false, now.Time)
+	limits.recordResult("job", "sha2", true, now.Time)
+	// a result completed before the recorded one is stale
+	limits.recordResult("job", "sha2", false, now.Add(-time.Minute))
+	limits.recordResult("old-job", "sha1", true, now.Add(-25*time.Hour))
+	limits.recordRetest("org", []string{"job"}, now.Time)
+	limits.recordRetest("old-org", nil, now.Add(-2*time.Hour))
+	limits.prune(now.Time)
+
+	expected := retestLimits{
+		Jobs: map[string]*jobRecord{"job": {Retests: []metav1.Time{now}, Results: map[string]jobResult{"sha2": {Passed: true, Time: now}}}},
+		Orgs: map[string]*orgRecord{"org": {Retests: []metav1.Time{now}}},
+	}
+	if diff := cmp.Diff(expected, limits); diff != "" {
+		t.Errorf("limits differ from expected:\n%s", diff)
+	}
+}
+
+func TestConfigMapBackoffCache(t *testing.T) {
+	// records are pruned relative to the current time when the configmap is reloaded after a conflict
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	name := types.NamespacedName{Namespace: "ci", Name: "retester-backoff"}
+	client := fakectrlruntimeclient.NewClientBuilder().Build()
+
+	first := &configMapBackoffCache{configMap: name, cacheRecordAge: time.Hour, logger: logger, client: client}
+	if err := first.loadFromConfigMapNow(now.Time); err != nil {
+		t.Fatalf("failed to load missing configmap: %v", err)
+	}
+	first.cache = map[string]*pullRequest{"pr1": {PRSha: "sha1", RetestsForBaseSha: 2, RetestsForPrSha: 3, LastConsideredTime: now}}
+	first.limits.recordRetest("org", []string{"job"}, now.Time)
+	if err := first.save(); err != nil {
+		t.Fatalf("failed to create configmap: %v", err)
+	}
+
+	second := &configMapBackoffCache{configMap: name, cacheRecordAge: time.Hour, logger: logger, client: client}
+	if err := second.loadFromConfigMapNow(now.Time); err != nil {
+		t.Fatalf("failed to load configmap: %v", err)
+	}
+	if diff := cmp.Diff(first.cache, second.cache); diff != "" {
+		t.Errorf("loaded cache differs from saved:\n%s", diff)
+	}
+	if diff := cmp.Diff(first.limits, second.limits); diff != "" {
+		t.Errorf("loaded limits differ from saved:\n%s", diff)
+	}
+	later := metav1.NewTime(now.Add(time.Minute))
+	second.cache["pr2"] = &pullRequest{PRSha: "sha2", RetestsForPrSha: 1, LastConsideredTime: later}
+	second.limits.recordRetest("org", []string{"job"}, later.Time)
+	if err := second.save(); err != nil {
+		t.Fatalf("failed to update configmap: %v", err)
+	}
+
+	// the first cache was loaded before the second one saved, so it must merge its records with the second's
+	first.cache["pr1"] = &pullRequest{PRSha: "sha1", RetestsForBaseSha: 3, RetestsForPrSha: 4, LastConsideredTime: later}
+	first.limits.recordRetest("other", nil, later.Time)
+	if err := first.save(); err != nil {
+		t.Fatalf("failed to save after a conflict: %v", err)
+	}
+	third := &configMapBackoffCache{configMap: name, cacheRecordAge: time.Hour, logger: logger, client: client}
+	if err := third.loadFromConfigMapNow(now.Time); err != nil {
+		t.Fatalf("failed to load configmap: %v", err)
+	}
+	expectedCache := map[string]*pullRequest{
+		"pr1": {PRSha: "sha1", RetestsForBaseSha: 3, RetestsForPrSha: 4, LastConsideredTime: later},
+		"pr2": {PRSha: "sha2", RetestsForPrSha: 1, LastConsideredTime: later},
+	}
+	if diff := cmp.Diff(expectedCache, third.cache); diff != "" {
+		t.Errorf("merged cache differs from expected:\n%s", diff)
+	}
+	expectedLimits := retestLimits{
+		Jobs: map[string]*jobRecord{"job": {Retests: []metav1.Time{now, later}}},
+		Orgs: map[string]*orgRecord{"org": {Retests: []metav1.Time{now, later}}, "other": {Retests: []metav1.Time{later}}},
+	}
+	if diff := cmp.Diff(expectedLimits, third.limits); diff != "" {
+		t.Errorf("merged limits differ from expected:\n%s", diff)
+	}
+}
+
+func TestConfigMapBackoffCacheSizeLimit(t *testing.T) {
+	cache := &configMapBackoffCache{
+		configMap: types.NamespacedName{Namespace: "ci", Name: "retester-backoff"},
+		logger:    logrus.NewEntry(logrus.StandardLogger()),
+		cache:     map[string]*pullRequest{},
+	}
+	sha := strings.Repeat("a", 40)
+	for i := 0; i < 20000; i++ {
+		cache.cache[fmt.Sprintf("openshift/ci-tools#%d", i)] = &pullRequest{PRSha: sha, BaseSha: sha, LastConsideredTime: metav1.NewTime(now.Add(time.Duration(i) * time.Second))}
+	}
+	data, err := cache.marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if size := len(data[configMapCacheKey]) + len(data[configMapLimitsKey]); size > maxConfigMapSize {
+		t.Errorf("expected the data to fit in a configmap, got %d bytes", size)
+	}
+	if _, kept := cache.cache["openshift/ci-tools#19999"]; !kept {
+		t.Error("expected the most recently considered record to be kept")
+	}
+	if _, kept := cache.cache["openshift/ci-tools#0"]; kept {
+		t.Error("expected the least recently considered record to be dropped")
+	}
+}
+
+func TestMigrateBackoffCache(t *testing.T) {
+	name := types.NamespacedName{Namespace: "ci", Name: "retester-backoff"}
+	client := fakectrlruntimeclient.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name},
+		Data:       map[string]string{configMapCacheKey: "pr2:\n  pr_sha: old\n"},
+	}).Build()
+	source := filepath.Join("testdata", "loadFromDiskNow", "basic_case.yaml")
+	if err := MigrateBackoffCache(source, 100*365*24*time.Hour, nil, client, name); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	expected, err := ioutil.ReadFile(source)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", source, err)
+	}
+	var expectedCache map[string]*pullRequest
+	if err := yaml.Unmarshal(expected, &expectedCache); err != nil {
+		t.Fatalf("failed to unmarshal %s: %v", source, err)
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := client.Get(context.TODO(), name, configMap); err != nil {
+		t.Fatalf("failed to get configmap: %v", err)
+	}
+	var actualCache map[string]*pullRequest
+	if err := yaml.Unmarshal([]byte(configMap.Data[configMapCacheKey]), &actualCache); err != nil {
+		t.Fatalf("failed to unmarshal configmap: %v", err)
+	}
+	if diff := cmp.Diff(expectedCache, actualCache); diff != "" {
+		t.Errorf("migrated cache differs from expected:\n%s", diff)
+	}
+}
+
+// fakeBackoffCache is an in-memory backoffCache which counts how many times it was saved
+type fakeBackoffCache struct {
+	cache  map[string]*pullRequest
+	limits retestLimits
+	saves  int
+}
+
+func (f *fakeBackoffCache) check(pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string) {
+	return check(&f.cache, pr, baseSha, policy)
+}
+
+func (f *fakeBackoffCache) limitRecords() *retestLimits {
+	return &f.limits
+}
+
+func (f *fakeBackoffCache) load() error {
+	return nil
+}
+
+func (f *fakeBackoffCache) save() error {
+	f.saves++
+	return nil
+}
+
+// queryingFakeClient returns the pull requests for every query
+type queryingFakeClient struct {
+	*MyFakeClient
+	prs     []tide.PullRequest
+	results []resultsPullRequest
+}
+
+func (f *queryingFakeClient) QueryWithGitHubAppsSupport(ctx context.Context, q interface{}, vars map[string]interface{}, org string) error {
+	switch sq := q.(type) {
+	case *searchQuery:
+		sq.Search.Nodes = nil
+		for _, pr := range f.prs {
+			sq.Search.Nodes = append(sq.Search.Nodes, PRNode{PullRequest: pr})
+		}
+	case *resultsSearchQuery:
+		sq.Search.Nodes = nil
+		for _, pr := range f.results {
+			sq.Search.Nodes = append(sq.Search.Nodes, resultsPRNode{PullRequest: pr})
+		}
+	default:
+		return fmt.Errorf("unexpected query type %T", q)
+	}
+	return nil
+}
+
+func resultsPR(repo, branch, head string, commit resultsCommit) resultsPullRequest {
+	pr := resultsPullRequest{HeadRefOID: githubv4.String(head)}
+	pr.Repository.NameWithOwner = githubv4.String(repo)
+	pr.BaseRef.Name = githubv4.String(branch)
+	pr.Commits.Nodes = append(pr.Commits.Nodes, struct{ Commit resultsCommit }{Commit: commit})
+	return pr
+}
+
+func statusCommit(oid string, contexts ...resultsContext) resultsCommit {
+	commit := resultsCommit{OID: githubv4.String(oid)}
+	commit.Status = &struct{ Contexts []resultsContext }{Contexts: contexts}
+	return commit
+}
+
+func TestRun(t *testing.T) {
+	config := &Config{Retester: Retester{
+		RetesterPolicy: RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 9, MaxRetestsForJobPerDay: 1}, Oranizations: map[string]Oranization{
+			"openshift": {RetesterPolicy: RetesterPolicy{Enabled: &True}},
+		},
+	}}
+	pr := tide.PullRequest{
+		Number:     1,
+		HeadRefOID: "a",
+		Repository: struct {
+			Name          githubv4.String
+			NameWithOwner githubv4.String
+			Owner         struct{ Login githubv4.String }
+		}{Name: "ci-tools", NameWithOwner: "openshift/ci-tools", Owner: struct{ Login githubv4.String }{Login: "openshift"}},
+	}
+	completed := time.Now().Add(-time.Hour)
+	ghc := &queryingFakeClient{MyFakeClient: &MyFakeClient{fakegithub.NewFakeClient()}, prs: []tide.PullRequest{pr}, results: []resultsPullRequest{
+		resultsPR("openshift/ci-tools", "master", "a", statusCommit("a", resultsContext{Context: "test-presubmit", State: githubv4.StatusStateFailure, CreatedAt: githubv4.DateTime{Time: completed}})),
+		resultsPR("openshift/ci-tools", "master", "b", statusCommit("b", resultsContext{Context: "test-presubmit", State: githubv4.StatusStateSuccess, CreatedAt: githubv4.DateTime{Time: completed}})),
+	}}
+	ghc.CombinedStatuses = map[string]*github.CombinedStatus{
+		"a": {Statuses: []github.Status{{State: "failure", Context: "test-presubmit", Description: "Job failed"}}},
+	}
+	configOpts := configflagutil.ConfigOptions{ConfigPath: filepath.Join("testdata", "prowconfig", "simple.yaml"), JobConfigPath: filepath.Join("testdata", "jobconfig", "simple.yaml")}
+	configAgent, err := configOpts.ConfigAgent()
+	if err != nil {
+		t.Fatalf("Error starting config agent: %v", err)
+	}
+	backoff := &fakeBackoffCache{cache: map[string]*pullRequest{}}
+	c := &RetestController{
+		ghClient:      ghc,
+		configGetter:  configAgent.Config,
+		logger:        logrus.NewEntry(logrus.StandardLogger()),
+		usesGitHubApp: true,
+		backoff:       backoff,
+		config:        config,
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := c.Run(); err != nil {
+			t.Fatalf("run %d failed: %v", i, err)
+		}
+	}
+
+	// the second run is paused by the job budget
+	if n := len(ghc.IssueComments[1]); n != 1 {
+		t.Errorf("expected a single comment, got %d", n)
+	}
+	if backoff.saves != 2 {
+		t.Errorf("expected the cache to be saved after each run, got %d saves", backoff.saves)
+	}
+	if diff := cmp.Diff(1, backoff.cache["openshift/ci-tools#1"].RetestsForPrSha); diff != "" {
+		t.Errorf("retests for PR differ from expected:\n%s", diff)
+	}
+	job := backoff.limits.Jobs["test-presubmit"]
+	if job == nil || len(job.Retests) != 1 || len(job.Results) != 2 {
+		t.Fatalf("unexpected job records: %+v", job)
+	}
+	if !job.Results["b"].Passed || !job.Results["b"].Time.Time.Equal(completed) {
+		t.Errorf("expected the passing PR to be recorded with the completion time of the job, got %+v", job.Results["b"])
+	}
+}
@@ -26,6 +26,7 @@ type s3Client interface {
 
 type s3BackOffCache struct {
 	cache          map[string]*pullRequest
+	limits         retestLimits
 	file           string
 	cacheRecordAge time.Duration
 	logger         *logrus.Entry
@@ -43,29 +44,47 @@ func (b *s3BackOffCache) loadFromAwsNow(now time.Time) error {
 		return nil
 	}
 
+	content, err := b.getObject(b.file)
+	if err != nil || content == nil {
+		return err
+	}
+	cache, err := loadAndDelete(content, b.logger, now, b.cacheRecordAge)
+	if err != nil {
+		return err
+	}
+	b.cache = cache
+
+	limitsContent, err := b.getObject(limitsFile(b.file))
+	if err != nil || limitsContent == nil {
+		return err
+	}
+	limits, err := loadLimits(limitsContent, now)
+	if err != nil {
+		return err
+	}
+	b.limits = limits
+	return nil
+}
+
+// getObject returns the content of the file in the retester AWS S3 bucket, or nil if it doesn't exist
+func (b *s3BackOffCache) getObject(file string) ([]byte, error) {
 	result, err := b.awsClient.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(retesterBucket),
-		Key:    aws.String(b.file),
+		Key:    aws.String(file),
 	})
 	if err != nil {
 		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
-			b.logger.WithField("file", b.file).Info("file doesn't exist in the s3 bucket")
-			return nil
+			b.logger.WithField("file", file).Info("file doesn't exist in the s3 bucket")
+			return nil, nil
 		}
-		return fmt.Errorf("error getting %s file from aws s3 bucket %s: %w", b.file, retesterBucket, err)
+		return nil, fmt.Errorf("error getting %s file from aws s3 bucket %s: %w", file, retesterBucket, err)
 	}
 
 	content, err := io.ReadAll(result.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", b.file, err)
-	}
-
-	cache, err := loadAndDelete(content, b.logger, now, b.cacheRecordAge)
-	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read file %s: %w", file, err)
 	}
-	b.cache = cache
-	return nil
+	return content, nil
 }
 
 // save uploads the contents of s3BackOffCache to the retester AWS S3 bucket
@@ -74,14 +93,24 @@ func (b *s3BackOffCache) save() error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
+	if err := b.putObject(b.file, content); err != nil {
+		return err
+	}
+	limitsContent, err := yaml.Marshal(b.limits)
+	if err != nil {
+		return fmt.Errorf("failed to marshal limits: %w", err)
+	}
+	return b.putObject(limitsFile(b.file), limitsContent)
+}
 
-	_, err = b.awsClient.PutObject(&s3.PutObjectInput{
+func (b *s3BackOffCache) putObject(file string, content []byte) error {
+	_, err := b.awsClient.PutObject(&s3.PutObjectInput{
 		Bucket: aws.String(retesterBucket),
-		Key:    aws.String(b.file),
+		Key:    aws.String(file),
 		Body:   bytes.NewReader(content),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to upload file %s into %s bucket: %w", b.file, retesterBucket, err)
+		return fmt.Errorf("failed to upload file %s into %s bucket: %w", file, retesterBucket, err)
 	}
 
 	return nil
@@ -90,3 +119,7 @@ func (b *s3BackOffCache) save() error {
 func (b *s3BackOffCache) check(pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string) {
 	return check(&b.cache, pr, baseSha, policy)
 }
+
+func (b *s3BackOffCache) limitRecords() *retestLimits {
+	return &b.limits
+}
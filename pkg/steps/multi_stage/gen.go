@@ -228,6 +228,9 @@ func (s *multiStageTestStep) generatePods(
 			addCliInjector(imagestream, pod)
 		}
 		addSharedDirSecret(s.name, pod)
+		if s.sharedDirStorage != nil && !genPodOpts.IsObserver {
+			addSharedDirStore(sharedDirStoreName(s.name), pod)
+		}
 		addCredentials(step.Credentials, pod)
 		if step.RunAsScript != nil && *step.RunAsScript {
 			addCommandScript(commandConfigMapForTest(s.name), pod)
@@ -528,6 +531,24 @@ func addSharedDirSecret(secret string, pod *coreapi.Pod) {
 	})
 }
 
+// addSharedDirStore mounts the volume that backs SHARED_DIR and lets the
+// entrypoint-wrapper move the files that do not fit in the secret to it
+func addSharedDirStore(claim string, pod *coreapi.Pod) {
+	volume := "shared-dir-store"
+	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
+		Name: volume,
+		VolumeSource: coreapi.VolumeSource{
+			PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+		},
+	})
+	container := &pod.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, coreapi.VolumeMount{
+		Name:      volume,
+		MountPath: SharedDirStoreMountPath,
+	})
+	container.Args = append([]string{"--shared-dir-store=" + SharedDirStoreMountPath}, container.Args...)
+}
+
 func addCredentials(credentials []api.CredentialReference, pod *coreapi.Pod) {
 	for _, credential := range credentials {
 		name := fmt.Sprintf("%s-%s", credential.Namespace, credential.Name)
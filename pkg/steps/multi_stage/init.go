@@ -12,6 +12,7 @@ import (
 	coreapi "k8s.io/api/core/v1"
 	rbacapi "k8s.io/api/rbac/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -37,7 +38,45 @@ func (s *multiStageTestStep) createSharedDirSecret(ctx context.Context) error {
 	if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
 		return fmt.Errorf("cannot delete shared directory %q: %w", s.name, err)
 	}
-	return s.client.Create(ctx, secret)
+	if err := s.client.Create(ctx, secret); err != nil {
+		return err
+	}
+	if s.sharedDirStorage != nil {
+		return s.createSharedDirStore(ctx)
+	}
+	return nil
+}
+
+// createSharedDirStore creates the volume that backs the shared directory. A
+// volume left over from a previous run is reused: files in it are only ever
+// read through the index in the shared directory, which starts out empty.
+func (s *multiStageTestStep) createSharedDirStore(ctx context.Context) error {
+	size, err := resource.ParseQuantity(s.sharedDirStorage.Size)
+	if err != nil {
+		return fmt.Errorf("invalid size of the shared directory storage: %w", err)
+	}
+	name := sharedDirStoreName(s.name)
+	logrus.Debugf("Creating multi-stage test shared directory storage %q", name)
+	claim := &coreapi.PersistentVolumeClaim{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: s.jobSpec.Namespace(),
+			Name:      name,
+			Labels:    map[string]string{MultiStageTestLabel: s.name},
+		},
+		Spec: coreapi.PersistentVolumeClaimSpec{
+			AccessModes: []coreapi.PersistentVolumeAccessMode{coreapi.ReadWriteOnce},
+			Resources: coreapi.ResourceRequirements{
+				Requests: coreapi.ResourceList{coreapi.ResourceStorage: size},
+			},
+		},
+	}
+	if class := s.sharedDirStorage.StorageClassName; class != "" {
+		claim.Spec.StorageClassName = &class
+	}
+	if err := s.client.Create(ctx, claim); err != nil && !kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("cannot create shared directory storage %q: %w", name, err)
+	}
+	return nil
 }
 
 func (s *multiStageTestStep) createCredentials(ctx context.Context) error {
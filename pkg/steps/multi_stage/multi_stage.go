@@ -58,12 +58,17 @@ const (
 const (
 	// MultiStageTestLabel is the label we use to mark a pod as part of a multi-stage test
 	MultiStageTestLabel = "ci.openshift.io/multi-stage-test"
+	// SharedDirSnapshotLabel is the label we use to mark the snapshots of the
+	// shared dir of a multi-stage test, so they can be deleted once it finishes
+	SharedDirSnapshotLabel = "ci.openshift.io/shared-dir-snapshot"
 	// ClusterProfileMountPath is where we mount the cluster profile in a pod
 	ClusterProfileMountPath = "/var/run/secrets/ci.openshift.io/cluster-profile"
 	// SecretMountPath is where we mount the shared dir secret
 	SecretMountPath = "/var/run/secrets/ci.openshift.io/multi-stage"
 	// SecretMountEnv is the env we use to expose the shared dir
 	SecretMountEnv = "SHARED_DIR"
+	// SharedDirStoreMountPath is where we mount the volume that backs the shared dir
+	SharedDirStoreMountPath = "/var/run/ci.openshift.io/shared-dir-store"
 	// ClusterProfileMountEnv is the env we use to expose the cluster profile dir
 	ClusterProfileMountEnv = "CLUSTER_PROFILE_DIR"
 	// CliMountPath is where we mount the cli in a pod
@@ -102,6 +107,12 @@ type multiStageTestStep struct {
 	leases          []api.StepLease
 	clusterClaim    *api.ClusterClaim
 	vpnConf         *vpnConf
+	// sharedDirStorage configures the volume that backs the shared dir
+	sharedDirStorage *api.SharedDirStorage
+	// sharedDir holds the files of the shared dir after the last step that ran
+	sharedDir map[string]sharedDirFile
+	// sharedDirVersions records how each step changed the shared dir
+	sharedDirVersions []sharedDirVersion
 }
 
 func MultiStageTestStep(
@@ -152,6 +163,7 @@ func newMultiStageTestStep(
 		flags:            flags,
 		leases:           leases,
 		clusterClaim:     testConfig.ClusterClaim,
+		sharedDirStorage: ms.SharedDirStorage,
 		subLock:          &sync.Mutex{},
 	}
 }
@@ -229,6 +241,7 @@ func (s *multiStageTestStep) run(ctx context.Context) error {
 		errs = append(errs, fmt.Errorf("%q post steps failed: %w", s.name, err))
 	}
 	<-observerDone // wait for the observers to finish so we get their jUnit
+	s.deleteSharedDirSnapshots()
 	return utilerrors.NewAggregate(errs)
 }
 
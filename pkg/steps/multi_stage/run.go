@@ -2,6 +2,7 @@ package multi_stage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -101,6 +102,9 @@ func (s *multiStageTestStep) runPods(ctx context.Context, pods []coreapi.Pod, be
 	var errs []error
 	for _, pod := range pods {
 		err := s.runPod(ctx, &pod, base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0))
+		if recordErr := s.recordSharedDir(ctx, pod.Name); recordErr != nil {
+			logrus.WithError(recordErr).Warnf("Failed to record the shared directory after step %s.", pod.Name)
+		}
 		if err == nil {
 			continue
 		}
@@ -177,6 +181,7 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 		Duration:    &duration,
 		Failed:      utilpointer.BoolPtr(err != nil),
 		Manifests:   client.Objects(),
+		Profile:     stepProfile(pod),
 	})
 	s.subTests = append(s.subTests, notifier.SubTests(fmt.Sprintf("%s - %s ", s.Description(), pod.Name))...)
 	s.subLock.Unlock()
@@ -198,3 +203,24 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 	}
 	return nil
 }
+
+// stepProfile reads the execution profile the entrypoint-wrapper leaves in the
+// termination message of the test container when the step succeeds
+func stepProfile(pod *coreapi.Pod) *api.StepProfile {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName || status.State.Terminated == nil {
+			continue
+		}
+		message := status.State.Terminated.Message
+		if !strings.HasPrefix(message, "{") {
+			return nil
+		}
+		var profile api.StepProfile
+		if err := json.Unmarshal([]byte(message), &profile); err != nil {
+			logrus.WithError(err).Debugf("Failed to parse the profile of step %s.", pod.Name)
+			return nil
+		}
+		return &profile
+	}
+	return nil
+}
@@ -250,3 +250,43 @@ func fakePodNameIndexer(object client.Object) []string {
 	}
 	return []string{p.Name}
 }
+
+func TestStepProfile(t *testing.T) {
+	started := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	terminated := func(container, message string) v1.ContainerStatus {
+		return v1.ContainerStatus{Name: container, State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Message: message}}}
+	}
+	for _, tc := range []struct {
+		name     string
+		statuses []v1.ContainerStatus
+		expected *api.StepProfile
+	}{{
+		name:     "no termination message",
+		statuses: []v1.ContainerStatus{terminated("test", "")},
+	}, {
+		name:     "end of the log of a failed step",
+		statuses: []v1.ContainerStatus{terminated("test", "make: *** [test] Error 1")},
+	}, {
+		name:     "malformed profile",
+		statuses: []v1.ContainerStatus{terminated("test", `{"wall_time": "1m"}`)},
+	}, {
+		name: "profile of the test container",
+		statuses: []v1.ContainerStatus{
+			terminated("sidecar", `{"wall_time": 1}`),
+			terminated("test", `{"started_at": "2022-10-01T12:00:00Z", "wall_time": 60000000000, "max_process_rss_bytes": 1024, "processes": [{"pid": 1, "command": "make test", "started_at": "2022-10-01T12:00:00Z", "duration": 60000000000}]}`),
+		},
+		expected: &api.StepProfile{
+			StartedAt:    started,
+			WallTime:     time.Minute,
+			MaxProcessRSSBytes: 1024,
+			Processes:    []api.ProcessProfile{{PID: 1, Command: "make test", StartedAt: started, Duration: time.Minute}},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := v1.Pod{Status: v1.PodStatus{ContainerStatuses: tc.statuses}}
+			if diff := cmp.Diff(tc.expected, stepProfile(&pod)); diff != "" {
+				t.Errorf("unexpected profile: %s", diff)
+			}
+		})
+	}
+}
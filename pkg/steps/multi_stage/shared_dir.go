@@ -0,0 +1,184 @@
+package multi_stage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+// sharedDirManifest is the artifact of the test that records how each step
+// changed the shared directory
+const sharedDirManifest = "shared-dir.json"
+
+// sharedDirStoreName names the volume that backs the shared directory of a test
+func sharedDirStoreName(test string) string {
+	return test + "-shared-dir-store"
+}
+
+// sharedDirSnapshotName names the snapshot of the shared directory taken after a step
+func sharedDirSnapshotName(pod string) string {
+	return pod + "-shared-dir"
+}
+
+// sharedDirFile describes a file in the shared directory. The contents are
+// never recorded, as they often are credentials.
+type sharedDirFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Stored is set for files moved to the volume that backs the shared directory
+	Stored bool `json:"stored,omitempty"`
+}
+
+// sharedDirVersion records the shared directory after a step
+type sharedDirVersion struct {
+	Step string `json:"step"`
+	// Snapshot is the secret that holds the shared directory after the step
+	Snapshot string `json:"snapshot,omitempty"`
+	// Size is the size of the secret that holds the shared directory
+	Size     int64           `json:"size"`
+	Added    []sharedDirFile `json:"added,omitempty"`
+	Modified []sharedDirFile `json:"modified,omitempty"`
+	Removed  []string        `json:"removed,omitempty"`
+}
+
+// sharedDirFiles lists the files in the secret that holds the shared directory,
+// including the ones moved to the backing volume
+func sharedDirFiles(data map[string][]byte) (map[string]sharedDirFile, error) {
+	files := map[string]sharedDirFile{}
+	for name, content := range data {
+		if name == api.SharedDirStoreIndexKey {
+			var index api.SharedDirStoreIndex
+			if err := json.Unmarshal(content, &index); err != nil {
+				return nil, fmt.Errorf("failed to parse the index of the shared directory storage: %w", err)
+			}
+			for stored, entry := range index {
+				files[stored] = sharedDirFile{Name: stored, Size: entry.Size, SHA256: entry.SHA256, Stored: true}
+			}
+			continue
+		}
+		digest := sha256.Sum256(content)
+		files[name] = sharedDirFile{Name: name, Size: int64(len(content)), SHA256: hex.EncodeToString(digest[:])}
+	}
+	return files, nil
+}
+
+// diffSharedDir records the changes a step made to the shared directory
+func diffSharedDir(step string, before, after map[string]sharedDirFile) sharedDirVersion {
+	version := sharedDirVersion{Step: step}
+	for name, file := range after {
+		previous, existed := before[name]
+		switch {
+		case !existed:
+			version.Added = append(version.Added, file)
+		case previous.SHA256 != file.SHA256:
+			version.Modified = append(version.Modified, file)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			version.Removed = append(version.Removed, name)
+		}
+	}
+	byName := func(files []sharedDirFile) func(i, j int) bool {
+		return func(i, j int) bool { return files[i].Name < files[j].Name }
+	}
+	sort.Slice(version.Added, byName(version.Added))
+	sort.Slice(version.Modified, byName(version.Modified))
+	sort.Strings(version.Removed)
+	return version
+}
+
+// recordSharedDir snapshots the shared directory after a step changed it and
+// records the changes in the artifacts of the test. The snapshots hold the same
+// credentials as the shared directory: the steps cannot read them, as their role
+// only grants access to the shared directory itself, and they are deleted when
+// the test finishes, leaving only the record of the changes behind.
+func (s *multiStageTestStep) recordSharedDir(ctx context.Context, step string) error {
+	secret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: s.name}, secret); err != nil {
+		return fmt.Errorf("failed to get the shared directory: %w", err)
+	}
+	files, err := sharedDirFiles(secret.Data)
+	if err != nil {
+		return err
+	}
+	version := diffSharedDir(step, s.sharedDir, files)
+	for _, content := range secret.Data {
+		version.Size += int64(len(content))
+	}
+	if len(version.Added)+len(version.Modified)+len(version.Removed) == 0 {
+		if n := len(s.sharedDirVersions); n > 0 {
+			version.Snapshot = s.sharedDirVersions[n-1].Snapshot
+		}
+	} else {
+		version.Snapshot = sharedDirSnapshotName(step)
+		snapshot := &coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{
+				Namespace: s.jobSpec.Namespace(),
+				Name:      version.Snapshot,
+				Labels:    map[string]string{MultiStageTestLabel: s.name, SharedDirSnapshotLabel: s.name},
+			},
+			Type: coreapi.SecretTypeOpaque,
+			Data: secret.Data,
+		}
+		if _, err := util.UpsertImmutableSecret(ctx, s.client, snapshot); err != nil {
+			return fmt.Errorf("failed to snapshot the shared directory: %w", err)
+		}
+	}
+	s.sharedDir = files
+	s.sharedDirVersions = append(s.sharedDirVersions, version)
+	return s.writeSharedDirManifest()
+}
+
+// writeSharedDirManifest writes the versions of the shared directory recorded
+// so far to the artifacts of the test
+func (s *multiStageTestStep) writeSharedDirManifest() error {
+	artifactDir, set := api.Artifacts()
+	if !set {
+		return nil
+	}
+	raw, err := json.MarshalIndent(s.sharedDirVersions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the versions of the shared directory: %w", err)
+	}
+	dir := filepath.Join(artifactDir, s.name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, sharedDirManifest), raw, 0644); err != nil {
+		return fmt.Errorf("failed to write the versions of the shared directory: %w", err)
+	}
+	return nil
+}
+
+// deleteSharedDirSnapshots deletes the snapshots of the shared directory taken
+// while the test ran
+func (s *multiStageTestStep) deleteSharedDirSnapshots() {
+	secrets := &coreapi.SecretList{}
+	if err := s.client.List(base_steps.CleanupCtx, secrets, ctrlruntimeclient.InNamespace(s.jobSpec.Namespace()), ctrlruntimeclient.MatchingLabels{SharedDirSnapshotLabel: s.name}); err != nil {
+		logrus.WithError(err).Warnf("Failed to list the snapshots of the shared directory of %s.", s.name)
+		return
+	}
+	for i := range secrets.Items {
+		if err := s.client.Delete(base_steps.CleanupCtx, &secrets.Items[i]); err != nil && !kerrors.IsNotFound(err) {
+			logrus.WithError(err).Warnf("Failed to delete the snapshot %s of the shared directory.", secrets.Items[i].Name)
+		}
+	}
+}
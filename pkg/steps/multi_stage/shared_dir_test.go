@@ -0,0 +1,134 @@
+package multi_stage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+const (
+	// sha256 digests of "a", "b" and "c"
+	digestA = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	digestB = "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+	digestC = "2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6"
+)
+
+func TestDiffSharedDir(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		before, after map[string][]byte
+		expected      sharedDirVersion
+	}{
+		{
+			name:     "first step adds files",
+			after:    map[string][]byte{"kubeconfig": []byte("a"), "metadata.json": []byte("b")},
+			expected: sharedDirVersion{Step: "step", Added: []sharedDirFile{{Name: "kubeconfig", Size: 1, SHA256: digestA}, {Name: "metadata.json", Size: 1, SHA256: digestB}}},
+		},
+		{
+			name:     "step modifies and removes files",
+			before:   map[string][]byte{"kubeconfig": []byte("a"), "metadata.json": []byte("b")},
+			after:    map[string][]byte{"kubeconfig": []byte("c")},
+			expected: sharedDirVersion{Step: "step", Modified: []sharedDirFile{{Name: "kubeconfig", Size: 1, SHA256: digestC}}, Removed: []string{"metadata.json"}},
+		},
+		{
+			name:     "unchanged files are not recorded",
+			before:   map[string][]byte{"kubeconfig": []byte("a")},
+			after:    map[string][]byte{"kubeconfig": []byte("a")},
+			expected: sharedDirVersion{Step: "step"},
+		},
+		{
+			name:   "files moved to the backing volume are listed from the index",
+			before: map[string][]byte{"must-gather.tar": []byte("a")},
+			after: map[string][]byte{
+				"kubeconfig":               []byte("a"),
+				api.SharedDirStoreIndexKey: []byte(`{"must-gather.tar":{"size":2097152,"sha256":"` + digestB + `"}}`),
+			},
+			expected: sharedDirVersion{
+				Step:     "step",
+				Added:    []sharedDirFile{{Name: "kubeconfig", Size: 1, SHA256: digestA}},
+				Modified: []sharedDirFile{{Name: "must-gather.tar", Size: 2097152, SHA256: digestB, Stored: true}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before, err := sharedDirFiles(tc.before)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			after, err := sharedDirFiles(tc.after)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, diffSharedDir("step", before, after)); diff != "" {
+				t.Errorf("unexpected version: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRecordSharedDir(t *testing.T) {
+	secret := &coreapi.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test"},
+		Data:       map[string][]byte{"kubeconfig": []byte("a")},
+	}
+	client := &testhelper_kube.FakePodClient{
+		FakePodExecutor: &testhelper_kube.FakePodExecutor{
+			LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(secret).Build()),
+		},
+	}
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	s := &multiStageTestStep{name: "test", client: client, jobSpec: &jobSpec}
+	ctx := context.Background()
+	if err := s.recordSharedDir(ctx, "test-install"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.recordSharedDir(ctx, "test-e2e"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret.Data["metadata.json"] = []byte("b")
+	if err := client.Update(ctx, secret); err != nil {
+		t.Fatalf("failed to update the shared directory: %v", err)
+	}
+	if err := s.recordSharedDir(ctx, "test-gather"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []sharedDirVersion{
+		{Step: "test-install", Snapshot: "test-install-shared-dir", Size: 1, Added: []sharedDirFile{{Name: "kubeconfig", Size: 1, SHA256: digestA}}},
+		{Step: "test-e2e", Snapshot: "test-install-shared-dir", Size: 1},
+		{Step: "test-gather", Snapshot: "test-gather-shared-dir", Size: 2, Added: []sharedDirFile{{Name: "metadata.json", Size: 1, SHA256: digestB}}},
+	}
+	if diff := cmp.Diff(expected, s.sharedDirVersions); diff != "" {
+		t.Errorf("unexpected versions: %s", diff)
+	}
+	snapshot := &coreapi.Secret{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "test-gather-shared-dir"}, snapshot); err != nil {
+		t.Fatalf("failed to get snapshot: %v", err)
+	}
+	if diff := cmp.Diff(secret.Data, snapshot.Data); diff != "" {
+		t.Errorf("unexpected snapshot: %s", diff)
+	}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "test-e2e-shared-dir"}, snapshot); err == nil {
+		t.Errorf("expected no snapshot of a step that did not change the shared directory")
+	}
+
+	s.deleteSharedDirSnapshots()
+	secrets := &coreapi.SecretList{}
+	if err := client.List(ctx, secrets, ctrlruntimeclient.InNamespace("ns")); err != nil {
+		t.Fatalf("failed to list secrets: %v", err)
+	}
+	if len(secrets.Items) != 1 || secrets.Items[0].Name != "test" {
+		t.Errorf("expected only the shared directory to be left, got %v", secrets.Items)
+	}
+}
@@ -81,8 +81,8 @@ func imagesFor(config api.ProjectDirectoryImageBuildStepConfiguration, workingDi
 		// use the index source for index images
 		sourceTag = api.IndexGeneratorName(config.To)
 	} else {
-		// default to using the normal pipeline source image
-		sourceTag = api.PipelineImageStreamTagReferenceSource
+		// default to using the normal pipeline source image of the repository
+		sourceTag = api.PipelineImageStreamTagReferenceForRef(api.PipelineImageStreamTagReferenceSource, config.Ref)
 		contextDir = config.ContextDir
 	}
 	if _, overwritten := config.Inputs[string(sourceTag)]; !overwritten {
@@ -123,7 +123,7 @@ func getWorkingDir(client ctrlruntimeclient.Client, source, namespace string) (s
 
 func (s *projectDirectoryImageBuildStep) Requires() []api.StepLink {
 	links := []api.StepLink{
-		api.InternalImageLink(api.PipelineImageStreamTagReferenceSource),
+		api.InternalImageLink(api.PipelineImageStreamTagReferenceForRef(api.PipelineImageStreamTagReferenceSource, s.config.Ref)),
 	}
 	if len(s.config.From) > 0 {
 		links = append(links, api.InternalImageLink(s.config.From))
@@ -71,6 +71,37 @@ func TestImagesFor(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "build of an additional repository",
+			config: api.ProjectDirectoryImageBuildStepConfiguration{
+				To: "output",
+				ProjectDirectoryImageBuildInputs: api.ProjectDirectoryImageBuildInputs{
+					ContextDir: "context",
+				},
+				Ref: "org.repo",
+			},
+			workingDir: func(tag string) (string, error) {
+				if tag != "pipeline:src-org.repo" {
+					return "", errors.New("unexpected source " + tag)
+				}
+				return "dir", nil
+			},
+			isBundleImage: func(tag string) bool {
+				return false
+			},
+			sourceTag: "src-org.repo",
+			images: []buildapi.ImageSource{
+				{
+					From: corev1.ObjectReference{
+						Kind: "ImageStreamTag",
+						Name: "pipeline:src-org.repo",
+					},
+					Paths: []buildapi.ImageSourcePath{
+						{SourcePath: "dir/context/.", DestinationDir: "."},
+					},
+				},
+			},
+		},
 		{
 			name: "user overwrites input",
 			config: api.ProjectDirectoryImageBuildStepConfiguration{
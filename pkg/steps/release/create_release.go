@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/provenance"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/steps"
 	"github.com/openshift/ci-tools/pkg/steps/utils"
@@ -53,6 +55,10 @@ type assembleReleaseStep struct {
 	resources api.ResourceConfiguration
 	client    kubernetes.PodClient
 	jobSpec   *api.JobSpec
+	// verifier verifies the provenance of the images imported into the payload, nil if it is not verified
+	verifier *provenance.Verifier
+	// provenanceClient reads the provenance recorded on the cluster hosting the central registry
+	provenanceClient func() (ctrlruntimeclient.Client, error)
 }
 
 func (s *assembleReleaseStep) Inputs() (api.InputDefinition, error) {
@@ -182,6 +188,12 @@ func (s *assembleReleaseStep) run(ctx context.Context) error {
 		return results.ForReason("missing_release").WithError(err).Errorf("could not resolve imagestream %s: %v", streamName, err)
 	}
 
+	if s.verifier != nil {
+		if err := s.verifyProvenance(ctx, stable); err != nil {
+			return results.ForReason("verifying_provenance").ForError(err)
+		}
+	}
+
 	// we want to expose the release payload as a CI version that looks just like
 	// the release versions for nightlies and CI release candidates
 	prefix := "0.0.1-0"
@@ -240,6 +252,35 @@ oc adm release extract --from=%q --to=${ARTIFACT_DIR}/release-payload-%s
 	return nil
 }
 
+// verifyProvenance verifies the provenance of the images imported into the stream from the release ImageStream.
+// The images built by the job replace some of them and are not promoted yet, so they have none to verify.
+func (s *assembleReleaseStep) verifyProvenance(ctx context.Context, stable *imageapi.ImageStream) error {
+	client, err := s.provenanceClient()
+	if err != nil {
+		return fmt.Errorf("failed to construct client to read the provenance of the images: %w", err)
+	}
+	images := map[string]string{}
+	for _, tag := range stable.Status.Tags {
+		if len(tag.Items) > 0 {
+			images[tag.Tag] = tag.Items[0].Image
+		}
+	}
+	var failed []string
+	for _, tag := range stable.Spec.Tags {
+		if tag.From == nil || tag.From.Kind != "DockerImage" {
+			continue
+		}
+		if err := provenance.Verify(ctx, client, s.verifier, s.config.Namespace, s.config.Name, tag.Name, images[tag.Name]); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", tag.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not verify the provenance of the images imported from %s/%s: %s", s.config.Namespace, s.config.Name, strings.Join(failed, ", "))
+	}
+	logrus.Infof("Verified the provenance of the images imported from %s/%s.", s.config.Namespace, s.config.Name)
+	return nil
+}
+
 func (s *assembleReleaseStep) Requires() []api.StepLink {
 	if s.config.IncludeBuiltImages {
 		return []api.StepLink{api.ImagesReadyLink()}
@@ -268,9 +309,11 @@ func (s *assembleReleaseStep) Objects() []ctrlruntimeclient.Object {
 }
 
 // AssembleReleaseStep builds a new update payload image based on the cluster version operator
-// and the operators defined in the release configuration.
+// and the operators defined in the release configuration. When a verifier is given, the images
+// imported from the release ImageStream must have provenance signed with its key, read from
+// app.ci with the credentials for the central registry in the pull secret.
 func AssembleReleaseStep(name, nodeName string, config *api.ReleaseTagConfiguration, resources api.ResourceConfiguration,
-	client kubernetes.PodClient, jobSpec *api.JobSpec) api.Step {
+	client kubernetes.PodClient, jobSpec *api.JobSpec, pullSecret *coreapi.Secret, verifier *provenance.Verifier) api.Step {
 	return &assembleReleaseStep{
 		config:    config,
 		name:      name,
@@ -278,5 +321,12 @@ func AssembleReleaseStep(name, nodeName string, config *api.ReleaseTagConfigurat
 		resources: resources,
 		client:    client,
 		jobSpec:   jobSpec,
+		verifier:  verifier,
+		provenanceClient: func() (ctrlruntimeclient.Client, error) {
+			if pullSecret == nil {
+				return nil, fmt.Errorf("a pull secret is required to read the provenance")
+			}
+			return appCIClientFor(pullSecret, "pull")
+		},
 	}
 }
@@ -0,0 +1,108 @@
+package release
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imageapi "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/provenance"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestVerifyProvenance(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	private, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	signer, err := provenance.NewSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private}))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	verifier, err := provenance.NewVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := coreapi.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register core scheme: %v", err)
+	}
+	client := fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).Build()
+	for tag, image := range map[string]string{"cli": "sha256:cli", "installer": "sha256:old"} {
+		envelope, err := signer.Sign(provenance.Statement{Type: provenance.StatementType, PredicateType: provenance.PredicateType, Subject: []provenance.Subject{{Digest: provenance.Digest(image)}}})
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		if err := provenance.Store(context.TODO(), client, "ocp", "4.12", tag, "registry.ci.openshift.org", envelope); err != nil {
+			t.Fatalf("failed to store: %v", err)
+		}
+	}
+
+	imported := func(tag string) imageapi.TagReference {
+		return imageapi.TagReference{Name: tag, From: &coreapi.ObjectReference{Kind: "DockerImage", Name: "registry.ci.openshift.org/ocp/4.12@sha256:" + tag}}
+	}
+	stable := func(tags ...imageapi.TagReference) *imageapi.ImageStream {
+		is := &imageapi.ImageStream{ObjectMeta: meta.ObjectMeta{Namespace: "ci-op", Name: "stable"}}
+		for _, tag := range tags {
+			is.Spec.Tags = append(is.Spec.Tags, tag)
+			is.Status.Tags = append(is.Status.Tags, imageapi.NamedTagEventList{Tag: tag.Name, Items: []imageapi.TagEvent{{Image: "sha256:" + tag.Name}}})
+		}
+		return is
+	}
+
+	testCases := []struct {
+		name          string
+		stable        *imageapi.ImageStream
+		expectedError error
+	}{
+		{
+			name:   "imported images with provenance",
+			stable: stable(imported("cli")),
+		},
+		{
+			name:   "images built by the job are not verified",
+			stable: stable(imported("cli"), imageapi.TagReference{Name: "tests", From: &coreapi.ObjectReference{Kind: "ImageStreamImage", Name: "pipeline@sha256:tests"}}),
+		},
+		{
+			name:          "imported images without provenance or with provenance about another image",
+			stable:        stable(imported("cli"), imported("installer"), imported("tests")),
+			expectedError: errors.New("could not verify the provenance of the images imported from ocp/4.12: installer: provenance is about a different image than sha256:installer, tests: no provenance recorded"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &assembleReleaseStep{
+				config:           &api.ReleaseTagConfiguration{Namespace: "ocp", Name: "4.12"},
+				verifier:         verifier,
+				provenanceClient: func() (ctrlruntimeclient.Client, error) { return client, nil },
+			}
+			if diff := cmp.Diff(tc.expectedError, s.verifyProvenance(context.TODO(), tc.stable), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("error differs from expected:\n%s", diff)
+			}
+		})
+	}
+}
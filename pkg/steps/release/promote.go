@@ -18,6 +18,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -26,6 +27,8 @@ import (
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
 	"github.com/openshift/ci-tools/pkg/kubernetes/pkg/credentialprovider"
+	"github.com/openshift/ci-tools/pkg/promotion/history"
+	"github.com/openshift/ci-tools/pkg/provenance"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/steps"
 )
@@ -41,6 +44,10 @@ type promotionStep struct {
 	pushSecret     *coreapi.Secret
 	registry       string
 	mirrorFunc     func(source, target string, tag api.ImageStreamTagReference, date string, imageMirror map[string]string)
+	// targetClient returns a client for the cluster with the ImageStreams that images are promoted into
+	targetClient func() (ctrlruntimeclient.Client, error)
+	// signer signs the provenance of the promoted images, nil if it is not recorded
+	signer *provenance.Signer
 }
 
 func targetName(config api.PromotionConfiguration) string {
@@ -95,6 +102,22 @@ func (s *promotionStep) run(ctx context.Context) error {
 		return fmt.Errorf("could not resolve pipeline imagestream: %w", err)
 	}
 
+	// Pinned tags and the promotion history live on the ImageStreams in the central registry,
+	// which we cannot expect to access when the registry is overridden
+	var targetClient ctrlruntimeclient.Client
+	var targets map[string]*imagev1.ImageStream
+	if s.configuration.PromotionConfiguration.RegistryOverride == "" {
+		if client, err := s.targetClient(); err != nil {
+			logger.WithError(err).Warn("Failed to construct client for the promotion targets, not considering pinned tags.")
+		} else {
+			targetClient = client
+			if targets, err = getTargetImageStreams(ctx, targetClient, tags); err != nil {
+				return err
+			}
+			tags = withoutPinnedTags(tags, targets, logger)
+		}
+	}
+
 	date := time.Now().Format("20060102")
 	imageMirrorTarget, namespaces := getImageMirrorTarget(tags, pipeline, s.registry, date, s.mirrorFunc)
 	if len(imageMirrorTarget) == 0 {
@@ -111,9 +134,224 @@ func (s *promotionStep) run(ctx context.Context) error {
 	if _, err := steps.RunPod(ctx, s.client, getPromotionPod(imageMirrorTarget, s.jobSpec.Namespace(), s.name)); err != nil {
 		return fmt.Errorf("unable to run promotion pod: %w", err)
 	}
+
+	// The history is recorded once, by the promotion into the central registry
+	if targetClient != nil && s.registry != api.QuayOpenShiftCIRepo {
+		by := fmt.Sprintf("%s/%s", s.jobSpec.Job, s.jobSpec.BuildID)
+		if err := recordPromotionHistory(ctx, targetClient, replacedImages(tags, pipeline, targets, by, time.Now()), logger); err != nil {
+			return err
+		}
+	}
+	// The provenance is recorded by every promotion, about the image in the registry it promotes into
+	if targetClient != nil && s.signer != nil {
+		s.recordProvenance(ctx, targetClient, provenanceFor(tags, pipeline, s.configuration.Images, s.jobSpec, s.registry, time.Now()), logger)
+	}
 	return nil
 }
 
+// recordProvenance signs the statements about the promoted images and stores them next to the target ImageStreams.
+// Images without provenance fail the verification before they are used, so this does not fail the promotion.
+func (s *promotionStep) recordProvenance(ctx context.Context, client ctrlruntimeclient.Client, statements map[string]map[string]provenance.Statement, logger *logrus.Entry) {
+	var keys []string
+	for key := range statements {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		namespace, name, _ := strings.Cut(key, "/")
+		var tags []string
+		for tag := range statements[key] {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			envelope, err := s.signer.Sign(statements[key][tag])
+			if err != nil {
+				logger.WithError(err).Warnf("Failed to sign the provenance of %s:%s.", key, tag)
+				continue
+			}
+			if err := provenance.Store(ctx, client, namespace, name, tag, s.registry, envelope); err != nil {
+				logger.WithError(err).Warnf("Failed to record the provenance of %s:%s.", key, tag)
+			}
+		}
+	}
+}
+
+// provenanceFor creates the statements about the promoted images, by the namespace/name of the target ImageStream and tag
+func provenanceFor(tags map[string][]api.ImageStreamTagReference, pipeline *imagev1.ImageStream, images []api.ProjectDirectoryImageBuildStepConfiguration, jobSpec *api.JobSpec, registry string, now time.Time) map[string]map[string]provenance.Statement {
+	statements := map[string]map[string]provenance.Statement{}
+	for src, dsts := range tags {
+		digest := provenance.Digest(findImage(pipeline, src))
+		if digest == nil {
+			continue
+		}
+		var build *api.ProjectDirectoryImageBuildStepConfiguration
+		for i := range images {
+			if string(images[i].To) == src {
+				build = &images[i]
+				break
+			}
+		}
+		baseImages := baseImageMaterials(build, pipeline)
+		for _, dst := range dsts {
+			key := fmt.Sprintf("%s/%s", dst.Namespace, dst.Name)
+			if statements[key] == nil {
+				statements[key] = map[string]provenance.Statement{}
+			}
+			subject := provenance.Subject{Name: fmt.Sprintf("%s/%s", registry, dst.ISTagName()), Digest: digest}
+			if registry == api.QuayOpenShiftCIRepo {
+				subject.Name = api.QuayImage(dst)
+			}
+			statements[key][dst.Tag] = provenance.NewStatement(subject, jobSpec, build, baseImages, now)
+		}
+	}
+	return statements
+}
+
+// baseImageMaterials resolves the images a build started from and copied content from to their digests
+func baseImageMaterials(build *api.ProjectDirectoryImageBuildStepConfiguration, pipeline *imagev1.ImageStream) []provenance.Material {
+	if build == nil {
+		return nil
+	}
+	inputs := sets.NewString()
+	if build.From != "" {
+		inputs.Insert(string(build.From))
+	}
+	for input := range build.Inputs {
+		inputs.Insert(input)
+	}
+	var materials []provenance.Material
+	for _, input := range inputs.List() {
+		reference := findDockerImageReference(pipeline, input)
+		digest := provenance.Digest(findImage(pipeline, input))
+		if reference == "" || digest == nil {
+			continue
+		}
+		materials = append(materials, provenance.Material{URI: reference, Digest: digest})
+	}
+	return materials
+}
+
+// getTargetImageStreams returns the existing ImageStreams that the tags are promoted into, by namespace/name
+func getTargetImageStreams(ctx context.Context, client ctrlruntimeclient.Client, tags map[string][]api.ImageStreamTagReference) (map[string]*imagev1.ImageStream, error) {
+	targets := map[string]*imagev1.ImageStream{}
+	for _, dsts := range tags {
+		for _, dst := range dsts {
+			key := fmt.Sprintf("%s/%s", dst.Namespace, dst.Name)
+			if _, seen := targets[key]; seen {
+				continue
+			}
+			is := &imagev1.ImageStream{}
+			if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: dst.Namespace, Name: dst.Name}, is); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, fmt.Errorf("could not get imagestream %s to promote into: %w", key, err)
+			}
+			targets[key] = is
+		}
+	}
+	return targets, nil
+}
+
+// withoutPinnedTags drops the destinations pinned on their ImageStreams
+func withoutPinnedTags(tags map[string][]api.ImageStreamTagReference, targets map[string]*imagev1.ImageStream, logger *logrus.Entry) map[string][]api.ImageStreamTagReference {
+	filtered := map[string][]api.ImageStreamTagReference{}
+	for src, dsts := range tags {
+		for _, dst := range dsts {
+			if is, ok := targets[fmt.Sprintf("%s/%s", dst.Namespace, dst.Name)]; ok && history.PinnedTags(is).Has(dst.Tag) {
+				logger.Infof("Not promoting to %s: the tag is pinned", dst.ISTagName())
+				continue
+			}
+			filtered[src] = append(filtered[src], dst)
+		}
+	}
+	return filtered
+}
+
+// replacedImages returns the records of the images replaced by the promotion, by the namespace/name of the target
+// ImageStream and tag. The targets must have been fetched before the images were mirrored.
+func replacedImages(tags map[string][]api.ImageStreamTagReference, pipeline *imagev1.ImageStream, targets map[string]*imagev1.ImageStream, by string, now time.Time) map[string]history.ByTag {
+	records := map[string]history.ByTag{}
+	for src, dsts := range tags {
+		image := findImage(pipeline, src)
+		if image == "" {
+			continue
+		}
+		for _, dst := range dsts {
+			key := fmt.Sprintf("%s/%s", dst.Namespace, dst.Name)
+			is, ok := targets[key]
+			if !ok {
+				continue
+			}
+			current := history.CurrentImage(is, dst.Tag)
+			if current == "" || current == image {
+				continue
+			}
+			if records[key] == nil {
+				records[key] = history.ByTag{}
+			}
+			records[key].Add(dst.Tag, history.Record{Image: current, Replaced: meta.NewTime(now), By: by})
+		}
+	}
+	return records
+}
+
+// recordPromotionHistory adds the records to the promotion history of the target ImageStreams. The mirroring
+// changed the ImageStreams since we read them, so they are fetched again on every attempt to write the history.
+func recordPromotionHistory(ctx context.Context, client ctrlruntimeclient.Client, records map[string]history.ByTag, logger *logrus.Entry) error {
+	var keys []string
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		namespace, name, _ := strings.Cut(key, "/")
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			is := &imagev1.ImageStream{}
+			if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: name}, is); err != nil {
+				return err
+			}
+			tagHistory, err := history.For(is)
+			if err != nil {
+				logger.WithError(err).Warnf("Discarding the invalid promotion history of imagestream %s.", key)
+				tagHistory = history.ByTag{}
+			}
+			for tag, tagRecords := range records[key] {
+				for i := len(tagRecords) - 1; i >= 0; i-- {
+					tagHistory.Add(tag, tagRecords[i])
+				}
+			}
+			if err := history.Set(is, tagHistory); err != nil {
+				return err
+			}
+			return client.Update(ctx, is)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record the promotion history of imagestream %s: %w", key, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// appCIClient returns a client for app.ci, authenticated with the push credentials
+func (s *promotionStep) appCIClient() (ctrlruntimeclient.Client, error) {
+	return appCIClientFor(s.pushSecret, "push")
+}
+
+// appCIClientFor returns a client for app.ci, authenticated with the credentials for its registry in the secret
+func appCIClientFor(secret *coreapi.Secret, kind string) (ctrlruntimeclient.Client, error) {
+	var dockercfg credentialprovider.DockerConfigJSON
+	if err := json.Unmarshal(secret.Data[coreapi.DockerConfigJsonKey], &dockercfg); err != nil {
+		return nil, fmt.Errorf("failed to deserialize %s secret: %w", kind, err)
+	}
+	appCIDockercfg, hasAppCIDockercfg := dockercfg.Auths[api.ServiceDomainAPPCIRegistry]
+	if !hasAppCIDockercfg {
+		return nil, fmt.Errorf("%s secret has no entry for %s", kind, api.ServiceDomainAPPCIRegistry)
+	}
+	return ctrlruntimeclient.New(&rest.Config{Host: api.APPCIKubeAPIURL, BearerToken: appCIDockercfg.Password}, ctrlruntimeclient.Options{})
+}
+
 func (s *promotionStep) ensureNamespaces(ctx context.Context, namespaces sets.Set[string]) error {
 	if len(namespaces) == 0 {
 		return nil
@@ -252,6 +490,16 @@ func getPromotionPod(imageMirrorTarget map[string]string, namespace string, name
 	}
 }
 
+// findImage returns the digest of the image a tag of the ImageStream points to
+func findImage(is *imagev1.ImageStream, tag string) string {
+	for _, t := range is.Status.Tags {
+		if t.Tag == tag && len(t.Items) > 0 {
+			return t.Items[0].Image
+		}
+	}
+	return ""
+}
+
 // findDockerImageReference returns DockerImageReference, the string that can be used to pull this image,
 // to a tag if it exists in the ImageStream's Spec
 func findDockerImageReference(is *imagev1.ImageStream, tag string) string {
@@ -415,8 +663,9 @@ func PromotionStep(
 	pushSecret *coreapi.Secret,
 	registry string,
 	mirrorFunc func(source, target string, tag api.ImageStreamTagReference, date string, imageMirror map[string]string),
+	signer *provenance.Signer,
 ) api.Step {
-	return &promotionStep{
+	s := &promotionStep{
 		name:           name,
 		configuration:  configuration,
 		requiredImages: requiredImages,
@@ -425,5 +674,8 @@ func PromotionStep(
 		pushSecret:     pushSecret,
 		registry:       registry,
 		mirrorFunc:     mirrorFunc,
+		signer:         signer,
 	}
+	s.targetClient = s.appCIClient
+	return s
 }
@@ -1,18 +1,29 @@
 package release
 
 import (
+	"context"
+	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
 
 	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"k8s.io/utils/diff"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	imageapi "github.com/openshift/api/image/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/promotion/history"
+	"github.com/openshift/ci-tools/pkg/provenance"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
@@ -806,3 +817,169 @@ func TestGetPublicImageReference(t *testing.T) {
 		})
 	}
 }
+
+func TestWithoutPinnedTags(t *testing.T) {
+	tags := map[string][]api.ImageStreamTagReference{
+		"cli":   {{Namespace: "ocp", Name: "4.12", Tag: "cli"}, {Namespace: "ocp", Name: "4.13", Tag: "cli"}},
+		"tests": {{Namespace: "ocp", Name: "4.12", Tag: "tests"}},
+	}
+	targets := map[string]*imageapi.ImageStream{
+		"ocp/4.12": {ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{history.PinnedTagsAnnotation: "cli,other"}}},
+		"ocp/4.13": {},
+	}
+	expected := map[string][]api.ImageStreamTagReference{
+		"cli":   {{Namespace: "ocp", Name: "4.13", Tag: "cli"}},
+		"tests": {{Namespace: "ocp", Name: "4.12", Tag: "tests"}},
+	}
+	if diff := cmp.Diff(expected, withoutPinnedTags(tags, targets, logrus.NewEntry(logrus.StandardLogger()))); diff != "" {
+		t.Errorf("tags differ from expected:\n%s", diff)
+	}
+}
+
+func TestReplacedImages(t *testing.T) {
+	now := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	tags := map[string][]api.ImageStreamTagReference{
+		"cli":     {{Namespace: "ocp", Name: "4.12", Tag: "cli"}},
+		"tests":   {{Namespace: "ocp", Name: "4.12", Tag: "tests"}},
+		"new":     {{Namespace: "ocp", Name: "4.12", Tag: "new"}},
+		"missing": {{Namespace: "ocp", Name: "4.12", Tag: "missing"}},
+		"other":   {{Namespace: "ocp", Name: "unknown", Tag: "other"}},
+	}
+	pipeline := &imageapi.ImageStream{Status: imageapi.ImageStreamStatus{Tags: []imageapi.NamedTagEventList{
+		{Tag: "cli", Items: []imageapi.TagEvent{{Image: "sha256:new-cli"}}},
+		{Tag: "tests", Items: []imageapi.TagEvent{{Image: "sha256:tests"}}},
+		{Tag: "new", Items: []imageapi.TagEvent{{Image: "sha256:new"}}},
+		{Tag: "other", Items: []imageapi.TagEvent{{Image: "sha256:other"}}},
+	}}}
+	target := &imageapi.ImageStream{
+		ObjectMeta: meta.ObjectMeta{Namespace: "ocp", Name: "4.12"},
+		Status: imageapi.ImageStreamStatus{Tags: []imageapi.NamedTagEventList{
+			{Tag: "cli", Items: []imageapi.TagEvent{{Image: "sha256:old-cli"}}},
+			{Tag: "tests", Items: []imageapi.TagEvent{{Image: "sha256:tests"}}},
+		}},
+	}
+
+	expected := map[string]history.ByTag{"ocp/4.12": {"cli": {{Image: "sha256:old-cli", Replaced: meta.NewTime(now), By: "job/1"}}}}
+	if diff := cmp.Diff(expected, replacedImages(tags, pipeline, map[string]*imageapi.ImageStream{"ocp/4.12": target}, "job/1", now)); diff != "" {
+		t.Errorf("records differ from expected:\n%s", diff)
+	}
+}
+
+// concurrentlyChangingClient changes the ImageStream on the server before the first update,
+// as the mirroring of the promoted images does
+type concurrentlyChangingClient struct {
+	ctrlruntimeclient.Client
+	changed bool
+}
+
+func (c *concurrentlyChangingClient) Update(ctx context.Context, obj ctrlruntimeclient.Object, opts ...ctrlruntimeclient.UpdateOption) error {
+	if !c.changed {
+		c.changed = true
+		current := &imageapi.ImageStream{}
+		if err := c.Client.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(obj), current); err != nil {
+			return err
+		}
+		current.Spec.Tags = append(current.Spec.Tags, imageapi.TagReference{Name: "cli"})
+		if err := c.Client.Update(ctx, current); err != nil {
+			return err
+		}
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestRecordPromotionHistory(t *testing.T) {
+	now := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	older := history.Record{Image: "sha256:older-cli", Replaced: meta.NewTime(now.Add(-time.Hour)), By: "job/0"}
+	existing, err := json.Marshal(history.ByTag{"cli": {older}})
+	if err != nil {
+		t.Fatalf("failed to marshal history: %v", err)
+	}
+	scheme := runtime.NewScheme()
+	if err := imageapi.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add imagev1 to scheme: %v", err)
+	}
+	target := &imageapi.ImageStream{ObjectMeta: meta.ObjectMeta{
+		Namespace:   "ocp",
+		Name:        "4.12",
+		Annotations: map[string]string{history.Annotation: string(existing)},
+	}}
+	client := &concurrentlyChangingClient{Client: fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(target).Build()}
+	record := history.Record{Image: "sha256:old-cli", Replaced: meta.NewTime(now), By: "job/1"}
+
+	if err := recordPromotionHistory(context.Background(), client, map[string]history.ByTag{"ocp/4.12": {"cli": {record}}}, logrus.NewEntry(logrus.StandardLogger())); err != nil {
+		t.Fatalf("failed to record the promotion history: %v", err)
+	}
+	if !client.changed {
+		t.Fatal("expected the imagestream to change before the history was written")
+	}
+	actual := &imageapi.ImageStream{}
+	if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "ocp", Name: "4.12"}, actual); err != nil {
+		t.Fatalf("failed to get imagestream: %v", err)
+	}
+	if diff := cmp.Diff([]imageapi.TagReference{{Name: "cli"}}, actual.Spec.Tags); diff != "" {
+		t.Errorf("the concurrent change was lost:\n%s", diff)
+	}
+	recorded, err := history.For(actual)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if diff := cmp.Diff(history.ByTag{"cli": {record, older}}, recorded); diff != "" {
+		t.Errorf("history differs from expected:\n%s", diff)
+	}
+}
+
+func TestProvenanceFor(t *testing.T) {
+	now := time.Date(2022, 2, 2, 0, 0, 0, 0, time.UTC)
+	jobSpec := &api.JobSpec{JobSpec: downwardapi.JobSpec{Job: "job", BuildID: "1", ProwJobID: "uuid"}}
+	tags := map[string][]api.ImageStreamTagReference{
+		"cli":     {{Namespace: "ocp", Name: "4.12", Tag: "cli"}, {Namespace: "ocp", Name: "cli", Tag: "sha"}},
+		"rpms":    {{Namespace: "ocp", Name: "4.12", Tag: "rpms"}},
+		"missing": {{Namespace: "ocp", Name: "4.12", Tag: "missing"}},
+	}
+	images := []api.ProjectDirectoryImageBuildStepConfiguration{{
+		From: "base",
+		To:   "cli",
+		ProjectDirectoryImageBuildInputs: api.ProjectDirectoryImageBuildInputs{
+			Inputs: map[string]api.ImageBuildInputs{"builder": {}, "not-imported": {}},
+		},
+	}}
+	pipeline := &imageapi.ImageStream{Status: imageapi.ImageStreamStatus{Tags: []imageapi.NamedTagEventList{
+		{Tag: "cli", Items: []imageapi.TagEvent{{Image: "sha256:cli", DockerImageReference: "registry/ci-op/pipeline@sha256:cli"}}},
+		{Tag: "rpms", Items: []imageapi.TagEvent{{Image: "sha256:rpms", DockerImageReference: "registry/ci-op/pipeline@sha256:rpms"}}},
+		{Tag: "base", Items: []imageapi.TagEvent{{Image: "sha256:base", DockerImageReference: "registry/ci-op/pipeline@sha256:base"}}},
+		{Tag: "builder", Items: []imageapi.TagEvent{{Image: "sha256:builder", DockerImageReference: "registry/ci-op/pipeline@sha256:builder"}}},
+	}}}
+
+	statement := func(subject string, digest string, build *api.ProjectDirectoryImageBuildStepConfiguration, baseImages []provenance.Material) provenance.Statement {
+		return provenance.NewStatement(provenance.Subject{Name: subject, Digest: provenance.Digest(digest)}, jobSpec, build, baseImages, now)
+	}
+	baseImages := []provenance.Material{
+		{URI: "registry/ci-op/pipeline@sha256:base", Digest: map[string]string{"sha256": "base"}},
+		{URI: "registry/ci-op/pipeline@sha256:builder", Digest: map[string]string{"sha256": "builder"}},
+	}
+	expected := map[string]map[string]provenance.Statement{
+		"ocp/4.12": {
+			"cli":  statement("registry.ci.openshift.org/ocp/4.12:cli", "sha256:cli", &images[0], baseImages),
+			"rpms": statement("registry.ci.openshift.org/ocp/4.12:rpms", "sha256:rpms", nil, nil),
+		},
+		"ocp/cli": {
+			"sha": statement("registry.ci.openshift.org/ocp/cli:sha", "sha256:cli", &images[0], baseImages),
+		},
+	}
+	if diff := cmp.Diff(expected, provenanceFor(tags, pipeline, images, jobSpec, "registry.ci.openshift.org", now)); diff != "" {
+		t.Errorf("statements differ from expected:\n%s", diff)
+	}
+
+	expectedQuay := map[string]map[string]provenance.Statement{
+		"ocp/4.12": {
+			"cli":  statement("quay.io/openshift/ci:ocp_4.12_cli", "sha256:cli", &images[0], baseImages),
+			"rpms": statement("quay.io/openshift/ci:ocp_4.12_rpms", "sha256:rpms", nil, nil),
+		},
+		"ocp/cli": {
+			"sha": statement("quay.io/openshift/ci:ocp_cli_sha", "sha256:cli", &images[0], baseImages),
+		},
+	}
+	if diff := cmp.Diff(expectedQuay, provenanceFor(tags, pipeline, images, jobSpec, api.QuayOpenShiftCIRepo, now)); diff != "" {
+		t.Errorf("statements about the images in quay.io differ from expected:\n%s", diff)
+	}
+}
@@ -167,16 +167,17 @@ func (s *sourceStep) run(ctx context.Context) error {
 }
 
 func createBuild(config api.SourceStepConfiguration, jobSpec *api.JobSpec, clonerefsRef corev1.ObjectReference, resources api.ResourceConfiguration, cloneAuthConfig *CloneAuthConfig, pullSecret *corev1.Secret, fromDigest string) *buildapi.Build {
-	var refs []prowv1.Refs
+	var allRefs []prowv1.Refs
 	if jobSpec.Refs != nil {
-		r := *jobSpec.Refs
-		if cloneAuthConfig != nil {
-			r.CloneURI = cloneAuthConfig.getCloneURI(r.Org, r.Repo)
-		}
-		refs = append(refs, r)
+		allRefs = append(allRefs, *jobSpec.Refs)
 	}
+	allRefs = append(allRefs, jobSpec.ExtraRefs...)
 
-	for _, r := range jobSpec.ExtraRefs {
+	var refs []prowv1.Refs
+	for _, r := range allRefs {
+		if config.Ref != "" && api.RefString(r.Org, r.Repo) != config.Ref {
+			continue
+		}
 		if cloneAuthConfig != nil {
 			r.CloneURI = cloneAuthConfig.getCloneURI(r.Org, r.Repo)
 		}
@@ -211,6 +211,49 @@ func TestCreateBuild(t *testing.T) {
 			clonerefsRef: coreapi.ObjectReference{Kind: "ImageStreamTag", Name: "clonerefs:latest", Namespace: "ci"},
 			resources:    map[string]api.ResourceRequirements{"*": {Requests: map[string]string{"cpu": "200m"}}},
 		},
+		{
+			name: "with extra refs, cloning only one of them",
+			config: api.SourceStepConfiguration{
+				From: "root-org.other",
+				To:   "src-org.other",
+				ClonerefsImage: api.ImageStreamTagReference{
+					Namespace: "ci",
+					Name:      "managed-clonerefs",
+					Tag:       "latest",
+				},
+				ClonerefsPath: "/clonerefs",
+				Ref:           "org.other",
+			},
+			jobSpec: &api.JobSpec{
+				JobSpec: downwardapi.JobSpec{
+					Job:       "job",
+					BuildID:   "buildId",
+					ProwJobID: "prowJobId",
+					ExtraRefs: []prowapi.Refs{{
+						Org:     "org",
+						Repo:    "repo",
+						BaseRef: "master",
+						BaseSHA: "masterSHA",
+						Pulls: []prowapi.Pull{{
+							Number: 1,
+							SHA:    "pullSHA",
+						}},
+					}, {
+						Org:       "org",
+						Repo:      "other",
+						BaseRef:   "master",
+						BaseSHA:   "masterSHA",
+						PathAlias: "example.com/other",
+						Pulls: []prowapi.Pull{{
+							Number: 2,
+							SHA:    "otherPullSHA",
+						}},
+					}},
+				},
+			},
+			clonerefsRef: coreapi.ObjectReference{Kind: "ImageStreamTag", Name: "clonerefs:latest", Namespace: "ci"},
+			resources:    map[string]api.ResourceRequirements{"*": {Requests: map[string]string{"cpu": "200m"}}},
+		},
 		{
 			name: "with extra refs setting workdir and path alias",
 			config: api.SourceStepConfiguration{
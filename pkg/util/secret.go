@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	coreapi "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -41,6 +43,50 @@ func SecretFromDir(path string) (*coreapi.Secret, error) {
 	return ret, nil
 }
 
+// maxOversizedFiles is how many of the largest files are named when data does
+// not fit in a secret
+const maxOversizedFiles = 5
+
+// ValidateSecretSize verifies that the data fits in a secret, naming the
+// largest files when it does not.
+func ValidateSecretSize(data map[string][]byte) error {
+	var total int
+	names := make([]string, 0, len(data))
+	for name, value := range data {
+		total += len(value)
+		names = append(names, name)
+	}
+	if total <= coreapi.MaxSecretSize {
+		return nil
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(data[names[i]]) != len(data[names[j]]) {
+			return len(data[names[i]]) > len(data[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxOversizedFiles {
+		names = names[:maxOversizedFiles]
+	}
+	var largest []string
+	for _, name := range names {
+		largest = append(largest, fmt.Sprintf("%s (%s)", name, FormatSize(len(data[name]))))
+	}
+	return fmt.Errorf("%s of data exceed the %s limit of a secret, the largest files are: %s", FormatSize(total), FormatSize(coreapi.MaxSecretSize), strings.Join(largest, ", "))
+}
+
+// FormatSize formats a number of bytes for humans.
+func FormatSize(size int) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KiB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
 // UpsertImmutableSecret adds new values to an existing secret.
 // New values are added, existing values are overwritten. The secret will be
 // created if it doesn't already exist. Updating an existing secret happens by re-creating it.
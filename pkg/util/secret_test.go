@@ -153,3 +153,42 @@ func TestUpsertImmutableSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSecretSize(t *testing.T) {
+	bytes := func(n int) []byte { return make([]byte, n) }
+	testCases := []struct {
+		name          string
+		data          map[string][]byte
+		expectedError error
+	}{
+		{
+			name: "data fits",
+			data: map[string][]byte{"kubeconfig": bytes(10 * 1024), "metadata.json": bytes(512)},
+		},
+		{
+			name: "data exactly at the limit",
+			data: map[string][]byte{"a": bytes(corev1.MaxSecretSize / 2), "b": bytes(corev1.MaxSecretSize / 2)},
+		},
+		{
+			name: "largest files are named",
+			data: map[string][]byte{
+				"kubeconfig":   bytes(10 * 1024),
+				"must-gather":  bytes(corev1.MaxSecretSize),
+				"install.log":  bytes(300 * 1024),
+				"a":            bytes(1),
+				"b":            bytes(1),
+				"c":            bytes(1),
+				"metadata.tgz": bytes(1),
+			},
+			expectedError: fmt.Errorf("1.3 MiB of data exceed the 1.0 MiB limit of a secret, the largest files are: must-gather (1.0 MiB), install.log (300.0 KiB), kubeconfig (10.0 KiB), a (1 B), b (1 B)"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := ValidateSecretSize(testCase.data)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("%s: unexpected error: %s", testCase.name, diff)
+			}
+		})
+	}
+}
@@ -481,6 +481,7 @@ func (v *Validator) validateTestConfigurationType(
 	if cluster := test.Cluster; cluster != "" && !api.ValidClusterName(string(cluster)) {
 		validationErrors = append(validationErrors, fmt.Errorf("%s.cluster is not a valid cluster: %s", fieldRoot, string(cluster)))
 	}
+	validationErrors = append(validationErrors, validateCapabilities(fieldRoot, test)...)
 	if testConfig := test.ContainerTestConfiguration; testConfig != nil {
 		typeCount++
 		if testConfig.MemoryBackedVolume != nil {
@@ -538,7 +539,16 @@ func (v *Validator) validateTestConfigurationType(
 			validationErrors = append(validationErrors, v.validateClusterProfile(fieldRoot, testConfig.ClusterProfile)...)
 		}
 		context := newContext(fieldPath(fieldRoot), testConfig.Environment, releases, inputImagesSeen)
+		if testConfig.Deprecation != nil {
+			validationErrors = append(validationErrors, context.addField("deprecation").errorf("only registry components can be deprecated"))
+		}
+		if testConfig.Workflow != nil {
+			if err := validateRegistryVersion(*testConfig.Workflow); err != nil {
+				validationErrors = append(validationErrors, context.addField("workflow").errorf("%v", err))
+			}
+		}
 		validationErrors = append(validationErrors, validateLeases(context.addField("leases"), testConfig.Leases)...)
+		validationErrors = append(validationErrors, validateSharedDirStorage(context.addField("shared_dir_storage"), testConfig.SharedDirStorage)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("pre"), testStagePre, testConfig.Pre, claimRelease)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("test"), testStageTest, testConfig.Test, claimRelease)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("post"), testStagePost, testConfig.Post, claimRelease)...)
@@ -551,6 +561,7 @@ func (v *Validator) validateTestConfigurationType(
 			validationErrors = append(validationErrors, v.validateClusterProfile(fieldRoot, testConfig.ClusterProfile)...)
 		}
 		validationErrors = append(validationErrors, validateLeases(context.addField("leases"), testConfig.Leases)...)
+		validationErrors = append(validationErrors, validateSharedDirStorage(context.addField("shared_dir_storage"), testConfig.SharedDirStorage)...)
 		for i, s := range testConfig.Pre {
 			validationErrors = append(validationErrors, v.validateLiteralTestStep(context.addField("pre").addIndex(i), testStagePre, s, claimRelease)...)
 		}
@@ -583,6 +594,9 @@ func (v *Validator) validateTestSteps(context *context, stage testStage, steps [
 		ret = append(ret, validateTestStep(contextI, s)...)
 		if s.LiteralTestStep != nil {
 			ret = append(ret, v.validateLiteralTestStep(contextI, stage, *s.LiteralTestStep, claimRelease)...)
+			if s.LiteralTestStep.Deprecation != nil {
+				ret = append(ret, contextI.addField("deprecation").errorf("only registry components can be deprecated"))
+			}
 		}
 	}
 	return
@@ -602,6 +616,8 @@ func validateTestStep(context *context, step api.TestStep) (ret []error) {
 	if step.Reference != nil {
 		if len(*step.Reference) == 0 {
 			ret = append(ret, context.addField("ref").errorf("length cannot be 0"))
+		} else if err := validateRegistryVersion(*step.Reference); err != nil {
+			ret = append(ret, context.addField("ref").errorf("%v", err))
 		} else if context.namesSeen.Has(*step.Reference) {
 			ret = append(ret, context.addField("ref").errorf("duplicated name %q", *step.Reference))
 		} else {
@@ -611,6 +627,8 @@ func validateTestStep(context *context, step api.TestStep) (ret []error) {
 	if step.Chain != nil {
 		if len(*step.Chain) == 0 {
 			ret = append(ret, context.addField("chain").errorf("length cannot be 0"))
+		} else if err := validateRegistryVersion(*step.Chain); err != nil {
+			ret = append(ret, context.addField("chain").errorf("%v", err))
 		} else if context.namesSeen.Has(*step.Chain) {
 			ret = append(ret, context.addField("chain").errorf("duplicated name %q", *step.Chain))
 		} else {
@@ -620,6 +638,24 @@ func validateTestStep(context *context, step api.TestStep) (ret []error) {
 	return
 }
 
+// validateRegistryVersion verifies the version a registry component is pinned
+// to, if any, in a name like `ipi-install@v3`
+func validateRegistryVersion(name string) error {
+	if !strings.Contains(name, api.RegistryVersionSeparator) {
+		return nil
+	}
+	base, version := api.SplitRegistryVersion(name)
+	switch {
+	case base == "":
+		return fmt.Errorf("%q: name of the pinned component cannot be empty", name)
+	case version == "":
+		return fmt.Errorf("%q: registry version cannot be empty", name)
+	case strings.Contains(base, api.RegistryVersionSeparator):
+		return fmt.Errorf("%q: component can only be pinned to a single registry version", name)
+	}
+	return nil
+}
+
 func (v *Validator) validateLiteralTestStep(context *context, stage testStage, step api.LiteralTestStep, claimRelease *api.ClaimRelease) (ret []error) {
 	if len(step.As) == 0 {
 		ret = append(ret, context.errorf("`as` is required"))
@@ -816,6 +852,23 @@ func validateDependencies(fieldRoot string, dependencies []api.StepDependency) [
 	return errs
 }
 
+func validateCapabilities(fieldRoot string, test api.TestStepConfiguration) []error {
+	var errs []error
+	if test.Cluster != "" && len(test.Capabilities) > 0 {
+		errs = append(errs, fmt.Errorf("%s: cluster and capabilities are mutually exclusive", fieldRoot))
+	}
+	seen := sets.NewString()
+	for i, capability := range test.Capabilities {
+		if valueErrs := validation.IsDNS1123Label(capability); len(valueErrs) > 0 {
+			errs = append(errs, fmt.Errorf("%s.capabilities[%d] is not a valid capability: %s", fieldRoot, i, strings.Join(valueErrs, ", ")))
+		} else if seen.Has(capability) {
+			errs = append(errs, fmt.Errorf("%s.capabilities[%d] is duplicated: %s", fieldRoot, i, capability))
+		}
+		seen.Insert(capability)
+	}
+	return errs
+}
+
 func validateDNSConfig(fieldRoot string, dnsConfig []api.StepDNSConfig) (ret []error) {
 	var errs []error
 	for i, dnsconfig := range dnsConfig {
@@ -827,6 +880,18 @@ func validateDNSConfig(fieldRoot string, dnsConfig []api.StepDNSConfig) (ret []e
 	return errs
 }
 
+func validateSharedDirStorage(context *context, storage *api.SharedDirStorage) (ret []error) {
+	if storage == nil {
+		return nil
+	}
+	if quantity, err := resource.ParseQuantity(storage.Size); err != nil {
+		ret = append(ret, context.errorf("'size' must be a Kubernetes quantity: %v", err))
+	} else if quantity.Sign() <= 0 {
+		ret = append(ret, context.errorf("'size' must be positive"))
+	}
+	return
+}
+
 func validateLeases(context *context, leases []api.StepLease) (ret []error) {
 	for i, l := range leases {
 		if l.ResourceType == "" {
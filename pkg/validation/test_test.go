@@ -12,6 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/utils/diff"
+	"k8s.io/utils/pointer"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/testhelper"
@@ -1062,6 +1063,32 @@ func TestValidateTestSteps(t *testing.T) {
 				Resources: resources},
 		}},
 		clusterClaim: api.ClaimRelease{ReleaseName: "myclaim-as", OverrideName: "myclaim"},
+	}, {
+		name: "deprecated literal step",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:          "as",
+				From:        "from",
+				Commands:    "commands",
+				Resources:   resources,
+				Deprecation: &api.Deprecation{Message: "use something else"}},
+		}},
+		errs: []error{errors.New("test[0].deprecation: only registry components can be deprecated")},
+	}, {
+		name:  "steps pinned to a registry version",
+		steps: []api.TestStep{{Reference: pointer.String("ref@v3")}, {Chain: pointer.String("chain@0123abc")}},
+	}, {
+		name: "malformed registry versions",
+		steps: []api.TestStep{
+			{Reference: pointer.String("ref@")},
+			{Chain: pointer.String("@v3")},
+			{Reference: pointer.String("ref@v2@v3")},
+		},
+		errs: []error{
+			errors.New(`test[0].ref: "ref@": registry version cannot be empty`),
+			errors.New(`test[1].chain: "@v3": name of the pinned component cannot be empty`),
+			errors.New(`test[2].ref: "ref@v2@v3": component can only be pinned to a single registry version`),
+		},
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			context := newContext("test", nil, tc.releases, make(testInputImages))
@@ -1534,6 +1561,67 @@ func TestValidateTestConfigurationType(t *testing.T) {
 			},
 			expected: []error{fmt.Errorf("test.cluster is not a valid cluster: bar")},
 		},
+		{
+			name: "deprecated test",
+			test: api.TestStepConfiguration{
+				MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+					Workflow:    pointer.String("workflow"),
+					Deprecation: &api.Deprecation{Message: "not in the registry"},
+				},
+			},
+			expected: []error{errors.New("test.deprecation: only registry components can be deprecated")},
+		},
+		{
+			name: "workflow pinned to an empty registry version",
+			test: api.TestStepConfiguration{
+				MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+					Workflow: pointer.String("workflow@"),
+				},
+			},
+			expected: []error{errors.New(`test.workflow: "workflow@": registry version cannot be empty`)},
+		},
+		{
+			name: "shared directory storage",
+			test: api.TestStepConfiguration{
+				MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+					Workflow:         pointer.String("workflow"),
+					SharedDirStorage: &api.SharedDirStorage{Size: "5Gi"},
+				},
+			},
+		},
+		{
+			name: "invalid shared directory storage",
+			test: api.TestStepConfiguration{
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+					SharedDirStorage: &api.SharedDirStorage{Size: "0"},
+				},
+			},
+			expected: []error{errors.New("test.steps.shared_dir_storage: 'size' must be positive")},
+		},
+		{
+			name: "capabilities",
+			test: api.TestStepConfiguration{
+				Capabilities: []string{"arm64", "vpn"},
+				ContainerTestConfiguration: &api.ContainerTestConfiguration{
+					From: "src",
+				},
+			},
+		},
+		{
+			name: "invalid capabilities",
+			test: api.TestStepConfiguration{
+				Cluster:      "build01",
+				Capabilities: []string{"vpn", "Nested_Virt", "vpn"},
+				ContainerTestConfiguration: &api.ContainerTestConfiguration{
+					From: "src",
+				},
+			},
+			expected: []error{
+				errors.New("test: cluster and capabilities are mutually exclusive"),
+				errors.New("test.capabilities[1] is not a valid capability: a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"),
+				errors.New("test.capabilities[2] is duplicated: vpn"),
+			},
+		},
 		{
 			name: "claim on a container test -> error",
 			test: api.TestStepConfiguration{
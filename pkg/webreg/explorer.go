@@ -0,0 +1,374 @@
+package webreg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	WorkflowQuery = "workflow"
+	EnvQuery      = "env"
+)
+
+// Sources of the effective value of a parameter of a step, in order of precedence
+const (
+	envSourceTest     = "test"
+	envSourceWorkflow = "workflow"
+	envSourceChain    = "chain"
+	envSourceStep     = "step"
+	envSourceUnset    = "unset"
+)
+
+const explorerPage = `
+<h2 id="title"><a href="#title">Workflow Explorer</a></h2>
+<p>Resolve a workflow the way ci-operator would for a test that sets the given environment, to see which steps run and with what configuration.</p>
+<form action="/explore" method="get">
+  <div class="form-group">
+    <label for="workflow">Workflow</label>
+    <input class="form-control" style="font-family:monospace" id="workflow" name="workflow" list="workflows" value="{{ .Workflow }}">
+    <datalist id="workflows">
+    {{ range $name := .Workflows }}
+      <option value="{{ $name }}">
+    {{ end }}
+    </datalist>
+  </div>
+  <div class="form-group">
+    <label for="env">Test environment, one <span style="font-family:monospace">NAME=value</span> per line</label>
+    <textarea class="form-control" style="font-family:monospace" id="env" name="env" rows="4">{{ .Environment }}</textarea>
+  </div>
+  <button type="submit" class="btn btn-primary">Resolve</button>
+</form>
+{{ if .Error }}
+  <div class="alert alert-danger" role="alert" style="margin-top: 1em; white-space: pre-wrap">{{ .Error }}</div>
+{{ end }}
+{{ if .Resolved }}
+  <h3 id="summary"><a href="#summary">Summary</a></h3>
+  <table class="table">
+  <tbody>
+    <tr><td>Workflow</td><td>{{ template "nameWithLinkWorkflow" .Workflow }}</td></tr>
+    {{ if .ClusterProfile }}<tr><td>Cluster profile</td><td style="font-family:monospace">{{ .ClusterProfile }}</td></tr>{{ end }}
+    <tr><td>Leases</td><td>{{ range $lease := .Leases }}<nobr style="font-family:monospace">{{ $lease.ResourceType }} ({{ $lease.Env }})</nobr> {{ else }}none{{ end }}</td></tr>
+    <tr><td>Observers</td><td>{{ range $observer := .Observers }}<nobr style="font-family:monospace">{{ $observer }}</nobr> {{ else }}none{{ end }}</td></tr>
+    <tr><td title="Sum of the median durations of all steps in past jobs">Expected duration</td><td>{{ if .Total }}{{ .Total }}{{ else }}unknown{{ end }}</td></tr>
+  </tbody>
+  </table>
+  <h3 id="timeline"><a href="#timeline">Timeline</a></h3>
+  <table class="table">
+  <thead>
+  <tr>
+    <th title="Phase the step runs in">Phase</th>
+    <th title="Name of the step">Step</th>
+    <th title="Expected start, from the median durations of the steps before it in past jobs">Start</th>
+    <th title="Median duration of the step in past jobs">Median</th>
+    <th title="Timeout and termination grace period of the step">Timeout</th>
+    <th title="Effective environment of the step and where each value comes from">Environment</th>
+    <th title="Images the step depends on and the variables exposing them">Dependencies</th>
+    <th title="Secrets mounted into the step">Credentials</th>
+    <th title="Resources leased for the step">Leases</th>
+    <th title="Observers running alongside the step">Observers</th>
+  </tr>
+  </thead>
+  <tbody>
+  {{ range $step := .Steps }}
+  <tr>
+    <td>{{ $step.Phase }}</td>
+    <td>{{ template "nameWithLinkReference" $step.Step.As }}</td>
+    <td>{{ if $step.Start }}+{{ $step.Start }}{{ else }}unknown{{ end }}</td>
+    <td>{{ if $step.Median }}{{ $step.Median }}{{ else }}unknown{{ end }}</td>
+    <td>
+      {{ if $step.Step.Timeout }}{{ $step.Step.Timeout.String }}{{ else }}default{{ end }}
+      {{ if $step.Step.GracePeriod }}<br>(grace period {{ $step.Step.GracePeriod.String }}){{ end }}
+    </td>
+    <td>
+      {{ range $env := $step.Environment }}
+        <nobr><span style="font-family:monospace">{{ $env.Name }}</span>{{ if $env.Value }}=<span style="font-family:monospace">{{ $env.Value }}</span>{{ end }} <span class="badge badge-secondary">{{ $env.Source }}</span></nobr><br>
+      {{ end }}
+    </td>
+    <td>
+      {{ range $dependency := $step.Step.Dependencies }}
+        <nobr style="font-family:monospace">{{ $dependency.Env }}={{ $dependency.Name }}</nobr><br>
+      {{ end }}
+    </td>
+    <td>
+      {{ range $credential := $step.Step.Credentials }}
+        <nobr style="font-family:monospace">{{ $credential.Namespace }}/{{ $credential.Name }}</nobr> at <nobr style="font-family:monospace">{{ $credential.MountPath }}</nobr><br>
+      {{ end }}
+    </td>
+    <td>
+      {{ range $lease := $step.Step.Leases }}
+        <nobr style="font-family:monospace">{{ $lease.ResourceType }} ({{ $lease.Env }})</nobr><br>
+      {{ end }}
+    </td>
+    <td>
+      {{ range $observer := $step.Step.Observers }}
+        <nobr style="font-family:monospace">{{ $observer }}</nobr><br>
+      {{ end }}
+    </td>
+  </tr>
+  {{ end }}
+  </tbody>
+  </table>
+{{ end }}
+`
+
+// StepDurations holds the median durations of steps in past jobs, by the name of the step
+type StepDurations map[string]time.Duration
+
+// stepGraphTest holds the parts of a step in a ci-operator step graph needed to
+// determine durations. api.CIOperatorStepDetails can't be used as the custom
+// unmarshalling of its embedded details drops the sub-steps.
+type stepGraphTest struct {
+	StepName string                         `json:"name"`
+	Substeps []api.CIOperatorStepDetailInfo `json:"substeps,omitempty"`
+}
+
+// LoadStepDurations determines the median durations of steps from the ci-operator step
+// graphs found anywhere under the directory
+func LoadStepDurations(dir string) (StepDurations, error) {
+	var graphs [][]stepGraphTest
+	if err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || entry.Name() != api.CIOperatorStepGraphJSONFilename {
+			return nil
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read step graph: %w", err)
+		}
+		var graph []stepGraphTest
+		if err := json.Unmarshal(raw, &graph); err != nil {
+			logrus.WithError(err).WithField("path", path).Warn("Ignoring step graph that could not be parsed.")
+			return nil
+		}
+		graphs = append(graphs, graph)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load step graphs: %w", err)
+	}
+	return stepDurationsFrom(graphs), nil
+}
+
+// stepDurationsFrom determines the median duration of every step of the registry that
+// ran in the multi-stage tests of the graphs. Multi-stage tests record the pods of their
+// steps as sub-steps, named after the test and the step.
+func stepDurationsFrom(graphs [][]stepGraphTest) StepDurations {
+	observed := map[string][]time.Duration{}
+	for _, graph := range graphs {
+		for _, test := range graph {
+			for _, pod := range test.Substeps {
+				if pod.Duration == nil || (pod.Failed != nil && *pod.Failed) {
+					continue
+				}
+				step := strings.TrimPrefix(pod.StepName, test.StepName+"-")
+				if step == pod.StepName {
+					continue
+				}
+				observed[step] = append(observed[step], *pod.Duration)
+			}
+		}
+	}
+	durations := StepDurations{}
+	for step, values := range observed {
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		middle := len(values) / 2
+		if len(values)%2 == 0 {
+			durations[step] = (values[middle-1] + values[middle]) / 2
+		} else {
+			durations[step] = values[middle]
+		}
+	}
+	return durations
+}
+
+type explorerEnv struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+type explorerStep struct {
+	Phase       string
+	Step        api.LiteralTestStep
+	Environment []explorerEnv
+	Start       *time.Duration
+	Median      *time.Duration
+}
+
+type explorer struct {
+	Workflows   []string
+	Workflow    string
+	Environment string
+	Error       string
+
+	Resolved       bool
+	ClusterProfile api.ClusterProfile
+	Leases         []api.StepLease
+	Observers      []string
+	Steps          []explorerStep
+	Total          *time.Duration
+}
+
+// parseEnvironment parses the test environment from lines of NAME=value
+func parseEnvironment(values []string) (api.TestEnvironment, error) {
+	env := api.TestEnvironment{}
+	for _, value := range values {
+		for _, line := range strings.Split(value, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			name, val, ok := strings.Cut(line, "=")
+			if !ok || name == "" {
+				return nil, fmt.Errorf("invalid environment %q, expected NAME=value", line)
+			}
+			env[name] = val
+		}
+	}
+	return env, nil
+}
+
+// explore resolves the workflow for a test with the environment and lays out its steps
+// in the order they run
+func explore(resolver registry.Resolver, refs registry.ReferenceByName, workflows registry.WorkflowByName, durations StepDurations, workflow string, env api.TestEnvironment) (explorer, error) {
+	result := explorer{Workflow: workflow}
+	resolved, err := resolver.Resolve("explorer", api.MultiStageTestConfiguration{Workflow: &workflow, Environment: env})
+	if err != nil {
+		return result, err
+	}
+	result.Resolved = true
+	result.ClusterProfile = resolved.ClusterProfile
+	result.Leases = api.LeasesForTest(&resolved)
+	for _, observer := range resolved.Observers {
+		result.Observers = append(result.Observers, observer.Name)
+	}
+	var elapsed time.Duration
+	known := true
+	for _, phase := range []struct {
+		name  string
+		steps []api.LiteralTestStep
+	}{
+		{name: "pre", steps: resolved.Pre},
+		{name: "test", steps: resolved.Test},
+		{name: "post", steps: resolved.Post},
+	} {
+		for _, step := range phase.steps {
+			item := explorerStep{
+				Phase:       phase.name,
+				Step:        step,
+				Environment: effectiveEnvironment(step, refs[step.As], workflows[workflow].Environment, env),
+			}
+			if known {
+				start := elapsed
+				item.Start = &start
+			}
+			if median, ok := durations[step.As]; ok {
+				item.Median = &median
+				elapsed += median
+			} else {
+				known = false
+			}
+			result.Steps = append(result.Steps, item)
+		}
+	}
+	if known && len(result.Steps) > 0 {
+		result.Total = &elapsed
+	}
+	return result, nil
+}
+
+// effectiveEnvironment determines the value each parameter of the resolved step has and
+// which level of the configuration it comes from
+func effectiveEnvironment(step, reference api.LiteralTestStep, workflowEnv, testEnv api.TestEnvironment) []explorerEnv {
+	defaults := map[string]*string{}
+	for _, param := range reference.Environment {
+		defaults[param.Name] = param.Default
+	}
+	var env []explorerEnv
+	for _, param := range step.Environment {
+		item := explorerEnv{Name: param.Name, Source: envSourceUnset}
+		if param.Default != nil {
+			item.Value = *param.Default
+		}
+		_, inTest := testEnv[param.Name]
+		_, inWorkflow := workflowEnv[param.Name]
+		stepDefault, inReference := defaults[param.Name]
+		switch {
+		case inTest:
+			item.Source = envSourceTest
+		case inWorkflow:
+			item.Source = envSourceWorkflow
+		case param.Default == nil:
+		case inReference && stepDefault != nil && *stepDefault == *param.Default:
+			item.Source = envSourceStep
+		case inReference:
+			item.Source = envSourceChain
+		default:
+			item.Source = envSourceStep
+		}
+		env = append(env, item)
+	}
+	return env
+}
+
+func explorerHandler(agent agents.RegistryAgent, durations func() StepDurations, w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	defer func() { logrus.Infof("rendered in %s", time.Since(start)) }()
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+
+	refs, _, workflows, _, _ := agent.GetRegistryComponents()
+	page, err := baseTemplate.Clone()
+	if err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+	if page, err = page.Parse(explorerPage); err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	workflow := req.URL.Query().Get(WorkflowQuery)
+	envValues := req.URL.Query()[EnvQuery]
+	data := explorer{Workflow: workflow, Environment: strings.Join(envValues, "\n")}
+	if workflow != "" {
+		env, err := parseEnvironment(envValues)
+		if err != nil {
+			data.Error = err.Error()
+		} else if _, ok := workflows[workflow]; !ok {
+			data.Error = fmt.Sprintf("Could not find workflow %s", workflow)
+		} else {
+			var stepDurations StepDurations
+			if durations != nil {
+				stepDurations = durations()
+			}
+			explored, err := explore(agent, refs, workflows, stepDurations, workflow, env)
+			if err != nil {
+				explored.Error = fmt.Sprintf("Failed to resolve the workflow: %v", err)
+			}
+			explored.Environment = data.Environment
+			data = explored
+		}
+		if data.Error != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	for name := range workflows {
+		data.Workflows = append(data.Workflows, name)
+	}
+	sort.Strings(data.Workflows)
+	writePage(w, "Registry Workflow Explorer", page, data)
+}
@@ -0,0 +1,248 @@
+package webreg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestParseEnvironment(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		values        []string
+		expected      api.TestEnvironment
+		expectedError error
+	}{
+		{
+			name:     "no environment",
+			expected: api.TestEnvironment{},
+		},
+		{
+			name:     "lines and separate values",
+			values:   []string{"FOO=foo\r\n\nBAR=a=b", "EMPTY="},
+			expected: api.TestEnvironment{"FOO": "foo", "BAR": "a=b", "EMPTY": ""},
+		},
+		{
+			name:          "line without a value",
+			values:        []string{"FOO"},
+			expectedError: errors.New(`invalid environment "FOO", expected NAME=value`),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := parseEnvironment(testCase.values)
+			if diff := cmp.Diff(testCase.expectedError, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("%s: got incorrect error: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect environment: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestExplore(t *testing.T) {
+	timeout := &prowv1.Duration{Duration: time.Hour}
+	refs := registry.ReferenceByName{
+		"install": {
+			As:          "install",
+			From:        "installer",
+			Commands:    "install",
+			Timeout:     timeout,
+			Credentials: []api.CredentialReference{{Namespace: "ns", Name: "creds", MountPath: "/creds"}},
+			Environment: []api.StepParameter{
+				{Name: "SIZE", Default: pointer.String("small")},
+				{Name: "REGION", Default: pointer.String("us-east-1")},
+				{Name: "ZONE", Default: pointer.String("a")},
+			},
+		},
+		"test": {
+			As:           "test",
+			From:         "tests",
+			Commands:     "test",
+			Dependencies: []api.StepDependency{{Name: "release:latest", Env: "RELEASE"}},
+			Leases:       []api.StepLease{{ResourceType: "gpu", Env: "GPU"}},
+			Environment:  []api.StepParameter{{Name: "SUITE", Default: pointer.String("")}},
+		},
+		"gather": {As: "gather", From: "cli", Commands: "gather"},
+	}
+	chains := registry.ChainByName{
+		"install-chain": {
+			As:          "install-chain",
+			Steps:       []api.TestStep{{Reference: pointer.String("install")}},
+			Environment: []api.StepParameter{{Name: "ZONE", Default: pointer.String("b")}},
+		},
+	}
+	workflows := registry.WorkflowByName{
+		"workflow": {
+			ClusterProfile: api.ClusterProfileAWS,
+			Pre:            []api.TestStep{{Chain: pointer.String("install-chain")}},
+			Test:           []api.TestStep{{Reference: pointer.String("test")}},
+			Post:           []api.TestStep{{Reference: pointer.String("gather")}},
+			Environment:    api.TestEnvironment{"REGION": "us-west-2"},
+		},
+	}
+	resolver := registry.NewResolver(refs, chains, workflows, registry.ObserverByName{})
+	duration := func(d time.Duration) *time.Duration { return &d }
+
+	var testCases = []struct {
+		name          string
+		env           api.TestEnvironment
+		durations     StepDurations
+		expected      explorer
+		expectedError bool
+	}{
+		{
+			name:      "all durations are known",
+			env:       api.TestEnvironment{"SIZE": "large"},
+			durations: StepDurations{"install": 30 * time.Minute, "test": time.Hour, "gather": time.Minute},
+			expected: explorer{
+				Workflow:       "workflow",
+				Resolved:       true,
+				ClusterProfile: api.ClusterProfileAWS,
+				Leases:         []api.StepLease{{ResourceType: "aws-quota-slice", Env: api.DefaultLeaseEnv, Count: 1}, {ResourceType: "gpu", Env: "GPU"}},
+				Steps: []explorerStep{
+					{
+						Phase: "pre",
+						Step: api.LiteralTestStep{
+							As: "install", From: "installer", Commands: "install", Timeout: timeout,
+							Credentials: []api.CredentialReference{{Namespace: "ns", Name: "creds", MountPath: "/creds"}},
+							Environment: []api.StepParameter{
+								{Name: "SIZE", Default: pointer.String("large")},
+								{Name: "REGION", Default: pointer.String("us-west-2")},
+								{Name: "ZONE", Default: pointer.String("b")},
+							},
+						},
+						Environment: []explorerEnv{
+							{Name: "SIZE", Value: "large", Source: envSourceTest},
+							{Name: "REGION", Value: "us-west-2", Source: envSourceWorkflow},
+							{Name: "ZONE", Value: "b", Source: envSourceChain},
+						},
+						Start:  duration(0),
+						Median: duration(30 * time.Minute),
+					},
+					{
+						Phase: "test",
+						Step: api.LiteralTestStep{
+							As: "test", From: "tests", Commands: "test",
+							Dependencies: []api.StepDependency{{Name: "release:latest", Env: "RELEASE"}},
+							Leases:       []api.StepLease{{ResourceType: "gpu", Env: "GPU"}},
+							Environment:  []api.StepParameter{{Name: "SUITE", Default: pointer.String("")}},
+						},
+						Environment: []explorerEnv{{Name: "SUITE", Source: envSourceStep}},
+						Start:       duration(30 * time.Minute),
+						Median:      duration(time.Hour),
+					},
+					{
+						Phase:  "post",
+						Step:   api.LiteralTestStep{As: "gather", From: "cli", Commands: "gather"},
+						Start:  duration(90 * time.Minute),
+						Median: duration(time.Minute),
+					},
+				},
+				Total: duration(91 * time.Minute),
+			},
+		},
+		{
+			name:      "steps after one without a duration have no start",
+			durations: StepDurations{"install": 30 * time.Minute, "gather": time.Minute},
+			expected: explorer{
+				Workflow:       "workflow",
+				Resolved:       true,
+				ClusterProfile: api.ClusterProfileAWS,
+				Leases:         []api.StepLease{{ResourceType: "aws-quota-slice", Env: api.DefaultLeaseEnv, Count: 1}, {ResourceType: "gpu", Env: "GPU"}},
+				Steps: []explorerStep{
+					{
+						Phase: "pre",
+						Step: api.LiteralTestStep{
+							As: "install", From: "installer", Commands: "install", Timeout: timeout,
+							Credentials: []api.CredentialReference{{Namespace: "ns", Name: "creds", MountPath: "/creds"}},
+							Environment: []api.StepParameter{
+								{Name: "SIZE", Default: pointer.String("small")},
+								{Name: "REGION", Default: pointer.String("us-west-2")},
+								{Name: "ZONE", Default: pointer.String("b")},
+							},
+						},
+						Environment: []explorerEnv{
+							{Name: "SIZE", Value: "small", Source: envSourceStep},
+							{Name: "REGION", Value: "us-west-2", Source: envSourceWorkflow},
+							{Name: "ZONE", Value: "b", Source: envSourceChain},
+						},
+						Start:  duration(0),
+						Median: duration(30 * time.Minute),
+					},
+					{
+						Phase: "test",
+						Step: api.LiteralTestStep{
+							As: "test", From: "tests", Commands: "test",
+							Dependencies: []api.StepDependency{{Name: "release:latest", Env: "RELEASE"}},
+							Leases:       []api.StepLease{{ResourceType: "gpu", Env: "GPU"}},
+							Environment:  []api.StepParameter{{Name: "SUITE", Default: pointer.String("")}},
+						},
+						Environment: []explorerEnv{{Name: "SUITE", Source: envSourceStep}},
+						Start:       duration(30 * time.Minute),
+					},
+					{
+						Phase:  "post",
+						Step:   api.LiteralTestStep{As: "gather", From: "cli", Commands: "gather"},
+						Median: duration(time.Minute),
+					},
+				},
+			},
+		},
+		{
+			name:          "environment no step uses",
+			env:           api.TestEnvironment{"UNUSED": "value"},
+			expected:      explorer{Workflow: "workflow"},
+			expectedError: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := explore(resolver, refs, workflows, testCase.durations, "workflow", testCase.env)
+			if (err != nil) != testCase.expectedError {
+				t.Fatalf("%s: expected error: %v, got: %v", testCase.name, testCase.expectedError, err)
+			}
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("%s: got incorrect exploration: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestLoadStepDurations(t *testing.T) {
+	dir := t.TempDir()
+	graphs := map[string]string{
+		"1": `[{"name":"e2e","substeps":[{"name":"e2e-install","duration":600000000000},{"name":"e2e-test","duration":60000000000}]}]`,
+		"2": `[{"name":"e2e","substeps":[{"name":"e2e-install","duration":1200000000000},{"name":"e2e-test","duration":3600000000000,"failed":true}]},{"name":"src","duration":1000}]`,
+		"3": `[{"name":"upgrade","substeps":[{"name":"upgrade-install","duration":1800000000000},{"name":"pod","duration":1000}]}]`,
+		"4": `not a step graph`,
+	}
+	for name, graph := range graphs {
+		if err := os.MkdirAll(filepath.Join(dir, name, "artifacts"), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "artifacts", api.CIOperatorStepGraphJSONFilename), []byte(graph), 0644); err != nil {
+			t.Fatalf("failed to write step graph: %v", err)
+		}
+	}
+	actual, err := LoadStepDurations(dir)
+	if err != nil {
+		t.Fatalf("failed to load step durations: %v", err)
+	}
+	expected := StepDurations{"install": 20 * time.Minute, "test": time.Minute}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("got incorrect step durations: %v", diff)
+	}
+}
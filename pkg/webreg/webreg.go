@@ -102,6 +102,7 @@ const mainPage = `
 
 const referencePage = `
 <h2 id="title"><a href="#title">Step:</a> <nobr style="font-family:monospace">{{ .Reference.As }}</nobr></h2>
+{{ template "deprecationBanner" (deprecation "reference" .Reference.Deprecation) }}
 <p id="documentation">{{ .Reference.Documentation }}</p>
 <h3 id="image"><a href="#image">Container image used for this step:</a> <span style="font-family:monospace">{{ fromImage .Reference.From .Reference.FromImage }}</span></h3>
 <p id="image">{{ fromImageDescription .Reference.From .Reference.FromImage }}<d/p>
@@ -117,6 +118,7 @@ const referencePage = `
 
 const chainPage = `
 <h2 id="title"><a href="#title">Chain:</a> <nobr style="font-family:monospace">{{ .Chain.As }}</nobr></h2>
+{{ template "deprecationBanner" (deprecation "chain" .Chain.Deprecation) }}
 <p id="documentation">{{ .Chain.Documentation }}</p>
 <h3 id="steps" title="Step run by the chain, in runtime order"><a href="#steps">Steps</a></h3>
 {{ template "stepTable" .Chain.Steps}}
@@ -135,6 +137,9 @@ const chainPage = `
 const workflowJobPage = `
 {{ $type := .Workflow.Type }}
 <h2 id="title"><a href="#title">{{ $type }}:</a> <nobr style="font-family:monospace">{{ .Workflow.As }}</nobr></h2>
+{{ if eq $type "Workflow" }}
+{{ template "deprecationBanner" (deprecation "workflow" .Workflow.Steps.Deprecation) }}
+{{ end }}
 {{ if .Workflow.Documentation }}
 	<p id="documentation">{{ .Workflow.Documentation }}</p>
 {{ end }}
@@ -155,6 +160,7 @@ const workflowJobPage = `
 <h3 id="graph" title="Visual representation of steps run by this {{ toLower $type }}"><a href="#graph">Step Graph</a></h3>
 {{ workflowGraph .Workflow.As .Workflow.Type }}
 {{ if eq $type "Workflow" }}
+<h3 id="explore"><a href="#explore">Explore:</a></h3><p><a href="/explore?workflow={{ .Workflow.As }}">See the resolved steps of this workflow for a test environment</a></p>
 <h3 id="github"><a href="#github">GitHub Link:</a></h3>{{ githubLink .Metadata.Path }}
 {{ ownersBlock .Metadata.Owners }}
 {{ end }}
@@ -181,6 +187,20 @@ const templateDefinitions = `
 	<nobr><a href="/workflow/{{ . }}" style="font-family:monospace">{{ . }}</a></nobr>
 {{ end }}
 
+{{ define "deprecationBadge" }}
+	{{ if . }}<span class="badge badge-warning" title="{{ .Message }}">deprecated</span>{{ end }}
+{{ end }}
+
+{{ define "deprecationBanner" }}
+	{{ if .Deprecation }}
+	<div class="alert alert-warning" role="alert" id="deprecation">
+		<b>This {{ .Type }} is deprecated{{ with .Deprecation.RemovalDate }} and will be removed after {{ . }}{{ end }}.</b>
+		{{ with .Deprecation.ReplacedBy }}Use the <a href="/{{ $.Type }}/{{ . }}" style="font-family:monospace">{{ . }}</a> {{ $.Type }} instead.{{ end }}
+		{{ with .Deprecation.Message }}<p>{{ . }}</p>{{ end }}
+	</div>
+	{{ end }}
+{{ end }}
+
 {{ define "referenceProperties" }}
   <table class="table">
   <thead>
@@ -431,7 +451,7 @@ const templateDefinitions = `
 		<tbody>
 			{{ range $name, $config := . }}
 				<tr>
-					<td><b>Name:</b> {{ template "nameWithLinkWorkflow" $name }}<p>
+					<td><b>Name:</b> {{ template "nameWithLinkWorkflow" $name }}{{ template "deprecationBadge" $config.Deprecation }}<p>
 						<b>Description:</b><br>{{ docsForName $name }}
 					</td>
 					<td>{{ if gt (len $config.Pre) 0 }}<b>Pre:</b>{{ template "stepList" $config.Pre }}{{ end }}
@@ -458,7 +478,7 @@ const templateDefinitions = `
 		<tbody>
 			{{ range $name, $config := . }}
 				<tr>
-					<td>{{ template "nameWithLinkChain" $name }}</td>
+					<td>{{ template "nameWithLinkChain" $name }}{{ template "deprecationBadge" $config.Deprecation }}</td>
 					<td>{{ docsForName $name }}</td>
 					<td>{{ template "stepList" $config.Steps }}</td>
 				</tr>
@@ -480,7 +500,7 @@ const templateDefinitions = `
 		<tbody>
 			{{ range $name, $config := . }}
 				<tr>
-					<td>{{ template "nameWithLinkReference" $name }}</td>
+					<td>{{ template "nameWithLinkReference" $name }}{{ template "deprecationBadge" $config.Deprecation }}</td>
 					<td>{{ docsForName $name }}</td>
 				</tr>
 			{{ end }}
@@ -681,11 +701,20 @@ func getBaseTemplate() (*template.Template, error) {
 			},
 			"githubLink":  githubLink,
 			"ownersBlock": ownersBlock,
+			"deprecation": func(componentType string, deprecation *api.Deprecation) deprecationBanner {
+				return deprecationBanner{Type: componentType, Deprecation: deprecation}
+			},
 		},
 	)
 	return base.Funcs(template.FuncMap{"markdown": markDowner}).Parse(templateDefinitions)
 }
 
+// deprecationBanner is the data of the banner on the page of a deprecated component
+type deprecationBanner struct {
+	Type        string
+	Deprecation *api.Deprecation
+}
+
 type stepNameAndType struct {
 	Name string
 	Type string
@@ -1064,7 +1093,9 @@ func mainPageHandler(agent agents.RegistryAgent, templateString string, w http.R
 	writePage(w, "Step Registry Help Page", page, comps)
 }
 
-func WebRegHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent) http.HandlerFunc {
+// WebRegHandler serves the registry UI. Durations are optional and provide the median
+// durations of steps in past jobs.
+func WebRegHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent, durations func() StepDurations) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		trimmedPath := strings.TrimPrefix(req.URL.Path, req.URL.Host)
 		// remove leading slash
@@ -1080,6 +1111,8 @@ func WebRegHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent)
 				searchHandler(confAgent, w, req)
 			case "job":
 				jobHandler(regAgent, confAgent, w, req)
+			case "explore":
+				explorerHandler(regAgent, durations, w, req)
 			case "ci-operator-reference":
 				ciOpConfigRefHandler(w)
 			default:
@@ -1317,6 +1350,7 @@ func referenceHandler(agent agents.RegistryAgent, w http.ResponseWriter, req *ht
 				OptionalOnSuccess: refs[name].OptionalOnSuccess,
 				BestEffort:        refs[name].BestEffort,
 				Cli:               refs[name].Cli,
+				Deprecation:       refs[name].Deprecation,
 			},
 			Documentation: docs[name],
 		},
@@ -1362,6 +1396,7 @@ func chainHandler(agent agents.RegistryAgent, w http.ResponseWriter, req *http.R
 			As:            name,
 			Documentation: docs[name],
 			Steps:         chains[name].Steps,
+			Deprecation:   chains[name].Deprecation,
 		},
 		Metadata: metadata[chainMetadataName],
 	}
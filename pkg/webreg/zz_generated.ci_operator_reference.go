@@ -406,6 +406,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        always_run: false\n" +
 	"        # As is the name of the test.\n" +
 	"        as: ' '\n" +
+	"        # Capabilities are the capabilities that the cluster where the test runs must have,\n" +
+	"        # e.g. arm64 or vpn. The capabilities of each cluster are declared in the configuration\n" +
+	"        # of the prow-job-dispatcher.\n" +
+	"        capabilities:\n" +
+	"            - \"\"\n" +
 	"        # Cluster specifies the name of the cluster where the test runs.\n" +
 	"        cluster: ' '\n" +
 	"        # ClusterClaim claims an OpenShift cluster and exposes environment variable ${KUBECONFIG} to the test container\n" +
@@ -474,6 +479,20 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"            dependency_overrides:\n" +
 	"                \"\": \"\"\n" +
+	"            # Deprecated lists the deprecated registry components the test uses.\n" +
+	"            deprecated:\n" +
+	"                - # Message explains why the component is deprecated and how to migrate.\n" +
+	"                  message: ' '\n" +
+	"                  # Name is the name of the component.\n" +
+	"                  name: ' '\n" +
+	"                  # RemovalDate is the date after which the component may be removed from\n" +
+	"                  # the registry, formatted as YYYY-MM-DD.\n" +
+	"                  removal_date: ' '\n" +
+	"                  # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                  # the deprecated one.\n" +
+	"                  replaced_by: ' '\n" +
+	"                  # Type is the type of the component: reference, chain or workflow.\n" +
+	"                  type: ' '\n" +
 	"            # DnsConfig for step's Pod.\n" +
 	"            dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -551,6 +570,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                      env: ' '\n" +
 	"                      # Name is the tag or stream:tag that this dependency references\n" +
 	"                      name: ' '\n" +
+	"                  # Deprecation marks a step in the registry as deprecated. It can only be\n" +
+	"                  # set in the registry.\n" +
+	"                  deprecation:\n" +
+	"                    # Message explains why the component is deprecated and how to migrate.\n" +
+	"                    message: ' '\n" +
+	"                    # RemovalDate is the date after which the component may be removed from\n" +
+	"                    # the registry, formatted as YYYY-MM-DD.\n" +
+	"                    removal_date: ' '\n" +
+	"                    # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                    # the deprecated one.\n" +
+	"                    replaced_by: ' '\n" +
 	"                  # DnsConfig for step's Pod.\n" +
 	"                  dnsConfig:\n" +
 	"                    # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -641,6 +671,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                      env: ' '\n" +
 	"                      # Name is the tag or stream:tag that this dependency references\n" +
 	"                      name: ' '\n" +
+	"                  # Deprecation marks a step in the registry as deprecated. It can only be\n" +
+	"                  # set in the registry.\n" +
+	"                  deprecation:\n" +
+	"                    # Message explains why the component is deprecated and how to migrate.\n" +
+	"                    message: ' '\n" +
+	"                    # RemovalDate is the date after which the component may be removed from\n" +
+	"                    # the registry, formatted as YYYY-MM-DD.\n" +
+	"                    removal_date: ' '\n" +
+	"                    # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                    # the deprecated one.\n" +
+	"                    replaced_by: ' '\n" +
 	"                  # DnsConfig for step's Pod.\n" +
 	"                  dnsConfig:\n" +
 	"                    # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -702,6 +743,14 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  run_as_script: false\n" +
 	"                  # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"                  timeout: 0s\n" +
+	"            # SharedDirStorage configures a volume for the files in SHARED_DIR that do\n" +
+	"            # not fit in the secret that holds it.\n" +
+	"            shared_dir_storage:\n" +
+	"                # Size is the size of the volume, like `5Gi`.\n" +
+	"                size: ' '\n" +
+	"                # StorageClassName is the storage class of the volume. The default storage\n" +
+	"                # class of the cluster is used when unset.\n" +
+	"                storage_class_name: ' '\n" +
 	"            # Test is the array of test steps that define the actual test.\n" +
 	"            test:\n" +
 	"                - # As is the name of the LiteralTestStep.\n" +
@@ -731,6 +780,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                      env: ' '\n" +
 	"                      # Name is the tag or stream:tag that this dependency references\n" +
 	"                      name: ' '\n" +
+	"                  # Deprecation marks a step in the registry as deprecated. It can only be\n" +
+	"                  # set in the registry.\n" +
+	"                  deprecation:\n" +
+	"                    # Message explains why the component is deprecated and how to migrate.\n" +
+	"                    message: ' '\n" +
+	"                    # RemovalDate is the date after which the component may be removed from\n" +
+	"                    # the registry, formatted as YYYY-MM-DD.\n" +
+	"                    removal_date: ' '\n" +
+	"                    # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                    # the deprecated one.\n" +
+	"                    replaced_by: ' '\n" +
 	"                  # DnsConfig for step's Pod.\n" +
 	"                  dnsConfig:\n" +
 	"                    # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -877,6 +937,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"            dependency_overrides:\n" +
 	"                \"\": \"\"\n" +
+	"            # Deprecation marks a workflow in the registry as deprecated. It can only\n" +
+	"            # be set in the registry.\n" +
+	"            deprecation:\n" +
+	"                # Message explains why the component is deprecated and how to migrate.\n" +
+	"                message: ' '\n" +
+	"                # RemovalDate is the date after which the component may be removed from\n" +
+	"                # the registry, formatted as YYYY-MM-DD.\n" +
+	"                removal_date: ' '\n" +
+	"                # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                # the deprecated one.\n" +
+	"                replaced_by: ' '\n" +
 	"            # DnsConfig for step's Pod.\n" +
 	"            dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -909,7 +980,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - as: ' '\n" +
 	"                  best_effort: false\n" +
-	"                  # Chain is the name of a step chain reference.\n" +
+	"                  # Chain is the name of a step chain reference. The name can be pinned to\n" +
+	"                  # a version of the registry, like `ipi-aws-pre@v3`.\n" +
 	"                  chain: \"\"\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
 	"                  # will be injected into this step.\n" +
@@ -924,6 +996,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
 	"                      name: ' '\n" +
+	"                  deprecation:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    message: ' '\n" +
+	"                    removal_date: ' '\n" +
+	"                    replaced_by: ' '\n" +
 	"                  dnsConfig:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    nameservers:\n" +
@@ -954,7 +1031,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - \"\"\n" +
 	"                  optional_on_success: false\n" +
-	"                  # Reference is the name of a step reference.\n" +
+	"                  # Reference is the name of a step reference. The name can be pinned to\n" +
+	"                  # a version of the registry, like `ipi-install@v3`.\n" +
 	"                  ref: \"\"\n" +
 	"                  # Resources defines the resource requirements for the step.\n" +
 	"                  resources:\n" +
@@ -972,7 +1050,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - as: ' '\n" +
 	"                  best_effort: false\n" +
-	"                  # Chain is the name of a step chain reference.\n" +
+	"                  # Chain is the name of a step chain reference. The name can be pinned to\n" +
+	"                  # a version of the registry, like `ipi-aws-pre@v3`.\n" +
 	"                  chain: \"\"\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
 	"                  # will be injected into this step.\n" +
@@ -987,6 +1066,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
 	"                      name: ' '\n" +
+	"                  deprecation:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    message: ' '\n" +
+	"                    removal_date: ' '\n" +
+	"                    replaced_by: ' '\n" +
 	"                  dnsConfig:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    nameservers:\n" +
@@ -1017,7 +1101,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - \"\"\n" +
 	"                  optional_on_success: false\n" +
-	"                  # Reference is the name of a step reference.\n" +
+	"                  # Reference is the name of a step reference. The name can be pinned to\n" +
+	"                  # a version of the registry, like `ipi-install@v3`.\n" +
 	"                  ref: \"\"\n" +
 	"                  # Resources defines the resource requirements for the step.\n" +
 	"                  resources:\n" +
@@ -1030,12 +1115,21 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                        \"\": \"\"\n" +
 	"                  run_as_script: false\n" +
 	"                  timeout: 0s\n" +
+	"            # SharedDirStorage configures a volume for the files in SHARED_DIR that do\n" +
+	"            # not fit in the secret that holds it.\n" +
+	"            shared_dir_storage:\n" +
+	"                # Size is the size of the volume, like `5Gi`.\n" +
+	"                size: ' '\n" +
+	"                # StorageClassName is the storage class of the volume. The default storage\n" +
+	"                # class of the cluster is used when unset.\n" +
+	"                storage_class_name: ' '\n" +
 	"            # Test is the array of test steps that define the actual test.\n" +
 	"            test:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - as: ' '\n" +
 	"                  best_effort: false\n" +
-	"                  # Chain is the name of a step chain reference.\n" +
+	"                  # Chain is the name of a step chain reference. The name can be pinned to\n" +
+	"                  # a version of the registry, like `ipi-aws-pre@v3`.\n" +
 	"                  chain: \"\"\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
 	"                  # will be injected into this step.\n" +
@@ -1050,6 +1144,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
 	"                      name: ' '\n" +
+	"                  deprecation:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    message: ' '\n" +
+	"                    removal_date: ' '\n" +
+	"                    replaced_by: ' '\n" +
 	"                  dnsConfig:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    nameservers:\n" +
@@ -1080,7 +1179,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - \"\"\n" +
 	"                  optional_on_success: false\n" +
-	"                  # Reference is the name of a step reference.\n" +
+	"                  # Reference is the name of a step reference. The name can be pinned to\n" +
+	"                  # a version of the registry, like `ipi-install@v3`.\n" +
 	"                  ref: \"\"\n" +
 	"                  # Resources defines the resource requirements for the step.\n" +
 	"                  resources:\n" +
@@ -1095,6 +1195,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  timeout: 0s\n" +
 	"            # Workflow is the name of the workflow to be used for this configuration. For fields defined in both\n" +
 	"            # the config and the workflow, the fields from the config will override what is set in Workflow.\n" +
+	"            # The name can be pinned to a version of the registry, like `ipi-aws@v3`, in which case all\n" +
+	"            # steps and chains of the workflow are resolved from that version as well.\n" +
 	"            workflow: \"\"\n" +
 	"        # Timeout overrides maximum prowjob duration\n" +
 	"        timeout: 0s\n" +
@@ -1186,6 +1288,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"      always_run: false\n" +
 	"      # As is the name of the test.\n" +
 	"      as: ' '\n" +
+	"      # Capabilities are the capabilities that the cluster where the test runs must have,\n" +
+	"      # e.g. arm64 or vpn. The capabilities of each cluster are declared in the configuration\n" +
+	"      # of the prow-job-dispatcher.\n" +
+	"      capabilities:\n" +
+	"        - \"\"\n" +
 	"      # Cluster specifies the name of the cluster where the test runs.\n" +
 	"      cluster: ' '\n" +
 	"      # ClusterClaim claims an OpenShift cluster and exposes environment variable ${KUBECONFIG} to the test container\n" +
@@ -1254,6 +1361,20 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"        dependency_overrides:\n" +
 	"            \"\": \"\"\n" +
+	"        # Deprecated lists the deprecated registry components the test uses.\n" +
+	"        deprecated:\n" +
+	"            - # Message explains why the component is deprecated and how to migrate.\n" +
+	"              message: ' '\n" +
+	"              # Name is the name of the component.\n" +
+	"              name: ' '\n" +
+	"              # RemovalDate is the date after which the component may be removed from\n" +
+	"              # the registry, formatted as YYYY-MM-DD.\n" +
+	"              removal_date: ' '\n" +
+	"              # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"              # the deprecated one.\n" +
+	"              replaced_by: ' '\n" +
+	"              # Type is the type of the component: reference, chain or workflow.\n" +
+	"              type: ' '\n" +
 	"        # DnsConfig for step's Pod.\n" +
 	"        dnsConfig:\n" +
 	"            # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1331,6 +1452,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  env: ' '\n" +
 	"                  # Name is the tag or stream:tag that this dependency references\n" +
 	"                  name: ' '\n" +
+	"              # Deprecation marks a step in the registry as deprecated. It can only be\n" +
+	"              # set in the registry.\n" +
+	"              deprecation:\n" +
+	"                # Message explains why the component is deprecated and how to migrate.\n" +
+	"                message: ' '\n" +
+	"                # RemovalDate is the date after which the component may be removed from\n" +
+	"                # the registry, formatted as YYYY-MM-DD.\n" +
+	"                removal_date: ' '\n" +
+	"                # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                # the deprecated one.\n" +
+	"                replaced_by: ' '\n" +
 	"              # DnsConfig for step's Pod.\n" +
 	"              dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1421,6 +1553,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  env: ' '\n" +
 	"                  # Name is the tag or stream:tag that this dependency references\n" +
 	"                  name: ' '\n" +
+	"              # Deprecation marks a step in the registry as deprecated. It can only be\n" +
+	"              # set in the registry.\n" +
+	"              deprecation:\n" +
+	"                # Message explains why the component is deprecated and how to migrate.\n" +
+	"                message: ' '\n" +
+	"                # RemovalDate is the date after which the component may be removed from\n" +
+	"                # the registry, formatted as YYYY-MM-DD.\n" +
+	"                removal_date: ' '\n" +
+	"                # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                # the deprecated one.\n" +
+	"                replaced_by: ' '\n" +
 	"              # DnsConfig for step's Pod.\n" +
 	"              dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1482,6 +1625,14 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"              run_as_script: false\n" +
 	"              # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"              timeout: 0s\n" +
+	"        # SharedDirStorage configures a volume for the files in SHARED_DIR that do\n" +
+	"        # not fit in the secret that holds it.\n" +
+	"        shared_dir_storage:\n" +
+	"            # Size is the size of the volume, like `5Gi`.\n" +
+	"            size: ' '\n" +
+	"            # StorageClassName is the storage class of the volume. The default storage\n" +
+	"            # class of the cluster is used when unset.\n" +
+	"            storage_class_name: ' '\n" +
 	"        # Test is the array of test steps that define the actual test.\n" +
 	"        test:\n" +
 	"            - # As is the name of the LiteralTestStep.\n" +
@@ -1511,6 +1662,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  env: ' '\n" +
 	"                  # Name is the tag or stream:tag that this dependency references\n" +
 	"                  name: ' '\n" +
+	"              # Deprecation marks a step in the registry as deprecated. It can only be\n" +
+	"              # set in the registry.\n" +
+	"              deprecation:\n" +
+	"                # Message explains why the component is deprecated and how to migrate.\n" +
+	"                message: ' '\n" +
+	"                # RemovalDate is the date after which the component may be removed from\n" +
+	"                # the registry, formatted as YYYY-MM-DD.\n" +
+	"                removal_date: ' '\n" +
+	"                # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"                # the deprecated one.\n" +
+	"                replaced_by: ' '\n" +
 	"              # DnsConfig for step's Pod.\n" +
 	"              dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1657,6 +1819,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"        dependency_overrides:\n" +
 	"            \"\": \"\"\n" +
+	"        # Deprecation marks a workflow in the registry as deprecated. It can only\n" +
+	"        # be set in the registry.\n" +
+	"        deprecation:\n" +
+	"            # Message explains why the component is deprecated and how to migrate.\n" +
+	"            message: ' '\n" +
+	"            # RemovalDate is the date after which the component may be removed from\n" +
+	"            # the registry, formatted as YYYY-MM-DD.\n" +
+	"            removal_date: ' '\n" +
+	"            # ReplacedBy is the name of the component of the same type that replaces\n" +
+	"            # the deprecated one.\n" +
+	"            replaced_by: ' '\n" +
 	"        # DnsConfig for step's Pod.\n" +
 	"        dnsConfig:\n" +
 	"            # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1689,7 +1862,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # LiteralTestStep is a full test step definition.\n" +
 	"            - as: ' '\n" +
 	"              best_effort: false\n" +
-	"              # Chain is the name of a step chain reference.\n" +
+	"              # Chain is the name of a step chain reference. The name can be pinned to\n" +
+	"              # a version of the registry, like `ipi-aws-pre@v3`.\n" +
 	"              chain: \"\"\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
 	"              # will be injected into this step.\n" +
@@ -1704,6 +1878,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
 	"                  name: ' '\n" +
+	"              deprecation:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                message: ' '\n" +
+	"                removal_date: ' '\n" +
+	"                replaced_by: ' '\n" +
 	"              dnsConfig:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                nameservers:\n" +
@@ -1734,7 +1913,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - \"\"\n" +
 	"              optional_on_success: false\n" +
-	"              # Reference is the name of a step reference.\n" +
+	"              # Reference is the name of a step reference. The name can be pinned to\n" +
+	"              # a version of the registry, like `ipi-install@v3`.\n" +
 	"              ref: \"\"\n" +
 	"              # Resources defines the resource requirements for the step.\n" +
 	"              resources:\n" +
@@ -1752,7 +1932,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # LiteralTestStep is a full test step definition.\n" +
 	"            - as: ' '\n" +
 	"              best_effort: false\n" +
-	"              # Chain is the name of a step chain reference.\n" +
+	"              # Chain is the name of a step chain reference. The name can be pinned to\n" +
+	"              # a version of the registry, like `ipi-aws-pre@v3`.\n" +
 	"              chain: \"\"\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
 	"              # will be injected into this step.\n" +
@@ -1767,6 +1948,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
 	"                  name: ' '\n" +
+	"              deprecation:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                message: ' '\n" +
+	"                removal_date: ' '\n" +
+	"                replaced_by: ' '\n" +
 	"              dnsConfig:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                nameservers:\n" +
@@ -1797,7 +1983,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - \"\"\n" +
 	"              optional_on_success: false\n" +
-	"              # Reference is the name of a step reference.\n" +
+	"              # Reference is the name of a step reference. The name can be pinned to\n" +
+	"              # a version of the registry, like `ipi-install@v3`.\n" +
 	"              ref: \"\"\n" +
 	"              # Resources defines the resource requirements for the step.\n" +
 	"              resources:\n" +
@@ -1810,12 +1997,21 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    \"\": \"\"\n" +
 	"              run_as_script: false\n" +
 	"              timeout: 0s\n" +
+	"        # SharedDirStorage configures a volume for the files in SHARED_DIR that do\n" +
+	"        # not fit in the secret that holds it.\n" +
+	"        shared_dir_storage:\n" +
+	"            # Size is the size of the volume, like `5Gi`.\n" +
+	"            size: ' '\n" +
+	"            # StorageClassName is the storage class of the volume. The default storage\n" +
+	"            # class of the cluster is used when unset.\n" +
+	"            storage_class_name: ' '\n" +
 	"        # Test is the array of test steps that define the actual test.\n" +
 	"        test:\n" +
 	"            # LiteralTestStep is a full test step definition.\n" +
 	"            - as: ' '\n" +
 	"              best_effort: false\n" +
-	"              # Chain is the name of a step chain reference.\n" +
+	"              # Chain is the name of a step chain reference. The name can be pinned to\n" +
+	"              # a version of the registry, like `ipi-aws-pre@v3`.\n" +
 	"              chain: \"\"\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
 	"              # will be injected into this step.\n" +
@@ -1830,6 +2026,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
 	"                  name: ' '\n" +
+	"              deprecation:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                message: ' '\n" +
+	"                removal_date: ' '\n" +
+	"                replaced_by: ' '\n" +
 	"              dnsConfig:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                nameservers:\n" +
@@ -1860,7 +2061,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - \"\"\n" +
 	"              optional_on_success: false\n" +
-	"              # Reference is the name of a step reference.\n" +
+	"              # Reference is the name of a step reference. The name can be pinned to\n" +
+	"              # a version of the registry, like `ipi-install@v3`.\n" +
 	"              ref: \"\"\n" +
 	"              # Resources defines the resource requirements for the step.\n" +
 	"              resources:\n" +
@@ -1875,6 +2077,8 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"              timeout: 0s\n" +
 	"        # Workflow is the name of the workflow to be used for this configuration. For fields defined in both\n" +
 	"        # the config and the workflow, the fields from the config will override what is set in Workflow.\n" +
+	"        # The name can be pinned to a version of the registry, like `ipi-aws@v3`, in which case all\n" +
+	"        # steps and chains of the workflow are resolved from that version as well.\n" +
 	"        workflow: \"\"\n" +
 	"      # Timeout overrides maximum prowjob duration\n" +
 	"      timeout: 0s\n" +